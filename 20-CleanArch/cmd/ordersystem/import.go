@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/bulk"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+)
+
+// runImport implements `ordersystem import [-format csv|ndjson]
+// [-subject s] [-batch n] [file]`, which creates the orders of file, or of
+// stdin, as the admin subject s. It prints an NDJSON line per row and the
+// summary, and fails if any row failed. The format defaults to the file's
+// extension, or NDJSON.
+func runImport(ctx context.Context, createOrder *usecase.CreateOrderUseCase, batchSize int, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatName := flags.String("format", "", "csv or ndjson")
+	subject := flags.String("subject", "import", "subject recorded as the creator of the orders")
+	flags.IntVar(&batchSize, "batch", batchSize, "orders created at a time")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+		if *formatName == "" {
+			*formatName = strings.TrimPrefix(filepath.Ext(path), ".")
+		}
+	}
+	format, err := bulk.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	rows, err := bulk.NewReader(format, input)
+	if err != nil {
+		return err
+	}
+
+	// Whoever can run the binary can reach the database, so the import
+	// acts as an admin.
+	ctx = auth.WithPrincipal(ctx, auth.Principal{Subject: *subject, Roles: []string{usecase.RoleAdmin}})
+	encoder := json.NewEncoder(os.Stdout)
+	summary, err := usecase.NewImportOrdersUseCase(createOrder, batchSize).Execute(ctx, rows, func(result usecase.ImportRowResultDTO) error {
+		return encoder.Encode(result)
+	})
+	if encodeErr := encoder.Encode(struct {
+		Summary usecase.ImportSummaryDTO `json:"summary"`
+	}{summary}); encodeErr != nil && err == nil {
+		err = encodeErr
+	}
+	if err != nil {
+		return err
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d row(s) failed", summary.Failed, summary.Rows)
+	}
+	return nil
+}
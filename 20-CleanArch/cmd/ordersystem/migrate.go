@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database/migration"
+	"golang.org/x/exp/slog"
+)
+
+// runMigrate implements `ordersystem migrate [up|down [steps]|status]`.
+func runMigrate(db *sql.DB, driver string, args []string) error {
+	if db == nil {
+		return errors.New("the memory driver has no schema to migrate")
+	}
+	migrator, err := migration.NewMigrator(db, driver)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Applied", applied, "migration(s)")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if args[1] == "all" {
+				steps = math.MaxInt
+			} else if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Reverted", reverted, "migration(s)")
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+	return nil
+}
+
+// migrateOnStartup applies pending migrations before the servers start.
+func migrateOnStartup(db *sql.DB, driver string) error {
+	migrator, err := migration.NewMigrator(db, driver)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	// Logged rather than printed, as stdout belongs to subcommands such as
+	// import.
+	slog.Info("applied migrations", "count", applied)
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// taxRuleFile is a rule of the TAX_RULES_FILE document:
+//
+//	{"rules": [{"name": "VAT", "category": "food", "region": "", "rate": 5, "compound": false}]}
+type taxRuleFile struct {
+	Name     string  `json:"name"`
+	Category string  `json:"category"`
+	Region   string  `json:"region"`
+	Rate     float64 `json:"rate"`
+	Compound bool    `json:"compound"`
+}
+
+// loadTaxEngine builds the tax engine from the rules in path. Without a
+// path there is no engine and orders keep the tax their clients send.
+func loadTaxEngine(path string, pricesIncludeTax bool) (*entity.TaxEngine, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var document struct {
+		Rules []taxRuleFile `json:"rules"`
+	}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("reading tax rules from %s: %w", path, err)
+	}
+	rules := make([]entity.TaxRule, len(document.Rules))
+	for i, rule := range document.Rules {
+		rules[i] = entity.TaxRule{
+			Name:     rule.Name,
+			Category: rule.Category,
+			Region:   rule.Region,
+			Rate:     rule.Rate,
+			Compound: rule.Compound,
+		}
+	}
+	return entity.NewTaxEngine(rules, pricesIncludeTax)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+)
+
+// runToken implements `ordersystem token [-subject s] [-scope "a b"]
+// [-role r] [-ttl d]`, which prints a bearer token signed with
+// AUTH_HMAC_SECRET for trying the APIs locally.
+func runToken(config auth.Config, args []string) error {
+	flags := flag.NewFlagSet("token", flag.ContinueOnError)
+	subject := flags.String("subject", "developer", "token subject")
+	scope := flags.String("scope", "orders:read orders:write", "space separated scopes")
+	role := flags.String("role", "", "comma separated roles")
+	ttl := flags.Duration("ttl", time.Hour, "token lifetime")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	principal := auth.Principal{Subject: *subject, Scopes: strings.Fields(*scope)}
+	if *role != "" {
+		principal.Roles = strings.Split(*role, ",")
+	}
+	token, err := auth.IssueHMACToken(config, principal, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
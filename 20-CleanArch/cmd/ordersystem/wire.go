@@ -4,56 +4,119 @@
 package main
 
 import (
-	"database/sql"
-
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
-	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
-	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/service"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/web"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
 	"github.com/google/wire"
 )
 
-var setOrderRepositoryDependency = wire.NewSet(
-	database.NewOrderRepository,
-	wire.Bind(new(entity.OrderRepositoryInterface), new(*database.OrderRepository)),
-)
+// eventHandlers declares which handlers run for each event the orders
+// raise. Adding an event type or a reaction to one only touches this table.
+// The broadcaster feeds the GraphQL subscriptions, the report handler the
+// reporting projection and the webhook handler the partners' webhooks.
+func eventHandlers(publisher events.PublisherInterface, broadcaster *events.Broadcaster, orderReportRepository entity.OrderReportRepositoryInterface, webhookRepository entity.WebhookRepositoryInterface, webhookWorker usecase.WebhookWorkerInterface) map[string][]events.EventHandlerInterface {
+	publish := handler.NewPublishEventHandler(publisher)
+	report := handler.NewOrderReportHandler(usecase.NewProjectOrderReportUseCase(orderReportRepository))
+	webhook := handler.NewWebhookHandler(usecase.NewEnqueueWebhookDeliveriesUseCase(webhookRepository), webhookWorker)
+	return map[string][]events.EventHandlerInterface{
+		entity.OrderCreatedEvent:   {publish, broadcaster, report, webhook},
+		entity.OrderUpdatedEvent:   {publish, report, webhook},
+		entity.OrderPaidEvent:      {publish, broadcaster, report, webhook},
+		entity.OrderCancelledEvent: {publish, broadcaster, report, webhook},
+		entity.OrderRefundedEvent:  {publish, broadcaster, report, webhook},
+	}
+}
 
-var setEventDispatcherDependency = wire.NewSet(
-	events.NewEventDispatcher,
-	event.NewOrderCreated,
-	wire.Bind(new(events.EventInterface), new(*event.OrderCreated)),
-	wire.Bind(new(events.EventDispatcherInterface), new(*events.EventDispatcher)),
-)
+func newRegisteredEventDispatcher(handlers map[string][]events.EventHandlerInterface, opts []events.Option) (*events.EventDispatcher, error) {
+	eventDispatcher := events.NewEventDispatcher(opts...)
+	for eventName, registered := range handlers {
+		for _, eventHandler := range registered {
+			if err := eventDispatcher.Register(eventName, eventHandler); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return eventDispatcher, nil
+}
 
-var setOrderCreatedEvent = wire.NewSet(
-	event.NewOrderCreated,
-	wire.Bind(new(events.EventInterface), new(*event.OrderCreated)),
-)
+func NewEventDispatcher(publisher events.PublisherInterface, broadcaster *events.Broadcaster, orderReportRepository entity.OrderReportRepositoryInterface, webhookRepository entity.WebhookRepositoryInterface, webhookWorker usecase.WebhookWorkerInterface, opts []events.Option) (*events.EventDispatcher, error) {
+	wire.Build(
+		eventHandlers,
+		newRegisteredEventDispatcher,
+	)
+	return &events.EventDispatcher{}, nil
+}
 
-func NewCreateOrderUseCase(db *sql.DB, eventDispatcher events.EventDispatcherInterface) *usecase.CreateOrderUseCase {
+func NewCreateOrderUseCase(orderRepository entity.OrderRepositoryInterface, idempotencyRepository entity.IdempotencyRepositoryInterface, eventDispatcher events.EventDispatcherInterface, taxEngine *entity.TaxEngine, couponRepository entity.CouponRepositoryInterface) *usecase.CreateOrderUseCase {
 	wire.Build(
-		setOrderRepositoryDependency,
-		setOrderCreatedEvent,
 		usecase.NewCreateOrderUseCase,
 	)
 	return &usecase.CreateOrderUseCase{}
 }
 
-func NewWebOrderHandler(db *sql.DB, eventDispatcher events.EventDispatcherInterface) *web.WebOrderHandler {
+func NewWebOrderHandler(orderRepository entity.OrderRepositoryInterface, idempotencyRepository entity.IdempotencyRepositoryInterface, eventDispatcher events.EventDispatcherInterface, taxEngine *entity.TaxEngine, couponRepository entity.CouponRepositoryInterface, importBatchSize int) *web.WebOrderHandler {
 	wire.Build(
-		setOrderRepositoryDependency,
-		setOrderCreatedEvent,
 		web.NewWebOrderHandler,
 	)
 	return &web.WebOrderHandler{}
 }
 
-func NewListOrdersUseCase(db *sql.DB) *usecase.ListOrdersUseCase {
+func NewWebCouponHandler(couponRepository entity.CouponRepositoryInterface) *web.WebCouponHandler {
+	wire.Build(
+		web.NewWebCouponHandler,
+	)
+	return &web.WebCouponHandler{}
+}
+
+func NewWebReportHandler(orderReportRepository entity.OrderReportRepositoryInterface) *web.WebReportHandler {
+	wire.Build(
+		web.NewWebReportHandler,
+	)
+	return &web.WebReportHandler{}
+}
+
+func NewWebWebhookHandler(webhookRepository entity.WebhookRepositoryInterface, webhookWorker usecase.WebhookWorkerInterface) *web.WebWebhookHandler {
+	wire.Build(
+		web.NewWebWebhookHandler,
+	)
+	return &web.WebWebhookHandler{}
+}
+
+func NewGetOrderReportUseCase(orderReportRepository entity.OrderReportRepositoryInterface) *usecase.GetOrderReportUseCase {
+	wire.Build(
+		usecase.NewGetOrderReportUseCase,
+	)
+	return &usecase.GetOrderReportUseCase{}
+}
+
+func NewListOrdersUseCase(orderRepository entity.OrderRepositoryInterface) *usecase.ListOrdersUseCase {
 	wire.Build(
-		setOrderRepositoryDependency,
 		usecase.NewListOrdersUseCase,
 	)
 	return &usecase.ListOrdersUseCase{}
 }
+
+func NewFindOrderInvoicesUseCase(invoiceRepository entity.InvoiceRepositoryInterface) *usecase.FindOrderInvoicesUseCase {
+	wire.Build(
+		usecase.NewFindOrderInvoicesUseCase,
+	)
+	return &usecase.FindOrderInvoicesUseCase{}
+}
+
+func NewOrderService(orderRepository entity.OrderRepositoryInterface, idempotencyRepository entity.IdempotencyRepositoryInterface, eventDispatcher events.EventDispatcherInterface, orderEvents *events.Broadcaster, taxEngine *entity.TaxEngine, couponRepository entity.CouponRepositoryInterface, orderReportRepository entity.OrderReportRepositoryInterface) *service.OrderService {
+	wire.Build(
+		usecase.NewCreateOrderUseCase,
+		usecase.NewListOrdersUseCase,
+		usecase.NewStreamOrdersUseCase,
+		usecase.NewUpdateOrderUseCase,
+		usecase.NewPayOrderUseCase,
+		usecase.NewCancelOrderUseCase,
+		usecase.NewRefundOrderUseCase,
+		usecase.NewGetOrderReportUseCase,
+		service.NewOrderService,
+	)
+	return &service.OrderService{}
+}
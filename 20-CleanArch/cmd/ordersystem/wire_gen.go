@@ -7,46 +7,99 @@
 package main
 
 import (
-	"database/sql"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
-	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
-	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/service"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/web"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
-	"github.com/google/wire"
-)
-
-import (
-	_ "github.com/go-sql-driver/mysql"
 )
 
 // Injectors from wire.go:
 
-func NewCreateOrderUseCase(db *sql.DB, eventDispatcher events.EventDispatcherInterface) *usecase.CreateOrderUseCase {
-	orderRepository := database.NewOrderRepository(db)
-	orderCreated := event.NewOrderCreated()
-	createOrderUseCase := usecase.NewCreateOrderUseCase(orderRepository, orderCreated, eventDispatcher)
+func NewEventDispatcher(publisher events.PublisherInterface, broadcaster *events.Broadcaster, orderReportRepository entity.OrderReportRepositoryInterface, webhookRepository entity.WebhookRepositoryInterface, webhookWorker usecase.WebhookWorkerInterface, opts []events.Option) (*events.EventDispatcher, error) {
+	v := eventHandlers(publisher, broadcaster, orderReportRepository, webhookRepository, webhookWorker)
+	eventDispatcher, err := newRegisteredEventDispatcher(v, opts)
+	if err != nil {
+		return nil, err
+	}
+	return eventDispatcher, nil
+}
+
+func NewCreateOrderUseCase(orderRepository entity.OrderRepositoryInterface, idempotencyRepository entity.IdempotencyRepositoryInterface, eventDispatcher events.EventDispatcherInterface, taxEngine *entity.TaxEngine, couponRepository entity.CouponRepositoryInterface) *usecase.CreateOrderUseCase {
+	createOrderUseCase := usecase.NewCreateOrderUseCase(orderRepository, idempotencyRepository, eventDispatcher, taxEngine, couponRepository)
 	return createOrderUseCase
 }
 
-func NewWebOrderHandler(db *sql.DB, eventDispatcher events.EventDispatcherInterface) *web.WebOrderHandler {
-	orderRepository := database.NewOrderRepository(db)
-	orderCreated := event.NewOrderCreated()
-	webOrderHandler := web.NewWebOrderHandler(eventDispatcher, orderRepository, orderCreated)
+func NewWebOrderHandler(orderRepository entity.OrderRepositoryInterface, idempotencyRepository entity.IdempotencyRepositoryInterface, eventDispatcher events.EventDispatcherInterface, taxEngine *entity.TaxEngine, couponRepository entity.CouponRepositoryInterface, importBatchSize int) *web.WebOrderHandler {
+	webOrderHandler := web.NewWebOrderHandler(eventDispatcher, orderRepository, idempotencyRepository, taxEngine, couponRepository, importBatchSize)
 	return webOrderHandler
 }
 
-func NewListOrdersUseCase(db *sql.DB) *usecase.ListOrdersUseCase {
-	orderRepository := database.NewOrderRepository(db)
+func NewWebCouponHandler(couponRepository entity.CouponRepositoryInterface) *web.WebCouponHandler {
+	webCouponHandler := web.NewWebCouponHandler(couponRepository)
+	return webCouponHandler
+}
+
+func NewWebReportHandler(orderReportRepository entity.OrderReportRepositoryInterface) *web.WebReportHandler {
+	webReportHandler := web.NewWebReportHandler(orderReportRepository)
+	return webReportHandler
+}
+
+func NewWebWebhookHandler(webhookRepository entity.WebhookRepositoryInterface, webhookWorker usecase.WebhookWorkerInterface) *web.WebWebhookHandler {
+	webWebhookHandler := web.NewWebWebhookHandler(webhookRepository, webhookWorker)
+	return webWebhookHandler
+}
+
+func NewGetOrderReportUseCase(orderReportRepository entity.OrderReportRepositoryInterface) *usecase.GetOrderReportUseCase {
+	getOrderReportUseCase := usecase.NewGetOrderReportUseCase(orderReportRepository)
+	return getOrderReportUseCase
+}
+
+func NewListOrdersUseCase(orderRepository entity.OrderRepositoryInterface) *usecase.ListOrdersUseCase {
 	listOrdersUseCase := usecase.NewListOrdersUseCase(orderRepository)
 	return listOrdersUseCase
 }
 
-// wire.go:
+func NewFindOrderInvoicesUseCase(invoiceRepository entity.InvoiceRepositoryInterface) *usecase.FindOrderInvoicesUseCase {
+	findOrderInvoicesUseCase := usecase.NewFindOrderInvoicesUseCase(invoiceRepository)
+	return findOrderInvoicesUseCase
+}
+
+func NewOrderService(orderRepository entity.OrderRepositoryInterface, idempotencyRepository entity.IdempotencyRepositoryInterface, eventDispatcher events.EventDispatcherInterface, orderEvents *events.Broadcaster, taxEngine *entity.TaxEngine, couponRepository entity.CouponRepositoryInterface, orderReportRepository entity.OrderReportRepositoryInterface) *service.OrderService {
+	createOrderUseCase := usecase.NewCreateOrderUseCase(orderRepository, idempotencyRepository, eventDispatcher, taxEngine, couponRepository)
+	listOrdersUseCase := usecase.NewListOrdersUseCase(orderRepository)
+	streamOrdersUseCase := usecase.NewStreamOrdersUseCase(orderRepository, orderEvents)
+	updateOrderUseCase := usecase.NewUpdateOrderUseCase(orderRepository, eventDispatcher, taxEngine, couponRepository)
+	payOrderUseCase := usecase.NewPayOrderUseCase(orderRepository, eventDispatcher)
+	cancelOrderUseCase := usecase.NewCancelOrderUseCase(orderRepository, eventDispatcher)
+	refundOrderUseCase := usecase.NewRefundOrderUseCase(orderRepository, eventDispatcher)
+	getOrderReportUseCase := usecase.NewGetOrderReportUseCase(orderReportRepository)
+	orderService := service.NewOrderService(createOrderUseCase, listOrdersUseCase, streamOrdersUseCase, updateOrderUseCase, payOrderUseCase, cancelOrderUseCase, refundOrderUseCase, getOrderReportUseCase)
+	return orderService
+}
 
-var setOrderRepositoryDependency = wire.NewSet(database.NewOrderRepository, wire.Bind(new(entity.OrderRepositoryInterface), new(*database.OrderRepository)))
+// wire.go:
 
-var setEventDispatcherDependency = wire.NewSet(events.NewEventDispatcher, event.NewOrderCreated, wire.Bind(new(events.EventInterface), new(*event.OrderCreated)), wire.Bind(new(events.EventDispatcherInterface), new(*events.EventDispatcher)))
+// eventHandlers declares which handlers run for each event the orders
+// raise. Adding an event type or a reaction to one only touches this table.
+// The broadcaster feeds the GraphQL subscriptions, the report handler the
+// reporting projection and the webhook handler the partners' webhooks.
+func eventHandlers(publisher events.PublisherInterface, broadcaster *events.Broadcaster, orderReportRepository entity.OrderReportRepositoryInterface, webhookRepository entity.WebhookRepositoryInterface, webhookWorker usecase.WebhookWorkerInterface) map[string][]events.EventHandlerInterface {
+	publish := handler.NewPublishEventHandler(publisher)
+	report := handler.NewOrderReportHandler(usecase.NewProjectOrderReportUseCase(orderReportRepository))
+	webhook := handler.NewWebhookHandler(usecase.NewEnqueueWebhookDeliveriesUseCase(webhookRepository), webhookWorker)
+	return map[string][]events.EventHandlerInterface{entity.OrderCreatedEvent: {publish, broadcaster, report, webhook}, entity.OrderUpdatedEvent: {publish, report, webhook}, entity.OrderPaidEvent: {publish, broadcaster, report, webhook}, entity.OrderCancelledEvent: {publish, broadcaster, report, webhook}, entity.OrderRefundedEvent: {publish, broadcaster, report, webhook}}
+}
 
-var setOrderCreatedEvent = wire.NewSet(event.NewOrderCreated, wire.Bind(new(events.EventInterface), new(*event.OrderCreated)))
+func newRegisteredEventDispatcher(handlers map[string][]events.EventHandlerInterface, opts []events.Option) (*events.EventDispatcher, error) {
+	eventDispatcher := events.NewEventDispatcher(opts...)
+	for eventName, registered := range handlers {
+		for _, eventHandler := range registered {
+			if err := eventDispatcher.Register(eventName, eventHandler); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return eventDispatcher, nil
+}
@@ -0,0 +1,85 @@
+// Command orderworker consumes the order events published by ordersystem and
+// issues an invoice for every created order.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/configs"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/messaging"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/telemetry"
+	"golang.org/x/exp/slog"
+)
+
+func main() {
+	configs, err := configs.LoadConfig(".")
+	if err != nil {
+		panic(err)
+	}
+	logger, err := telemetry.NewLogger(os.Stderr, configs.LogLevel, configs.LogFormat)
+	if err != nil {
+		panic(err)
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTelemetry, err := telemetry.Setup(ctx, telemetry.Config{
+		ServiceName:     configs.OTelServiceName,
+		TracesExporter:  configs.OTelTracesExporter,
+		MetricsExporter: configs.OTelMetricsExporter,
+		OTLPEndpoint:    configs.OTelOTLPEndpoint,
+		OTLPInsecure:    configs.OTelOTLPInsecure,
+		SampleRatio:     configs.OTelSampleRatio,
+		MetricInterval:  configs.OTelMetricInterval,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), configs.ShutdownTimeout)
+		defer cancel()
+		shutdownTelemetry(ctx)
+	}()
+
+	var db *sql.DB
+	if configs.DBDriver != database.DriverMemory {
+		db, err = database.Open(configs.DBDriver, configs.DataSourceName())
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+	}
+	invoiceRepository, err := database.NewInvoiceRepositoryForDriver(configs.DBDriver, db)
+	if err != nil {
+		panic(err)
+	}
+
+	transport, err := messaging.NewTransport(messaging.TransportConfig{
+		Transport:    configs.EventTransport,
+		RabbitMQURL:  configs.RabbitMQURL,
+		NATSURL:      configs.NATSURL,
+		KafkaBrokers: configs.KafkaBrokerList(),
+		Group:        configs.OrderWorkerQueue,
+		Prefetch:     configs.OrderWorkerPrefetch,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer transport.Close()
+
+	handler := messaging.NewInvoiceOnOrderCreatedHandler(usecase.NewGenerateInvoiceUseCase(invoiceRepository))
+
+	slog.Info("consuming events", "topic", messaging.OrderCreatedTopic, "transport", configs.EventTransport)
+	if err := messaging.Subscribe(ctx, transport.Subscriber, messaging.OrderCreatedTopic, handler); err != nil {
+		panic(err)
+	}
+	slog.Info("order worker stopped")
+}
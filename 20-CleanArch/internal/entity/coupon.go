@@ -0,0 +1,150 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCoupon       = errors.New("invalid coupon")
+	ErrCouponAlreadyExists = errors.New("coupon already exists")
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponInactive      = errors.New("coupon is not active")
+	ErrCouponExhausted     = errors.New("coupon has no redemptions left")
+	ErrCouponLimitReached  = errors.New("coupon redemption limit reached for this customer")
+	ErrCouponNotRedeemed   = errors.New("coupon was not redeemed for this order")
+)
+
+type DiscountType string
+
+const (
+	DiscountPercentage DiscountType = "percentage"
+	DiscountFixed      DiscountType = "fixed"
+)
+
+func (t DiscountType) IsValid() bool {
+	return t == DiscountPercentage || t == DiscountFixed
+}
+
+// Coupon takes Value percent, or Value off, the price of an order before
+// tax. A zero ValidFrom or ValidUntil leaves that end of the validity window
+// open, and a zero cap means no cap.
+type Coupon struct {
+	Code       string
+	Type       DiscountType
+	Value      float64
+	ValidFrom  time.Time
+	ValidUntil time.Time
+	// MaxRedemptions caps the redemptions of the coupon overall, and
+	// MaxRedemptionsPerCustomer those by the same customer.
+	MaxRedemptions            int
+	MaxRedemptionsPerCustomer int
+	Redemptions               int
+	Active                    bool
+	CreatedAt                 time.Time
+}
+
+func NewCoupon(code string, discountType DiscountType, value float64, validFrom, validUntil time.Time, maxRedemptions, maxRedemptionsPerCustomer int) (*Coupon, error) {
+	coupon := &Coupon{
+		Code:                      NormalizeCouponCode(code),
+		Type:                      discountType,
+		Value:                     value,
+		ValidFrom:                 validFrom.UTC(),
+		ValidUntil:                validUntil.UTC(),
+		MaxRedemptions:            maxRedemptions,
+		MaxRedemptionsPerCustomer: maxRedemptionsPerCustomer,
+		Active:                    true,
+		CreatedAt:                 now(),
+	}
+	err := coupon.IsValid()
+	if err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// NormalizeCouponCode makes codes case-insensitive, the way customers type
+// them.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (c *Coupon) IsValid() error {
+	if c.Code == "" {
+		return fmt.Errorf("%w: missing code", ErrInvalidCoupon)
+	}
+	if !c.Type.IsValid() {
+		return fmt.Errorf("%w: unknown discount type", ErrInvalidCoupon)
+	}
+	if c.Value <= 0 || (c.Type == DiscountPercentage && c.Value > 100) {
+		return fmt.Errorf("%w: discount value out of range", ErrInvalidCoupon)
+	}
+	if !c.ValidFrom.IsZero() && !c.ValidUntil.IsZero() && !c.ValidFrom.Before(c.ValidUntil) {
+		return fmt.Errorf("%w: validity window ends before it starts", ErrInvalidCoupon)
+	}
+	if c.MaxRedemptions < 0 || c.MaxRedemptionsPerCustomer < 0 {
+		return fmt.Errorf("%w: negative redemption cap", ErrInvalidCoupon)
+	}
+	return nil
+}
+
+// CanRedeem tells whether the coupon may be redeemed at at, leaving the
+// per-customer limit, which needs the customer's redemptions, to the
+// repository.
+func (c *Coupon) CanRedeem(at time.Time) error {
+	if !c.Active {
+		return ErrCouponInactive
+	}
+	if (!c.ValidFrom.IsZero() && at.Before(c.ValidFrom)) || (!c.ValidUntil.IsZero() && !at.Before(c.ValidUntil)) {
+		return ErrCouponInactive
+	}
+	if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+		return ErrCouponExhausted
+	}
+	return nil
+}
+
+// Discount is the amount the coupon takes off price, rounded to the cent
+// and never more than price itself.
+func (c *Coupon) Discount(price float64) float64 {
+	discount := c.Value
+	if c.Type == DiscountPercentage {
+		discount = price * c.Value / 100
+	}
+	return math.Min(roundCents(discount), price)
+}
+
+// Apply takes the discount off price, returning what remains and the
+// discount.
+func (c *Coupon) Apply(price float64) (float64, float64) {
+	discount := c.Discount(price)
+	return roundCents(price - discount), discount
+}
+
+// Deactivate stops further redemptions; existing orders keep their
+// discount.
+func (c *Coupon) Deactivate() {
+	c.Active = false
+}
+
+// CouponRedemption records a coupon applied to an order by a customer.
+type CouponRedemption struct {
+	Code       string
+	OrderID    string
+	Customer   string
+	Discount   float64
+	RedeemedAt time.Time
+}
+
+func NewCouponRedemption(code, orderID, customer string, discount float64) *CouponRedemption {
+	return &CouponRedemption{
+		Code:       NormalizeCouponCode(code),
+		OrderID:    orderID,
+		Customer:   customer,
+		Discount:   discount,
+		RedeemedAt: now(),
+	}
+}
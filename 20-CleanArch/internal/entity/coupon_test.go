@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAnInvalidCoupon_WhenCreate_ThenShouldReceiveAnError(t *testing.T) {
+	validFrom := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	_, err := NewCoupon(" ", DiscountFixed, 5, time.Time{}, time.Time{}, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+	_, err = NewCoupon("SUMMER", "bogo", 5, time.Time{}, time.Time{}, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+	_, err = NewCoupon("SUMMER", DiscountPercentage, 101, time.Time{}, time.Time{}, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+	_, err = NewCoupon("SUMMER", DiscountFixed, 0, time.Time{}, time.Time{}, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+	_, err = NewCoupon("SUMMER", DiscountFixed, 5, validFrom, validFrom, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+	_, err = NewCoupon("SUMMER", DiscountFixed, 5, time.Time{}, time.Time{}, -1, 0)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+}
+
+func TestGivenAValidCoupon_WhenCreate_ThenShouldNormalizeItsCode(t *testing.T) {
+	coupon, err := NewCoupon(" summer10 ", DiscountPercentage, 10, time.Time{}, time.Time{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "SUMMER10", coupon.Code)
+	assert.True(t, coupon.Active)
+}
+
+func TestGivenACoupon_WhenDiscount_ThenShouldRoundToTheCentAndNotExceedThePrice(t *testing.T) {
+	percentage, err := NewCoupon("TEN", DiscountPercentage, 10, time.Time{}, time.Time{}, 0, 0)
+	assert.NoError(t, err)
+	fixed, err := NewCoupon("FIVE", DiscountFixed, 5, time.Time{}, time.Time{}, 0, 0)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1.23, percentage.Discount(12.34))
+	assert.Equal(t, 5.0, fixed.Discount(12.34))
+	assert.Equal(t, 3.0, fixed.Discount(3))
+
+	price, discount := percentage.Apply(12.34)
+	assert.Equal(t, 11.11, price)
+	assert.Equal(t, 1.23, discount)
+}
+
+func TestGivenACoupon_WhenCanRedeem_ThenShouldCheckItsWindowAndCap(t *testing.T) {
+	validFrom := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	validUntil := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	coupon, err := NewCoupon("SUMMER", DiscountFixed, 5, validFrom, validUntil, 1, 0)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, coupon.CanRedeem(validFrom.Add(-time.Second)), ErrCouponInactive)
+	assert.NoError(t, coupon.CanRedeem(validFrom))
+	assert.ErrorIs(t, coupon.CanRedeem(validUntil), ErrCouponInactive)
+
+	coupon.Redemptions = 1
+	assert.ErrorIs(t, coupon.CanRedeem(validFrom), ErrCouponExhausted)
+
+	coupon.Redemptions = 0
+	coupon.Deactivate()
+	assert.ErrorIs(t, coupon.CanRedeem(validFrom), ErrCouponInactive)
+}
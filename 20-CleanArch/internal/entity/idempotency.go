@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	errInvalidIdempotencyKey    = errors.New("invalid idempotency key")
+)
+
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyLease is how long a request holds the key it runs under. A
+// retry of the same request after the lease ran out takes the key over, as
+// the request holding it is taken to have died.
+const IdempotencyLease = time.Minute
+
+// IdempotencyRecord remembers the request a client sent under an idempotency
+// key and, once it completed, the response to replay on retries. Response is
+// nil while the original request is still running. ExpiresAt is set by the
+// repository from its configured retention.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Response    []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	// LockedUntil ends the lease of the request running under the key.
+	LockedUntil time.Time
+	// Attempts counts the requests that held the key. Above one, an earlier
+	// request lost its lease and may have done its work without completing
+	// the record.
+	Attempts int
+}
+
+func NewIdempotencyRecord(key, requestHash string) (*IdempotencyRecord, error) {
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return nil, errInvalidIdempotencyKey
+	}
+	createdAt := now()
+	return &IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   createdAt,
+		LockedUntil: createdAt.Add(IdempotencyLease),
+		Attempts:    1,
+	}, nil
+}
+
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.Response != nil
+}
+
+// CanTakeOver tells whether request, a retry under the same key, may take
+// the key over at at: the record is still pending, its lease has run out and
+// it holds the same request.
+func (r *IdempotencyRecord) CanTakeOver(request *IdempotencyRecord, at time.Time) bool {
+	return !r.IsCompleted() && !at.Before(r.LockedUntil) && r.RequestHash == request.RequestHash
+}
+
+func (r *IdempotencyRecord) IsExpired(at time.Time) bool {
+	return !at.Before(r.ExpiresAt)
+}
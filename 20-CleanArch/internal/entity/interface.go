@@ -1,6 +1,93 @@
 package entity
 
+import (
+	"context"
+	"time"
+)
+
 type OrderRepositoryInterface interface {
-	Save(order *Order) error
-	GetOrders(listOrders *ListOrders) ([]Order, error)
+	Save(ctx context.Context, order *Order) error
+	GetOrders(ctx context.Context, listOrders *ListOrders) (*OrdersPage, error)
+	FindByID(ctx context.Context, id string) (*Order, error)
+	// Update stores order only if its persisted status is still
+	// previousStatus, returning ErrOrderModified otherwise and
+	// ErrOrderNotFound when there is no such order.
+	Update(ctx context.Context, order *Order, previousStatus OrderStatus) error
+}
+
+type IdempotencyRepositoryInterface interface {
+	// Reserve stores record unless a live record already holds its key, in
+	// which case that record is returned and nothing is written. A pending
+	// record whose lease ran out is taken over by the same request instead,
+	// counting the attempt in record.Attempts.
+	Reserve(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, response []byte) error
+	Release(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type InvoiceRepositoryInterface interface {
+	// Save returns ErrInvoiceAlreadyExists when the order was already
+	// invoiced.
+	Save(ctx context.Context, invoice *Invoice) error
+	FindByOrderID(ctx context.Context, orderID string) (*Invoice, error)
+	// FindByOrderIDs returns the invoices issued for any of orderIDs, in no
+	// particular order; orders not invoiced yet are left out.
+	FindByOrderIDs(ctx context.Context, orderIDs []string) ([]*Invoice, error)
+}
+
+type CouponRepositoryInterface interface {
+	// Save returns ErrCouponAlreadyExists when the code is taken.
+	Save(ctx context.Context, coupon *Coupon) error
+	FindByCode(ctx context.Context, code string) (*Coupon, error)
+	// List returns every coupon, oldest first.
+	List(ctx context.Context) ([]*Coupon, error)
+	SetActive(ctx context.Context, code string, active bool) error
+	// Redeem records redemption if the coupon can be redeemed at its
+	// RedeemedAt and its customer is under the per-customer limit.
+	// Concurrent redemptions never take a coupon past either cap.
+	Redeem(ctx context.Context, redemption *CouponRedemption) error
+	// Release undoes the redemption of code for orderID, returning
+	// ErrCouponNotRedeemed when there is none.
+	Release(ctx context.Context, code string, orderID string) error
+}
+
+type OrderReportRepositoryInterface interface {
+	// Project applies the event eventID to the reports of orderID, once:
+	// an event already applied is skipped. project gets what the reports
+	// know of the order, nil before its creation, and returns what they
+	// should know after the event and what it adds to the stats of a day.
+	// Events of the same order are projected one at a time.
+	Project(ctx context.Context, eventID string, orderID string, project func(order *ReportOrder) (*ReportOrder, OrderStats, error)) error
+	// Daily returns the stats of the days in [from, to) on which anything
+	// happened, oldest first.
+	Daily(ctx context.Context, from, to time.Time) ([]OrderStats, error)
+}
+
+type WebhookRepositoryInterface interface {
+	SaveSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	// UpdateSubscription returns ErrWebhookNotFound when there is no such
+	// subscription.
+	UpdateSubscription(ctx context.Context, subscription *WebhookSubscription) error
+	// DeleteSubscription deletes the subscription and its deliveries.
+	DeleteSubscription(ctx context.Context, id string) error
+	FindSubscription(ctx context.Context, id string) (*WebhookSubscription, error)
+	// ListSubscriptions returns every subscription, oldest first.
+	ListSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+	// SaveDeliveries stores deliveries, skipping those of an event already
+	// delivered to the same subscription, so a redelivered event is sent
+	// once.
+	SaveDeliveries(ctx context.Context, deliveries []*WebhookDelivery) error
+	// ClaimDueDeliveries returns up to limit pending deliveries due at now,
+	// earliest due first, and pushes them back by lease so no other worker
+	// claims them while they are being sent. A delivery whose worker dies
+	// is due again once the lease runs out.
+	ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	// UpdateDelivery returns ErrWebhookDeliveryNotFound when there is no
+	// such delivery.
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	FindDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+	// ListDeliveries returns the latest limit deliveries to subscriptionID,
+	// newest first, only those in status unless it is empty.
+	ListDeliveries(ctx context.Context, subscriptionID string, status WebhookDeliveryStatus, limit int) ([]*WebhookDelivery, error)
 }
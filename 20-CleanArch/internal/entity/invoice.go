@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvoiceAlreadyExists = errors.New("invoice already exists")
+	ErrInvoiceNotFound      = errors.New("invoice not found")
+)
+
+// Invoice is issued once per order; its ID is derived from the order ID so a
+// redelivered OrderCreated message cannot bill the same order twice.
+type Invoice struct {
+	ID       string
+	OrderID  string
+	Amount   float64
+	Tax      float64
+	Total    float64
+	IssuedAt time.Time
+}
+
+func NewInvoice(orderID string, amount float64, tax float64) (*Invoice, error) {
+	invoice := &Invoice{
+		ID:       "INV-" + orderID,
+		OrderID:  orderID,
+		Amount:   amount,
+		Tax:      tax,
+		Total:    amount + tax,
+		IssuedAt: now(),
+	}
+	err := invoice.IsValid()
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (i *Invoice) IsValid() error {
+	if i.OrderID == "" {
+		return errors.New("invalid order id")
+	}
+	if i.Amount <= 0 {
+		return errors.New("invalid amount")
+	}
+	if i.Tax < 0 {
+		return errors.New("invalid tax")
+	}
+	return nil
+}
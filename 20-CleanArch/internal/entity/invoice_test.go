@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAnEmptyOrderID_WhenCreateANewInvoice_ThenShouldReceiveAnError(t *testing.T) {
+	_, err := NewInvoice("", 10, 2)
+	assert.EqualError(t, err, "invalid order id")
+}
+
+func TestGivenAnEmptyAmount_WhenCreateANewInvoice_ThenShouldReceiveAnError(t *testing.T) {
+	_, err := NewInvoice("123", 0, 2)
+	assert.EqualError(t, err, "invalid amount")
+}
+
+func TestGivenAValidOrder_WhenCreateANewInvoice_ThenShouldDeriveIDAndTotal(t *testing.T) {
+	invoice, err := NewInvoice("123", 10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "INV-123", invoice.ID)
+	assert.Equal(t, "123", invoice.OrderID)
+	assert.Equal(t, 12.0, invoice.Total)
+	assert.False(t, invoice.IssuedAt.IsZero())
+}
@@ -1,20 +1,178 @@
 package entity
 
-import "errors"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const MaxListOrdersLimit = 100
+
+// ErrInvalidListOrders is wrapped by every error NewListOrders and
+// DecodeOrderCursor return, so callers can report them as bad input.
+var ErrInvalidListOrders = errors.New("invalid order listing")
 
 var (
-	errInvalidPage  = errors.New("invalid page")
-	errInvalidLimit = errors.New("invalid limit")
+	errInvalidLimit         = fmt.Errorf("%w: invalid limit", ErrInvalidListOrders)
+	errInvalidCursor        = fmt.Errorf("%w: invalid cursor", ErrInvalidListOrders)
+	errInvalidStatus        = fmt.Errorf("%w: invalid status", ErrInvalidListOrders)
+	errInvalidPriceRange    = fmt.Errorf("%w: invalid price range", ErrInvalidListOrders)
+	errInvalidCreatedRange  = fmt.Errorf("%w: invalid created date range", ErrInvalidListOrders)
+	errInvalidSortField     = fmt.Errorf("%w: invalid sort field", ErrInvalidListOrders)
+	errInvalidSortDirection = fmt.Errorf("%w: invalid sort direction", ErrInvalidListOrders)
+)
+
+type OrderSortField string
+
+const (
+	OrderSortByCreatedAt  OrderSortField = "created_at"
+	OrderSortByPrice      OrderSortField = "price"
+	OrderSortByFinalPrice OrderSortField = "final_price"
+)
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
 )
 
+type OrderSort struct {
+	Field     OrderSortField
+	Direction SortDirection
+}
+
+type OrderFilter struct {
+	Status      OrderStatus
+	MinPrice    *float64
+	MaxPrice    *float64
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// Matches reports whether order passes every condition of the filter; the
+// SQL repositories apply the same conditions in their queries.
+func (f OrderFilter) Matches(order Order) bool {
+	if f.Status != "" && order.Status != f.Status {
+		return false
+	}
+	if f.MinPrice != nil && order.Price < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && order.Price > *f.MaxPrice {
+		return false
+	}
+	if f.CreatedFrom != nil && order.CreatedAt.Before(*f.CreatedFrom) {
+		return false
+	}
+	if f.CreatedTo != nil && !order.CreatedAt.Before(*f.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+// OrderCursor is the keyset position of an order within a sorted listing: the
+// value of the sort column plus the ID as a tie-breaker.
+type OrderCursor struct {
+	Field      OrderSortField `json:"f"`
+	Price      float64        `json:"p,omitempty"`
+	FinalPrice float64        `json:"fp,omitempty"`
+	CreatedAt  time.Time      `json:"c,omitempty"`
+	ID         string         `json:"id"`
+}
+
+func NewOrderCursor(order Order, field OrderSortField) OrderCursor {
+	cursor := OrderCursor{Field: field, ID: order.ID}
+	switch field {
+	case OrderSortByPrice:
+		cursor.Price = order.Price
+	case OrderSortByFinalPrice:
+		cursor.FinalPrice = order.FinalPrice
+	default:
+		cursor.CreatedAt = order.CreatedAt
+	}
+	return cursor
+}
+
+// Value returns the sort column value the cursor points at.
+func (c OrderCursor) Value() interface{} {
+	switch c.Field {
+	case OrderSortByPrice:
+		return c.Price
+	case OrderSortByFinalPrice:
+		return c.FinalPrice
+	default:
+		return c.CreatedAt
+	}
+}
+
+func (c OrderCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeOrderCursor(encoded string) (*OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor OrderCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errInvalidCursor
+	}
+	if cursor.ID == "" || !cursor.Field.IsValid() {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (f OrderSortField) IsValid() bool {
+	switch f {
+	case OrderSortByCreatedAt, OrderSortByPrice, OrderSortByFinalPrice:
+		return true
+	}
+	return false
+}
+
+func (d SortDirection) IsValid() bool {
+	return d == SortAsc || d == SortDesc
+}
+
 type ListOrders struct {
-	Page, Limit int
+	Limit  int
+	After  *OrderCursor
+	Filter OrderFilter
+	Sort   OrderSort
+}
+
+type OrdersPage struct {
+	Orders      []Order
+	TotalCount  int
+	HasNextPage bool
 }
 
-func NewListOrders(page, limit int) (*ListOrders, error) {
+// NewListOrders builds a keyset page request. An empty cursor starts from the
+// beginning and an empty sort defaults to created_at ascending.
+func NewListOrders(limit int, after string, filter OrderFilter, sort OrderSort) (*ListOrders, error) {
+	if sort.Field == "" {
+		sort.Field = OrderSortByCreatedAt
+	}
+	if sort.Direction == "" {
+		sort.Direction = SortAsc
+	}
 	listOrders := &ListOrders{
-		Page:  page,
-		Limit: limit,
+		Limit:  limit,
+		Filter: filter,
+		Sort:   sort,
+	}
+	if after != "" {
+		cursor, err := DecodeOrderCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		listOrders.After = cursor
 	}
 	err := listOrders.IsValid()
 	if err != nil {
@@ -24,11 +182,26 @@ func NewListOrders(page, limit int) (*ListOrders, error) {
 }
 
 func (o *ListOrders) IsValid() error {
-	if o.Page <= 0 {
-		return errInvalidPage
-	}
-	if o.Limit <= 0 {
+	if o.Limit <= 0 || o.Limit > MaxListOrdersLimit {
 		return errInvalidLimit
 	}
+	if o.Filter.Status != "" && !o.Filter.Status.IsValid() {
+		return errInvalidStatus
+	}
+	if o.Filter.MinPrice != nil && o.Filter.MaxPrice != nil && *o.Filter.MinPrice > *o.Filter.MaxPrice {
+		return errInvalidPriceRange
+	}
+	if o.Filter.CreatedFrom != nil && o.Filter.CreatedTo != nil && o.Filter.CreatedFrom.After(*o.Filter.CreatedTo) {
+		return errInvalidCreatedRange
+	}
+	if !o.Sort.Field.IsValid() {
+		return errInvalidSortField
+	}
+	if !o.Sort.Direction.IsValid() {
+		return errInvalidSortDirection
+	}
+	if o.After != nil && o.After.Field != o.Sort.Field {
+		return errInvalidCursor
+	}
 	return nil
 }
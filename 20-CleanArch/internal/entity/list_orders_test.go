@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAnInvalidLimit_WhenCreateListOrders_ThenShouldReceiveAnError(t *testing.T) {
+	_, err := NewListOrders(0, "", OrderFilter{}, OrderSort{})
+	assert.Equal(t, errInvalidLimit, err)
+	_, err = NewListOrders(MaxListOrdersLimit+1, "", OrderFilter{}, OrderSort{})
+	assert.Equal(t, errInvalidLimit, err)
+	assert.ErrorIs(t, err, ErrInvalidListOrders)
+}
+
+func TestGivenNoSort_WhenCreateListOrders_ThenShouldSortByCreatedAtAscending(t *testing.T) {
+	listOrders, err := NewListOrders(10, "", OrderFilter{}, OrderSort{})
+	assert.Nil(t, err)
+	assert.Equal(t, OrderSortByCreatedAt, listOrders.Sort.Field)
+	assert.Equal(t, SortAsc, listOrders.Sort.Direction)
+	assert.Nil(t, listOrders.After)
+}
+
+func TestGivenAnInvertedPriceRange_WhenCreateListOrders_ThenShouldReceiveAnError(t *testing.T) {
+	minPrice, maxPrice := 20.0, 10.0
+	_, err := NewListOrders(10, "", OrderFilter{MinPrice: &minPrice, MaxPrice: &maxPrice}, OrderSort{})
+	assert.Equal(t, errInvalidPriceRange, err)
+}
+
+func TestGivenAnUnknownStatus_WhenCreateListOrders_ThenShouldReceiveAnError(t *testing.T) {
+	_, err := NewListOrders(10, "", OrderFilter{Status: "shipped"}, OrderSort{})
+	assert.Equal(t, errInvalidStatus, err)
+}
+
+func TestGivenAnOrder_WhenEncodeCursor_ThenShouldDecodeToTheSameKey(t *testing.T) {
+	order := Order{ID: "123", Price: 10.5, CreatedAt: time.Date(2023, 9, 1, 12, 0, 0, 1000, time.UTC)}
+
+	cursor, err := DecodeOrderCursor(NewOrderCursor(order, OrderSortByPrice).Encode())
+	assert.Nil(t, err)
+	assert.Equal(t, "123", cursor.ID)
+	assert.Equal(t, 10.5, cursor.Value())
+
+	cursor, err = DecodeOrderCursor(NewOrderCursor(order, OrderSortByCreatedAt).Encode())
+	assert.Nil(t, err)
+	assert.True(t, order.CreatedAt.Equal(cursor.Value().(time.Time)))
+}
+
+func TestGivenACursorForAnotherSort_WhenCreateListOrders_ThenShouldReceiveAnError(t *testing.T) {
+	after := NewOrderCursor(Order{ID: "123", Price: 10}, OrderSortByPrice).Encode()
+	_, err := NewListOrders(10, after, OrderFilter{}, OrderSort{Field: OrderSortByCreatedAt})
+	assert.Equal(t, errInvalidCursor, err)
+
+	_, err = NewListOrders(10, "not-a-cursor", OrderFilter{}, OrderSort{})
+	assert.Equal(t, errInvalidCursor, err)
+}
@@ -1,36 +1,101 @@
 package entity
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrOrderAlreadyExists      = errors.New("order already exists")
+	ErrOrderNotFound           = errors.New("order not found")
+	ErrOrderModified           = errors.New("order was modified concurrently")
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrInvalidOrderID          = errors.New("invalid id")
+	ErrInvalidOrderPrice       = errors.New("invalid price")
+	ErrInvalidOrderTax         = errors.New("invalid tax")
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRefunded  OrderStatus = "refunded"
+)
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusPaid, OrderStatusCancelled, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
 
 type Order struct {
 	ID         string
 	Price      float64
 	Tax        float64
 	FinalPrice float64
+	Status     OrderStatus
+	CreatedAt  time.Time
+	// CreatedBy is the subject of the principal that created the order.
+	CreatedBy string
+	// Category and Region select the tax rules that apply to the order.
+	Category string
+	Region   string
+	// TaxLines break Tax down by tax; they are empty when the caller
+	// supplied the tax.
+	TaxLines []TaxLine
+	// CouponCode names the coupon redeemed for the order, and Discount what
+	// it took off the price before tax; Price is what remains.
+	CouponCode string
+	Discount   float64
+	// taxCalculated is set while the tax is the one a TaxEngine worked out.
+	taxCalculated bool
+	events        []DomainEvent
 }
 
 func NewOrder(id string, price float64, tax float64) (*Order, error) {
+	return NewPricedOrder(id, TaxBreakdown{Price: price, Tax: tax})
+}
+
+// NewPricedOrder creates an order priced as breakdown.
+func NewPricedOrder(id string, breakdown TaxBreakdown) (*Order, error) {
 	order := &Order{
-		ID:    id,
-		Price: price,
-		Tax:   tax,
+		ID:            id,
+		Price:         breakdown.Price,
+		Tax:           breakdown.Tax,
+		Status:        OrderStatusPending,
+		CreatedAt:     now(),
+		TaxLines:      breakdown.Lines,
+		Discount:      breakdown.Discount,
+		taxCalculated: breakdown.Calculated,
 	}
 	err := order.IsValid()
 	if err != nil {
 		return nil, err
 	}
+	order.record(OrderCreated{At: order.CreatedAt})
 	return order, nil
 }
 
+// now returns the current time in UTC truncated to microseconds, the finest
+// precision every supported database stores, so cursors built from a saved
+// order compare equal to the persisted value.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
 func (o *Order) IsValid() error {
 	if o.ID == "" {
-		return errors.New("invalid id")
+		return ErrInvalidOrderID
 	}
-	if o.Price <= 0 {
-		return errors.New("invalid price")
+	if o.Price < 0 || (o.Price == 0 && o.Discount == 0) {
+		return ErrInvalidOrderPrice
 	}
-	if o.Tax <= 0 {
-		return errors.New("invalid tax")
+	if o.Tax < 0 || (o.Tax == 0 && !o.taxCalculated) {
+		return ErrInvalidOrderTax
 	}
 	return nil
 }
@@ -43,3 +108,67 @@ func (o *Order) CalculateFinalPrice() error {
 	}
 	return nil
 }
+
+// Update changes the price and tax of a pending order. The tax is taken as
+// given, so the tax lines are dropped.
+func (o *Order) Update(price float64, tax float64) error {
+	return o.Reprice(TaxBreakdown{Price: price, Tax: tax, Discount: o.Discount})
+}
+
+// Reprice updates a pending order to the price, discount and taxes of
+// breakdown.
+func (o *Order) Reprice(breakdown TaxBreakdown) error {
+	if o.Status != OrderStatusPending {
+		return ErrInvalidStatusTransition
+	}
+	updated := *o
+	updated.Price = breakdown.Price
+	updated.Tax = breakdown.Tax
+	updated.Discount = breakdown.Discount
+	updated.taxCalculated = breakdown.Calculated
+	if err := updated.CalculateFinalPrice(); err != nil {
+		return err
+	}
+	event := OrderUpdated{PreviousPrice: o.Price, PreviousTax: o.Tax, At: now()}
+	o.Price, o.Tax, o.FinalPrice = updated.Price, updated.Tax, updated.FinalPrice
+	o.Discount, o.TaxLines, o.taxCalculated = breakdown.Discount, breakdown.Lines, breakdown.Calculated
+	o.record(event)
+	return nil
+}
+
+// Pay moves a pending order to paid.
+func (o *Order) Pay() error {
+	return o.transition(OrderStatusPending, OrderStatusPaid, OrderPaidEvent, "")
+}
+
+// Cancel moves a pending order to cancelled. A paid order cannot be
+// cancelled; it has to be refunded.
+func (o *Order) Cancel(reason string) error {
+	return o.transition(OrderStatusPending, OrderStatusCancelled, OrderCancelledEvent, reason)
+}
+
+// Refund moves a paid order to refunded.
+func (o *Order) Refund(reason string) error {
+	return o.transition(OrderStatusPaid, OrderStatusRefunded, OrderRefundedEvent, reason)
+}
+
+func (o *Order) transition(from, to OrderStatus, eventName, reason string) error {
+	if o.Status != from {
+		return ErrInvalidStatusTransition
+	}
+	o.Status = to
+	o.record(OrderStatusChanged{Name: eventName, PreviousStatus: from, Status: to, Reason: reason, At: now()})
+	return nil
+}
+
+func (o *Order) record(event DomainEvent) {
+	o.events = append(o.events, event)
+}
+
+// PullEvents returns the events raised since the last call and forgets
+// them.
+func (o *Order) PullEvents() []DomainEvent {
+	events := o.events
+	o.events = nil
+	return events
+}
@@ -0,0 +1,49 @@
+package entity
+
+import "time"
+
+// Names of the events an Order raises. They double as the event names the
+// dispatcher routes on.
+const (
+	OrderCreatedEvent   = "OrderCreated"
+	OrderUpdatedEvent   = "OrderUpdated"
+	OrderPaidEvent      = "OrderPaid"
+	OrderCancelledEvent = "OrderCancelled"
+	OrderRefundedEvent  = "OrderRefunded"
+)
+
+// DomainEvent records something that happened to an aggregate. Use cases
+// collect them after a successful change and dispatch them.
+type DomainEvent interface {
+	EventName() string
+	OccurredAt() time.Time
+}
+
+type OrderCreated struct {
+	At time.Time
+}
+
+func (e OrderCreated) EventName() string     { return OrderCreatedEvent }
+func (e OrderCreated) OccurredAt() time.Time { return e.At }
+
+type OrderUpdated struct {
+	PreviousPrice float64
+	PreviousTax   float64
+	At            time.Time
+}
+
+func (e OrderUpdated) EventName() string     { return OrderUpdatedEvent }
+func (e OrderUpdated) OccurredAt() time.Time { return e.At }
+
+// OrderStatusChanged is raised for the paid, cancelled and refunded
+// transitions; Name tells them apart.
+type OrderStatusChanged struct {
+	Name           string
+	PreviousStatus OrderStatus
+	Status         OrderStatus
+	Reason         string
+	At             time.Time
+}
+
+func (e OrderStatusChanged) EventName() string     { return e.Name }
+func (e OrderStatusChanged) OccurredAt() time.Time { return e.At }
@@ -21,6 +21,38 @@ func TestGivenAnEmptyTax_WhenCreateANewOrder_ThenShouldReceiveAnError(t *testing
 	assert.Error(t, order.IsValid(), "invalid tax")
 }
 
+func TestGivenAZeroTax_WhenCreateAnOrder_ThenShouldOnlyAcceptItFromTheTaxEngine(t *testing.T) {
+	_, err := NewOrder("123", 10, 0)
+	assert.ErrorIs(t, err, ErrInvalidOrderTax)
+	_, err = NewPricedOrder("123", TaxBreakdown{Price: 10, Tax: -1, Calculated: true})
+	assert.ErrorIs(t, err, ErrInvalidOrderTax)
+
+	order, err := NewPricedOrder("123", TaxBreakdown{Price: 10, Lines: []TaxLine{{Name: "VAT"}}, Calculated: true})
+	assert.NoError(t, err)
+	assert.NoError(t, order.CalculateFinalPrice())
+	assert.Equal(t, 10.0, order.FinalPrice)
+	assert.ErrorIs(t, order.Update(20, 0), ErrInvalidOrderTax)
+	assert.NoError(t, order.Reprice(TaxBreakdown{Price: 20, Calculated: true}))
+	assert.Equal(t, 20.0, order.FinalPrice)
+	assert.Empty(t, order.TaxLines)
+}
+
+func TestGivenAZeroPrice_WhenCreateAnOrder_ThenShouldOnlyAcceptItAfterADiscount(t *testing.T) {
+	_, err := NewPricedOrder("123", TaxBreakdown{Tax: 1})
+	assert.ErrorIs(t, err, ErrInvalidOrderPrice)
+	_, err = NewPricedOrder("123", TaxBreakdown{Price: -1, Tax: 1, Discount: 10})
+	assert.ErrorIs(t, err, ErrInvalidOrderPrice)
+
+	order, err := NewPricedOrder("123", TaxBreakdown{Discount: 10, Calculated: true})
+	assert.NoError(t, err)
+	assert.NoError(t, order.CalculateFinalPrice())
+	assert.Equal(t, 0.0, order.FinalPrice)
+	assert.Equal(t, 10.0, order.Discount)
+	assert.NoError(t, order.Update(0, 1))
+	assert.Equal(t, 10.0, order.Discount)
+	assert.ErrorIs(t, order.Reprice(TaxBreakdown{Calculated: true}), ErrInvalidOrderPrice)
+}
+
 func TestGivenAValidParams_WhenICallNewOrder_ThenIShouldReceiveCreateOrderWithAllParams(t *testing.T) {
 	order := Order{
 		ID:    "123",
@@ -47,3 +79,87 @@ func TestGivenAPriceAndTax_WhenICallCalculatePrice_ThenIShouldSetFinalPrice(t *t
 	assert.Nil(t, order.CalculateFinalPrice())
 	assert.Equal(t, 12.0, order.FinalPrice)
 }
+
+func TestGivenANewOrder_WhenPullEvents_ThenShouldReturnOrderCreatedOnce(t *testing.T) {
+	order, err := NewOrder("123", 10.0, 2.0)
+	assert.Nil(t, err)
+
+	events := order.PullEvents()
+	assert.Len(t, events, 1)
+	assert.Equal(t, OrderCreatedEvent, events[0].EventName())
+	assert.Empty(t, order.PullEvents())
+}
+
+func TestGivenAPendingOrder_WhenUpdate_ThenShouldRecalculateAndRecordTheOldValues(t *testing.T) {
+	order, err := NewOrder("123", 10.0, 2.0)
+	assert.Nil(t, err)
+	order.PullEvents()
+
+	assert.Nil(t, order.Update(20.0, 4.0))
+	assert.Equal(t, 24.0, order.FinalPrice)
+	events := order.PullEvents()
+	assert.Len(t, events, 1)
+	assert.Equal(t, OrderUpdated{PreviousPrice: 10.0, PreviousTax: 2.0, At: events[0].OccurredAt()}, events[0])
+}
+
+func TestGivenAnInvalidPrice_WhenUpdate_ThenShouldLeaveTheOrderUnchanged(t *testing.T) {
+	order, err := NewOrder("123", 10.0, 2.0)
+	assert.Nil(t, err)
+	order.PullEvents()
+
+	assert.Error(t, order.Update(0, 4.0))
+	assert.Equal(t, 10.0, order.Price)
+	assert.Empty(t, order.PullEvents())
+}
+
+func TestGivenEachStatus_WhenTransition_ThenShouldOnlyAllowTheLifecycle(t *testing.T) {
+	transitions := map[string]func(*Order) error{
+		OrderPaidEvent:      func(o *Order) error { return o.Pay() },
+		OrderCancelledEvent: func(o *Order) error { return o.Cancel("reason") },
+		OrderRefundedEvent:  func(o *Order) error { return o.Refund("reason") },
+	}
+	allowed := map[OrderStatus]map[string]OrderStatus{
+		OrderStatusPending:   {OrderPaidEvent: OrderStatusPaid, OrderCancelledEvent: OrderStatusCancelled},
+		OrderStatusPaid:      {OrderRefundedEvent: OrderStatusRefunded},
+		OrderStatusCancelled: {},
+		OrderStatusRefunded:  {},
+	}
+	for from, targets := range allowed {
+		for name, transition := range transitions {
+			order := &Order{ID: "123", Price: 10, Tax: 2, Status: from}
+			err := transition(order)
+			to, ok := targets[name]
+			if !ok {
+				assert.ErrorIs(t, err, ErrInvalidStatusTransition, "%s from %s", name, from)
+				assert.Equal(t, from, order.Status)
+				assert.Empty(t, order.PullEvents())
+				continue
+			}
+			assert.Nil(t, err, "%s from %s", name, from)
+			assert.Equal(t, to, order.Status)
+			events := order.PullEvents()
+			assert.Len(t, events, 1)
+			assert.Equal(t, name, events[0].EventName())
+			assert.Equal(t, from, events[0].(OrderStatusChanged).PreviousStatus)
+		}
+	}
+}
+
+func TestGivenANonPendingOrder_WhenUpdate_ThenShouldReturnErrInvalidStatusTransition(t *testing.T) {
+	order := &Order{ID: "123", Price: 10, Tax: 2, Status: OrderStatusPaid}
+	assert.ErrorIs(t, order.Update(20.0, 4.0), ErrInvalidStatusTransition)
+}
+
+func TestGivenATaxBreakdown_WhenReprice_ThenShouldKeepItsTaxLines(t *testing.T) {
+	order, err := NewOrder("123", 10.0, 2.0)
+	assert.Nil(t, err)
+	engine, err := NewTaxEngine([]TaxRule{{Name: "VAT", Rate: 10}}, false)
+	assert.Nil(t, err)
+
+	assert.Nil(t, order.Reprice(engine.Calculate(20.0, "", "")))
+	assert.Equal(t, 22.0, order.FinalPrice)
+	assert.Equal(t, []TaxLine{{Name: "VAT", Rate: 10, Amount: 2}}, order.TaxLines)
+
+	assert.Nil(t, order.Update(30.0, 1.0))
+	assert.Empty(t, order.TaxLines)
+}
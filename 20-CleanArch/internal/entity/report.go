@@ -0,0 +1,189 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidReport = errors.New("invalid report")
+	// ErrReportOrderNotFound is returned when an order changes before the
+	// reports have seen it created, which happens when events arrive out of
+	// order; the change is retried once the order is there.
+	ErrReportOrderNotFound = errors.New("order not projected into the reports yet")
+)
+
+// ReportGranularity is the length of the periods a report is split into.
+type ReportGranularity string
+
+const (
+	GranularityDay   ReportGranularity = "day"
+	GranularityWeek  ReportGranularity = "week"
+	GranularityMonth ReportGranularity = "month"
+)
+
+func (g ReportGranularity) IsValid() bool {
+	return g == GranularityDay || g == GranularityWeek || g == GranularityMonth
+}
+
+// PeriodStart returns the start of the period holding t, in UTC. Weeks start
+// on Monday, as ISO weeks do.
+func (g ReportGranularity) PeriodStart(t time.Time) time.Time {
+	day := ReportDay(t)
+	switch g {
+	case GranularityWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case GranularityMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// NextPeriod returns the start of the period after the one starting at
+// start.
+func (g ReportGranularity) NextPeriod(start time.Time) time.Time {
+	switch g {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// ReportDay is the UTC day t falls on, the unit the reports are kept in.
+func ReportDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// ReportOrder is what the reports remember of an order, so later events,
+// which only carry what changed, can be turned into the right amounts.
+type ReportOrder struct {
+	ID         string
+	CreatedOn  time.Time
+	Price      float64
+	Tax        float64
+	FinalPrice float64
+	Discount   float64
+	Status     OrderStatus
+}
+
+// OrderStats adds up what happened to orders on a Day. Orders count towards
+// the sales of the day they were created on, and towards the payments,
+// cancellations and refunds of the day those happened.
+type OrderStats struct {
+	Day           time.Time
+	OrdersCreated int
+	// GrossSales sums the final prices of the orders created, NetSales their
+	// prices before tax, TaxBilled their tax and Discounts what coupons took
+	// off them.
+	GrossSales float64
+	NetSales   float64
+	TaxBilled  float64
+	Discounts  float64
+	OrdersPaid int
+	// PaidRevenue and PaidTax sum the final prices and tax of the orders
+	// paid; RefundedAmount and RefundedTax those of the orders refunded.
+	PaidRevenue     float64
+	PaidTax         float64
+	OrdersCancelled int
+	OrdersRefunded  int
+	RefundedAmount  float64
+	RefundedTax     float64
+}
+
+// Add adds the counts and amounts of other to s, keeping the amounts
+// rounded to the cent.
+func (s *OrderStats) Add(other OrderStats) {
+	s.OrdersCreated += other.OrdersCreated
+	s.GrossSales = roundCents(s.GrossSales + other.GrossSales)
+	s.NetSales = roundCents(s.NetSales + other.NetSales)
+	s.TaxBilled = roundCents(s.TaxBilled + other.TaxBilled)
+	s.Discounts = roundCents(s.Discounts + other.Discounts)
+	s.OrdersPaid += other.OrdersPaid
+	s.PaidRevenue = roundCents(s.PaidRevenue + other.PaidRevenue)
+	s.PaidTax = roundCents(s.PaidTax + other.PaidTax)
+	s.OrdersCancelled += other.OrdersCancelled
+	s.OrdersRefunded += other.OrdersRefunded
+	s.RefundedAmount = roundCents(s.RefundedAmount + other.RefundedAmount)
+	s.RefundedTax = roundCents(s.RefundedTax + other.RefundedTax)
+}
+
+// AverageOrderValue is the mean final price of the orders created, or zero
+// without any.
+func (s OrderStats) AverageOrderValue() float64 {
+	if s.OrdersCreated == 0 {
+		return 0
+	}
+	return roundCents(s.GrossSales / float64(s.OrdersCreated))
+}
+
+// NetRevenue is what was paid less what was refunded.
+func (s OrderStats) NetRevenue() float64 {
+	return roundCents(s.PaidRevenue - s.RefundedAmount)
+}
+
+// TaxCollected is the tax of the orders paid less that of the orders
+// refunded.
+func (s OrderStats) TaxCollected() float64 {
+	return roundCents(s.PaidTax - s.RefundedTax)
+}
+
+// ReportRange is the span of whole periods a report covers, From inclusive
+// and To exclusive.
+type ReportRange struct {
+	From        time.Time
+	To          time.Time
+	Granularity ReportGranularity
+}
+
+// MaxReportPeriods bounds how many periods one report may be split into.
+const MaxReportPeriods = 1000
+
+// NewReportRange widens [from, to) to whole periods of granularity, which
+// defaults to days.
+func NewReportRange(from, to time.Time, granularity ReportGranularity) (*ReportRange, error) {
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+	if !granularity.IsValid() {
+		return nil, fmt.Errorf("%w: unknown granularity %q", ErrInvalidReport, granularity)
+	}
+	if from.IsZero() || to.IsZero() {
+		return nil, fmt.Errorf("%w: missing from or to", ErrInvalidReport)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: range ends before it starts", ErrInvalidReport)
+	}
+	r := &ReportRange{From: granularity.PeriodStart(from), Granularity: granularity}
+	r.To = granularity.PeriodStart(to)
+	if r.To.Before(to) {
+		r.To = granularity.NextPeriod(r.To)
+	}
+	periods := 0
+	for start := r.From; start.Before(r.To); start = granularity.NextPeriod(start) {
+		periods++
+		if periods > MaxReportPeriods {
+			return nil, fmt.Errorf("%w: range spans more than %d periods", ErrInvalidReport, MaxReportPeriods)
+		}
+	}
+	return r, nil
+}
+
+// Periods splits the daily stats of the range into its periods, every
+// period present even when nothing happened in it.
+func (r *ReportRange) Periods(days []OrderStats) []OrderStats {
+	var periods []OrderStats
+	index := make(map[time.Time]int)
+	for start := r.From; start.Before(r.To); start = r.Granularity.NextPeriod(start) {
+		index[start] = len(periods)
+		periods = append(periods, OrderStats{Day: start})
+	}
+	for _, day := range days {
+		if i, ok := index[r.Granularity.PeriodStart(day.Day)]; ok {
+			periods[i].Add(day)
+		}
+	}
+	return periods
+}
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenATime_WhenPeriodStart_ThenShouldStartItsDayWeekOrMonth(t *testing.T) {
+	// A Wednesday in UTC, still Tuesday in Brazil.
+	at := time.Date(2023, 10, 31, 22, 30, 0, 0, time.FixedZone("BRT", -3*60*60))
+
+	assert.Equal(t, time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), GranularityDay.PeriodStart(at))
+	assert.Equal(t, time.Date(2023, 10, 30, 0, 0, 0, 0, time.UTC), GranularityWeek.PeriodStart(at))
+	assert.Equal(t, time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), GranularityMonth.PeriodStart(at))
+
+	sunday := time.Date(2023, 11, 5, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2023, 10, 30, 0, 0, 0, 0, time.UTC), GranularityWeek.PeriodStart(sunday))
+}
+
+func TestGivenAnInvalidRange_WhenNewReportRange_ThenShouldReceiveAnError(t *testing.T) {
+	from := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	_, err := NewReportRange(from, from.AddDate(0, 0, 7), "year")
+	assert.ErrorIs(t, err, ErrInvalidReport)
+	_, err = NewReportRange(time.Time{}, from, GranularityDay)
+	assert.ErrorIs(t, err, ErrInvalidReport)
+	_, err = NewReportRange(from, from, GranularityDay)
+	assert.ErrorIs(t, err, ErrInvalidReport)
+	_, err = NewReportRange(from, from.AddDate(5, 0, 0), GranularityDay)
+	assert.ErrorIs(t, err, ErrInvalidReport)
+}
+
+func TestGivenARange_WhenNewReportRange_ThenShouldWidenItToWholePeriods(t *testing.T) {
+	r, err := NewReportRange(time.Date(2023, 11, 8, 12, 0, 0, 0, time.UTC), time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC), GranularityWeek)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 11, 6, 0, 0, 0, 0, time.UTC), r.From)
+	assert.Equal(t, time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC), r.To)
+
+	r, err = NewReportRange(time.Date(2023, 11, 8, 0, 0, 0, 0, time.UTC), time.Date(2023, 11, 9, 0, 0, 0, 1, time.UTC), "")
+	assert.NoError(t, err)
+	assert.Equal(t, GranularityDay, r.Granularity)
+	assert.Equal(t, time.Date(2023, 11, 10, 0, 0, 0, 0, time.UTC), r.To)
+}
+
+func TestGivenDailyStats_WhenPeriods_ThenShouldAddThemUpPerPeriodAndKeepEmptyPeriods(t *testing.T) {
+	r, err := NewReportRange(time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), GranularityMonth)
+	assert.NoError(t, err)
+	periods := r.Periods([]OrderStats{
+		{Day: time.Date(2023, 10, 3, 0, 0, 0, 0, time.UTC), OrdersCreated: 1, GrossSales: 11, OrdersPaid: 1, PaidRevenue: 11, PaidTax: 1},
+		{Day: time.Date(2023, 10, 31, 0, 0, 0, 0, time.UTC), OrdersCreated: 2, GrossSales: 22.1, OrdersRefunded: 1, RefundedAmount: 11, RefundedTax: 1},
+		{Day: time.Date(2023, 12, 24, 0, 0, 0, 0, time.UTC), OrdersCreated: 1, GrossSales: 0.2},
+	})
+
+	assert.Len(t, periods, 3)
+	assert.Equal(t, time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), periods[0].Day)
+	assert.Equal(t, 3, periods[0].OrdersCreated)
+	assert.Equal(t, 33.1, periods[0].GrossSales)
+	assert.Equal(t, 11.03, periods[0].AverageOrderValue())
+	assert.Equal(t, 0.0, periods[0].NetRevenue())
+	assert.Equal(t, 0.0, periods[0].TaxCollected())
+	assert.Equal(t, OrderStats{Day: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)}, periods[1])
+	assert.Equal(t, 0.0, periods[1].AverageOrderValue())
+	assert.Equal(t, 1, periods[2].OrdersCreated)
+}
@@ -0,0 +1,172 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrInvalidTaxRule = errors.New("invalid tax rule")
+	ErrTaxMismatch    = errors.New("tax does not match the tax rules")
+)
+
+// TaxRule levies Rate percent under Name on the orders of Category sold in
+// Region; an empty Category or Region matches any. Rules sharing a Name are
+// alternatives: the most specific match wins, so a reduced rate for one
+// category overrides the general rate of the same tax.
+type TaxRule struct {
+	Name     string
+	Category string
+	Region   string
+	Rate     float64
+	// Compound taxes are levied on the price plus the taxes before them
+	// instead of on the price alone.
+	Compound bool
+}
+
+func (r TaxRule) IsValid() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidTaxRule)
+	}
+	if r.Rate < 0 || math.IsNaN(r.Rate) || math.IsInf(r.Rate, 0) {
+		return fmt.Errorf("%w: %s has rate %v", ErrInvalidTaxRule, r.Name, r.Rate)
+	}
+	return nil
+}
+
+func (r TaxRule) matches(category, region string) bool {
+	return (r.Category == "" || r.Category == category) && (r.Region == "" || r.Region == region)
+}
+
+// specificity ranks matching rules of the same tax: category and region,
+// then category, then region, then neither.
+func (r TaxRule) specificity() int {
+	specificity := 0
+	if r.Category != "" {
+		specificity += 2
+	}
+	if r.Region != "" {
+		specificity++
+	}
+	return specificity
+}
+
+// TaxLine is one tax levied on an order, as stored with it.
+type TaxLine struct {
+	Name     string
+	Rate     float64
+	Compound bool
+	Amount   float64
+}
+
+// TaxBreakdown is the outcome of pricing an order: its price before tax,
+// the taxes levied on it and their total.
+type TaxBreakdown struct {
+	Price float64
+	Lines []TaxLine
+	Tax   float64
+	// Discount is what a coupon took off the price; with a discount the
+	// price may be zero.
+	Discount float64
+	// Calculated is set when a TaxEngine worked the taxes out. Unlike a tax
+	// the caller supplies, a calculated tax may be zero, as with a zero rate
+	// or no matching rule.
+	Calculated bool
+}
+
+// Matches reports whether tax, as a client worked it out, is the breakdown's
+// tax to the cent.
+func (b TaxBreakdown) Matches(tax float64) bool {
+	return math.Abs(tax-b.Tax) < 0.005
+}
+
+// TaxEngine prices orders by its rules. With pricesIncludeTax the prices it
+// is given already contain the tax, which it then takes out of them.
+type TaxEngine struct {
+	rules            []TaxRule
+	pricesIncludeTax bool
+}
+
+func NewTaxEngine(rules []TaxRule, pricesIncludeTax bool) (*TaxEngine, error) {
+	for _, rule := range rules {
+		if err := rule.IsValid(); err != nil {
+			return nil, err
+		}
+	}
+	return &TaxEngine{rules: rules, pricesIncludeTax: pricesIncludeTax}, nil
+}
+
+func (e *TaxEngine) PricesIncludeTax() bool {
+	return e.pricesIncludeTax
+}
+
+// Calculate works out the taxes on price for an order of category sold in
+// region. Simple taxes come first, each on the price before tax; compound
+// taxes follow in rule order, each on the price plus every tax before it.
+// Amounts are rounded to the cent, and with tax-inclusive prices the price
+// before tax absorbs the rounding, so it and the tax add up to price.
+func (e *TaxEngine) Calculate(price float64, category, region string) TaxBreakdown {
+	rules := e.match(category, region)
+
+	net := price
+	if e.pricesIncludeTax {
+		simple, factor := 0.0, 1.0
+		for _, rule := range rules {
+			if rule.Compound {
+				factor *= 1 + rule.Rate/100
+			} else {
+				simple += rule.Rate / 100
+			}
+		}
+		net = price / ((1 + simple) * factor)
+	}
+
+	breakdown := TaxBreakdown{Lines: make([]TaxLine, 0, len(rules)), Calculated: true}
+	for _, rule := range rules {
+		base := net
+		if rule.Compound {
+			base += breakdown.Tax
+		}
+		amount := roundCents(base * rule.Rate / 100)
+		breakdown.Lines = append(breakdown.Lines, TaxLine{Name: rule.Name, Rate: rule.Rate, Compound: rule.Compound, Amount: amount})
+		breakdown.Tax = roundCents(breakdown.Tax + amount)
+	}
+	breakdown.Price = price
+	if e.pricesIncludeTax {
+		breakdown.Price = roundCents(price - breakdown.Tax)
+	}
+	return breakdown
+}
+
+// match picks the most specific matching rule of every tax, simple taxes
+// before compound ones and otherwise in the order the taxes first appear.
+func (e *TaxEngine) match(category, region string) []TaxRule {
+	var names []string
+	chosen := make(map[string]TaxRule)
+	for _, rule := range e.rules {
+		if !rule.matches(category, region) {
+			continue
+		}
+		current, ok := chosen[rule.Name]
+		if !ok {
+			names = append(names, rule.Name)
+		}
+		if !ok || rule.specificity() > current.specificity() {
+			chosen[rule.Name] = rule
+		}
+	}
+	rules := make([]TaxRule, 0, len(names))
+	for _, compound := range []bool{false, true} {
+		for _, name := range names {
+			if chosen[name].Compound == compound {
+				rules = append(rules, chosen[name])
+			}
+		}
+	}
+	return rules
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testTaxRules = []TaxRule{
+	{Name: "VAT", Rate: 10},
+	{Name: "VAT", Category: "food", Rate: 5},
+	{Name: "VAT", Category: "food", Region: "north", Rate: 2},
+	{Name: "Luxury", Category: "luxury", Rate: 5, Compound: true},
+	{Name: "State", Region: "north", Rate: 1},
+}
+
+func newTestTaxEngine(t *testing.T, pricesIncludeTax bool) *TaxEngine {
+	engine, err := NewTaxEngine(testTaxRules, pricesIncludeTax)
+	assert.NoError(t, err)
+	return engine
+}
+
+func TestGivenTaxExclusivePrices_WhenCalculate_ThenShouldAddTheMatchingTaxes(t *testing.T) {
+	engine := newTestTaxEngine(t, false)
+
+	breakdown := engine.Calculate(100, "", "south")
+
+	assert.Equal(t, TaxBreakdown{Price: 100, Tax: 10, Lines: []TaxLine{{Name: "VAT", Rate: 10, Amount: 10}}, Calculated: true}, breakdown)
+}
+
+func TestGivenRulesOfTheSameTax_WhenCalculate_ThenShouldApplyTheMostSpecific(t *testing.T) {
+	engine := newTestTaxEngine(t, false)
+
+	assert.Equal(t, []TaxLine{{Name: "VAT", Rate: 5, Amount: 5}}, engine.Calculate(100, "food", "south").Lines)
+	assert.Equal(t, []TaxLine{
+		{Name: "VAT", Rate: 2, Amount: 2},
+		{Name: "State", Rate: 1, Amount: 1},
+	}, engine.Calculate(100, "food", "north").Lines)
+}
+
+func TestGivenACompoundTax_WhenCalculate_ThenShouldLevyItOnThePriceAndTheTaxesBefore(t *testing.T) {
+	engine := newTestTaxEngine(t, false)
+
+	breakdown := engine.Calculate(100, "luxury", "north")
+
+	assert.Equal(t, []TaxLine{
+		{Name: "VAT", Rate: 10, Amount: 10},
+		{Name: "State", Rate: 1, Amount: 1},
+		{Name: "Luxury", Rate: 5, Compound: true, Amount: 5.55},
+	}, breakdown.Lines)
+	assert.Equal(t, 16.55, breakdown.Tax)
+	assert.Equal(t, 100.0, breakdown.Price)
+}
+
+func TestGivenTaxInclusivePrices_WhenCalculate_ThenShouldTakeTheTaxesOutOfThePrice(t *testing.T) {
+	engine := newTestTaxEngine(t, true)
+
+	breakdown := engine.Calculate(116.55, "luxury", "north")
+	assert.Equal(t, 100.0, breakdown.Price)
+	assert.Equal(t, 16.55, breakdown.Tax)
+
+	// 10.00 / 1.1 does not divide evenly: the price absorbs the rounding.
+	breakdown = engine.Calculate(10, "", "")
+	assert.Equal(t, 9.09, breakdown.Price)
+	assert.Equal(t, 0.91, breakdown.Tax)
+	assert.True(t, breakdown.Matches(0.91))
+	assert.False(t, breakdown.Matches(0.9))
+}
+
+func TestGivenNoMatchingRule_WhenCalculate_ThenShouldLevyNoTax(t *testing.T) {
+	engine, err := NewTaxEngine([]TaxRule{{Name: "State", Region: "north", Rate: 1}}, false)
+	assert.NoError(t, err)
+
+	breakdown := engine.Calculate(100, "", "south")
+
+	assert.Empty(t, breakdown.Lines)
+	assert.Equal(t, 0.0, breakdown.Tax)
+}
+
+func TestGivenAnInvalidRule_WhenNewTaxEngine_ThenShouldReturnErrInvalidTaxRule(t *testing.T) {
+	_, err := NewTaxEngine([]TaxRule{{Rate: 10}}, false)
+	assert.ErrorIs(t, err, ErrInvalidTaxRule)
+	_, err = NewTaxEngine([]TaxRule{{Name: "VAT", Rate: -1}}, false)
+	assert.ErrorIs(t, err, ErrInvalidTaxRule)
+}
@@ -0,0 +1,201 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidWebhook               = errors.New("invalid webhook")
+	ErrWebhookNotFound              = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryNotReplayable = errors.New("only failed webhook deliveries can be replayed")
+)
+
+// MinWebhookSecretLength keeps partner-chosen secrets hard to guess.
+const MinWebhookSecretLength = 16
+
+// webhookEvents are the events a webhook can subscribe to.
+var webhookEvents = map[string]bool{
+	OrderCreatedEvent:   true,
+	OrderUpdatedEvent:   true,
+	OrderPaidEvent:      true,
+	OrderCancelledEvent: true,
+	OrderRefundedEvent:  true,
+}
+
+// WebhookSubscription has the events in Events POSTed to URL, signed with
+// Secret, while it is Active.
+type WebhookSubscription struct {
+	ID        string
+	URL       string
+	Events    []string
+	Secret    string
+	Active    bool
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// NewWebhookSubscription generates a secret when secret is empty.
+func NewWebhookSubscription(id, rawURL string, events []string, secret, createdBy string) (*WebhookSubscription, error) {
+	if secret == "" {
+		secret = NewWebhookSecret()
+	}
+	subscription := &WebhookSubscription{
+		ID:        id,
+		URL:       strings.TrimSpace(rawURL),
+		Events:    NormalizeWebhookEvents(events),
+		Secret:    secret,
+		Active:    true,
+		CreatedBy: createdBy,
+		CreatedAt: now(),
+	}
+	err := subscription.IsValid()
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// NewWebhookSecret returns a random secret, "whsec_" and 32 bytes in hex.
+func NewWebhookSecret() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return "whsec_" + hex.EncodeToString(b[:])
+}
+
+// NormalizeWebhookEvents sorts events and drops the duplicates.
+func NormalizeWebhookEvents(events []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func (s *WebhookSubscription) IsValid() error {
+	if s.ID == "" {
+		return fmt.Errorf("%w: missing id", ErrInvalidWebhook)
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidWebhook)
+	}
+	if len(s.Events) == 0 {
+		return fmt.Errorf("%w: missing events", ErrInvalidWebhook)
+	}
+	for _, event := range s.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	if len(s.Secret) < MinWebhookSecretLength {
+		return fmt.Errorf("%w: secret shorter than %d characters", ErrInvalidWebhook, MinWebhookSecretLength)
+	}
+	return nil
+}
+
+func (s *WebhookSubscription) Subscribes(event string) bool {
+	for _, subscribed := range s.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (s WebhookDeliveryStatus) IsValid() bool {
+	return s == WebhookDeliveryPending || s == WebhookDeliverySucceeded || s == WebhookDeliveryFailed
+}
+
+// WebhookDelivery is the sending of an event to a subscription, and the log
+// of its attempts. A pending delivery is due at NextAttemptAt; it ends
+// succeeded, or failed once out of attempts, and a failed delivery may be
+// replayed. ResponseStatus and LastError describe the last attempt, the
+// status being 0 when no response came back.
+type WebhookDelivery struct {
+	ID             string
+	SubscriptionID string
+	EventID        string
+	Event          string
+	Payload        []byte
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastAttemptAt  time.Time
+	ResponseStatus int
+	LastError      string
+	CreatedAt      time.Time
+}
+
+// NewWebhookDelivery returns a delivery of the event eventID, due now.
+func NewWebhookDelivery(id, subscriptionID, eventID, event string, payload []byte) *WebhookDelivery {
+	createdAt := now()
+	return &WebhookDelivery{
+		ID:             id,
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		Event:          event,
+		Payload:        payload,
+		Status:         WebhookDeliveryPending,
+		NextAttemptAt:  createdAt,
+		CreatedAt:      createdAt,
+	}
+}
+
+// Succeed records a successful attempt made at at.
+func (d *WebhookDelivery) Succeed(at time.Time, responseStatus int) {
+	d.Attempts++
+	d.Status = WebhookDeliverySucceeded
+	d.LastAttemptAt = at.UTC()
+	d.ResponseStatus = responseStatus
+	d.LastError = ""
+}
+
+// Fail records a failed attempt made at at, to be retried at retryAt or,
+// with a zero retryAt, given up on.
+func (d *WebhookDelivery) Fail(at time.Time, responseStatus int, reason string, retryAt time.Time) {
+	d.Attempts++
+	d.LastAttemptAt = at.UTC()
+	d.ResponseStatus = responseStatus
+	d.LastError = reason
+	if retryAt.IsZero() {
+		d.Status = WebhookDeliveryFailed
+		return
+	}
+	d.NextAttemptAt = retryAt.UTC()
+}
+
+// Replay makes a failed delivery pending again, due at at with all its
+// attempts ahead of it.
+func (d *WebhookDelivery) Replay(at time.Time) error {
+	if d.Status != WebhookDeliveryFailed {
+		return ErrWebhookDeliveryNotReplayable
+	}
+	d.Status = WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = at.UTC()
+	return nil
+}
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAnInvalidWebhook_WhenCreate_ThenShouldReceiveAnError(t *testing.T) {
+	events := []string{OrderPaidEvent}
+	_, err := NewWebhookSubscription("", "https://partner.example/hooks", events, "", "alice")
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	_, err = NewWebhookSubscription("wh-1", "partner.example/hooks", events, "", "alice")
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	_, err = NewWebhookSubscription("wh-1", "ftp://partner.example/hooks", events, "", "alice")
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	_, err = NewWebhookSubscription("wh-1", "https://partner.example/hooks", nil, "", "alice")
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	_, err = NewWebhookSubscription("wh-1", "https://partner.example/hooks", []string{"OrderShipped"}, "", "alice")
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+	_, err = NewWebhookSubscription("wh-1", "https://partner.example/hooks", events, "short", "alice")
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+}
+
+func TestGivenAValidWebhook_WhenCreate_ThenShouldNormalizeItsEventsAndGenerateASecret(t *testing.T) {
+	subscription, err := NewWebhookSubscription("wh-1", " https://partner.example/hooks ",
+		[]string{OrderPaidEvent, OrderCreatedEvent, OrderPaidEvent}, "", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://partner.example/hooks", subscription.URL)
+	assert.Equal(t, []string{OrderCreatedEvent, OrderPaidEvent}, subscription.Events)
+	assert.Regexp(t, `^whsec_[0-9a-f]{64}$`, subscription.Secret)
+	assert.True(t, subscription.Active)
+	assert.True(t, subscription.Subscribes(OrderPaidEvent))
+	assert.False(t, subscription.Subscribes(OrderRefundedEvent))
+}
+
+func TestGivenADelivery_WhenItFailsForGood_ThenShouldOnlyThenBeReplayable(t *testing.T) {
+	delivery := NewWebhookDelivery("d-1", "wh-1", "event-1", OrderPaidEvent, []byte(`{}`))
+	at := time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+	assert.ErrorIs(t, delivery.Replay(at), ErrWebhookDeliveryNotReplayable)
+
+	delivery.Fail(at, 503, "503 Service Unavailable", at.Add(time.Minute))
+	assert.Equal(t, WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, at.Add(time.Minute), delivery.NextAttemptAt)
+	delivery.Fail(at.Add(time.Minute), 0, "connection refused", time.Time{})
+	assert.Equal(t, WebhookDeliveryFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Equal(t, "connection refused", delivery.LastError)
+
+	assert.NoError(t, delivery.Replay(at.Add(time.Hour)))
+	assert.Equal(t, WebhookDeliveryPending, delivery.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+	assert.Equal(t, at.Add(time.Hour), delivery.NextAttemptAt)
+
+	delivery.Succeed(at.Add(time.Hour), 204)
+	assert.Equal(t, WebhookDeliverySucceeded, delivery.Status)
+	assert.Empty(t, delivery.LastError)
+	assert.ErrorIs(t, delivery.Replay(at), ErrWebhookDeliveryNotReplayable)
+}
@@ -0,0 +1,78 @@
+package event
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+// FromDomain turns an event raised by order into a new dispatchable event
+// stamped with the time the change happened and the correlation ID ctx
+// carries, if any. Every call returns a fresh instance, so concurrent
+// dispatches never share a payload.
+func FromDomain(ctx context.Context, domainEvent entity.DomainEvent, order entity.Order) (events.EventInterface, error) {
+	correlationID := events.CorrelationIDFromContext(ctx)
+	switch e := domainEvent.(type) {
+	case entity.OrderCreated:
+		ev := NewOrderCreated()
+		ev.SetPayload(OrderCreatedPayload{
+			ID:         order.ID,
+			Price:      order.Price,
+			Tax:        order.Tax,
+			FinalPrice: order.FinalPrice,
+			Status:     string(order.Status),
+			CreatedAt:  order.CreatedAt,
+			CreatedBy:  order.CreatedBy,
+			Category:   order.Category,
+			Region:     order.Region,
+			TaxLines:   newTaxLinePayloads(order.TaxLines),
+			CouponCode: order.CouponCode,
+			Discount:   order.Discount,
+		})
+		ev.OccurredAt, ev.CorrelationID = e.OccurredAt(), correlationID
+		return ev, nil
+	case entity.OrderUpdated:
+		ev := NewOrderUpdated()
+		ev.SetPayload(OrderUpdatedPayload{
+			ID:            order.ID,
+			Price:         order.Price,
+			Tax:           order.Tax,
+			FinalPrice:    order.FinalPrice,
+			TaxLines:      newTaxLinePayloads(order.TaxLines),
+			Discount:      order.Discount,
+			PreviousPrice: e.PreviousPrice,
+			PreviousTax:   e.PreviousTax,
+			UpdatedAt:     e.OccurredAt(),
+		})
+		ev.OccurredAt, ev.CorrelationID = e.OccurredAt(), correlationID
+		return ev, nil
+	case entity.OrderStatusChanged:
+		ev := &OrderStatusChanged{Name: e.EventName()}
+		ev.SetPayload(OrderStatusChangedPayload{
+			ID:             order.ID,
+			PreviousStatus: string(e.PreviousStatus),
+			Status:         string(e.Status),
+			Reason:         e.Reason,
+			FinalPrice:     order.FinalPrice,
+			ChangedAt:      e.OccurredAt(),
+		})
+		ev.OccurredAt, ev.CorrelationID = e.OccurredAt(), correlationID
+		return ev, nil
+	}
+	return nil, fmt.Errorf("unsupported domain event %s", domainEvent.EventName())
+}
+
+func newTaxLinePayloads(lines []entity.TaxLine) []TaxLinePayload {
+	var payloads []TaxLinePayload
+	for _, line := range lines {
+		payloads = append(payloads, TaxLinePayload{
+			Name:     line.Name,
+			Rate:     line.Rate,
+			Compound: line.Compound,
+			Amount:   line.Amount,
+		})
+	}
+	return payloads
+}
@@ -0,0 +1,44 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenARequestCorrelationID_WhenFromDomain_ThenShouldStampItOnEveryEvent(t *testing.T) {
+	order, err := entity.NewOrder("123", 10, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, order.CalculateFinalPrice())
+	assert.NoError(t, order.Update(20, 2))
+	assert.NoError(t, order.Pay())
+	ctx := events.WithCorrelationID(context.Background(), "request-1")
+
+	domainEvents := order.PullEvents()
+	assert.Len(t, domainEvents, 3)
+	for _, domainEvent := range domainEvents {
+		ev, err := FromDomain(ctx, domainEvent, *order)
+		assert.NoError(t, err)
+		enveloped := ev.(events.EnvelopeInterface)
+		assert.Equal(t, "request-1", enveloped.GetCorrelationID())
+		assert.Equal(t, "123", enveloped.GetAggregateID())
+	}
+}
+
+func TestGivenAnotherPayloadType_WhenSetPayload_ThenShouldPanicAndKeepThePayload(t *testing.T) {
+	created := NewOrderCreated()
+	created.SetPayload(OrderCreatedPayload{ID: "123"})
+	updated, changed := NewOrderUpdated(), &OrderStatusChanged{Name: "OrderPaid"}
+
+	assert.PanicsWithValue(t, "event: OrderCreated takes an OrderCreatedPayload, got event.OrderUpdatedPayload", func() {
+		created.SetPayload(OrderUpdatedPayload{ID: "456"})
+	})
+	assert.Panics(t, func() { updated.SetPayload("not a payload") })
+	assert.Panics(t, func() { changed.SetPayload(nil) })
+	assert.Equal(t, "123", created.Payload.ID)
+	assert.Empty(t, updated.GetID())
+	assert.Empty(t, changed.Payload.ID)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+// OrderReportHandler projects the order events into the reports.
+type OrderReportHandler struct {
+	ProjectOrderReport *usecase.ProjectOrderReportUseCase
+}
+
+func NewOrderReportHandler(projectOrderReport *usecase.ProjectOrderReportUseCase) *OrderReportHandler {
+	return &OrderReportHandler{
+		ProjectOrderReport: projectOrderReport,
+	}
+}
+
+// Handle needs the event's envelope, whose ID makes a retried or replayed
+// event count once.
+func (h *OrderReportHandler) Handle(ctx context.Context, ev events.EventInterface) error {
+	envelope, ok := ev.(events.EnvelopeInterface)
+	if !ok {
+		return fmt.Errorf("event %s has no envelope", ev.GetName())
+	}
+	input := usecase.OrderReportEventInputDTO{
+		EventID: envelope.GetID(),
+		Event:   ev.GetName(),
+	}
+	switch payload := ev.GetPayload().(type) {
+	case event.OrderCreatedPayload:
+		input.OrderID = payload.ID
+		input.OccurredAt = payload.CreatedAt
+		input.CreatedAt = payload.CreatedAt
+		input.Price, input.Tax, input.FinalPrice, input.Discount = payload.Price, payload.Tax, payload.FinalPrice, payload.Discount
+	case event.OrderUpdatedPayload:
+		input.OrderID = payload.ID
+		input.OccurredAt = payload.UpdatedAt
+		input.Price, input.Tax, input.FinalPrice, input.Discount = payload.Price, payload.Tax, payload.FinalPrice, payload.Discount
+	case event.OrderStatusChangedPayload:
+		input.OrderID = payload.ID
+		input.OccurredAt = payload.ChangedAt
+	default:
+		return fmt.Errorf("unsupported payload %T for event %s", payload, ev.GetName())
+	}
+	return h.ProjectOrderReport.Execute(ctx, input)
+}
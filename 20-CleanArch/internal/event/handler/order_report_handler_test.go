@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAnEventForAnOrderNotCreatedYet_WhenHandle_ThenShouldFailUntilTheCreationIsProjected(t *testing.T) {
+	repository := database.NewMemoryOrderReportRepository()
+	handler := NewOrderReportHandler(usecase.NewProjectOrderReportUseCase(repository))
+	createdAt := time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+	paid := event.NewOrderPaid()
+	paid.SetPayload(event.OrderStatusChangedPayload{ID: "a", Status: "paid", FinalPrice: 11, ChangedAt: createdAt.Add(time.Hour)})
+	created := event.NewOrderCreated()
+	created.SetPayload(event.OrderCreatedPayload{ID: "a", Price: 10, Tax: 1, FinalPrice: 11, CreatedAt: createdAt})
+
+	assert.ErrorIs(t, handler.Handle(context.Background(), paid), entity.ErrReportOrderNotFound)
+	assert.NoError(t, handler.Handle(context.Background(), created))
+	assert.NoError(t, handler.Handle(context.Background(), paid))
+	// A redelivered event is counted once.
+	assert.NoError(t, handler.Handle(context.Background(), paid))
+
+	days, err := repository.Daily(context.Background(), createdAt, createdAt.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+	assert.Len(t, days, 1)
+	assert.Equal(t, 1, days[0].OrdersCreated)
+	assert.Equal(t, 1, days[0].OrdersPaid)
+	assert.Equal(t, 11.0, days[0].PaidRevenue)
+	assert.Equal(t, 1.0, days[0].PaidTax)
+}
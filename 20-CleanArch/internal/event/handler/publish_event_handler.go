@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"golang.org/x/exp/slog"
+)
+
+// EventSource identifies this service as the source of the events it
+// publishes.
+const EventSource = "/ordersystem"
+
+// PublishEventHandler forwards any dispatched event to the broker, on the
+// topic named after the event.
+type PublishEventHandler struct {
+	Publisher events.PublisherInterface
+}
+
+func NewPublishEventHandler(publisher events.PublisherInterface) *PublishEventHandler {
+	return &PublishEventHandler{
+		Publisher: publisher,
+	}
+}
+
+// Handle publishes the event as a binary mode CloudEvent.
+func (h *PublishEventHandler) Handle(ctx context.Context, event events.EventInterface) error {
+	cloudEvent, err := newCloudEvent(ctx, event)
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "publishing event",
+		"event", cloudEvent.Type,
+		"event_id", cloudEvent.ID,
+		"subject", cloudEvent.Subject,
+		"correlation_id", cloudEvent.CorrelationID,
+	)
+	return h.Publisher.Publish(ctx, cloudEvent.Message())
+}
+
+// newCloudEvent returns event as this service's CloudEvent. Without a
+// correlation ID of its own the event takes the one of the request that
+// raised it, or else starts a new chain with its own ID.
+func newCloudEvent(ctx context.Context, event events.EventInterface) (*events.CloudEvent, error) {
+	cloudEvent, err := events.NewCloudEvent(EventSource, event)
+	if err != nil {
+		return nil, err
+	}
+	if cloudEvent.CorrelationID == "" {
+		cloudEvent.CorrelationID = events.CorrelationIDFromContext(ctx)
+	}
+	if cloudEvent.CorrelationID == "" {
+		cloudEvent.CorrelationID = cloudEvent.ID
+	}
+	return cloudEvent, nil
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+// WebhookHandler queues the order events for the webhooks subscribed to
+// them, and wakes the webhook worker up to send them.
+type WebhookHandler struct {
+	EnqueueWebhookDeliveries *usecase.EnqueueWebhookDeliveriesUseCase
+	Worker                   usecase.WebhookWorkerInterface
+}
+
+func NewWebhookHandler(enqueueWebhookDeliveries *usecase.EnqueueWebhookDeliveriesUseCase, worker usecase.WebhookWorkerInterface) *WebhookHandler {
+	return &WebhookHandler{
+		EnqueueWebhookDeliveries: enqueueWebhookDeliveries,
+		Worker:                   worker,
+	}
+}
+
+// Handle queues the event as a structured mode CloudEvent, the body of
+// every delivery. Its ID, the envelope's, makes a retried event queue once.
+func (h *WebhookHandler) Handle(ctx context.Context, event events.EventInterface) error {
+	cloudEvent, err := newCloudEvent(ctx, event)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(cloudEvent)
+	if err != nil {
+		return err
+	}
+	enqueued, err := h.EnqueueWebhookDeliveries.Execute(ctx, usecase.EnqueueWebhookDeliveriesInputDTO{
+		EventID: cloudEvent.ID,
+		Event:   cloudEvent.Type,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+	if enqueued > 0 && h.Worker != nil {
+		h.Worker.Notify()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type notifyCounter int
+
+func (n *notifyCounter) Notify() { *n++ }
+
+func TestGivenAnEventHandledTwice_WhenHandle_ThenShouldQueueOneDeliveryPerSubscriber(t *testing.T) {
+	repository := database.NewMemoryWebhookRepository()
+	for _, subscribed := range [][]string{{entity.OrderPaidEvent}, {entity.OrderCreatedEvent}} {
+		subscription, err := entity.NewWebhookSubscription(subscribed[0], "https://partner.example/hooks", subscribed, "", "alice")
+		assert.NoError(t, err)
+		assert.NoError(t, repository.SaveSubscription(context.Background(), subscription))
+	}
+	var notified notifyCounter
+	handler := NewWebhookHandler(usecase.NewEnqueueWebhookDeliveriesUseCase(repository), &notified)
+	paid := event.NewOrderPaid()
+	paid.SetPayload(event.OrderStatusChangedPayload{ID: "a", Status: "paid", FinalPrice: 11, ChangedAt: time.Now()})
+	ctx := events.WithCorrelationID(context.Background(), "request-1")
+
+	assert.NoError(t, handler.Handle(ctx, paid))
+	assert.NoError(t, handler.Handle(ctx, paid))
+
+	deliveries, err := repository.ListDeliveries(context.Background(), entity.OrderPaidEvent, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, paid.GetID(), deliveries[0].EventID)
+	var cloudEvent events.CloudEvent
+	assert.NoError(t, json.Unmarshal(deliveries[0].Payload, &cloudEvent))
+	assert.Equal(t, entity.OrderPaidEvent, cloudEvent.Type)
+	assert.Equal(t, "a", cloudEvent.Subject)
+	assert.Equal(t, "request-1", cloudEvent.CorrelationID)
+	deliveries, err = repository.ListDeliveries(context.Background(), entity.OrderCreatedEvent, "", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries)
+	assert.Equal(t, notifyCounter(2), notified)
+}
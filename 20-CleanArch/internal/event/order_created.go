@@ -1,10 +1,43 @@
 package event
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+// OrderCreatedSchemaVersion is bumped whenever OrderCreatedPayload changes
+// in a way consumers must know about.
+const OrderCreatedSchemaVersion = 1
+
+type OrderCreatedPayload struct {
+	ID         string           `json:"id"`
+	Price      float64          `json:"price"`
+	Tax        float64          `json:"tax"`
+	FinalPrice float64          `json:"final_price"`
+	Status     string           `json:"status"`
+	CreatedAt  time.Time        `json:"created_at"`
+	CreatedBy  string           `json:"created_by,omitempty"`
+	Category   string           `json:"category,omitempty"`
+	Region     string           `json:"region,omitempty"`
+	TaxLines   []TaxLinePayload `json:"tax_lines,omitempty"`
+	CouponCode string           `json:"coupon_code,omitempty"`
+	Discount   float64          `json:"discount,omitempty"`
+}
+
+// TaxLinePayload is one tax of an order's tax breakdown.
+type TaxLinePayload struct {
+	Name     string  `json:"name"`
+	Rate     float64 `json:"rate"`
+	Compound bool    `json:"compound"`
+	Amount   float64 `json:"amount"`
+}
 
 type OrderCreated struct {
+	events.Envelope
 	Name    string
-	Payload interface{}
+	Payload OrderCreatedPayload
 }
 
 func NewOrderCreated() *OrderCreated {
@@ -21,10 +54,14 @@ func (e *OrderCreated) GetPayload() interface{} {
 	return e.Payload
 }
 
+// SetPayload records a new occurrence of the event: it takes an
+// OrderCreatedPayload and stamps a fresh envelope for that order. Any other
+// payload is a programming error and panics.
 func (e *OrderCreated) SetPayload(payload interface{}) {
-	e.Payload = payload
-}
-
-func (e *OrderCreated) GetDateTime() time.Time {
-	return time.Now()
+	typed, ok := payload.(OrderCreatedPayload)
+	if !ok {
+		panic(fmt.Sprintf("event: %s takes an OrderCreatedPayload, got %T", e.Name, payload))
+	}
+	e.Payload = typed
+	e.Envelope = events.NewEnvelope(e.Payload.ID, OrderCreatedSchemaVersion)
 }
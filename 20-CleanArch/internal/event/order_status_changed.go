@@ -0,0 +1,58 @@
+package event
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+const OrderStatusChangedSchemaVersion = 1
+
+// OrderStatusChangedPayload is shared by OrderPaid, OrderCancelled and
+// OrderRefunded.
+type OrderStatusChangedPayload struct {
+	ID             string    `json:"id"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	FinalPrice     float64   `json:"final_price"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+type OrderStatusChanged struct {
+	events.Envelope
+	Name    string
+	Payload OrderStatusChangedPayload
+}
+
+func NewOrderPaid() *OrderStatusChanged {
+	return &OrderStatusChanged{Name: "OrderPaid"}
+}
+
+func NewOrderCancelled() *OrderStatusChanged {
+	return &OrderStatusChanged{Name: "OrderCancelled"}
+}
+
+func NewOrderRefunded() *OrderStatusChanged {
+	return &OrderStatusChanged{Name: "OrderRefunded"}
+}
+
+func (e *OrderStatusChanged) GetName() string {
+	return e.Name
+}
+
+func (e *OrderStatusChanged) GetPayload() interface{} {
+	return e.Payload
+}
+
+// SetPayload takes an OrderStatusChangedPayload and stamps a fresh envelope
+// for that order. Any other payload panics.
+func (e *OrderStatusChanged) SetPayload(payload interface{}) {
+	typed, ok := payload.(OrderStatusChangedPayload)
+	if !ok {
+		panic(fmt.Sprintf("event: %s takes an OrderStatusChangedPayload, got %T", e.Name, payload))
+	}
+	e.Payload = typed
+	e.Envelope = events.NewEnvelope(e.Payload.ID, OrderStatusChangedSchemaVersion)
+}
@@ -0,0 +1,53 @@
+package event
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+const OrderUpdatedSchemaVersion = 1
+
+type OrderUpdatedPayload struct {
+	ID            string           `json:"id"`
+	Price         float64          `json:"price"`
+	Tax           float64          `json:"tax"`
+	FinalPrice    float64          `json:"final_price"`
+	TaxLines      []TaxLinePayload `json:"tax_lines,omitempty"`
+	Discount      float64          `json:"discount,omitempty"`
+	PreviousPrice float64          `json:"previous_price"`
+	PreviousTax   float64          `json:"previous_tax"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+type OrderUpdated struct {
+	events.Envelope
+	Name    string
+	Payload OrderUpdatedPayload
+}
+
+func NewOrderUpdated() *OrderUpdated {
+	return &OrderUpdated{
+		Name: "OrderUpdated",
+	}
+}
+
+func (e *OrderUpdated) GetName() string {
+	return e.Name
+}
+
+func (e *OrderUpdated) GetPayload() interface{} {
+	return e.Payload
+}
+
+// SetPayload takes an OrderUpdatedPayload and stamps a fresh envelope for
+// that order. Any other payload panics.
+func (e *OrderUpdated) SetPayload(payload interface{}) {
+	typed, ok := payload.(OrderUpdatedPayload)
+	if !ok {
+		panic(fmt.Sprintf("event: %s takes an OrderUpdatedPayload, got %T", e.Name, payload))
+	}
+	e.Payload = typed
+	e.Envelope = events.NewEnvelope(e.Payload.ID, OrderUpdatedSchemaVersion)
+}
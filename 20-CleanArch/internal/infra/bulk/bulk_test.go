@@ -0,0 +1,110 @@
+package bulk
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, reader usecase.ImportRowReader) []usecase.ImportRow {
+	var rows []usecase.ImportRow
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows
+		}
+		assert.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestGivenACSVImport_WhenRead_ThenShouldReportEachRowAtItsLine(t *testing.T) {
+	reader, err := NewReader(FormatCSV, strings.NewReader(
+		"id, price, tax, coupon_code\n"+
+			"a,100,10,\n"+
+			"b,ten,1,\n"+
+			"c,50,,summer10\n"+
+			"d,50\n",
+	))
+	assert.NoError(t, err)
+
+	rows := readAll(t, reader)
+
+	assert.Len(t, rows, 4)
+	assert.Equal(t, usecase.ImportRow{Line: 2, Order: usecase.OrderInputDTO{ID: "a", Price: 100, Tax: 10}}, rows[0])
+	assert.Equal(t, 3, rows[1].Line)
+	assert.EqualError(t, rows[1].Err, `invalid price "ten"`)
+	assert.Equal(t, usecase.OrderInputDTO{ID: "c", Price: 50, CouponCode: "summer10"}, rows[2].Order)
+	assert.NoError(t, rows[2].Err)
+	assert.EqualError(t, rows[3].Err, "expected 4 fields, got 2")
+}
+
+func TestGivenABadCSVHeader_WhenNewReader_ThenShouldReceiveAnError(t *testing.T) {
+	_, err := NewReader(FormatCSV, strings.NewReader("id,price,status\n"))
+	assert.ErrorContains(t, err, `unknown CSV column "status"`)
+	_, err = NewReader(FormatCSV, strings.NewReader("id,tax\n"))
+	assert.EqualError(t, err, `missing CSV column "price"`)
+	_, err = NewReader(FormatCSV, strings.NewReader(""))
+	assert.EqualError(t, err, "missing CSV header")
+}
+
+func TestGivenAnNDJSONImport_WhenRead_ThenShouldDecodeEachLineStrictly(t *testing.T) {
+	reader, err := NewReader(FormatNDJSON, strings.NewReader(
+		`{"id":"a","price":100,"region":"north"}`+"\n"+
+			"\n"+
+			`{"id":"b","price":100,"status":"paid"}`+"\n"+
+			`{"id":"c",`+"\n",
+	))
+	assert.NoError(t, err)
+
+	rows := readAll(t, reader)
+
+	assert.Len(t, rows, 3)
+	assert.Equal(t, usecase.ImportRow{Line: 1, Order: usecase.OrderInputDTO{ID: "a", Price: 100, Region: "north"}}, rows[0])
+	assert.Equal(t, 3, rows[1].Line)
+	assert.ErrorContains(t, rows[1].Err, `unknown field "status"`)
+	assert.Equal(t, 4, rows[2].Line)
+	assert.Error(t, rows[2].Err)
+}
+
+func TestGivenOrders_WhenWriteCSV_ThenShouldWriteAHeaderAndARowEach(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(FormatCSV, &buf)
+
+	assert.NoError(t, writer.Write(usecase.OrderOutputDTO{
+		ID: "a", Price: 90, Tax: 9, FinalPrice: 99, Status: "pending",
+		CreatedAt: time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC), CreatedBy: "alice",
+		CouponCode: "SUMMER10", Discount: 10,
+	}))
+	assert.NoError(t, writer.Flush())
+
+	assert.Equal(t,
+		"id,price,tax,final_price,status,created_at,created_by,category,region,coupon_code,discount\n"+
+			"a,90,9,99,pending,2023-09-01T00:00:00Z,alice,,,SUMMER10,10\n",
+		buf.String())
+}
+
+func TestGivenNoOrders_WhenFlushCSV_ThenShouldWriteTheHeader(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(FormatCSV, &buf)
+
+	assert.NoError(t, writer.Flush())
+
+	assert.Equal(t, "id,price,tax,final_price,status,created_at,created_by,category,region,coupon_code,discount\n", buf.String())
+}
+
+func TestGivenContentTypes_WhenFormatFromContentType_ThenShouldMapThem(t *testing.T) {
+	format, err := FormatFromContentType("text/csv; charset=utf-8")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+	format, err = FormatFromContentType("application/x-ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatNDJSON, format)
+	_, err = FormatFromContentType("application/json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+// Package bulk reads and writes orders in bulk, as CSV or as NDJSON (one
+// JSON object per line).
+package bulk
+
+import (
+	"fmt"
+	"mime"
+)
+
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// ParseFormat reads a format name, defaulting to NDJSON when it is empty.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON, "":
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected csv or ndjson", name)
+}
+
+// FormatFromContentType maps the media type of a request body to its
+// format.
+func FormatFromContentType(contentType string) (Format, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("unsupported content type %q: %w", contentType, err)
+	}
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, nil
+	case "application/x-ndjson", "application/ndjson":
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unsupported content type %q, expected text/csv or application/x-ndjson", contentType)
+}
+
+// ContentType is the media type of documents in f.
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
@@ -0,0 +1,153 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+)
+
+// maxLineSize bounds an NDJSON line, so a stream without newlines cannot
+// exhaust the memory.
+const maxLineSize = 1 << 20
+
+// importColumns are the CSV columns an import understands; id and price
+// are required.
+var importColumns = []string{"id", "price", "tax", "category", "region", "coupon_code"}
+
+// NewReader reads the orders to import from r in format.
+func NewReader(format Format, r io.Reader) (usecase.ImportRowReader, error) {
+	if format == FormatCSV {
+		return newCSVReader(r)
+	}
+	return newNDJSONReader(r), nil
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// newCSVReader reads the header up front, so a file with unknown or missing
+// columns is rejected before any row is imported.
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing CSV header")
+		}
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isImportColumn(name) {
+			return nil, fmt.Errorf("unknown CSV column %q, expected some of %s", name, strings.Join(importColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range importColumns[:2] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func isImportColumn(name string) bool {
+	for _, column := range importColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *csvReader) Next() (usecase.ImportRow, error) {
+	record, err := c.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return usecase.ImportRow{Line: parseErr.StartLine, Err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return usecase.ImportRow{}, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	row := usecase.ImportRow{Line: line}
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	row.Order = usecase.OrderInputDTO{
+		ID:         field("id"),
+		Category:   field("category"),
+		Region:     field("region"),
+		CouponCode: field("coupon_code"),
+	}
+	if len(record) != len(c.columns) {
+		row.Err = fmt.Errorf("expected %d fields, got %d", len(c.columns), len(record))
+		return row, nil
+	}
+	if row.Order.Price, err = strconv.ParseFloat(field("price"), 64); err != nil {
+		row.Err = fmt.Errorf("invalid price %q", field("price"))
+		return row, nil
+	}
+	if tax := field("tax"); tax != "" {
+		if row.Order.Tax, err = strconv.ParseFloat(tax, 64); err != nil {
+			row.Err = fmt.Errorf("invalid tax %q", tax)
+		}
+	}
+	return row, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &ndjsonReader{scanner: scanner}
+}
+
+// Next skips blank lines and decodes the next one strictly: unknown fields
+// and trailing data make the row fail.
+func (n *ndjsonReader) Next() (usecase.ImportRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		row := usecase.ImportRow{Line: n.line}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.Order); err != nil {
+			row.Err = err
+		} else if decoder.More() {
+			row.Err = errors.New("unexpected data after the order")
+		}
+		return row, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return usecase.ImportRow{}, err
+	}
+	return usecase.ImportRow{}, io.EOF
+}
@@ -0,0 +1,85 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+)
+
+// exportColumns are the CSV columns of an export. The tax lines are only
+// part of NDJSON exports.
+var exportColumns = []string{"id", "price", "tax", "final_price", "status", "created_at", "created_by", "category", "region", "coupon_code", "discount"}
+
+// Writer writes exported orders one at a time; Flush must be called after
+// the last one.
+type Writer interface {
+	Write(order usecase.OrderOutputDTO) error
+	Flush() error
+}
+
+// NewWriter writes orders to w in format.
+func NewWriter(format Format, w io.Writer) Writer {
+	if format == FormatCSV {
+		return &csvWriter{writer: csv.NewWriter(w)}
+	}
+	return &ndjsonWriter{encoder: json.NewEncoder(w)}
+}
+
+type csvWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvWriter) Write(order usecase.OrderOutputDTO) error {
+	if !c.wroteHeader {
+		if err := c.writer.Write(exportColumns); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	return c.writer.Write([]string{
+		order.ID,
+		formatFloat(order.Price),
+		formatFloat(order.Tax),
+		formatFloat(order.FinalPrice),
+		order.Status,
+		order.CreatedAt.Format(time.RFC3339Nano),
+		order.CreatedBy,
+		order.Category,
+		order.Region,
+		order.CouponCode,
+		formatFloat(order.Discount),
+	})
+}
+
+// Flush writes the header of an export without orders too.
+func (c *csvWriter) Flush() error {
+	if !c.wroteHeader {
+		if err := c.writer.Write(exportColumns); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(order usecase.OrderOutputDTO) error {
+	return n.encoder.Encode(order)
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return nil
+}
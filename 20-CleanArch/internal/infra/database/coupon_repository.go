@@ -0,0 +1,202 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// CouponRepository stores coupons in the coupons table and their
+// redemptions in coupon_redemptions.
+type CouponRepository struct {
+	Db      *sql.DB
+	dialect dialect
+}
+
+func newCouponRepository(db *sql.DB, dialect dialect) *CouponRepository {
+	return &CouponRepository{Db: db, dialect: dialect}
+}
+
+const couponColumns = "code, discount_type, value, valid_from, valid_until, max_redemptions, max_redemptions_per_customer, redemptions, active, created_at"
+
+func scanCoupon(row rowScanner) (*entity.Coupon, error) {
+	var coupon entity.Coupon
+	var validFrom, validUntil sql.NullTime
+	err := row.Scan(&coupon.Code, &coupon.Type, &coupon.Value, &validFrom, &validUntil,
+		&coupon.MaxRedemptions, &coupon.MaxRedemptionsPerCustomer, &coupon.Redemptions, &coupon.Active, &coupon.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	coupon.ValidFrom = validFrom.Time
+	coupon.ValidUntil = validUntil.Time
+	return &coupon, nil
+}
+
+// nullTime stores the zero time, an open end of a validity window, as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func (r *CouponRepository) Save(ctx context.Context, coupon *entity.Coupon) error {
+	_, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("INSERT INTO coupons ("+couponColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		coupon.Code, coupon.Type, coupon.Value, nullTime(coupon.ValidFrom), nullTime(coupon.ValidUntil),
+		coupon.MaxRedemptions, coupon.MaxRedemptionsPerCustomer, coupon.Redemptions, coupon.Active, coupon.CreatedAt,
+	)
+	if err != nil {
+		if r.dialect.isUniqueViolation(err) {
+			return entity.ErrCouponAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *CouponRepository) FindByCode(ctx context.Context, code string) (*entity.Coupon, error) {
+	return r.findByCode(ctx, r.Db, code)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *CouponRepository) findByCode(ctx context.Context, db queryRower, code string) (*entity.Coupon, error) {
+	coupon, err := scanCoupon(db.QueryRowContext(ctx,
+		r.dialect.rebind("SELECT "+couponColumns+" FROM coupons WHERE code = ?"),
+		entity.NormalizeCouponCode(code),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrCouponNotFound
+	}
+	return coupon, err
+}
+
+func (r *CouponRepository) List(ctx context.Context) ([]*entity.Coupon, error) {
+	rows, err := r.Db.QueryContext(ctx, "SELECT "+couponColumns+" FROM coupons ORDER BY created_at, code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	coupons := []*entity.Coupon{}
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+	return coupons, rows.Err()
+}
+
+func (r *CouponRepository) SetActive(ctx context.Context, code string, active bool) error {
+	result, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("UPDATE coupons SET active = ? WHERE code = ?"),
+		active, entity.NormalizeCouponCode(code),
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// MySQL reports no affected rows when the value does not change.
+		_, err := r.FindByCode(ctx, code)
+		return err
+	}
+	return nil
+}
+
+// Redeem counts the redemption with a guarded increment first. That write
+// locks the coupon row until the transaction ends, so concurrent
+// redemptions of a coupon run one after the other and each counts the
+// customer's redemptions committed before it.
+func (r *CouponRepository) Redeem(ctx context.Context, redemption *entity.CouponRedemption) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx,
+		r.dialect.rebind("UPDATE coupons SET redemptions = redemptions + 1 WHERE code = ? AND (max_redemptions = 0 OR redemptions < max_redemptions)"),
+		redemption.Code,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	coupon, err := r.findByCode(ctx, tx, redemption.Code)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return entity.ErrCouponExhausted
+	}
+	// The increment is already counted, hence the coupon is checked as it
+	// was before it.
+	coupon.Redemptions--
+	if err := coupon.CanRedeem(redemption.RedeemedAt); err != nil {
+		return err
+	}
+	if coupon.MaxRedemptionsPerCustomer > 0 {
+		var redeemed int
+		err := tx.QueryRowContext(ctx,
+			r.dialect.rebind("SELECT COUNT(*) FROM coupon_redemptions WHERE code = ? AND customer = ?"),
+			redemption.Code, redemption.Customer,
+		).Scan(&redeemed)
+		if err != nil {
+			return err
+		}
+		if redeemed >= coupon.MaxRedemptionsPerCustomer {
+			return entity.ErrCouponLimitReached
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		r.dialect.rebind("INSERT INTO coupon_redemptions (code, order_id, customer, discount, redeemed_at) VALUES (?, ?, ?, ?, ?)"),
+		redemption.Code, redemption.OrderID, redemption.Customer, redemption.Discount, redemption.RedeemedAt,
+	)
+	if err != nil {
+		// The coupon was already redeemed for this order ID, so the order
+		// exists or is being created.
+		if r.dialect.isUniqueViolation(err) {
+			return entity.ErrOrderAlreadyExists
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *CouponRepository) Release(ctx context.Context, code string, orderID string) error {
+	code = entity.NormalizeCouponCode(code)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx,
+		r.dialect.rebind("DELETE FROM coupon_redemptions WHERE code = ? AND order_id = ?"),
+		code, orderID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return entity.ErrCouponNotRedeemed
+	}
+	_, err = tx.ExecContext(ctx, r.dialect.rebind("UPDATE coupons SET redemptions = redemptions - 1 WHERE code = ?"), code)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,255 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/stretchr/testify/suite"
+)
+
+type CouponRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func(t *testing.T) entity.CouponRepositoryInterface
+	repo          entity.CouponRepositoryInterface
+}
+
+func (suite *CouponRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository(suite.T())
+}
+
+func TestMemoryCouponRepositoryContract(t *testing.T) {
+	suite.Run(t, &CouponRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.CouponRepositoryInterface {
+			return NewMemoryCouponRepository()
+		},
+	})
+}
+
+func TestSQLiteCouponRepositoryContract(t *testing.T) {
+	suite.Run(t, &CouponRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.CouponRepositoryInterface {
+			return newCouponRepository(openContractDB(t, DriverSQLite, ":memory:"), sqliteDialect)
+		},
+	})
+}
+
+func TestMySQLCouponRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	suite.Run(t, &CouponRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.CouponRepositoryInterface {
+			return newCouponRepository(openContractDB(t, DriverMySQL, dsn), mysqlDialect)
+		},
+	})
+}
+
+func TestPostgresCouponRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	suite.Run(t, &CouponRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.CouponRepositoryInterface {
+			return newCouponRepository(openContractDB(t, DriverPostgres, dsn), postgresDialect)
+		},
+	})
+}
+
+func (suite *CouponRepositoryContractSuite) saveCoupon(code string, maxRedemptions, maxPerCustomer int) *entity.Coupon {
+	coupon, err := entity.NewCoupon(code, entity.DiscountPercentage, 10, time.Time{}, time.Time{}, maxRedemptions, maxPerCustomer)
+	suite.NoError(err)
+	coupon.CreatedAt = coupon.CreatedAt.Truncate(time.Second)
+	suite.NoError(suite.repo.Save(context.Background(), coupon))
+	return coupon
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenACoupon_WhenSaveAndFindByCode_ThenShouldReturnIt() {
+	coupon, err := entity.NewCoupon("summer10", entity.DiscountFixed, 5,
+		time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), time.Time{}, 100, 1)
+	suite.NoError(err)
+	coupon.CreatedAt = time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	suite.NoError(suite.repo.Save(context.Background(), coupon))
+
+	found, err := suite.repo.FindByCode(context.Background(), " Summer10 ")
+	suite.NoError(err)
+	suite.Equal("SUMMER10", found.Code)
+	suite.Equal(entity.DiscountFixed, found.Type)
+	suite.Equal(5.0, found.Value)
+	suite.True(found.ValidFrom.Equal(coupon.ValidFrom))
+	suite.True(found.ValidUntil.IsZero())
+	suite.Equal(100, found.MaxRedemptions)
+	suite.Equal(1, found.MaxRedemptionsPerCustomer)
+	suite.True(found.Active)
+	suite.True(found.CreatedAt.Equal(coupon.CreatedAt))
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenAnExistingCode_WhenSave_ThenShouldReturnAlreadyExists() {
+	suite.saveCoupon("SUMMER10", 0, 0)
+
+	coupon, err := entity.NewCoupon("summer10", entity.DiscountFixed, 5, time.Time{}, time.Time{}, 0, 0)
+	suite.NoError(err)
+	suite.ErrorIs(suite.repo.Save(context.Background(), coupon), entity.ErrCouponAlreadyExists)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenAnUnknownCode_WhenFindOrSetActive_ThenShouldReturnNotFound() {
+	_, err := suite.repo.FindByCode(context.Background(), "NOPE")
+	suite.ErrorIs(err, entity.ErrCouponNotFound)
+	suite.ErrorIs(suite.repo.SetActive(context.Background(), "NOPE", false), entity.ErrCouponNotFound)
+	suite.ErrorIs(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("NOPE", "order-1", "alice", 1)), entity.ErrCouponNotFound)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenCoupons_WhenList_ThenShouldReturnThemOldestFirst() {
+	for i, code := range []string{"B", "A", "C"} {
+		coupon, err := entity.NewCoupon(code, entity.DiscountFixed, 5, time.Time{}, time.Time{}, 0, 0)
+		suite.NoError(err)
+		coupon.CreatedAt = time.Date(2023, 5, 1, i, 0, 0, 0, time.UTC)
+		suite.NoError(suite.repo.Save(context.Background(), coupon))
+	}
+
+	coupons, err := suite.repo.List(context.Background())
+	suite.NoError(err)
+	suite.Len(coupons, 3)
+	suite.Equal("B", coupons[0].Code)
+	suite.Equal("A", coupons[1].Code)
+	suite.Equal("C", coupons[2].Code)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenADeactivatedCoupon_WhenRedeem_ThenShouldReturnInactive() {
+	suite.saveCoupon("SUMMER10", 0, 0)
+	suite.NoError(suite.repo.SetActive(context.Background(), "summer10", false))
+	suite.NoError(suite.repo.SetActive(context.Background(), "summer10", false))
+
+	found, err := suite.repo.FindByCode(context.Background(), "SUMMER10")
+	suite.NoError(err)
+	suite.False(found.Active)
+	err = suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-1", "alice", 1))
+	suite.ErrorIs(err, entity.ErrCouponInactive)
+
+	found, err = suite.repo.FindByCode(context.Background(), "SUMMER10")
+	suite.NoError(err)
+	suite.Equal(0, found.Redemptions)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenACouponOutsideItsWindow_WhenRedeem_ThenShouldReturnInactive() {
+	coupon, err := entity.NewCoupon("LATER", entity.DiscountFixed, 5, time.Now().Add(time.Hour), time.Time{}, 0, 0)
+	suite.NoError(err)
+	suite.NoError(suite.repo.Save(context.Background(), coupon))
+
+	err = suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("LATER", "order-1", "alice", 5))
+	suite.ErrorIs(err, entity.ErrCouponInactive)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenACappedCoupon_WhenRedeemPastTheCap_ThenShouldReturnExhausted() {
+	suite.saveCoupon("SUMMER10", 2, 0)
+
+	suite.NoError(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-1", "alice", 1)))
+	suite.NoError(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-2", "bob", 1)))
+	err := suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-3", "carol", 1))
+	suite.ErrorIs(err, entity.ErrCouponExhausted)
+
+	found, err := suite.repo.FindByCode(context.Background(), "SUMMER10")
+	suite.NoError(err)
+	suite.Equal(2, found.Redemptions)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenAPerCustomerLimit_WhenTheCustomerRedeemsAgain_ThenShouldReturnLimitReached() {
+	suite.saveCoupon("SUMMER10", 0, 1)
+
+	suite.NoError(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-1", "alice", 1)))
+	err := suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-2", "alice", 1))
+	suite.ErrorIs(err, entity.ErrCouponLimitReached)
+	suite.NoError(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-3", "bob", 1)))
+
+	found, err := suite.repo.FindByCode(context.Background(), "SUMMER10")
+	suite.NoError(err)
+	suite.Equal(2, found.Redemptions)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenARedeemedOrder_WhenRedeemAgain_ThenShouldReturnOrderAlreadyExists() {
+	suite.saveCoupon("SUMMER10", 0, 0)
+
+	suite.NoError(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-1", "alice", 1)))
+	err := suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-1", "alice", 1))
+	suite.ErrorIs(err, entity.ErrOrderAlreadyExists)
+
+	found, err := suite.repo.FindByCode(context.Background(), "SUMMER10")
+	suite.NoError(err)
+	suite.Equal(1, found.Redemptions)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenARedemption_WhenRelease_ThenShouldFreeItsSlot() {
+	suite.saveCoupon("SUMMER10", 1, 1)
+	suite.NoError(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-1", "alice", 1)))
+
+	suite.NoError(suite.repo.Release(context.Background(), "summer10", "order-1"))
+	suite.ErrorIs(suite.repo.Release(context.Background(), "SUMMER10", "order-1"), entity.ErrCouponNotRedeemed)
+
+	suite.NoError(suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", "order-2", "alice", 1)))
+	found, err := suite.repo.FindByCode(context.Background(), "SUMMER10")
+	suite.NoError(err)
+	suite.Equal(1, found.Redemptions)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenConcurrentRedemptions_WhenTheyRace_ThenShouldHonourTheCaps() {
+	suite.saveCoupon("SUMMER10", 5, 2)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			customer := fmt.Sprintf("customer-%d", i%2)
+			results <- suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", fmt.Sprintf("order-%02d", i), customer, 1))
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	redeemed := 0
+	for err := range results {
+		if err == nil {
+			redeemed++
+			continue
+		}
+		suite.ErrorIs(err, entity.ErrCouponLimitReached)
+	}
+	// Two customers may redeem twice each, within the overall cap of five.
+	suite.Equal(4, redeemed)
+	found, err := suite.repo.FindByCode(context.Background(), "SUMMER10")
+	suite.NoError(err)
+	suite.Equal(4, found.Redemptions)
+}
+
+func (suite *CouponRepositoryContractSuite) TestGivenConcurrentRedemptions_WhenTheyExceedTheCap_ThenOnlyTheCapShouldSucceed() {
+	suite.saveCoupon("SUMMER10", 3, 0)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results <- suite.repo.Redeem(context.Background(), entity.NewCouponRedemption("SUMMER10", fmt.Sprintf("order-%02d", i), "alice", 1))
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	redeemed := 0
+	for err := range results {
+		if err == nil {
+			redeemed++
+			continue
+		}
+		suite.ErrorIs(err, entity.ErrCouponExhausted)
+	}
+	suite.Equal(3, redeemed)
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database/placeholder"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Supported values for configs.DBDriver.
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite3"
+	DriverMemory   = "memory"
+)
+
+// dialect holds what differs between the SQL backends: placeholder syntax and
+// how a primary key violation is reported.
+type dialect struct {
+	name               string
+	numberedParameters bool
+	isUniqueViolation  func(err error) bool
+}
+
+var (
+	mysqlDialect = dialect{
+		name: DriverMySQL,
+		isUniqueViolation: func(err error) bool {
+			var mysqlErr *mysql.MySQLError
+			return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+		},
+	}
+	postgresDialect = dialect{
+		name:               DriverPostgres,
+		numberedParameters: true,
+		isUniqueViolation: func(err error) bool {
+			var pqErr *pq.Error
+			return errors.As(err, &pqErr) && pqErr.Code == "23505"
+		},
+	}
+	sqliteDialect = dialect{
+		name: DriverSQLite,
+		isUniqueViolation: func(err error) bool {
+			var sqliteErr sqlite3.Error
+			return errors.As(err, &sqliteErr) &&
+				(sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique)
+		},
+	}
+)
+
+// rebind rewrites ? placeholders into $1, $2... for backends that need it.
+func (d dialect) rebind(query string) string {
+	if !d.numberedParameters {
+		return query
+	}
+	return placeholder.Number(query)
+}
+
+// inClause returns the "(?, ?, ...)" list for an IN condition on values
+// and the matching arguments.
+func inClause(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
+
+// NewOrderRepositoryForDriver returns the OrderRepositoryInterface
+// implementation matching configs.DBDriver. db is ignored, and may be nil,
+// for the in-memory store.
+func NewOrderRepositoryForDriver(driver string, db *sql.DB) (entity.OrderRepositoryInterface, error) {
+	switch driver {
+	case DriverMySQL:
+		return NewOrderRepository(db), nil
+	case DriverPostgres:
+		return NewPostgresOrderRepository(db), nil
+	case DriverSQLite:
+		return NewSQLiteOrderRepository(db), nil
+	case DriverMemory:
+		return NewMemoryOrderRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// NewIdempotencyRepositoryForDriver returns the idempotency store matching
+// configs.DBDriver, keeping records for retention.
+func NewIdempotencyRepositoryForDriver(driver string, db *sql.DB, retention time.Duration) (entity.IdempotencyRepositoryInterface, error) {
+	switch driver {
+	case DriverMySQL:
+		return newIdempotencyRepository(db, retention, mysqlDialect), nil
+	case DriverPostgres:
+		return newIdempotencyRepository(db, retention, postgresDialect), nil
+	case DriverSQLite:
+		return newIdempotencyRepository(db, retention, sqliteDialect), nil
+	case DriverMemory:
+		return NewMemoryIdempotencyRepository(retention), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// NewInvoiceRepositoryForDriver returns the invoice store matching
+// configs.DBDriver.
+func NewInvoiceRepositoryForDriver(driver string, db *sql.DB) (entity.InvoiceRepositoryInterface, error) {
+	switch driver {
+	case DriverMySQL:
+		return newInvoiceRepository(db, mysqlDialect), nil
+	case DriverPostgres:
+		return newInvoiceRepository(db, postgresDialect), nil
+	case DriverSQLite:
+		return newInvoiceRepository(db, sqliteDialect), nil
+	case DriverMemory:
+		return NewMemoryInvoiceRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// NewCouponRepositoryForDriver returns the coupon store matching
+// configs.DBDriver.
+func NewCouponRepositoryForDriver(driver string, db *sql.DB) (entity.CouponRepositoryInterface, error) {
+	switch driver {
+	case DriverMySQL:
+		return newCouponRepository(db, mysqlDialect), nil
+	case DriverPostgres:
+		return newCouponRepository(db, postgresDialect), nil
+	case DriverSQLite:
+		return newCouponRepository(db, sqliteDialect), nil
+	case DriverMemory:
+		return NewMemoryCouponRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// NewOrderReportRepositoryForDriver returns the reporting projection store
+// matching configs.DBDriver.
+func NewOrderReportRepositoryForDriver(driver string, db *sql.DB) (entity.OrderReportRepositoryInterface, error) {
+	switch driver {
+	case DriverMySQL:
+		return newOrderReportRepository(db, mysqlDialect), nil
+	case DriverPostgres:
+		return newOrderReportRepository(db, postgresDialect), nil
+	case DriverSQLite:
+		return newOrderReportRepository(db, sqliteDialect), nil
+	case DriverMemory:
+		return NewMemoryOrderReportRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// NewWebhookRepositoryForDriver returns the webhook subscription and
+// delivery store matching configs.DBDriver.
+func NewWebhookRepositoryForDriver(driver string, db *sql.DB) (entity.WebhookRepositoryInterface, error) {
+	switch driver {
+	case DriverMySQL:
+		return newWebhookRepository(db, mysqlDialect), nil
+	case DriverPostgres:
+		return newWebhookRepository(db, postgresDialect), nil
+	case DriverSQLite:
+		return newWebhookRepository(db, sqliteDialect), nil
+	case DriverMemory:
+		return NewMemoryWebhookRepository(), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAPostgresDialect_WhenRebind_ThenShouldNumberPlaceholders(t *testing.T) {
+	query := "SELECT id FROM orders WHERE price >= ? AND (price > ? OR (price = ? AND id > ?)) LIMIT ?"
+	assert.Equal(t, "SELECT id FROM orders WHERE price >= $1 AND (price > $2 OR (price = $3 AND id > $4)) LIMIT $5", postgresDialect.rebind(query))
+	assert.Equal(t, query, mysqlDialect.rebind(query))
+}
+
+func TestGivenAnUnknownDriver_WhenNewOrderRepositoryForDriver_ThenShouldReceiveAnError(t *testing.T) {
+	_, err := NewOrderRepositoryForDriver("oracle", nil)
+	assert.Error(t, err)
+
+	repo, err := NewOrderRepositoryForDriver(DriverMemory, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryOrderRepository{}, repo)
+}
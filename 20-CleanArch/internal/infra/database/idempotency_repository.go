@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// IdempotencyRepository keeps idempotency records in the idempotency_keys
+// table for retention after they are created.
+type IdempotencyRepository struct {
+	Db        *sql.DB
+	retention time.Duration
+	dialect   dialect
+}
+
+func newIdempotencyRepository(db *sql.DB, retention time.Duration, dialect dialect) *IdempotencyRepository {
+	return &IdempotencyRepository{Db: db, retention: retention, dialect: dialect}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	record.ExpiresAt = record.CreatedAt.Add(r.retention)
+	// A second attempt covers a key that expired, was released or was taken
+	// over between the failed insert and the lookup.
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := r.Db.ExecContext(ctx,
+			r.dialect.rebind("INSERT INTO idempotency_keys (idempotency_key, request_hash, created_at, expires_at, locked_until, attempts) VALUES (?, ?, ?, ?, ?, ?)"),
+			record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt, record.LockedUntil, record.Attempts,
+		)
+		if err == nil {
+			return nil, nil
+		}
+		if !r.dialect.isUniqueViolation(err) {
+			return nil, err
+		}
+
+		existing, err := r.get(ctx, record.Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if existing.IsExpired(record.CreatedAt) {
+			_, err = r.Db.ExecContext(ctx,
+				r.dialect.rebind("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND expires_at <= ?"),
+				record.Key, record.CreatedAt,
+			)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !existing.CanTakeOver(record, record.CreatedAt) {
+			return existing, nil
+		}
+		// The attempt count guards the takeover, so only one retry wins it.
+		result, err := r.Db.ExecContext(ctx,
+			r.dialect.rebind("UPDATE idempotency_keys SET locked_until = ?, attempts = attempts + 1 WHERE idempotency_key = ? AND response IS NULL AND attempts = ?"),
+			record.LockedUntil, record.Key, existing.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 1 {
+			record.CreatedAt, record.ExpiresAt, record.Attempts = existing.CreatedAt, existing.ExpiresAt, existing.Attempts+1
+			return nil, nil
+		}
+	}
+	return nil, entity.ErrIdempotencyKeyInProgress
+}
+
+func (r *IdempotencyRepository) get(ctx context.Context, key string) (*entity.IdempotencyRecord, error) {
+	var record entity.IdempotencyRecord
+	var response sql.NullString
+	var lockedUntil sql.NullTime
+	err := r.Db.QueryRowContext(ctx,
+		r.dialect.rebind("SELECT idempotency_key, request_hash, response, created_at, expires_at, locked_until, attempts FROM idempotency_keys WHERE idempotency_key = ?"),
+		key,
+	).Scan(&record.Key, &record.RequestHash, &response, &record.CreatedAt, &record.ExpiresAt, &lockedUntil, &record.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	if response.Valid {
+		record.Response = []byte(response.String)
+	}
+	// Records reserved before leases existed have none, and can be taken
+	// over right away.
+	record.LockedUntil = lockedUntil.Time
+	return &record, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, response []byte) error {
+	_, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("UPDATE idempotency_keys SET response = ? WHERE idempotency_key = ?"),
+		string(response), key,
+	)
+	return err
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND response IS NULL"),
+		key,
+	)
+	return err
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("DELETE FROM idempotency_keys WHERE expires_at <= ?"),
+		before.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencyRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func(t *testing.T) entity.IdempotencyRepositoryInterface
+	repo          entity.IdempotencyRepositoryInterface
+}
+
+func (suite *IdempotencyRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository(suite.T())
+}
+
+func TestMemoryIdempotencyRepositoryContract(t *testing.T) {
+	suite.Run(t, &IdempotencyRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.IdempotencyRepositoryInterface {
+			return NewMemoryIdempotencyRepository(time.Hour)
+		},
+	})
+}
+
+func TestSQLiteIdempotencyRepositoryContract(t *testing.T) {
+	suite.Run(t, &IdempotencyRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.IdempotencyRepositoryInterface {
+			return newIdempotencyRepository(openContractDB(t, DriverSQLite, ":memory:"), time.Hour, sqliteDialect)
+		},
+	})
+}
+
+func TestMySQLIdempotencyRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	suite.Run(t, &IdempotencyRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.IdempotencyRepositoryInterface {
+			return newIdempotencyRepository(openContractDB(t, DriverMySQL, dsn), time.Hour, mysqlDialect)
+		},
+	})
+}
+
+func TestPostgresIdempotencyRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	suite.Run(t, &IdempotencyRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.IdempotencyRepositoryInterface {
+			return newIdempotencyRepository(openContractDB(t, DriverPostgres, dsn), time.Hour, postgresDialect)
+		},
+	})
+}
+
+func (suite *IdempotencyRepositoryContractSuite) newRecord(key string, age time.Duration) *entity.IdempotencyRecord {
+	record, err := entity.NewIdempotencyRecord(key, "hash-"+key)
+	suite.NoError(err)
+	record.CreatedAt = record.CreatedAt.Add(-age)
+	record.LockedUntil = record.LockedUntil.Add(-age)
+	return record
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenAFreshKey_WhenReserve_ThenShouldOwnIt() {
+	existing, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.Nil(existing)
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenAReservedKey_WhenReserveAgain_ThenShouldReturnTheRecordInProgress() {
+	_, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+
+	existing, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.NotNil(existing)
+	suite.Equal("hash-key-1", existing.RequestHash)
+	suite.False(existing.IsCompleted())
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenAPendingKeyWhoseLeaseRanOut_WhenTheSameRequestReservesIt_ThenShouldTakeItOverOnce() {
+	_, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 2*entity.IdempotencyLease))
+	suite.NoError(err)
+
+	retry := suite.newRecord("key-1", 0)
+	existing, err := suite.repo.Reserve(context.Background(), retry)
+	suite.NoError(err)
+	suite.Nil(existing)
+	suite.Equal(2, retry.Attempts)
+
+	existing, err = suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.NotNil(existing)
+	suite.Equal(2, existing.Attempts)
+	suite.False(existing.IsCompleted())
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenAKeyWhoseLeaseRanOut_WhenAnotherRequestOrTheCompletedOneReservesIt_ThenShouldReturnTheRecord() {
+	_, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 2*entity.IdempotencyLease))
+	suite.NoError(err)
+	_, err = suite.repo.Reserve(context.Background(), suite.newRecord("key-2", 2*entity.IdempotencyLease))
+	suite.NoError(err)
+	suite.NoError(suite.repo.Complete(context.Background(), "key-2", []byte(`{"id":"a"}`)))
+
+	other := suite.newRecord("key-1", 0)
+	other.RequestHash = "another-hash"
+	existing, err := suite.repo.Reserve(context.Background(), other)
+	suite.NoError(err)
+	suite.NotNil(existing)
+	suite.Equal("hash-key-1", existing.RequestHash)
+
+	existing, err = suite.repo.Reserve(context.Background(), suite.newRecord("key-2", 0))
+	suite.NoError(err)
+	suite.NotNil(existing)
+	suite.Equal(`{"id":"a"}`, string(existing.Response))
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenACompletedKey_WhenReserveAgain_ThenShouldReturnTheResponse() {
+	_, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.NoError(suite.repo.Complete(context.Background(), "key-1", []byte(`{"id":"a"}`)))
+	suite.NoError(suite.repo.Release(context.Background(), "key-1"))
+
+	existing, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.NotNil(existing)
+	suite.Equal(`{"id":"a"}`, string(existing.Response))
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenAReleasedKey_WhenReserveAgain_ThenShouldOwnIt() {
+	_, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.NoError(suite.repo.Release(context.Background(), "key-1"))
+
+	existing, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.Nil(existing)
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenAnExpiredKey_WhenReserveAgain_ThenShouldOwnIt() {
+	_, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 2*time.Hour))
+	suite.NoError(err)
+	suite.NoError(suite.repo.Complete(context.Background(), "key-1", []byte(`{"id":"a"}`)))
+
+	existing, err := suite.repo.Reserve(context.Background(), suite.newRecord("key-1", 0))
+	suite.NoError(err)
+	suite.Nil(existing)
+}
+
+func (suite *IdempotencyRepositoryContractSuite) TestGivenExpiredKeys_WhenDeleteExpired_ThenShouldKeepLiveOnes() {
+	_, err := suite.repo.Reserve(context.Background(), suite.newRecord("old-1", 2*time.Hour))
+	suite.NoError(err)
+	_, err = suite.repo.Reserve(context.Background(), suite.newRecord("old-2", 3*time.Hour))
+	suite.NoError(err)
+	_, err = suite.repo.Reserve(context.Background(), suite.newRecord("live", 0))
+	suite.NoError(err)
+
+	deleted, err := suite.repo.DeleteExpired(context.Background(), time.Now())
+	suite.NoError(err)
+	suite.Equal(int64(2), deleted)
+
+	existing, err := suite.repo.Reserve(context.Background(), suite.newRecord("live", 0))
+	suite.NoError(err)
+	suite.NotNil(existing)
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// InvoiceRepository stores invoices in the invoices table. The unique
+// order_id column makes Save safe to retry for the same order.
+type InvoiceRepository struct {
+	Db      *sql.DB
+	dialect dialect
+}
+
+func newInvoiceRepository(db *sql.DB, dialect dialect) *InvoiceRepository {
+	return &InvoiceRepository{Db: db, dialect: dialect}
+}
+
+func (r *InvoiceRepository) Save(ctx context.Context, invoice *entity.Invoice) error {
+	_, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("INSERT INTO invoices ("+invoiceColumns+") VALUES (?, ?, ?, ?, ?, ?)"),
+		invoice.ID, invoice.OrderID, invoice.Amount, invoice.Tax, invoice.Total, invoice.IssuedAt,
+	)
+	if err != nil {
+		if r.dialect.isUniqueViolation(err) {
+			return entity.ErrInvoiceAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+const invoiceColumns = "id, order_id, amount, tax, total, issued_at"
+
+func (r *InvoiceRepository) FindByOrderID(ctx context.Context, orderID string) (*entity.Invoice, error) {
+	invoice, err := scanInvoice(r.Db.QueryRowContext(ctx,
+		r.dialect.rebind("SELECT "+invoiceColumns+" FROM invoices WHERE order_id = ?"),
+		orderID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// FindByOrderIDs reads all the invoices in a single query, which is what
+// the GraphQL dataloader batches lookups into.
+func (r *InvoiceRepository) FindByOrderIDs(ctx context.Context, orderIDs []string) ([]*entity.Invoice, error) {
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+	in, args := inClause(orderIDs)
+	rows, err := r.Db.QueryContext(ctx,
+		r.dialect.rebind("SELECT "+invoiceColumns+" FROM invoices WHERE order_id IN "+in),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invoices []*entity.Invoice
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+	return invoices, rows.Err()
+}
+
+func scanInvoice(row rowScanner) (*entity.Invoice, error) {
+	var invoice entity.Invoice
+	err := row.Scan(&invoice.ID, &invoice.OrderID, &invoice.Amount, &invoice.Tax, &invoice.Total, &invoice.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/stretchr/testify/suite"
+)
+
+type InvoiceRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func(t *testing.T) entity.InvoiceRepositoryInterface
+	repo          entity.InvoiceRepositoryInterface
+}
+
+func (suite *InvoiceRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository(suite.T())
+}
+
+func TestMemoryInvoiceRepositoryContract(t *testing.T) {
+	suite.Run(t, &InvoiceRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.InvoiceRepositoryInterface {
+			return NewMemoryInvoiceRepository()
+		},
+	})
+}
+
+func TestSQLiteInvoiceRepositoryContract(t *testing.T) {
+	suite.Run(t, &InvoiceRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.InvoiceRepositoryInterface {
+			return newInvoiceRepository(openContractDB(t, DriverSQLite, ":memory:"), sqliteDialect)
+		},
+	})
+}
+
+func TestMySQLInvoiceRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	suite.Run(t, &InvoiceRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.InvoiceRepositoryInterface {
+			return newInvoiceRepository(openContractDB(t, DriverMySQL, dsn), mysqlDialect)
+		},
+	})
+}
+
+func TestPostgresInvoiceRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	suite.Run(t, &InvoiceRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.InvoiceRepositoryInterface {
+			return newInvoiceRepository(openContractDB(t, DriverPostgres, dsn), postgresDialect)
+		},
+	})
+}
+
+func (suite *InvoiceRepositoryContractSuite) TestGivenAnInvoice_WhenSave_ThenShouldFindItByOrderID() {
+	invoice, err := entity.NewInvoice("123", 10.5, 2.25)
+	suite.NoError(err)
+	suite.NoError(suite.repo.Save(context.Background(), invoice))
+
+	found, err := suite.repo.FindByOrderID(context.Background(), "123")
+	suite.NoError(err)
+	suite.Equal(invoice.ID, found.ID)
+	suite.Equal(invoice.Amount, found.Amount)
+	suite.Equal(invoice.Tax, found.Tax)
+	suite.Equal(invoice.Total, found.Total)
+	suite.True(invoice.IssuedAt.Equal(found.IssuedAt))
+}
+
+func (suite *InvoiceRepositoryContractSuite) TestGivenAnInvoicedOrder_WhenSaveAgain_ThenShouldReturnErrInvoiceAlreadyExists() {
+	invoice, err := entity.NewInvoice("123", 10, 2)
+	suite.NoError(err)
+	suite.NoError(suite.repo.Save(context.Background(), invoice))
+
+	suite.ErrorIs(suite.repo.Save(context.Background(), invoice), entity.ErrInvoiceAlreadyExists)
+}
+
+func (suite *InvoiceRepositoryContractSuite) TestGivenAnUnknownOrder_WhenFindByOrderID_ThenShouldReturnErrInvoiceNotFound() {
+	_, err := suite.repo.FindByOrderID(context.Background(), "missing")
+	suite.ErrorIs(err, entity.ErrInvoiceNotFound)
+}
+
+func (suite *InvoiceRepositoryContractSuite) TestGivenSomeInvoicedOrders_WhenFindByOrderIDs_ThenShouldReturnOnlyTheirInvoices() {
+	for _, orderID := range []string{"1", "2", "3"} {
+		invoice, err := entity.NewInvoice(orderID, 10, 1)
+		suite.NoError(err)
+		suite.NoError(suite.repo.Save(context.Background(), invoice))
+	}
+
+	invoices, err := suite.repo.FindByOrderIDs(context.Background(), []string{"1", "3", "missing"})
+	suite.NoError(err)
+	orderIDs := make([]string, len(invoices))
+	for i, invoice := range invoices {
+		orderIDs[i] = invoice.OrderID
+	}
+	suite.ElementsMatch([]string{"1", "3"}, orderIDs)
+
+	invoices, err = suite.repo.FindByOrderIDs(context.Background(), nil)
+	suite.NoError(err)
+	suite.Empty(invoices)
+}
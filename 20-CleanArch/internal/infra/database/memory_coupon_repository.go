@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+type couponRedemptionKey struct {
+	code    string
+	orderID string
+}
+
+// MemoryCouponRepository keeps coupons and their redemptions in process;
+// a single lock makes each redemption atomic.
+type MemoryCouponRepository struct {
+	mu          sync.Mutex
+	coupons     map[string]entity.Coupon
+	redemptions map[couponRedemptionKey]entity.CouponRedemption
+}
+
+func NewMemoryCouponRepository() *MemoryCouponRepository {
+	return &MemoryCouponRepository{
+		coupons:     make(map[string]entity.Coupon),
+		redemptions: make(map[couponRedemptionKey]entity.CouponRedemption),
+	}
+}
+
+func (r *MemoryCouponRepository) Save(ctx context.Context, coupon *entity.Coupon) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.coupons[coupon.Code]; ok {
+		return entity.ErrCouponAlreadyExists
+	}
+	r.coupons[coupon.Code] = *coupon
+	return nil
+}
+
+func (r *MemoryCouponRepository) FindByCode(ctx context.Context, code string) (*entity.Coupon, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	coupon, ok := r.coupons[entity.NormalizeCouponCode(code)]
+	if !ok {
+		return nil, entity.ErrCouponNotFound
+	}
+	return &coupon, nil
+}
+
+func (r *MemoryCouponRepository) List(ctx context.Context) ([]*entity.Coupon, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	coupons := make([]*entity.Coupon, 0, len(r.coupons))
+	for _, coupon := range r.coupons {
+		coupon := coupon
+		coupons = append(coupons, &coupon)
+	}
+	r.mu.Unlock()
+	sort.Slice(coupons, func(i, j int) bool {
+		if result := compareTimes(coupons[i].CreatedAt, coupons[j].CreatedAt); result != 0 {
+			return result < 0
+		}
+		return coupons[i].Code < coupons[j].Code
+	})
+	return coupons, nil
+}
+
+func (r *MemoryCouponRepository) SetActive(ctx context.Context, code string, active bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	coupon, ok := r.coupons[entity.NormalizeCouponCode(code)]
+	if !ok {
+		return entity.ErrCouponNotFound
+	}
+	coupon.Active = active
+	r.coupons[coupon.Code] = coupon
+	return nil
+}
+
+func (r *MemoryCouponRepository) Redeem(ctx context.Context, redemption *entity.CouponRedemption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	coupon, ok := r.coupons[redemption.Code]
+	if !ok {
+		return entity.ErrCouponNotFound
+	}
+	if err := coupon.CanRedeem(redemption.RedeemedAt); err != nil {
+		return err
+	}
+	if coupon.MaxRedemptionsPerCustomer > 0 {
+		redeemed := 0
+		for key, existing := range r.redemptions {
+			if key.code == coupon.Code && existing.Customer == redemption.Customer {
+				redeemed++
+			}
+		}
+		if redeemed >= coupon.MaxRedemptionsPerCustomer {
+			return entity.ErrCouponLimitReached
+		}
+	}
+	key := couponRedemptionKey{code: redemption.Code, orderID: redemption.OrderID}
+	if _, ok := r.redemptions[key]; ok {
+		return entity.ErrOrderAlreadyExists
+	}
+	r.redemptions[key] = *redemption
+	coupon.Redemptions++
+	r.coupons[coupon.Code] = coupon
+	return nil
+}
+
+func (r *MemoryCouponRepository) Release(ctx context.Context, code string, orderID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := couponRedemptionKey{code: entity.NormalizeCouponCode(code), orderID: orderID}
+	if _, ok := r.redemptions[key]; !ok {
+		return entity.ErrCouponNotRedeemed
+	}
+	delete(r.redemptions, key)
+	coupon := r.coupons[key.code]
+	coupon.Redemptions--
+	r.coupons[key.code] = coupon
+	return nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+type MemoryIdempotencyRepository struct {
+	mu        sync.Mutex
+	records   map[string]entity.IdempotencyRecord
+	retention time.Duration
+}
+
+func NewMemoryIdempotencyRepository(retention time.Duration) *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{
+		records:   make(map[string]entity.IdempotencyRecord),
+		retention: retention,
+	}
+}
+
+func (r *MemoryIdempotencyRepository) Reserve(ctx context.Context, record *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	record.ExpiresAt = record.CreatedAt.Add(r.retention)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.records[record.Key]; ok && !existing.IsExpired(record.CreatedAt) {
+		if !existing.CanTakeOver(record, record.CreatedAt) {
+			return &existing, nil
+		}
+		record.CreatedAt, record.ExpiresAt, record.Attempts = existing.CreatedAt, existing.ExpiresAt, existing.Attempts+1
+	}
+	r.records[record.Key] = *record
+	return nil, nil
+}
+
+func (r *MemoryIdempotencyRepository) Complete(ctx context.Context, key string, response []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.records[key]; ok {
+		record.Response = append([]byte(nil), response...)
+		r.records[key] = record
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.records[key]; ok && !record.IsCompleted() {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for key, record := range r.records {
+		if record.IsExpired(before) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+type MemoryInvoiceRepository struct {
+	mu       sync.RWMutex
+	invoices map[string]entity.Invoice
+}
+
+func NewMemoryInvoiceRepository() *MemoryInvoiceRepository {
+	return &MemoryInvoiceRepository{
+		invoices: make(map[string]entity.Invoice),
+	}
+}
+
+func (r *MemoryInvoiceRepository) Save(ctx context.Context, invoice *entity.Invoice) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.invoices[invoice.OrderID]; ok {
+		return entity.ErrInvoiceAlreadyExists
+	}
+	r.invoices[invoice.OrderID] = *invoice
+	return nil
+}
+
+func (r *MemoryInvoiceRepository) FindByOrderID(ctx context.Context, orderID string) (*entity.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	invoice, ok := r.invoices[orderID]
+	if !ok {
+		return nil, entity.ErrInvoiceNotFound
+	}
+	return &invoice, nil
+}
+
+func (r *MemoryInvoiceRepository) FindByOrderIDs(ctx context.Context, orderIDs []string) ([]*entity.Invoice, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var invoices []*entity.Invoice
+	for _, orderID := range orderIDs {
+		if invoice, ok := r.invoices[orderID]; ok {
+			invoices = append(invoices, &invoice)
+		}
+	}
+	return invoices, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// MemoryOrderReportRepository keeps the reporting projection in process;
+// a single lock applies one event at a time.
+type MemoryOrderReportRepository struct {
+	mu      sync.Mutex
+	orders  map[string]entity.ReportOrder
+	days    map[time.Time]entity.OrderStats
+	applied map[string]struct{}
+}
+
+func NewMemoryOrderReportRepository() *MemoryOrderReportRepository {
+	return &MemoryOrderReportRepository{
+		orders:  make(map[string]entity.ReportOrder),
+		days:    make(map[time.Time]entity.OrderStats),
+		applied: make(map[string]struct{}),
+	}
+}
+
+func (r *MemoryOrderReportRepository) Project(ctx context.Context, eventID string, orderID string, project func(order *entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.applied[eventID]; ok {
+		return nil
+	}
+	var current *entity.ReportOrder
+	if order, ok := r.orders[orderID]; ok {
+		current = &order
+	}
+	next, stats, err := project(current)
+	if err != nil {
+		return err
+	}
+	r.orders[next.ID] = *next
+	if stats != (entity.OrderStats{Day: stats.Day}) {
+		day := entity.ReportDay(stats.Day)
+		total := r.days[day]
+		total.Day = day
+		total.Add(stats)
+		r.days[day] = total
+	}
+	r.applied[eventID] = struct{}{}
+	return nil
+}
+
+func (r *MemoryOrderReportRepository) Daily(ctx context.Context, from, to time.Time) ([]entity.OrderStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	from, to = entity.ReportDay(from), entity.ReportDay(to)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	days := []entity.OrderStats{}
+	for day, stats := range r.days {
+		if !day.Before(from) && day.Before(to) {
+			days = append(days, stats)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day.Before(days[j].Day) })
+	return days, nil
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// MemoryOrderRepository is a thread-safe, process-local order store with the
+// same filtering, ordering and keyset semantics as OrderRepository.
+type MemoryOrderRepository struct {
+	mu     sync.RWMutex
+	orders map[string]entity.Order
+}
+
+func NewMemoryOrderRepository() *MemoryOrderRepository {
+	return &MemoryOrderRepository{
+		orders: make(map[string]entity.Order),
+	}
+}
+
+func (r *MemoryOrderRepository) Save(ctx context.Context, order *entity.Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.orders[order.ID]; ok {
+		return entity.ErrOrderAlreadyExists
+	}
+	r.orders[order.ID] = stored(order)
+	return nil
+}
+
+func (r *MemoryOrderRepository) FindByID(ctx context.Context, id string) (*entity.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, entity.ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (r *MemoryOrderRepository) Update(ctx context.Context, order *entity.Order, previousStatus entity.OrderStatus) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.orders[order.ID]
+	if !ok {
+		return entity.ErrOrderNotFound
+	}
+	if current.Status != previousStatus {
+		return entity.ErrOrderModified
+	}
+	r.orders[order.ID] = stored(order)
+	return nil
+}
+
+// stored copies order without its pending domain events, which belong to
+// the caller, and with tax lines of its own.
+func stored(order *entity.Order) entity.Order {
+	saved := *order
+	saved.PullEvents()
+	saved.TaxLines = append([]entity.TaxLine(nil), order.TaxLines...)
+	return saved
+}
+
+func (r *MemoryOrderRepository) GetOrders(ctx context.Context, listOrders *entity.ListOrders) (*entity.OrdersPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	matching := make([]entity.Order, 0, len(r.orders))
+	for _, order := range r.orders {
+		if listOrders.Filter.Matches(order) {
+			matching = append(matching, order)
+		}
+	}
+	r.mu.RUnlock()
+
+	field := listOrders.Sort.Field
+	desc := listOrders.Sort.Direction == entity.SortDesc
+	sort.Slice(matching, func(i, j int) bool {
+		return compareOrders(matching[i], matching[j], field, desc) < 0
+	})
+
+	page := &entity.OrdersPage{Orders: []entity.Order{}, TotalCount: len(matching)}
+	for _, order := range matching {
+		if cursor := listOrders.After; cursor != nil {
+			position := entity.Order{ID: cursor.ID, Price: cursor.Price, FinalPrice: cursor.FinalPrice, CreatedAt: cursor.CreatedAt}
+			if compareOrders(order, position, field, desc) <= 0 {
+				continue
+			}
+		}
+		if len(page.Orders) == listOrders.Limit {
+			page.HasNextPage = true
+			break
+		}
+		page.Orders = append(page.Orders, order)
+	}
+	return page, nil
+}
+
+// compareOrders orders a before b by (field, id), reversed when desc is set.
+func compareOrders(a, b entity.Order, field entity.OrderSortField, desc bool) int {
+	result := compareValues(a, b, field)
+	if result == 0 {
+		result = compareStrings(a.ID, b.ID)
+	}
+	if desc {
+		return -result
+	}
+	return result
+}
+
+func compareValues(a, b entity.Order, field entity.OrderSortField) int {
+	switch field {
+	case entity.OrderSortByPrice:
+		return compareFloats(a.Price, b.Price)
+	case entity.OrderSortByFinalPrice:
+		return compareFloats(a.FinalPrice, b.FinalPrice)
+	default:
+		return compareTimes(a.CreatedAt, b.CreatedAt)
+	}
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
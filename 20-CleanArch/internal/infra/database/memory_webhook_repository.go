@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+type webhookDeliveryKey struct {
+	subscriptionID string
+	eventID        string
+}
+
+// MemoryWebhookRepository keeps webhook subscriptions and their deliveries
+// in process. Stored values are copied in and out, their slices included,
+// so callers never share them.
+type MemoryWebhookRepository struct {
+	mu            sync.Mutex
+	subscriptions map[string]entity.WebhookSubscription
+	deliveries    map[string]entity.WebhookDelivery
+	delivered     map[webhookDeliveryKey]bool
+}
+
+func NewMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		subscriptions: make(map[string]entity.WebhookSubscription),
+		deliveries:    make(map[string]entity.WebhookDelivery),
+		delivered:     make(map[webhookDeliveryKey]bool),
+	}
+}
+
+func copyWebhookSubscription(subscription entity.WebhookSubscription) *entity.WebhookSubscription {
+	subscription.Events = append([]string(nil), subscription.Events...)
+	return &subscription
+}
+
+func copyWebhookDelivery(delivery entity.WebhookDelivery) *entity.WebhookDelivery {
+	delivery.Payload = append([]byte(nil), delivery.Payload...)
+	return &delivery
+}
+
+func (r *MemoryWebhookRepository) SaveSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions[subscription.ID] = *copyWebhookSubscription(*subscription)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) UpdateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.subscriptions[subscription.ID]
+	if !ok {
+		return entity.ErrWebhookNotFound
+	}
+	stored.URL = subscription.URL
+	stored.Events = append([]string(nil), subscription.Events...)
+	stored.Secret = subscription.Secret
+	stored.Active = subscription.Active
+	r.subscriptions[subscription.ID] = stored
+	return nil
+}
+
+func (r *MemoryWebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subscriptions[id]; !ok {
+		return entity.ErrWebhookNotFound
+	}
+	delete(r.subscriptions, id)
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.SubscriptionID == id {
+			delete(r.deliveries, deliveryID)
+			delete(r.delivered, webhookDeliveryKey{subscriptionID: id, eventID: delivery.EventID})
+		}
+	}
+	return nil
+}
+
+func (r *MemoryWebhookRepository) FindSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, entity.ErrWebhookNotFound
+	}
+	return copyWebhookSubscription(subscription), nil
+}
+
+func (r *MemoryWebhookRepository) ListSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	subscriptions := make([]*entity.WebhookSubscription, 0, len(r.subscriptions))
+	for _, subscription := range r.subscriptions {
+		subscriptions = append(subscriptions, copyWebhookSubscription(subscription))
+	}
+	r.mu.Unlock()
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if result := compareTimes(subscriptions[i].CreatedAt, subscriptions[j].CreatedAt); result != 0 {
+			return result < 0
+		}
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+	return subscriptions, nil
+}
+
+func (r *MemoryWebhookRepository) SaveDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range deliveries {
+		key := webhookDeliveryKey{subscriptionID: delivery.SubscriptionID, eventID: delivery.EventID}
+		if r.delivered[key] {
+			continue
+		}
+		r.delivered[key] = true
+		r.deliveries[delivery.ID] = *copyWebhookDelivery(*delivery)
+	}
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*entity.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := []*entity.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.Status == entity.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, copyWebhookDelivery(delivery))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if result := compareTimes(due[i].NextAttemptAt, due[j].NextAttemptAt); result != 0 {
+			return result < 0
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	leasedUntil := now.UTC().Add(lease)
+	for _, delivery := range due {
+		delivery.NextAttemptAt = leasedUntil
+		stored := r.deliveries[delivery.ID]
+		stored.NextAttemptAt = leasedUntil
+		r.deliveries[delivery.ID] = stored
+	}
+	return due, nil
+}
+
+func (r *MemoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.deliveries[delivery.ID]
+	if !ok {
+		return entity.ErrWebhookDeliveryNotFound
+	}
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.LastAttemptAt = delivery.LastAttemptAt
+	stored.ResponseStatus = delivery.ResponseStatus
+	stored.LastError = delivery.LastError
+	r.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (r *MemoryWebhookRepository) FindDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, entity.ErrWebhookDeliveryNotFound
+	}
+	return copyWebhookDelivery(delivery), nil
+}
+
+func (r *MemoryWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID string, status entity.WebhookDeliveryStatus, limit int) ([]*entity.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	deliveries := []*entity.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, copyWebhookDelivery(delivery))
+		}
+	}
+	r.mu.Unlock()
+	sort.Slice(deliveries, func(i, j int) bool {
+		if result := compareTimes(deliveries[i].CreatedAt, deliveries[j].CreatedAt); result != 0 {
+			return result > 0
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
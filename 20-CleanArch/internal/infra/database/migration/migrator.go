@@ -0,0 +1,320 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database/placeholder"
+	"github.com/go-sql-driver/mysql"
+)
+
+//go:embed sql
+var migrationFiles embed.FS
+
+var (
+	ErrUnsupportedDriver = errors.New("migrations are not supported for this driver")
+	ErrLockTimeout       = errors.New("timed out waiting for the migration lock")
+)
+
+// lockName identifies the advisory lock held while migrating, so replicas
+// starting together apply each version once.
+const lockName = "ordersystem_schema_migrations"
+
+var (
+	fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrator applies the SQL files embedded under sql/<driver> in version
+// order and records them in the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, driver string) (*Migrator, error) {
+	migrations, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		driver:     driver,
+		migrations: migrations,
+	}, nil
+}
+
+func load(driver string) ([]Migration, error) {
+	dir := path.Join("sql", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, ErrUnsupportedDriver
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, migration.Up, true); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the latest steps applied migrations and returns how many ran.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, migration.Down, false); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	versions, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := versions[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, script string, up bool) error {
+	if m.driver == "mysql" {
+		return m.applyMySQL(ctx, conn, migration, script, up)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range statements(script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	if err := m.record(ctx, tx, migration, up); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applyMySQL runs the statements one by one: MySQL commits each DDL
+// statement on its own, so a migration failing halfway stays half applied
+// and unrecorded. Statements the failed run already applied are skipped when
+// it is run again, which lets the migration complete.
+func (m *Migrator) applyMySQL(ctx context.Context, conn *sql.Conn, migration Migration, script string, up bool) error {
+	for _, statement := range statements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil && !alreadyApplied(err, up) {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return m.record(ctx, conn, migration, up)
+}
+
+// alreadyApplied reports whether err is MySQL refusing a statement because
+// its change is already there: a table, column or index that already exists
+// going up, or one that is already gone going down. Each ALTER TABLE is
+// atomic, so one that fails this way was applied whole.
+func alreadyApplied(err error, up bool) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case 1050, 1060, 1061: // table, column or key name already exists
+		return up
+	case 1051, 1091, 1146: // unknown table, can't drop column or key, no such table
+		return !up
+	}
+	return false
+}
+
+// execer is satisfied by both *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrator) record(ctx context.Context, db execer, migration Migration, up bool) error {
+	if up {
+		_, err := db.ExecContext(ctx, m.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC())
+		return err
+	}
+	_, err := db.ExecContext(ctx, m.rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+	return err
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	versions := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL, name varchar(255) NOT NULL, applied_at timestamp NOT NULL, PRIMARY KEY (version))")
+	return err
+}
+
+// withLock runs fn on a single connection holding the migration lock. MySQL
+// and PostgreSQL use session advisory locks; SQLite serialises writers on
+// its own and each migration runs in a transaction.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch m.driver {
+	case "mysql":
+		var acquired sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", lockName).Scan(&acquired); err != nil {
+			return err
+		}
+		if acquired.Int64 != 1 {
+			return ErrLockTimeout
+		}
+		defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+	case "postgres":
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext($1))", lockName); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", lockName)
+	}
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) rebind(query string) string {
+	if m.driver != "postgres" {
+		return query
+	}
+	return placeholder.Number(query)
+}
+
+// statements splits a migration script on semicolons, since not every driver
+// accepts several statements per Exec.
+func statements(script string) []string {
+	var result []string
+	for _, statement := range strings.Split(script, ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			result = append(result, statement)
+		}
+	}
+	return result
+}
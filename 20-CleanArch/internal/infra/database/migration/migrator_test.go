@@ -0,0 +1,132 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	// sqlite3
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type MigratorTestSuite struct {
+	suite.Suite
+	Db       *sql.DB
+	migrator *Migrator
+}
+
+func (suite *MigratorTestSuite) SetupTest() {
+	db, err := sql.Open("sqlite3", ":memory:")
+	suite.NoError(err)
+	db.SetMaxOpenConns(1)
+	suite.Db = db
+	suite.migrator, err = NewMigrator(db, "sqlite3")
+	suite.NoError(err)
+}
+
+func (suite *MigratorTestSuite) TearDownTest() {
+	suite.Db.Close()
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(MigratorTestSuite))
+}
+
+func (suite *MigratorTestSuite) TestGivenAnEmptyDatabase_WhenUp_ThenShouldApplyEveryMigrationOnce() {
+	applied, err := suite.migrator.Up(context.Background())
+	suite.NoError(err)
+	suite.Equal(len(suite.migrator.migrations), applied)
+
+	_, err = suite.Db.Exec("INSERT INTO orders (id, price, tax, final_price, status, created_at) VALUES ('a', 10, 1, 11, 'pending', '2023-09-01 00:00:00+00:00')")
+	suite.NoError(err)
+
+	applied, err = suite.migrator.Up(context.Background())
+	suite.NoError(err)
+	suite.Equal(0, applied)
+}
+
+func (suite *MigratorTestSuite) TestGivenAppliedMigrations_WhenStatus_ThenShouldReportEachVersion() {
+	statuses, err := suite.migrator.Status(context.Background())
+	suite.NoError(err)
+	suite.Len(statuses, len(suite.migrator.migrations))
+	for _, status := range statuses {
+		suite.False(status.Applied)
+		suite.Nil(status.AppliedAt)
+	}
+
+	_, err = suite.migrator.Up(context.Background())
+	suite.NoError(err)
+	statuses, err = suite.migrator.Status(context.Background())
+	suite.NoError(err)
+	suite.Equal(int64(1), statuses[0].Version)
+	suite.Equal("create_orders", statuses[0].Name)
+	for _, status := range statuses {
+		suite.True(status.Applied)
+		suite.NotNil(status.AppliedAt)
+	}
+}
+
+func (suite *MigratorTestSuite) TestGivenAppliedMigrations_WhenDown_ThenShouldRevertTheLatestFirst() {
+	_, err := suite.migrator.Up(context.Background())
+	suite.NoError(err)
+	total := len(suite.migrator.migrations)
+
+	reverted, err := suite.migrator.Down(context.Background(), 1)
+	suite.NoError(err)
+	suite.Equal(1, reverted)
+	statuses, err := suite.migrator.Status(context.Background())
+	suite.NoError(err)
+	suite.True(statuses[total-2].Applied)
+	suite.False(statuses[total-1].Applied)
+
+	reverted, err = suite.migrator.Down(context.Background(), total+1)
+	suite.NoError(err)
+	suite.Equal(total-1, reverted)
+	_, err = suite.Db.Exec("SELECT id FROM orders")
+	suite.Error(err)
+}
+
+func (suite *MigratorTestSuite) TestGivenAnOrdersTableFromInitSQL_WhenUp_ThenShouldAdoptItAndKeepRows() {
+	_, err := suite.Db.Exec("CREATE TABLE orders (id varchar(255) NOT NULL, price float NOT NULL, tax float NOT NULL, final_price float NOT NULL, PRIMARY KEY (id))")
+	suite.NoError(err)
+	_, err = suite.Db.Exec("INSERT INTO orders (id, price, tax, final_price) VALUES ('a', 10, 1, 11)")
+	suite.NoError(err)
+
+	_, err = suite.migrator.Up(context.Background())
+	suite.NoError(err)
+
+	var status string
+	suite.NoError(suite.Db.QueryRow("SELECT status FROM orders WHERE id = 'a'").Scan(&status))
+	suite.Equal("pending", status)
+}
+
+func (suite *MigratorTestSuite) TestGivenAnUnknownDriver_WhenNewMigrator_ThenShouldReceiveAnError() {
+	_, err := NewMigrator(suite.Db, "memory")
+	suite.Equal(ErrUnsupportedDriver, err)
+}
+
+func TestGivenMySQLErrors_WhenAlreadyApplied_ThenShouldOnlySkipChangesAlreadyInPlace(t *testing.T) {
+	tests := []struct {
+		number uint16
+		up     bool
+		want   bool
+	}{
+		{1050, true, true},   // table exists
+		{1060, true, true},   // duplicate column
+		{1061, true, true},   // duplicate key name
+		{1050, false, false}, // table exists going down
+		{1091, false, true},  // column or key already dropped
+		{1051, false, true},  // table already dropped
+		{1091, true, false},  // dropping going up is a real failure
+		{1064, true, false},  // syntax error
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, alreadyApplied(&mysql.MySQLError{Number: tt.number}, tt.up), "error %d, up %v", tt.number, tt.up)
+	}
+	assert.False(t, alreadyApplied(errors.New("connection refused"), true))
+}
@@ -0,0 +1,9 @@
+DROP INDEX idx_orders_final_price ON orders;
+DROP INDEX idx_orders_price ON orders;
+DROP INDEX idx_orders_created_at ON orders;
+ALTER TABLE orders
+    DROP COLUMN created_at,
+    DROP COLUMN status,
+    MODIFY price float NOT NULL,
+    MODIFY tax float NOT NULL,
+    MODIFY final_price float NOT NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE orders
+    MODIFY price double NOT NULL,
+    MODIFY tax double NOT NULL,
+    MODIFY final_price double NOT NULL,
+    ADD COLUMN status varchar(32) NOT NULL DEFAULT 'pending',
+    ADD COLUMN created_at datetime(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6);
+CREATE INDEX idx_orders_created_at ON orders (created_at, id);
+CREATE INDEX idx_orders_price ON orders (price, id);
+CREATE INDEX idx_orders_final_price ON orders (final_price, id);
@@ -0,0 +1 @@
+CREATE TABLE invoices (id varchar(255) NOT NULL, order_id varchar(255) NOT NULL, amount double NOT NULL, tax double NOT NULL, total double NOT NULL, issued_at datetime(6) NOT NULL, PRIMARY KEY (id), UNIQUE KEY uq_invoices_order_id (order_id));
@@ -0,0 +1,4 @@
+CREATE TABLE coupons (code varchar(64) NOT NULL, discount_type varchar(16) NOT NULL, value double NOT NULL, valid_from datetime(6) NULL, valid_until datetime(6) NULL, max_redemptions int NOT NULL, max_redemptions_per_customer int NOT NULL, redemptions int NOT NULL DEFAULT 0, active boolean NOT NULL, created_at datetime(6) NOT NULL, PRIMARY KEY (code));
+CREATE TABLE coupon_redemptions (code varchar(64) NOT NULL, order_id varchar(255) NOT NULL, customer varchar(255) NOT NULL, discount double NOT NULL, redeemed_at datetime(6) NOT NULL, PRIMARY KEY (code, order_id));
+CREATE INDEX idx_coupon_redemptions_customer ON coupon_redemptions (code, customer);
+ALTER TABLE orders ADD COLUMN coupon_code varchar(64) NOT NULL DEFAULT '', ADD COLUMN discount double NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+CREATE TABLE report_orders (order_id varchar(255) NOT NULL, created_on datetime(6) NOT NULL, price double NOT NULL, tax double NOT NULL, final_price double NOT NULL, discount double NOT NULL, status varchar(16) NOT NULL, PRIMARY KEY (order_id));
+CREATE TABLE report_daily (day datetime(6) NOT NULL, orders_created int NOT NULL DEFAULT 0, gross_sales double NOT NULL DEFAULT 0, net_sales double NOT NULL DEFAULT 0, tax_billed double NOT NULL DEFAULT 0, discounts double NOT NULL DEFAULT 0, orders_paid int NOT NULL DEFAULT 0, paid_revenue double NOT NULL DEFAULT 0, paid_tax double NOT NULL DEFAULT 0, orders_cancelled int NOT NULL DEFAULT 0, orders_refunded int NOT NULL DEFAULT 0, refunded_amount double NOT NULL DEFAULT 0, refunded_tax double NOT NULL DEFAULT 0, PRIMARY KEY (day));
+CREATE TABLE report_applied_events (event_id varchar(255) NOT NULL, applied_at datetime(6) NOT NULL, PRIMARY KEY (event_id));
@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_keys ADD COLUMN locked_until datetime(6) NULL;
+ALTER TABLE idempotency_keys ADD COLUMN attempts int NOT NULL DEFAULT 1;
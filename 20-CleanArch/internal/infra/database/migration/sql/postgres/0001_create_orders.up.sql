@@ -0,0 +1 @@
+CREATE TABLE IF NOT EXISTS orders (id varchar(255) COLLATE "C" NOT NULL, price double precision NOT NULL, tax double precision NOT NULL, final_price double precision NOT NULL, PRIMARY KEY (id));
@@ -0,0 +1,6 @@
+DROP INDEX idx_orders_final_price;
+DROP INDEX idx_orders_price;
+DROP INDEX idx_orders_created_at;
+ALTER TABLE orders
+    DROP COLUMN created_at,
+    DROP COLUMN status;
@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    ADD COLUMN status varchar(32) NOT NULL DEFAULT 'pending',
+    ADD COLUMN created_at timestamp(6) with time zone NOT NULL DEFAULT now();
+CREATE INDEX idx_orders_created_at ON orders (created_at, id);
+CREATE INDEX idx_orders_price ON orders (price, id);
+CREATE INDEX idx_orders_final_price ON orders (final_price, id);
@@ -0,0 +1,2 @@
+CREATE TABLE idempotency_keys (idempotency_key varchar(255) NOT NULL, request_hash char(64) NOT NULL, response text NULL, created_at timestamp(6) with time zone NOT NULL, expires_at timestamp(6) with time zone NOT NULL, PRIMARY KEY (idempotency_key));
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
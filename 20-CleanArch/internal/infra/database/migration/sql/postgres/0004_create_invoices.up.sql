@@ -0,0 +1 @@
+CREATE TABLE invoices (id varchar(255) NOT NULL, order_id varchar(255) NOT NULL, amount double precision NOT NULL, tax double precision NOT NULL, total double precision NOT NULL, issued_at timestamp(6) with time zone NOT NULL, PRIMARY KEY (id), CONSTRAINT uq_invoices_order_id UNIQUE (order_id));
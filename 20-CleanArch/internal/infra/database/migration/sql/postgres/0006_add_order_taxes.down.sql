@@ -0,0 +1,2 @@
+DROP TABLE order_tax_lines;
+ALTER TABLE orders DROP COLUMN category, DROP COLUMN region;
@@ -0,0 +1,2 @@
+ALTER TABLE orders ADD COLUMN category varchar(255) NOT NULL DEFAULT '', ADD COLUMN region varchar(255) NOT NULL DEFAULT '';
+CREATE TABLE order_tax_lines (order_id varchar(255) COLLATE "C" NOT NULL, seq integer NOT NULL, name varchar(255) NOT NULL, rate double precision NOT NULL, compound boolean NOT NULL, amount double precision NOT NULL, PRIMARY KEY (order_id, seq));
@@ -0,0 +1,4 @@
+CREATE TABLE coupons (code varchar(64) COLLATE "C" NOT NULL, discount_type varchar(16) NOT NULL, value double precision NOT NULL, valid_from timestamp(6) with time zone NULL, valid_until timestamp(6) with time zone NULL, max_redemptions integer NOT NULL, max_redemptions_per_customer integer NOT NULL, redemptions integer NOT NULL DEFAULT 0, active boolean NOT NULL, created_at timestamp(6) with time zone NOT NULL, PRIMARY KEY (code));
+CREATE TABLE coupon_redemptions (code varchar(64) COLLATE "C" NOT NULL, order_id varchar(255) COLLATE "C" NOT NULL, customer varchar(255) NOT NULL, discount double precision NOT NULL, redeemed_at timestamp(6) with time zone NOT NULL, PRIMARY KEY (code, order_id));
+CREATE INDEX idx_coupon_redemptions_customer ON coupon_redemptions (code, customer);
+ALTER TABLE orders ADD COLUMN coupon_code varchar(64) NOT NULL DEFAULT '', ADD COLUMN discount double precision NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE report_applied_events;
+DROP TABLE report_daily;
+DROP TABLE report_orders;
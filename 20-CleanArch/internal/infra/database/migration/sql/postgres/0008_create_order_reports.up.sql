@@ -0,0 +1,3 @@
+CREATE TABLE report_orders (order_id varchar(255) COLLATE "C" NOT NULL, created_on timestamp(6) with time zone NOT NULL, price double precision NOT NULL, tax double precision NOT NULL, final_price double precision NOT NULL, discount double precision NOT NULL, status varchar(16) NOT NULL, PRIMARY KEY (order_id));
+CREATE TABLE report_daily (day timestamp(6) with time zone NOT NULL, orders_created integer NOT NULL DEFAULT 0, gross_sales double precision NOT NULL DEFAULT 0, net_sales double precision NOT NULL DEFAULT 0, tax_billed double precision NOT NULL DEFAULT 0, discounts double precision NOT NULL DEFAULT 0, orders_paid integer NOT NULL DEFAULT 0, paid_revenue double precision NOT NULL DEFAULT 0, paid_tax double precision NOT NULL DEFAULT 0, orders_cancelled integer NOT NULL DEFAULT 0, orders_refunded integer NOT NULL DEFAULT 0, refunded_amount double precision NOT NULL DEFAULT 0, refunded_tax double precision NOT NULL DEFAULT 0, PRIMARY KEY (day));
+CREATE TABLE report_applied_events (event_id varchar(255) COLLATE "C" NOT NULL, applied_at timestamp(6) with time zone NOT NULL, PRIMARY KEY (event_id));
@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_keys ADD COLUMN locked_until timestamp(6) with time zone NULL;
+ALTER TABLE idempotency_keys ADD COLUMN attempts integer NOT NULL DEFAULT 1;
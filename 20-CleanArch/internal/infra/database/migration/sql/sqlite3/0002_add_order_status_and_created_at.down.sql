@@ -0,0 +1,5 @@
+DROP INDEX idx_orders_final_price;
+DROP INDEX idx_orders_price;
+DROP INDEX idx_orders_created_at;
+ALTER TABLE orders DROP COLUMN created_at;
+ALTER TABLE orders DROP COLUMN status;
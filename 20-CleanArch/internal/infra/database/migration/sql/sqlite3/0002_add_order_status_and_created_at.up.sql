@@ -0,0 +1,5 @@
+ALTER TABLE orders ADD COLUMN status varchar(32) NOT NULL DEFAULT 'pending';
+ALTER TABLE orders ADD COLUMN created_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+CREATE INDEX idx_orders_created_at ON orders (created_at, id);
+CREATE INDEX idx_orders_price ON orders (price, id);
+CREATE INDEX idx_orders_final_price ON orders (final_price, id);
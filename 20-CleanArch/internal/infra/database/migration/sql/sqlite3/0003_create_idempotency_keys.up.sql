@@ -0,0 +1,2 @@
+CREATE TABLE idempotency_keys (idempotency_key varchar(255) NOT NULL, request_hash char(64) NOT NULL, response text NULL, created_at datetime NOT NULL, expires_at datetime NOT NULL, PRIMARY KEY (idempotency_key));
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
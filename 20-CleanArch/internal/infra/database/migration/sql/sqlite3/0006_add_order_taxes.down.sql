@@ -0,0 +1,3 @@
+DROP TABLE order_tax_lines;
+ALTER TABLE orders DROP COLUMN region;
+ALTER TABLE orders DROP COLUMN category;
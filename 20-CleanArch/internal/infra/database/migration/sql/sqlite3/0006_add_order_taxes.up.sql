@@ -0,0 +1,3 @@
+ALTER TABLE orders ADD COLUMN category varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN region varchar(255) NOT NULL DEFAULT '';
+CREATE TABLE order_tax_lines (order_id varchar(255) NOT NULL, seq integer NOT NULL, name varchar(255) NOT NULL, rate double NOT NULL, compound boolean NOT NULL, amount double NOT NULL, PRIMARY KEY (order_id, seq));
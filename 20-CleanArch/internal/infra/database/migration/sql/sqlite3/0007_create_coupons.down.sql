@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN discount;
+ALTER TABLE orders DROP COLUMN coupon_code;
+DROP TABLE coupon_redemptions;
+DROP TABLE coupons;
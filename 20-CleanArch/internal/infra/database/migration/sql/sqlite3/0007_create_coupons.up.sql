@@ -0,0 +1,5 @@
+CREATE TABLE coupons (code varchar(64) NOT NULL, discount_type varchar(16) NOT NULL, value double NOT NULL, valid_from datetime NULL, valid_until datetime NULL, max_redemptions integer NOT NULL, max_redemptions_per_customer integer NOT NULL, redemptions integer NOT NULL DEFAULT 0, active boolean NOT NULL, created_at datetime NOT NULL, PRIMARY KEY (code));
+CREATE TABLE coupon_redemptions (code varchar(64) NOT NULL, order_id varchar(255) NOT NULL, customer varchar(255) NOT NULL, discount double NOT NULL, redeemed_at datetime NOT NULL, PRIMARY KEY (code, order_id));
+CREATE INDEX idx_coupon_redemptions_customer ON coupon_redemptions (code, customer);
+ALTER TABLE orders ADD COLUMN coupon_code varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN discount double NOT NULL DEFAULT 0;
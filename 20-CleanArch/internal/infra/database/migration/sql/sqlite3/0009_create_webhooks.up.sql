@@ -0,0 +1,3 @@
+CREATE TABLE webhook_subscriptions (id varchar(255) NOT NULL, url varchar(2048) NOT NULL, events varchar(255) NOT NULL, secret varchar(255) NOT NULL, active boolean NOT NULL, created_by varchar(255) NOT NULL, created_at datetime NOT NULL, PRIMARY KEY (id));
+CREATE TABLE webhook_deliveries (id varchar(255) NOT NULL, subscription_id varchar(255) NOT NULL, event_id varchar(255) NOT NULL, event varchar(64) NOT NULL, payload text NOT NULL, status varchar(16) NOT NULL, attempts integer NOT NULL DEFAULT 0, next_attempt_at datetime NOT NULL, last_attempt_at datetime NULL, response_status integer NOT NULL DEFAULT 0, last_error text NOT NULL, created_at datetime NOT NULL, PRIMARY KEY (id), UNIQUE (subscription_id, event_id));
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
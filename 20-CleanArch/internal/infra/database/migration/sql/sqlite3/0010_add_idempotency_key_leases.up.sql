@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_keys ADD COLUMN locked_until datetime NULL;
+ALTER TABLE idempotency_keys ADD COLUMN attempts integer NOT NULL DEFAULT 1;
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+var dbSystems = map[string]attribute.KeyValue{
+	DriverMySQL:    semconv.DBSystemMySQL,
+	DriverPostgres: semconv.DBSystemPostgreSQL,
+	DriverSQLite:   semconv.DBSystemSqlite,
+}
+
+// Open opens the database of configs.DBDriver with every query traced and
+// the connection pool statistics exported as metrics. The memory driver
+// has no database to open.
+func Open(driver, dataSourceName string) (*sql.DB, error) {
+	system, ok := dbSystems[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	attrs := otelsql.WithAttributes(system)
+	db, err := otelsql.Open(driver, dataSourceName, attrs, otelsql.WithSpanOptions(otelsql.SpanOptions{
+		// Without these every query also gets spans for reading its rows
+		// and resetting the pooled connection.
+		OmitRows:             true,
+		OmitConnResetSession: true,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if err := otelsql.RegisterDBStatsMetrics(db, attrs); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// OrderReportRepository keeps the reporting projection: what it knows of
+// each order in report_orders, the stats of each day in report_daily and
+// the events already applied in report_applied_events.
+type OrderReportRepository struct {
+	Db      *sql.DB
+	dialect dialect
+}
+
+func newOrderReportRepository(db *sql.DB, dialect dialect) *OrderReportRepository {
+	return &OrderReportRepository{Db: db, dialect: dialect}
+}
+
+const reportOrderColumns = "order_id, created_on, price, tax, final_price, discount, status"
+
+var reportDailyStatColumns = []string{
+	"orders_created", "gross_sales", "net_sales", "tax_billed", "discounts",
+	"orders_paid", "paid_revenue", "paid_tax",
+	"orders_cancelled", "orders_refunded", "refunded_amount", "refunded_tax",
+}
+
+func reportDailyValues(stats entity.OrderStats) []interface{} {
+	return []interface{}{
+		stats.OrdersCreated, stats.GrossSales, stats.NetSales, stats.TaxBilled, stats.Discounts,
+		stats.OrdersPaid, stats.PaidRevenue, stats.PaidTax,
+		stats.OrdersCancelled, stats.OrdersRefunded, stats.RefundedAmount, stats.RefundedTax,
+	}
+}
+
+// addToDailyQuery adds a day's stats to its row, creating the row on the
+// first event of the day.
+func (r *OrderReportRepository) addToDailyQuery() string {
+	columns := strings.Join(reportDailyStatColumns, ", ")
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(reportDailyStatColumns)+1), ", ")
+	additions := make([]string, len(reportDailyStatColumns))
+	for i, column := range reportDailyStatColumns {
+		if r.dialect.name == DriverMySQL {
+			additions[i] = column + " = " + column + " + VALUES(" + column + ")"
+		} else {
+			additions[i] = column + " = report_daily." + column + " + excluded." + column
+		}
+	}
+	conflict := " ON CONFLICT (day) DO UPDATE SET "
+	if r.dialect.name == DriverMySQL {
+		conflict = " ON DUPLICATE KEY UPDATE "
+	}
+	return r.dialect.rebind("INSERT INTO report_daily (day, " + columns + ") VALUES (" + placeholders + ")" +
+		conflict + strings.Join(additions, ", "))
+}
+
+// Project records the event first: a replayed event then fails on the
+// primary key before anything else is read, and on SQLite the transaction
+// takes the write lock up front. The order's row is read FOR UPDATE where
+// the backend supports it, so events of one order are applied one at a
+// time.
+func (r *OrderReportRepository) Project(ctx context.Context, eventID string, orderID string, project func(order *entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error)) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx,
+		r.dialect.rebind("INSERT INTO report_applied_events (event_id, applied_at) VALUES (?, ?)"),
+		eventID, time.Now().UTC(),
+	)
+	if err != nil {
+		if r.dialect.isUniqueViolation(err) {
+			return nil
+		}
+		return err
+	}
+	query := "SELECT " + reportOrderColumns + " FROM report_orders WHERE order_id = ?"
+	if r.dialect.name != DriverSQLite {
+		query += " FOR UPDATE"
+	}
+	current, err := scanReportOrder(tx.QueryRowContext(ctx, r.dialect.rebind(query), orderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	next, stats, err := project(current)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		_, err = tx.ExecContext(ctx,
+			r.dialect.rebind("INSERT INTO report_orders ("+reportOrderColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)"),
+			next.ID, next.CreatedOn, next.Price, next.Tax, next.FinalPrice, next.Discount, next.Status,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx,
+			r.dialect.rebind("UPDATE report_orders SET price = ?, tax = ?, final_price = ?, discount = ?, status = ? WHERE order_id = ?"),
+			next.Price, next.Tax, next.FinalPrice, next.Discount, next.Status, next.ID,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	if stats != (entity.OrderStats{Day: stats.Day}) {
+		args := append([]interface{}{entity.ReportDay(stats.Day)}, reportDailyValues(stats)...)
+		if _, err := tx.ExecContext(ctx, r.addToDailyQuery(), args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func scanReportOrder(row rowScanner) (*entity.ReportOrder, error) {
+	var order entity.ReportOrder
+	err := row.Scan(&order.ID, &order.CreatedOn, &order.Price, &order.Tax, &order.FinalPrice, &order.Discount, &order.Status)
+	if err != nil {
+		return nil, err
+	}
+	order.CreatedOn = order.CreatedOn.UTC()
+	return &order, nil
+}
+
+func (r *OrderReportRepository) Daily(ctx context.Context, from, to time.Time) ([]entity.OrderStats, error) {
+	rows, err := r.Db.QueryContext(ctx,
+		r.dialect.rebind("SELECT day, "+strings.Join(reportDailyStatColumns, ", ")+" FROM report_daily WHERE day >= ? AND day < ? ORDER BY day"),
+		entity.ReportDay(from), entity.ReportDay(to),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	days := []entity.OrderStats{}
+	for rows.Next() {
+		var s entity.OrderStats
+		err := rows.Scan(&s.Day,
+			&s.OrdersCreated, &s.GrossSales, &s.NetSales, &s.TaxBilled, &s.Discounts,
+			&s.OrdersPaid, &s.PaidRevenue, &s.PaidTax,
+			&s.OrdersCancelled, &s.OrdersRefunded, &s.RefundedAmount, &s.RefundedTax,
+		)
+		if err != nil {
+			return nil, err
+		}
+		s.Day = s.Day.UTC()
+		days = append(days, s)
+	}
+	return days, rows.Err()
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/stretchr/testify/suite"
+)
+
+type OrderReportRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func(t *testing.T) entity.OrderReportRepositoryInterface
+	repo          entity.OrderReportRepositoryInterface
+}
+
+func (suite *OrderReportRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository(suite.T())
+}
+
+func TestMemoryOrderReportRepositoryContract(t *testing.T) {
+	suite.Run(t, &OrderReportRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderReportRepositoryInterface {
+			return NewMemoryOrderReportRepository()
+		},
+	})
+}
+
+func TestSQLiteOrderReportRepositoryContract(t *testing.T) {
+	suite.Run(t, &OrderReportRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderReportRepositoryInterface {
+			return newOrderReportRepository(openContractDB(t, DriverSQLite, ":memory:"), sqliteDialect)
+		},
+	})
+}
+
+func TestMySQLOrderReportRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	suite.Run(t, &OrderReportRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderReportRepositoryInterface {
+			return newOrderReportRepository(openContractDB(t, DriverMySQL, dsn), mysqlDialect)
+		},
+	})
+}
+
+func TestPostgresOrderReportRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	suite.Run(t, &OrderReportRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderReportRepositoryInterface {
+			return newOrderReportRepository(openContractDB(t, DriverPostgres, dsn), postgresDialect)
+		},
+	})
+}
+
+var reportDay = time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+
+// projectCreated projects the creation of an order priced 10 plus 1 of tax
+// on day.
+func (suite *OrderReportRepositoryContractSuite) projectCreated(eventID, orderID string, day time.Time) error {
+	return suite.repo.Project(context.Background(), eventID, orderID, func(order *entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error) {
+		suite.Nil(order)
+		return &entity.ReportOrder{ID: orderID, CreatedOn: day, Price: 10, Tax: 1, FinalPrice: 11, Status: entity.OrderStatusPending},
+			entity.OrderStats{Day: day, OrdersCreated: 1, GrossSales: 11, NetSales: 10, TaxBilled: 1}, nil
+	})
+}
+
+func (suite *OrderReportRepositoryContractSuite) TestGivenProjectedOrders_WhenDaily_ThenShouldAddThemUpPerDay() {
+	suite.NoError(suite.projectCreated("event-1", "order-1", reportDay))
+	suite.NoError(suite.projectCreated("event-2", "order-2", reportDay))
+	suite.NoError(suite.projectCreated("event-3", "order-3", reportDay.AddDate(0, 0, 2)))
+	suite.NoError(suite.projectCreated("event-4", "order-4", reportDay.AddDate(0, 0, 7)))
+
+	days, err := suite.repo.Daily(context.Background(), reportDay, reportDay.AddDate(0, 0, 7))
+	suite.NoError(err)
+	suite.Len(days, 2)
+	suite.True(days[0].Day.Equal(reportDay))
+	suite.Equal(2, days[0].OrdersCreated)
+	suite.Equal(22.0, days[0].GrossSales)
+	suite.Equal(20.0, days[0].NetSales)
+	suite.Equal(2.0, days[0].TaxBilled)
+	suite.True(days[1].Day.Equal(reportDay.AddDate(0, 0, 2)))
+	suite.Equal(1, days[1].OrdersCreated)
+}
+
+func (suite *OrderReportRepositoryContractSuite) TestGivenAProjectedOrder_WhenProjectAnotherEvent_ThenShouldPassWhatTheReportsKnow() {
+	suite.NoError(suite.projectCreated("event-1", "order-1", reportDay))
+	paidOn := reportDay.AddDate(0, 0, 1)
+
+	err := suite.repo.Project(context.Background(), "event-2", "order-1", func(order *entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error) {
+		suite.Require().NotNil(order)
+		suite.Equal("order-1", order.ID)
+		suite.True(order.CreatedOn.Equal(reportDay))
+		suite.Equal(11.0, order.FinalPrice)
+		suite.Equal(entity.OrderStatusPending, order.Status)
+		paid := *order
+		paid.Status = entity.OrderStatusPaid
+		return &paid, entity.OrderStats{Day: paidOn, OrdersPaid: 1, PaidRevenue: order.FinalPrice, PaidTax: order.Tax}, nil
+	})
+	suite.NoError(err)
+
+	err = suite.repo.Project(context.Background(), "event-3", "order-1", func(order *entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error) {
+		suite.Equal(entity.OrderStatusPaid, order.Status)
+		return order, entity.OrderStats{Day: paidOn}, nil
+	})
+	suite.NoError(err)
+
+	days, err := suite.repo.Daily(context.Background(), reportDay, paidOn.AddDate(0, 0, 1))
+	suite.NoError(err)
+	suite.Len(days, 2)
+	suite.Equal(1, days[1].OrdersPaid)
+	suite.Equal(11.0, days[1].PaidRevenue)
+	suite.Equal(1.0, days[1].PaidTax)
+	suite.Equal(0, days[1].OrdersCreated)
+}
+
+func (suite *OrderReportRepositoryContractSuite) TestGivenAnAppliedEvent_WhenProjectItAgain_ThenShouldSkipIt() {
+	suite.NoError(suite.projectCreated("event-1", "order-1", reportDay))
+
+	err := suite.repo.Project(context.Background(), "event-1", "order-1", func(*entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error) {
+		suite.Fail("an applied event was projected again")
+		return nil, entity.OrderStats{}, nil
+	})
+	suite.NoError(err)
+
+	days, err := suite.repo.Daily(context.Background(), reportDay, reportDay.AddDate(0, 0, 1))
+	suite.NoError(err)
+	suite.Len(days, 1)
+	suite.Equal(1, days[0].OrdersCreated)
+}
+
+func (suite *OrderReportRepositoryContractSuite) TestGivenAFailingProjection_WhenProject_ThenShouldStoreNothingAndAllowARetry() {
+	errNotYet := errors.New("not yet")
+	err := suite.repo.Project(context.Background(), "event-1", "order-1", func(*entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error) {
+		return nil, entity.OrderStats{}, errNotYet
+	})
+	suite.ErrorIs(err, errNotYet)
+
+	days, err := suite.repo.Daily(context.Background(), reportDay, reportDay.AddDate(0, 0, 1))
+	suite.NoError(err)
+	suite.Empty(days)
+	suite.NoError(suite.projectCreated("event-1", "order-1", reportDay))
+}
+
+func (suite *OrderReportRepositoryContractSuite) TestGivenConcurrentProjections_WhenTheyRace_ThenShouldCountEveryOrder() {
+	const orders = 20
+	var wg sync.WaitGroup
+	errs := make([]error, orders)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = suite.projectCreated(fmt.Sprintf("event-%d", i), fmt.Sprintf("order-%d", i), reportDay)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		suite.NoError(err)
+	}
+
+	days, err := suite.repo.Daily(context.Background(), reportDay, reportDay.AddDate(0, 0, 1))
+	suite.NoError(err)
+	suite.Len(days, 1)
+	suite.Equal(orders, days[0].OrdersCreated)
+	suite.Equal(220.0, days[0].GrossSales)
+}
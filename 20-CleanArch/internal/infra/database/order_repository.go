@@ -1,45 +1,262 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
 )
 
+// OrderRepository stores orders in a SQL database. The same queries serve
+// MySQL, PostgreSQL and SQLite; only the dialect changes.
 type OrderRepository struct {
-	Db *sql.DB
+	Db      *sql.DB
+	dialect dialect
 }
 
+// NewOrderRepository returns a MySQL backed repository.
 func NewOrderRepository(db *sql.DB) *OrderRepository {
-	return &OrderRepository{Db: db}
+	return &OrderRepository{Db: db, dialect: mysqlDialect}
 }
 
-func (r *OrderRepository) Save(order *entity.Order) error {
-	stmt, err := r.Db.Prepare("INSERT INTO orders (id, price, tax, final_price) VALUES (?, ?, ?, ?)")
+func NewPostgresOrderRepository(db *sql.DB) *OrderRepository {
+	return &OrderRepository{Db: db, dialect: postgresDialect}
+}
+
+func NewSQLiteOrderRepository(db *sql.DB) *OrderRepository {
+	return &OrderRepository{Db: db, dialect: sqliteDialect}
+}
+
+// orderColumns are the columns scanOrder reads, in order.
+const orderColumns = "id, price, tax, final_price, status, created_at, created_by, category, region, coupon_code, discount"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row rowScanner) (entity.Order, error) {
+	var order entity.Order
+	err := row.Scan(&order.ID, &order.Price, &order.Tax, &order.FinalPrice, &order.Status, &order.CreatedAt, &order.CreatedBy, &order.Category, &order.Region, &order.CouponCode, &order.Discount)
+	return order, err
+}
+
+// Save inserts the order and its tax lines in one transaction.
+func (r *OrderRepository) Save(ctx context.Context, order *entity.Order) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	_, err = stmt.Exec(order.ID, order.Price, order.Tax, order.FinalPrice)
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx,
+		r.dialect.rebind("INSERT INTO orders ("+orderColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		order.ID, order.Price, order.Tax, order.FinalPrice, order.Status, order.CreatedAt, order.CreatedBy, order.Category, order.Region, order.CouponCode, order.Discount,
+	)
 	if err != nil {
+		if r.dialect.isUniqueViolation(err) {
+			return entity.ErrOrderAlreadyExists
+		}
 		return err
 	}
+	if err := r.insertTaxLines(ctx, tx, order); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *OrderRepository) insertTaxLines(ctx context.Context, tx *sql.Tx, order *entity.Order) error {
+	for seq, line := range order.TaxLines {
+		_, err := tx.ExecContext(ctx,
+			r.dialect.rebind("INSERT INTO order_tax_lines (order_id, seq, name, rate, compound, amount) VALUES (?, ?, ?, ?, ?, ?)"),
+			order.ID, seq, line.Name, line.Rate, line.Compound, line.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (r *OrderRepository) GetOrders(listOrders *entity.ListOrders) ([]entity.Order, error) {
-	orders := []entity.Order{}
-	offset := (listOrders.Page - 1) * listOrders.Limit
-	rows, err := r.Db.Query("SELECT id, price, tax, final_price FROM orders LIMIT ? OFFSET ?", listOrders.Limit, offset)
+// loadTaxLines reads the tax lines of orders in a single query.
+func (r *OrderRepository) loadTaxLines(ctx context.Context, orders []entity.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]string, len(orders))
+	positions := make(map[string]int, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		positions[order.ID] = i
+	}
+	in, args := inClause(ids)
+	rows, err := r.Db.QueryContext(ctx,
+		r.dialect.rebind("SELECT order_id, name, rate, compound, amount FROM order_tax_lines WHERE order_id IN "+in+" ORDER BY order_id, seq"),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var orderID string
+		var line entity.TaxLine
+		if err := rows.Scan(&orderID, &line.Name, &line.Rate, &line.Compound, &line.Amount); err != nil {
+			return err
+		}
+		order := &orders[positions[orderID]]
+		order.TaxLines = append(order.TaxLines, line)
+	}
+	return rows.Err()
+}
+
+func (r *OrderRepository) FindByID(ctx context.Context, id string) (*entity.Order, error) {
+	order, err := scanOrder(r.Db.QueryRowContext(ctx,
+		r.dialect.rebind("SELECT "+orderColumns+" FROM orders WHERE id = ?"),
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	orders := []entity.Order{order}
+	if err := r.loadTaxLines(ctx, orders); err != nil {
+		return nil, err
+	}
+	return &orders[0], nil
+}
+
+// Update guards the write with the status the order was read in, so of two
+// concurrent transitions only the first one lands. The tax lines are
+// replaced in the same transaction.
+func (r *OrderRepository) Update(ctx context.Context, order *entity.Order, previousStatus entity.OrderStatus) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx,
+		r.dialect.rebind("UPDATE orders SET price = ?, tax = ?, final_price = ?, discount = ?, status = ? WHERE id = ? AND status = ?"),
+		order.Price, order.Tax, order.FinalPrice, order.Discount, order.Status, order.ID, previousStatus,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// MySQL reports no affected rows when nothing changes, so tell an
+		// unchanged row apart from a missing or already moved one.
+		var status entity.OrderStatus
+		err := tx.QueryRowContext(ctx, r.dialect.rebind("SELECT status FROM orders WHERE id = ?"), order.ID).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status != previousStatus {
+			return entity.ErrOrderModified
+		}
+	}
+	if _, err := tx.ExecContext(ctx, r.dialect.rebind("DELETE FROM order_tax_lines WHERE order_id = ?"), order.ID); err != nil {
+		return err
+	}
+	if err := r.insertTaxLines(ctx, tx, order); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOrders returns a keyset page: rows strictly after the cursor in
+// (sort column, id) order. One extra row is fetched to know whether another
+// page follows.
+func (r *OrderRepository) GetOrders(ctx context.Context, listOrders *entity.ListOrders) (*entity.OrdersPage, error) {
+	where, args := filterClause(listOrders.Filter)
+
+	var totalCount int
+	err := r.Db.QueryRowContext(ctx, r.dialect.rebind("SELECT COUNT(*) FROM orders"+where), args...).Scan(&totalCount)
 	if err != nil {
 		return nil, err
 	}
+
+	column := string(listOrders.Sort.Field)
+	direction, comparator := "ASC", ">"
+	if listOrders.Sort.Direction == entity.SortDesc {
+		direction, comparator = "DESC", "<"
+	}
+	if cursor := listOrders.After; cursor != nil {
+		keyset := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, comparator)
+		if where == "" {
+			where = " WHERE " + keyset
+		} else {
+			where += " AND " + keyset
+		}
+		args = append(args, cursor.Value(), cursor.Value(), cursor.ID)
+	}
+	query := fmt.Sprintf(
+		"SELECT %s FROM orders%s ORDER BY %s %s, id %s LIMIT ?",
+		orderColumns, where, column, direction, direction,
+	)
+	args = append(args, listOrders.Limit+1)
+
+	rows, err := r.Db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &entity.OrdersPage{Orders: []entity.Order{}, TotalCount: totalCount}
 	for rows.Next() {
-		var order entity.Order
-		err := rows.Scan(&order.ID, &order.Price, &order.Tax, &order.FinalPrice)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, err
 		}
-		orders = append(orders, order)
+		page.Orders = append(page.Orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Orders) > listOrders.Limit {
+		page.Orders = page.Orders[:listOrders.Limit]
+		page.HasNextPage = true
+	}
+	if err := r.loadTaxLines(ctx, page.Orders); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func filterClause(filter entity.OrderFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "price >= ?")
+		args = append(args, *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "price <= ?")
+		args = append(args, *filter.MaxPrice)
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedFrom.UTC())
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedTo.UTC())
+	}
+	if len(conditions) == 0 {
+		return "", nil
 	}
-	return orders, nil
+	return " WHERE " + strings.Join(conditions, " AND "), args
 }
@@ -0,0 +1,377 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database/migration"
+	"github.com/stretchr/testify/suite"
+)
+
+// OrderRepositoryContractSuite runs the same behavioural checks against every
+// OrderRepositoryInterface implementation. MySQL and PostgreSQL only run when
+// TEST_MYSQL_DSN or TEST_POSTGRES_DSN points at a disposable database.
+type OrderRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func(t *testing.T) entity.OrderRepositoryInterface
+	repo          entity.OrderRepositoryInterface
+}
+
+func (suite *OrderRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository(suite.T())
+}
+
+func TestMemoryOrderRepositoryContract(t *testing.T) {
+	suite.Run(t, &OrderRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderRepositoryInterface {
+			return NewMemoryOrderRepository()
+		},
+	})
+}
+
+func TestSQLiteOrderRepositoryContract(t *testing.T) {
+	suite.Run(t, &OrderRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderRepositoryInterface {
+			db := openContractDB(t, DriverSQLite, ":memory:")
+			return NewSQLiteOrderRepository(db)
+		},
+	})
+}
+
+func TestMySQLOrderRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	suite.Run(t, &OrderRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderRepositoryInterface {
+			db := openContractDB(t, DriverMySQL, dsn)
+			return NewOrderRepository(db)
+		},
+	})
+}
+
+func TestPostgresOrderRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	suite.Run(t, &OrderRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.OrderRepositoryInterface {
+			db := openContractDB(t, DriverPostgres, dsn)
+			return NewPostgresOrderRepository(db)
+		},
+	})
+}
+
+// openContractDB opens the database the way the binaries do, traced, so
+// the contract also covers errors passing through the instrumented driver.
+func openContractDB(t *testing.T, driver, dsn string) *sql.DB {
+	db, err := Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	migrator, err := migration.NewMigrator(db, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		migrator.Down(context.Background(), math.MaxInt)
+		db.Close()
+	})
+	if _, err := migrator.Down(context.Background(), math.MaxInt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func (suite *OrderRepositoryContractSuite) saveOrders(prices ...float64) {
+	createdAt := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	for i, price := range prices {
+		order, err := entity.NewOrder(fmt.Sprintf("order-%02d", i), price, 1.0)
+		suite.NoError(err)
+		suite.NoError(order.CalculateFinalPrice())
+		order.CreatedAt = createdAt.Add(time.Duration(i) * time.Hour)
+		suite.NoError(suite.repo.Save(context.Background(), order))
+	}
+}
+
+func (suite *OrderRepositoryContractSuite) listAll(filter entity.OrderFilter, sort entity.OrderSort) []entity.Order {
+	listOrders, err := entity.NewListOrders(entity.MaxListOrdersLimit, "", filter, sort)
+	suite.NoError(err)
+	page, err := suite.repo.GetOrders(context.Background(), listOrders)
+	suite.NoError(err)
+	return page.Orders
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAnOrder_WhenSave_ThenShouldReturnItUnchanged() {
+	order, err := entity.NewOrder("123", 10.5, 2.25)
+	suite.NoError(err)
+	suite.NoError(order.CalculateFinalPrice())
+	order.CreatedBy = "alice"
+	suite.NoError(suite.repo.Save(context.Background(), order))
+
+	orders := suite.listAll(entity.OrderFilter{}, entity.OrderSort{})
+	suite.Len(orders, 1)
+	suite.Equal(order.ID, orders[0].ID)
+	suite.Equal(order.Price, orders[0].Price)
+	suite.Equal(order.Tax, orders[0].Tax)
+	suite.Equal(order.FinalPrice, orders[0].FinalPrice)
+	suite.Equal(order.Status, orders[0].Status)
+	suite.True(order.CreatedAt.Equal(orders[0].CreatedAt))
+	suite.Equal("alice", orders[0].CreatedBy)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAnExistingID_WhenSave_ThenShouldReturnErrOrderAlreadyExists() {
+	order, err := entity.NewOrder("123", 10.0, 2.0)
+	suite.NoError(err)
+	suite.NoError(order.CalculateFinalPrice())
+	suite.NoError(suite.repo.Save(context.Background(), order))
+
+	suite.ErrorIs(suite.repo.Save(context.Background(), order), entity.ErrOrderAlreadyExists)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenNoOrders_WhenGetOrders_ThenShouldReturnAnEmptyPage() {
+	listOrders, err := entity.NewListOrders(10, "", entity.OrderFilter{}, entity.OrderSort{})
+	suite.NoError(err)
+	page, err := suite.repo.GetOrders(context.Background(), listOrders)
+	suite.NoError(err)
+	suite.NotNil(page.Orders)
+	suite.Empty(page.Orders)
+	suite.Equal(0, page.TotalCount)
+	suite.False(page.HasNextPage)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenEachSort_WhenGetOrders_ThenShouldOrderByFieldThenID() {
+	suite.saveOrders(50, 10, 30, 30, 20)
+	ids := func(orders []entity.Order) []string {
+		var result []string
+		for _, order := range orders {
+			result = append(result, order.ID)
+		}
+		return result
+	}
+
+	suite.Equal([]string{"order-00", "order-01", "order-02", "order-03", "order-04"},
+		ids(suite.listAll(entity.OrderFilter{}, entity.OrderSort{Field: entity.OrderSortByCreatedAt, Direction: entity.SortAsc})))
+	suite.Equal([]string{"order-01", "order-04", "order-02", "order-03", "order-00"},
+		ids(suite.listAll(entity.OrderFilter{}, entity.OrderSort{Field: entity.OrderSortByPrice, Direction: entity.SortAsc})))
+	suite.Equal([]string{"order-00", "order-03", "order-02", "order-04", "order-01"},
+		ids(suite.listAll(entity.OrderFilter{}, entity.OrderSort{Field: entity.OrderSortByFinalPrice, Direction: entity.SortDesc})))
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenACursor_WhenGetOrders_ThenShouldWalkAllPagesOnce() {
+	suite.saveOrders(50, 10, 30, 30, 20, 40, 30)
+	sort := entity.OrderSort{Field: entity.OrderSortByPrice, Direction: entity.SortDesc}
+
+	var seen []string
+	after := ""
+	for {
+		listOrders, err := entity.NewListOrders(2, after, entity.OrderFilter{}, sort)
+		suite.NoError(err)
+		page, err := suite.repo.GetOrders(context.Background(), listOrders)
+		suite.NoError(err)
+		suite.Equal(7, page.TotalCount)
+		for _, order := range page.Orders {
+			seen = append(seen, order.ID)
+		}
+		if !page.HasNextPage {
+			break
+		}
+		after = entity.NewOrderCursor(page.Orders[len(page.Orders)-1], sort.Field).Encode()
+	}
+	suite.Equal([]string{"order-00", "order-05", "order-06", "order-03", "order-02", "order-04", "order-01"}, seen)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAFilter_WhenGetOrders_ThenShouldReturnOnlyMatchingOrders() {
+	suite.saveOrders(10, 20, 30, 40, 50)
+	minPrice, maxPrice := 15.0, 45.0
+	createdFrom := time.Date(2023, 9, 1, 1, 0, 0, 0, time.UTC)
+	createdTo := time.Date(2023, 9, 1, 3, 0, 0, 0, time.UTC)
+	filter := entity.OrderFilter{
+		Status:      entity.OrderStatusPending,
+		MinPrice:    &minPrice,
+		MaxPrice:    &maxPrice,
+		CreatedFrom: &createdFrom,
+		CreatedTo:   &createdTo,
+	}
+
+	listOrders, err := entity.NewListOrders(10, "", filter, entity.OrderSort{})
+	suite.NoError(err)
+	page, err := suite.repo.GetOrders(context.Background(), listOrders)
+	suite.NoError(err)
+	suite.Equal(2, page.TotalCount)
+	suite.Len(page.Orders, 2)
+	suite.Equal("order-01", page.Orders[0].ID)
+	suite.Equal("order-02", page.Orders[1].ID)
+
+	filter.Status = entity.OrderStatusPaid
+	suite.Empty(suite.listAll(filter, entity.OrderSort{}))
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenACancelledContext_WhenSave_ThenShouldAbortAndNotPersist() {
+	order, err := entity.NewOrder("123", 10.0, 2.0)
+	suite.NoError(err)
+	suite.NoError(order.CalculateFinalPrice())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	suite.ErrorIs(suite.repo.Save(ctx, order), context.Canceled)
+	suite.Empty(suite.listAll(entity.OrderFilter{}, entity.OrderSort{}))
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAnExpiredDeadline_WhenGetOrders_ThenShouldAbortTheQuery() {
+	suite.saveOrders(10, 20, 30)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	listOrders, err := entity.NewListOrders(10, "", entity.OrderFilter{}, entity.OrderSort{})
+	suite.NoError(err)
+	page, err := suite.repo.GetOrders(ctx, listOrders)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	suite.Nil(page)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenConcurrentWriters_WhenSave_ThenShouldKeepEveryOrder() {
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			order, err := entity.NewOrder(fmt.Sprintf("order-%02d", i), 10.0, 1.0)
+			suite.NoError(err)
+			suite.NoError(order.CalculateFinalPrice())
+			suite.NoError(suite.repo.Save(context.Background(), order))
+		}(i)
+	}
+	wg.Wait()
+
+	suite.Len(suite.listAll(entity.OrderFilter{}, entity.OrderSort{}), 20)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAnUnknownID_WhenFindByID_ThenShouldReturnErrOrderNotFound() {
+	order, err := suite.repo.FindByID(context.Background(), "missing")
+	suite.ErrorIs(err, entity.ErrOrderNotFound)
+	suite.Nil(order)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAPaidOrder_WhenUpdate_ThenFindByIDShouldReturnTheNewState() {
+	suite.saveOrders(10)
+	order, err := suite.repo.FindByID(context.Background(), "order-00")
+	suite.NoError(err)
+	suite.NoError(order.Pay())
+
+	suite.NoError(suite.repo.Update(context.Background(), order, entity.OrderStatusPending))
+
+	found, err := suite.repo.FindByID(context.Background(), "order-00")
+	suite.NoError(err)
+	suite.Equal(entity.OrderStatusPaid, found.Status)
+	suite.Equal(11.0, found.FinalPrice)
+	suite.Empty(found.PullEvents())
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAStalePreviousStatus_WhenUpdate_ThenShouldReturnErrOrderModified() {
+	suite.saveOrders(10)
+	first, err := suite.repo.FindByID(context.Background(), "order-00")
+	suite.NoError(err)
+	second, err := suite.repo.FindByID(context.Background(), "order-00")
+	suite.NoError(err)
+	suite.NoError(first.Pay())
+	suite.NoError(second.Cancel("duplicate"))
+
+	suite.NoError(suite.repo.Update(context.Background(), first, entity.OrderStatusPending))
+	suite.ErrorIs(suite.repo.Update(context.Background(), second, entity.OrderStatusPending), entity.ErrOrderModified)
+
+	found, err := suite.repo.FindByID(context.Background(), "order-00")
+	suite.NoError(err)
+	suite.Equal(entity.OrderStatusPaid, found.Status)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenUnchangedValues_WhenUpdate_ThenShouldSucceed() {
+	suite.saveOrders(10)
+	order, err := suite.repo.FindByID(context.Background(), "order-00")
+	suite.NoError(err)
+	suite.NoError(order.Update(order.Price, order.Tax))
+
+	suite.NoError(suite.repo.Update(context.Background(), order, entity.OrderStatusPending))
+
+	found, err := suite.repo.FindByID(context.Background(), "order-00")
+	suite.NoError(err)
+	suite.Equal(entity.OrderStatusPending, found.Status)
+	suite.Equal(11.0, found.FinalPrice)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenAnUnknownOrder_WhenUpdate_ThenShouldReturnErrOrderNotFound() {
+	order, err := entity.NewOrder("missing", 10.0, 2.0)
+	suite.NoError(err)
+
+	suite.ErrorIs(suite.repo.Update(context.Background(), order, entity.OrderStatusPending), entity.ErrOrderNotFound)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenATaxedOrder_WhenSaveAndReprice_ThenShouldKeepItsTaxLines() {
+	engine, err := entity.NewTaxEngine([]entity.TaxRule{
+		{Name: "VAT", Rate: 10},
+		{Name: "Luxury", Category: "luxury", Rate: 5, Compound: true},
+	}, false)
+	suite.NoError(err)
+	breakdown := engine.Calculate(100, "luxury", "north")
+	order, err := entity.NewOrder("123", breakdown.Price, breakdown.Tax)
+	suite.NoError(err)
+	order.Category, order.Region, order.TaxLines = "luxury", "north", breakdown.Lines
+	suite.NoError(order.CalculateFinalPrice())
+	suite.NoError(suite.repo.Save(context.Background(), order))
+
+	orders := suite.listAll(entity.OrderFilter{}, entity.OrderSort{})
+	suite.Len(orders, 1)
+	suite.Equal("luxury", orders[0].Category)
+	suite.Equal("north", orders[0].Region)
+	suite.Equal(breakdown.Lines, orders[0].TaxLines)
+
+	found, err := suite.repo.FindByID(context.Background(), "123")
+	suite.NoError(err)
+	suite.NoError(found.Reprice(engine.Calculate(200, found.Category, found.Region)))
+	suite.NoError(suite.repo.Update(context.Background(), found, entity.OrderStatusPending))
+
+	found, err = suite.repo.FindByID(context.Background(), "123")
+	suite.NoError(err)
+	suite.Equal([]entity.TaxLine{
+		{Name: "VAT", Rate: 10, Amount: 20},
+		{Name: "Luxury", Rate: 5, Compound: true, Amount: 11},
+	}, found.TaxLines)
+	suite.Equal(231.0, found.FinalPrice)
+}
+
+func (suite *OrderRepositoryContractSuite) TestGivenADiscountedOrder_WhenSaveAndUpdate_ThenShouldKeepItsCouponAndDiscount() {
+	order, err := entity.NewOrder("123", 90, 9)
+	suite.NoError(err)
+	order.CouponCode, order.Discount = "SUMMER10", 10
+	suite.NoError(order.CalculateFinalPrice())
+	suite.NoError(suite.repo.Save(context.Background(), order))
+
+	found, err := suite.repo.FindByID(context.Background(), "123")
+	suite.NoError(err)
+	suite.Equal("SUMMER10", found.CouponCode)
+	suite.Equal(10.0, found.Discount)
+
+	suite.NoError(found.Update(180, 18))
+	found.Discount = 20
+	suite.NoError(suite.repo.Update(context.Background(), found, entity.OrderStatusPending))
+
+	orders := suite.listAll(entity.OrderFilter{}, entity.OrderSort{})
+	suite.Len(orders, 1)
+	suite.Equal("SUMMER10", orders[0].CouponCode)
+	suite.Equal(20.0, orders[0].Discount)
+	suite.Equal(198.0, orders[0].FinalPrice)
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"math/rand"
@@ -18,10 +19,11 @@ type OrderRepositoryTestSuite struct {
 	Db *sql.DB
 }
 
-func (suite *OrderRepositoryTestSuite) SetupSuite() {
+func (suite *OrderRepositoryTestSuite) SetupTest() {
 	db, err := sql.Open("sqlite3", ":memory:")
 	suite.NoError(err)
-	db.Exec("CREATE TABLE orders (id varchar(255) NOT NULL, price float NOT NULL, tax float NOT NULL, final_price float NOT NULL, PRIMARY KEY (id))")
+	db.Exec("CREATE TABLE orders (id varchar(255) NOT NULL, price double NOT NULL, tax double NOT NULL, final_price double NOT NULL, status varchar(32) NOT NULL DEFAULT 'pending', created_at datetime NOT NULL, created_by varchar(255) NOT NULL DEFAULT '', category varchar(255) NOT NULL DEFAULT '', region varchar(255) NOT NULL DEFAULT '', coupon_code varchar(64) NOT NULL DEFAULT '', discount double NOT NULL DEFAULT 0, PRIMARY KEY (id))")
+	db.Exec("CREATE TABLE order_tax_lines (order_id varchar(255) NOT NULL, seq integer NOT NULL, name varchar(255) NOT NULL, rate double NOT NULL, compound boolean NOT NULL, amount double NOT NULL, PRIMARY KEY (order_id, seq))")
 	suite.Db = db
 }
 
@@ -37,23 +39,25 @@ func (suite *OrderRepositoryTestSuite) TestGivenAnOrder_WhenSave_ThenShouldSaveO
 	order, err := entity.NewOrder("123", 10.0, 2.0)
 	suite.NoError(err)
 	suite.NoError(order.CalculateFinalPrice())
-	repo := NewOrderRepository(suite.Db)
-	err = repo.Save(order)
+	repo := NewSQLiteOrderRepository(suite.Db)
+	err = repo.Save(context.Background(), order)
 	suite.NoError(err)
 
 	var orderResult entity.Order
-	err = suite.Db.QueryRow("Select id, price, tax, final_price from orders where id = ?", order.ID).
-		Scan(&orderResult.ID, &orderResult.Price, &orderResult.Tax, &orderResult.FinalPrice)
+	err = suite.Db.QueryRow("Select id, price, tax, final_price, status, created_at from orders where id = ?", order.ID).
+		Scan(&orderResult.ID, &orderResult.Price, &orderResult.Tax, &orderResult.FinalPrice, &orderResult.Status, &orderResult.CreatedAt)
 
 	suite.NoError(err)
 	suite.Equal(order.ID, orderResult.ID)
 	suite.Equal(order.Price, orderResult.Price)
 	suite.Equal(order.Tax, orderResult.Tax)
 	suite.Equal(order.FinalPrice, orderResult.FinalPrice)
+	suite.Equal(entity.OrderStatusPending, orderResult.Status)
+	suite.True(order.CreatedAt.Equal(orderResult.CreatedAt))
 }
 
 func (suite *OrderRepositoryTestSuite) TestShouldInsertedOrdersAndReturnAll() {
-	repo := NewOrderRepository(suite.Db)
+	repo := NewSQLiteOrderRepository(suite.Db)
 	numOrders := 10
 	minPrice := 10.0
 	maxPrice := 100.0
@@ -61,13 +65,15 @@ func (suite *OrderRepositoryTestSuite) TestShouldInsertedOrdersAndReturnAll() {
 		order, err := entity.NewOrder(fmt.Sprintf("%d", i), minPrice+rand.Float64()*(maxPrice-minPrice), minPrice+rand.Float64()*(maxPrice-minPrice))
 		suite.NoError(err)
 		suite.NoError(order.CalculateFinalPrice())
-		err = repo.Save(order)
+		err = repo.Save(context.Background(), order)
 		suite.NoError(err)
 	}
 
-	listOrders, err := entity.NewListOrders(1, 10)
+	listOrders, err := entity.NewListOrders(10, "", entity.OrderFilter{}, entity.OrderSort{})
 	suite.NoError(err)
-	orders, err := repo.GetOrders(listOrders)
+	page, err := repo.GetOrders(context.Background(), listOrders)
 	suite.NoError(err)
-	suite.Equal(10, len(orders))
+	suite.Equal(10, len(page.Orders))
+	suite.Equal(10, page.TotalCount)
+	suite.False(page.HasNextPage)
 }
@@ -0,0 +1,26 @@
+// Package placeholder rewrites the ? bind parameters the repositories and the
+// migrator write their queries with. It lives apart from database so that
+// migration, which the database tests import, can share it.
+package placeholder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Number rewrites ? placeholders into $1, $2... for backends, such as
+// Postgres, that only accept numbered parameters.
+func Number(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,13 @@
+package placeholder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAQuery_WhenNumber_ThenShouldNumberThePlaceholdersInOrder(t *testing.T) {
+	query := "SELECT id FROM orders WHERE price >= ? AND (price > ? OR (price = ? AND id > ?)) LIMIT ?"
+	assert.Equal(t, "SELECT id FROM orders WHERE price >= $1 AND (price > $2 OR (price = $3 AND id > $4)) LIMIT $5", Number(query))
+	assert.Equal(t, "SELECT 1", Number("SELECT 1"))
+}
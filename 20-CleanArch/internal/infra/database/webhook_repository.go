@@ -0,0 +1,255 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// WebhookRepository stores webhook subscriptions in webhook_subscriptions,
+// with their events comma-separated, and the deliveries to them in
+// webhook_deliveries.
+type WebhookRepository struct {
+	Db      *sql.DB
+	dialect dialect
+}
+
+func newWebhookRepository(db *sql.DB, dialect dialect) *WebhookRepository {
+	return &WebhookRepository{Db: db, dialect: dialect}
+}
+
+const (
+	webhookSubscriptionColumns = "id, url, events, secret, active, created_by, created_at"
+	webhookDeliveryColumns     = "id, subscription_id, event_id, event, payload, status, attempts, next_attempt_at, last_attempt_at, response_status, last_error, created_at"
+)
+
+func scanWebhookSubscription(row rowScanner) (*entity.WebhookSubscription, error) {
+	var subscription entity.WebhookSubscription
+	var events string
+	err := row.Scan(&subscription.ID, &subscription.URL, &events, &subscription.Secret,
+		&subscription.Active, &subscription.CreatedBy, &subscription.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Events = strings.Split(events, ",")
+	return &subscription, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	var payload string
+	var lastAttemptAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.Event, &payload,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &lastAttemptAt,
+		&delivery.ResponseStatus, &delivery.LastError, &delivery.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = []byte(payload)
+	delivery.LastAttemptAt = lastAttemptAt.Time
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) SaveSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	_, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("INSERT INTO webhook_subscriptions ("+webhookSubscriptionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		subscription.ID, subscription.URL, strings.Join(subscription.Events, ","), subscription.Secret,
+		subscription.Active, subscription.CreatedBy, subscription.CreatedAt,
+	)
+	return err
+}
+
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, subscription *entity.WebhookSubscription) error {
+	result, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("UPDATE webhook_subscriptions SET url = ?, events = ?, secret = ?, active = ? WHERE id = ?"),
+		subscription.URL, strings.Join(subscription.Events, ","), subscription.Secret, subscription.Active, subscription.ID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// MySQL reports no affected rows when nothing changes.
+		_, err := r.FindSubscription(ctx, subscription.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx, r.dialect.rebind("DELETE FROM webhook_subscriptions WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return entity.ErrWebhookNotFound
+	}
+	_, err = tx.ExecContext(ctx, r.dialect.rebind("DELETE FROM webhook_deliveries WHERE subscription_id = ?"), id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *WebhookRepository) FindSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	subscription, err := scanWebhookSubscription(r.Db.QueryRowContext(ctx,
+		r.dialect.rebind("SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE id = ?"), id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrWebhookNotFound
+	}
+	return subscription, err
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	rows, err := r.Db.QueryContext(ctx, "SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subscriptions := []*entity.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// SaveDeliveries inserts the deliveries one by one, outside a transaction:
+// a duplicate would abort a PostgreSQL transaction, and a failure halfway
+// is completed when the event is handled again.
+func (r *WebhookRepository) SaveDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	query := r.dialect.rebind("INSERT INTO webhook_deliveries (" + webhookDeliveryColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	for _, delivery := range deliveries {
+		_, err := r.Db.ExecContext(ctx, query,
+			delivery.ID, delivery.SubscriptionID, delivery.EventID, delivery.Event, string(delivery.Payload),
+			delivery.Status, delivery.Attempts, delivery.NextAttemptAt, nullTime(delivery.LastAttemptAt),
+			delivery.ResponseStatus, delivery.LastError, delivery.CreatedAt,
+		)
+		if err != nil && !r.dialect.isUniqueViolation(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimDueDeliveries reads the due deliveries, then claims each with an
+// update guarded on it still being due: of concurrent workers reading the
+// same delivery, only the first to update it gets it.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*entity.WebhookDelivery, error) {
+	now = now.UTC().Truncate(time.Microsecond)
+	rows, err := r.Db.QueryContext(ctx,
+		r.dialect.rebind("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?"),
+		entity.WebhookDeliveryPending, now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	due := []*entity.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, delivery)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	claimed := []*entity.WebhookDelivery{}
+	leasedUntil := now.Add(lease)
+	for _, delivery := range due {
+		result, err := r.Db.ExecContext(ctx,
+			r.dialect.rebind("UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?"),
+			leasedUntil, delivery.ID, entity.WebhookDeliveryPending, now,
+		)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 1 {
+			delivery.NextAttemptAt = leasedUntil
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	result, err := r.Db.ExecContext(ctx,
+		r.dialect.rebind("UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_attempt_at = ?, response_status = ?, last_error = ? WHERE id = ?"),
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, nullTime(delivery.LastAttemptAt),
+		delivery.ResponseStatus, delivery.LastError, delivery.ID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		_, err := r.FindDelivery(ctx, delivery.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	delivery, err := scanWebhookDelivery(r.Db.QueryRowContext(ctx,
+		r.dialect.rebind("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = ?"), id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrWebhookDeliveryNotFound
+	}
+	return delivery, err
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID string, status entity.WebhookDeliveryStatus, limit int) ([]*entity.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries WHERE subscription_id = ?"
+	args := []interface{}{subscriptionID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+	rows, err := r.Db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []*entity.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookRepositoryContractSuite struct {
+	suite.Suite
+	newRepository func(t *testing.T) entity.WebhookRepositoryInterface
+	repo          entity.WebhookRepositoryInterface
+}
+
+func (suite *WebhookRepositoryContractSuite) SetupTest() {
+	suite.repo = suite.newRepository(suite.T())
+}
+
+func TestMemoryWebhookRepositoryContract(t *testing.T) {
+	suite.Run(t, &WebhookRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.WebhookRepositoryInterface {
+			return NewMemoryWebhookRepository()
+		},
+	})
+}
+
+func TestSQLiteWebhookRepositoryContract(t *testing.T) {
+	suite.Run(t, &WebhookRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.WebhookRepositoryInterface {
+			return newWebhookRepository(openContractDB(t, DriverSQLite, ":memory:"), sqliteDialect)
+		},
+	})
+}
+
+func TestMySQLWebhookRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+	suite.Run(t, &WebhookRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.WebhookRepositoryInterface {
+			return newWebhookRepository(openContractDB(t, DriverMySQL, dsn), mysqlDialect)
+		},
+	})
+}
+
+func TestPostgresWebhookRepositoryContract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	suite.Run(t, &WebhookRepositoryContractSuite{
+		newRepository: func(t *testing.T) entity.WebhookRepositoryInterface {
+			return newWebhookRepository(openContractDB(t, DriverPostgres, dsn), postgresDialect)
+		},
+	})
+}
+
+var webhookDay = time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+
+func (suite *WebhookRepositoryContractSuite) saveSubscription(id string, createdAt time.Time) *entity.WebhookSubscription {
+	subscription, err := entity.NewWebhookSubscription(id, "https://partner.example/"+id,
+		[]string{entity.OrderCreatedEvent, entity.OrderPaidEvent}, "", "alice")
+	suite.NoError(err)
+	subscription.CreatedAt = createdAt
+	suite.NoError(suite.repo.SaveSubscription(context.Background(), subscription))
+	return subscription
+}
+
+// saveDelivery stores a delivery of eventID to subscriptionID created, and
+// due, at createdAt.
+func (suite *WebhookRepositoryContractSuite) saveDelivery(id, subscriptionID, eventID string, createdAt time.Time) *entity.WebhookDelivery {
+	delivery := entity.NewWebhookDelivery(id, subscriptionID, eventID, entity.OrderPaidEvent, []byte(`{"id":"`+eventID+`"}`))
+	delivery.CreatedAt, delivery.NextAttemptAt = createdAt, createdAt
+	suite.NoError(suite.repo.SaveDeliveries(context.Background(), []*entity.WebhookDelivery{delivery}))
+	return delivery
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGivenSubscriptions_WhenUpdateAndList_ThenShouldReturnThemOldestFirst() {
+	second := suite.saveSubscription("wh-2", webhookDay.Add(time.Hour))
+	first := suite.saveSubscription("wh-1", webhookDay)
+	second.URL = "https://partner.example/moved"
+	second.Events = []string{entity.OrderRefundedEvent}
+	second.Active = false
+	suite.NoError(suite.repo.UpdateSubscription(context.Background(), second))
+
+	subscriptions, err := suite.repo.ListSubscriptions(context.Background())
+	suite.NoError(err)
+	suite.Len(subscriptions, 2)
+	suite.Equal(first.ID, subscriptions[0].ID)
+	suite.Equal(first.Secret, subscriptions[0].Secret)
+	suite.Equal(first.Events, subscriptions[0].Events)
+	suite.Equal("alice", subscriptions[0].CreatedBy)
+	suite.True(subscriptions[0].CreatedAt.Equal(webhookDay))
+	suite.Equal("https://partner.example/moved", subscriptions[1].URL)
+	suite.Equal([]string{entity.OrderRefundedEvent}, subscriptions[1].Events)
+	suite.False(subscriptions[1].Active)
+
+	_, err = suite.repo.FindSubscription(context.Background(), "wh-3")
+	suite.ErrorIs(err, entity.ErrWebhookNotFound)
+	suite.ErrorIs(suite.repo.UpdateSubscription(context.Background(), &entity.WebhookSubscription{ID: "wh-3"}), entity.ErrWebhookNotFound)
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGivenAnEventDeliveredTwice_WhenSaveDeliveries_ThenShouldKeepTheFirst() {
+	suite.saveSubscription("wh-1", webhookDay)
+	suite.saveDelivery("d-1", "wh-1", "event-1", webhookDay)
+	suite.saveDelivery("d-2", "wh-1", "event-1", webhookDay)
+
+	deliveries, err := suite.repo.ListDeliveries(context.Background(), "wh-1", "", 10)
+	suite.NoError(err)
+	suite.Len(deliveries, 1)
+	suite.Equal("d-1", deliveries[0].ID)
+	suite.Equal(`{"id":"event-1"}`, string(deliveries[0].Payload))
+	suite.Equal(entity.WebhookDeliveryPending, deliveries[0].Status)
+	suite.True(deliveries[0].LastAttemptAt.IsZero())
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGivenDueDeliveries_WhenClaim_ThenShouldLeaseThemOnce() {
+	suite.saveSubscription("wh-1", webhookDay)
+	suite.saveDelivery("d-1", "wh-1", "event-1", webhookDay.Add(time.Minute))
+	suite.saveDelivery("d-2", "wh-1", "event-2", webhookDay)
+	suite.saveDelivery("d-3", "wh-1", "event-3", webhookDay.Add(time.Hour))
+	now := webhookDay.Add(2 * time.Minute)
+
+	claimed, err := suite.repo.ClaimDueDeliveries(context.Background(), now, 10, time.Minute)
+	suite.NoError(err)
+	suite.Len(claimed, 2)
+	suite.Equal("d-2", claimed[0].ID)
+	suite.Equal("d-1", claimed[1].ID)
+	suite.True(claimed[0].NextAttemptAt.Equal(now.Add(time.Minute)))
+
+	claimed, err = suite.repo.ClaimDueDeliveries(context.Background(), now, 10, time.Minute)
+	suite.NoError(err)
+	suite.Empty(claimed)
+
+	// Once the lease runs out, an unfinished delivery is due again.
+	claimed, err = suite.repo.ClaimDueDeliveries(context.Background(), now.Add(time.Minute), 1, time.Minute)
+	suite.NoError(err)
+	suite.Len(claimed, 1)
+	suite.Equal("d-1", claimed[0].ID)
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGivenAnAttemptedDelivery_WhenUpdate_ThenShouldLogTheAttempt() {
+	suite.saveSubscription("wh-1", webhookDay)
+	delivery := suite.saveDelivery("d-1", "wh-1", "event-1", webhookDay)
+	delivery.Fail(webhookDay.Add(time.Minute), 500, "500 Internal Server Error", time.Time{})
+	suite.NoError(suite.repo.UpdateDelivery(context.Background(), delivery))
+
+	found, err := suite.repo.FindDelivery(context.Background(), "d-1")
+	suite.NoError(err)
+	suite.Equal(entity.WebhookDeliveryFailed, found.Status)
+	suite.Equal(1, found.Attempts)
+	suite.Equal(500, found.ResponseStatus)
+	suite.Equal("500 Internal Server Error", found.LastError)
+	suite.True(found.LastAttemptAt.Equal(webhookDay.Add(time.Minute)))
+
+	claimed, err := suite.repo.ClaimDueDeliveries(context.Background(), webhookDay.Add(time.Hour), 10, time.Minute)
+	suite.NoError(err)
+	suite.Empty(claimed)
+
+	_, err = suite.repo.FindDelivery(context.Background(), "d-2")
+	suite.ErrorIs(err, entity.ErrWebhookDeliveryNotFound)
+	suite.ErrorIs(suite.repo.UpdateDelivery(context.Background(), &entity.WebhookDelivery{ID: "d-2"}), entity.ErrWebhookDeliveryNotFound)
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGivenDeliveries_WhenList_ThenShouldReturnTheLatestFirst() {
+	suite.saveSubscription("wh-1", webhookDay)
+	suite.saveSubscription("wh-2", webhookDay)
+	for i, eventID := range []string{"event-1", "event-2", "event-3"} {
+		delivery := suite.saveDelivery("d-"+eventID, "wh-1", eventID, webhookDay.Add(time.Duration(i)*time.Minute))
+		if eventID != "event-2" {
+			delivery.Fail(webhookDay, 0, "timeout", time.Time{})
+			suite.NoError(suite.repo.UpdateDelivery(context.Background(), delivery))
+		}
+	}
+	suite.saveDelivery("d-other", "wh-2", "event-1", webhookDay)
+
+	deliveries, err := suite.repo.ListDeliveries(context.Background(), "wh-1", entity.WebhookDeliveryFailed, 10)
+	suite.NoError(err)
+	suite.Len(deliveries, 2)
+	suite.Equal("d-event-3", deliveries[0].ID)
+	suite.Equal("d-event-1", deliveries[1].ID)
+
+	deliveries, err = suite.repo.ListDeliveries(context.Background(), "wh-1", "", 2)
+	suite.NoError(err)
+	suite.Len(deliveries, 2)
+	suite.Equal("d-event-3", deliveries[0].ID)
+	suite.Equal("d-event-2", deliveries[1].ID)
+}
+
+func (suite *WebhookRepositoryContractSuite) TestGivenASubscription_WhenDelete_ThenShouldDeleteItsDeliveries() {
+	suite.saveSubscription("wh-1", webhookDay)
+	suite.saveDelivery("d-1", "wh-1", "event-1", webhookDay)
+
+	suite.NoError(suite.repo.DeleteSubscription(context.Background(), "wh-1"))
+
+	_, err := suite.repo.FindSubscription(context.Background(), "wh-1")
+	suite.ErrorIs(err, entity.ErrWebhookNotFound)
+	_, err = suite.repo.FindDelivery(context.Background(), "d-1")
+	suite.ErrorIs(err, entity.ErrWebhookDeliveryNotFound)
+	suite.ErrorIs(suite.repo.DeleteSubscription(context.Background(), "wh-1"), entity.ErrWebhookNotFound)
+}
@@ -0,0 +1,77 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/graph/model"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaderWait is how long a loader collects keys before it runs its batch.
+// gqlgen resolves the fields of a list's elements concurrently, so the
+// lookups of one page land in the same batch.
+const loaderWait = 2 * time.Millisecond
+
+// Loaders batch the lookups of the relations nested under orders, so a page
+// of orders costs one query per relation instead of one per order.
+type Loaders struct {
+	InvoiceByOrderID *dataloader.Loader[string, *model.Invoice]
+}
+
+func NewLoaders(findOrderInvoices *usecase.FindOrderInvoicesUseCase) *Loaders {
+	return &Loaders{
+		InvoiceByOrderID: dataloader.NewBatchedLoader(
+			func(ctx context.Context, orderIDs []string) []*dataloader.Result[*model.Invoice] {
+				results := make([]*dataloader.Result[*model.Invoice], len(orderIDs))
+				invoices, err := findOrderInvoices.Execute(ctx, orderIDs)
+				for i, orderID := range orderIDs {
+					result := &dataloader.Result[*model.Invoice]{Error: err}
+					if invoice, ok := invoices[orderID]; ok {
+						result.Data = newInvoiceModel(invoice)
+					}
+					results[i] = result
+				}
+				return results
+			},
+			dataloader.WithWait[string, *model.Invoice](loaderWait),
+		),
+	}
+}
+
+type loadersKey struct{}
+
+func WithLoaders(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+func LoadersFromContext(ctx context.Context) (*Loaders, bool) {
+	loaders, ok := ctx.Value(loadersKey{}).(*Loaders)
+	return loaders, ok
+}
+
+// Dataloaders is a gqlgen extension that gives each response fresh
+// Loaders. Their cache thereby lasts one response: a query sees consistent
+// data and each subscription event sees current data.
+type Dataloaders struct {
+	FindOrderInvoices *usecase.FindOrderInvoicesUseCase
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = Dataloaders{}
+
+func (Dataloaders) ExtensionName() string {
+	return "Dataloaders"
+}
+
+func (Dataloaders) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d Dataloaders) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	return next(WithLoaders(ctx, NewLoaders(d.FindOrderInvoices)))
+}
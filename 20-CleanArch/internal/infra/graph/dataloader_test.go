@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchRecordingInvoiceRepository records the order IDs of every batch
+// lookup.
+type batchRecordingInvoiceRepository struct {
+	*database.MemoryInvoiceRepository
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *batchRecordingInvoiceRepository) FindByOrderIDs(ctx context.Context, orderIDs []string) ([]*entity.Invoice, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, orderIDs)
+	r.mu.Unlock()
+	return r.MemoryInvoiceRepository.FindByOrderIDs(ctx, orderIDs)
+}
+
+func TestGivenAPageOfOrders_WhenQueryingTheirInvoices_ThenShouldLoadThemInOneBatch(t *testing.T) {
+	orderRepository := database.NewMemoryOrderRepository()
+	invoiceRepository := &batchRecordingInvoiceRepository{MemoryInvoiceRepository: database.NewMemoryInvoiceRepository()}
+	for _, id := range []string{"a", "b", "c"} {
+		order, err := entity.NewOrder(id, 10, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, order.CalculateFinalPrice())
+		assert.NoError(t, orderRepository.Save(context.Background(), order))
+	}
+	for _, id := range []string{"a", "c"} {
+		invoice, err := entity.NewInvoice(id, 10, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, invoiceRepository.Save(context.Background(), invoice))
+	}
+	c := client.New(newTestServer(t, &Resolver{
+		ListOrdersUseCase:        *usecase.NewListOrdersUseCase(orderRepository),
+		FindOrderInvoicesUseCase: *usecase.NewFindOrderInvoicesUseCase(invoiceRepository),
+	}))
+
+	var resp struct {
+		ListOrders struct {
+			Edges []struct {
+				Node struct {
+					ID      string
+					Invoice *struct {
+						ID    string
+						Total float64
+					}
+				}
+			}
+		}
+	}
+	err := c.Post(`{ listOrders(first: 10) { edges { node { id Invoice { id total } } } } }`, &resp, as(testPrincipal))
+
+	assert.NoError(t, err)
+	invoices := map[string]string{}
+	for _, edge := range resp.ListOrders.Edges {
+		if edge.Node.Invoice != nil {
+			invoices[edge.Node.ID] = edge.Node.Invoice.ID
+			assert.Equal(t, 11.0, edge.Node.Invoice.Total)
+		}
+	}
+	assert.Len(t, resp.ListOrders.Edges, 3)
+	assert.Equal(t, map[string]string{"a": "INV-a", "c": "INV-c"}, invoices)
+	assert.Len(t, invoiceRepository.batches, 1)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, invoiceRepository.batches[0])
+}
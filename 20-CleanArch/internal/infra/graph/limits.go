@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/graph/model"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// complexityRoot prices list fields by what they may return: a page of
+// orders costs first times the selection made on each order. Other fields
+// keep gqlgen's default of one plus their selection.
+func complexityRoot() ComplexityRoot {
+	var root ComplexityRoot
+	root.Query.ListOrders = func(childComplexity int, first int, after *string, filter *model.OrderFilter, sort *model.OrderSort) int {
+		if first < 1 {
+			first = 1
+		}
+		return first * childComplexity
+	}
+	return root
+}
+
+// DepthLimit is a gqlgen extension that rejects operations whose selections
+// nest deeper than Max. Introspection fields are not counted, so GraphQL
+// tools keep working.
+type DepthLimit struct {
+	Max int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit{}
+
+func (DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (DepthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if depth := selectionDepth(rc.Operation.SelectionSet); depth > d.Max {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.Max)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+	return nil
+}
+
+// selectionDepth counts the fields nested in set, following fragments.
+// Validation has already rejected fragment cycles.
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+	for _, selection := range set {
+		var d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLimitedServer(t *testing.T, config ServerConfig) *client.Client {
+	verifier, err := auth.NewVerifier(testAuthConfig)
+	assert.NoError(t, err)
+	config.Verifier = verifier
+	return client.New(NewServer(newOrdersResolver(), config))
+}
+
+const invoicesQuery = `{ listOrders(first: 1) { edges { node { Invoice { id } } } } }`
+
+func TestGivenADepthLimit_WhenAQueryNestsDeeper_ThenShouldRejectIt(t *testing.T) {
+	var resp map[string]interface{}
+
+	err := newLimitedServer(t, ServerConfig{DepthLimit: 4}).Post(invoicesQuery, &resp, as(testPrincipal))
+	assert.ErrorContains(t, err, "operation has depth 5, which exceeds the limit of 4")
+
+	err = newLimitedServer(t, ServerConfig{}).Post(invoicesQuery, &resp, as(testPrincipal))
+	assert.NoError(t, err)
+}
+
+func TestGivenAComplexityLimit_WhenAPageIsTooLarge_ThenShouldRejectIt(t *testing.T) {
+	c := newLimitedServer(t, ServerConfig{ComplexityLimit: 100})
+	var resp map[string]interface{}
+
+	err := c.Post(`{ listOrders(first: 50) { edges { node { id Price Tax } } } }`, &resp, as(testPrincipal))
+	assert.ErrorContains(t, err, "operation has complexity")
+
+	err = c.Post(`{ listOrders(first: 10) { edges { node { id Price Tax } } } }`, &resp, as(testPrincipal))
+	assert.NoError(t, err)
+}
+
+func TestGivenTheDefaultLimits_WhenIntrospecting_ThenShouldAnswer(t *testing.T) {
+	c := newLimitedServer(t, ServerConfig{})
+	var resp map[string]interface{}
+
+	err := c.Post(`{ __schema { types { name fields { name type { name ofType { name ofType { name ofType { name } } } } } } } }`, &resp)
+
+	assert.NoError(t, err)
+}
+
+func TestGivenAPersistedQueryHash_WhenTheQueryWasRegistered_ThenShouldRunItByHashAlone(t *testing.T) {
+	c := newLimitedServer(t, ServerConfig{})
+	query := `{ listOrders(first: 1) { totalCount } }`
+	sum := sha256.Sum256([]byte(query))
+	persisted := client.Extensions(map[string]interface{}{
+		"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hex.EncodeToString(sum[:])},
+	})
+	var resp map[string]interface{}
+
+	err := c.Post("", &resp, persisted, as(testPrincipal))
+	assert.ErrorContains(t, err, "PersistedQueryNotFound")
+
+	assert.NoError(t, c.Post(query, &resp, persisted, as(testPrincipal)))
+	assert.NoError(t, c.Post("", &resp, persisted, as(testPrincipal)))
+}
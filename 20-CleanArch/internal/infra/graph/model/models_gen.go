@@ -2,20 +2,253 @@
 
 package model
 
-type ListOrdersInput struct {
-	Page  int `json:"Page"`
-	Limit int `json:"Limit"`
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type Invoice struct {
+	ID       string    `json:"id"`
+	Amount   float64   `json:"amount"`
+	Tax      float64   `json:"tax"`
+	Total    float64   `json:"total"`
+	IssuedAt time.Time `json:"issuedAt"`
 }
 
 type Order struct {
-	ID         string  `json:"id"`
-	Price      float64 `json:"Price"`
-	Tax        float64 `json:"Tax"`
-	FinalPrice float64 `json:"FinalPrice"`
+	ID         string    `json:"id"`
+	Price      float64   `json:"Price"`
+	Tax        float64   `json:"Tax"`
+	FinalPrice float64   `json:"FinalPrice"`
+	Status     string    `json:"Status"`
+	CreatedAt  time.Time `json:"CreatedAt"`
+	CreatedBy  string    `json:"CreatedBy"`
+	Category   string    `json:"Category"`
+	Region     string    `json:"Region"`
+	// The taxes making up Tax; empty when the client supplied the tax.
+	TaxLines []*TaxLine `json:"TaxLines"`
+	// The coupon redeemed for the order, empty if none; Price is what remains after its discount.
+	CouponCode string  `json:"CouponCode"`
+	Discount   float64 `json:"Discount"`
+	// Null until the order worker has issued it. Batched across the orders of a response.
+	Invoice *Invoice `json:"Invoice,omitempty"`
+}
+
+type OrderConnection struct {
+	Edges      []*OrderEdge `json:"edges"`
+	PageInfo   *PageInfo    `json:"pageInfo"`
+	TotalCount int          `json:"totalCount"`
+}
+
+type OrderEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Order `json:"node"`
+}
+
+type OrderFilter struct {
+	Status      *string    `json:"Status,omitempty"`
+	MinPrice    *float64   `json:"MinPrice,omitempty"`
+	MaxPrice    *float64   `json:"MaxPrice,omitempty"`
+	CreatedFrom *time.Time `json:"CreatedFrom,omitempty"`
+	CreatedTo   *time.Time `json:"CreatedTo,omitempty"`
 }
 
 type OrderInput struct {
 	ID    string  `json:"id"`
 	Price float64 `json:"Price"`
-	Tax   float64 `json:"Tax"`
+	// Optional when the server prices the tax; if given, it must match the server's.
+	Tax      *float64 `json:"Tax,omitempty"`
+	Category *string  `json:"Category,omitempty"`
+	Region   *string  `json:"Region,omitempty"`
+	// Takes the coupon's discount off Price before tax.
+	CouponCode *string `json:"CouponCode,omitempty"`
+}
+
+type OrderReport struct {
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Granularity ReportGranularity    `json:"granularity"`
+	Periods     []*OrderReportPeriod `json:"periods"`
+	Total       *OrderReportPeriod   `json:"total"`
+}
+
+// The totals of a period. Orders count towards the sales of the period they were
+// created in, and towards the payments, cancellations and refunds of the period
+// those happened in.
+type OrderReportPeriod struct {
+	Start             time.Time `json:"start"`
+	End               time.Time `json:"end"`
+	Orders            int       `json:"orders"`
+	GrossSales        float64   `json:"grossSales"`
+	NetSales          float64   `json:"netSales"`
+	TaxBilled         float64   `json:"taxBilled"`
+	Discounts         float64   `json:"discounts"`
+	AverageOrderValue float64   `json:"averageOrderValue"`
+	OrdersPaid        int       `json:"ordersPaid"`
+	PaidRevenue       float64   `json:"paidRevenue"`
+	OrdersCancelled   int       `json:"ordersCancelled"`
+	OrdersRefunded    int       `json:"ordersRefunded"`
+	RefundedAmount    float64   `json:"refundedAmount"`
+	// paidRevenue less refundedAmount.
+	NetRevenue float64 `json:"netRevenue"`
+	// The tax of the orders paid less that of the orders refunded.
+	TaxCollected float64 `json:"taxCollected"`
+}
+
+type OrderSort struct {
+	Field     OrderSortField `json:"Field"`
+	Direction SortDirection  `json:"Direction"`
+}
+
+type OrderStatusChange struct {
+	ID             string    `json:"id"`
+	PreviousStatus string    `json:"previousStatus"`
+	Status         string    `json:"status"`
+	Reason         *string   `json:"reason,omitempty"`
+	FinalPrice     float64   `json:"finalPrice"`
+	ChangedAt      time.Time `json:"changedAt"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+// A tax levied on an order. Compound taxes are levied on the price plus the taxes before them.
+type TaxLine struct {
+	Name     string  `json:"name"`
+	Rate     float64 `json:"rate"`
+	Compound bool    `json:"compound"`
+	Amount   float64 `json:"amount"`
+}
+
+type OrderSortField string
+
+const (
+	OrderSortFieldCreatedAt  OrderSortField = "CREATED_AT"
+	OrderSortFieldPrice      OrderSortField = "PRICE"
+	OrderSortFieldFinalPrice OrderSortField = "FINAL_PRICE"
+)
+
+var AllOrderSortField = []OrderSortField{
+	OrderSortFieldCreatedAt,
+	OrderSortFieldPrice,
+	OrderSortFieldFinalPrice,
+}
+
+func (e OrderSortField) IsValid() bool {
+	switch e {
+	case OrderSortFieldCreatedAt, OrderSortFieldPrice, OrderSortFieldFinalPrice:
+		return true
+	}
+	return false
+}
+
+func (e OrderSortField) String() string {
+	return string(e)
+}
+
+func (e *OrderSortField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderSortField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderSortField", str)
+	}
+	return nil
+}
+
+func (e OrderSortField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReportGranularity string
+
+const (
+	ReportGranularityDay   ReportGranularity = "DAY"
+	ReportGranularityWeek  ReportGranularity = "WEEK"
+	ReportGranularityMonth ReportGranularity = "MONTH"
+)
+
+var AllReportGranularity = []ReportGranularity{
+	ReportGranularityDay,
+	ReportGranularityWeek,
+	ReportGranularityMonth,
+}
+
+func (e ReportGranularity) IsValid() bool {
+	switch e {
+	case ReportGranularityDay, ReportGranularityWeek, ReportGranularityMonth:
+		return true
+	}
+	return false
+}
+
+func (e ReportGranularity) String() string {
+	return string(e)
+}
+
+func (e *ReportGranularity) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReportGranularity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReportGranularity", str)
+	}
+	return nil
+}
+
+func (e ReportGranularity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SortDirection string
+
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+var AllSortDirection = []SortDirection{
+	SortDirectionAsc,
+	SortDirectionDesc,
+}
+
+func (e SortDirection) IsValid() bool {
+	switch e {
+	case SortDirectionAsc, SortDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e SortDirection) String() string {
+	return string(e)
+}
+
+func (e *SortDirection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortDirection", str)
+	}
+	return nil
+}
+
+func (e SortDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
@@ -1,12 +1,149 @@
 package graph
 
-import "github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+import (
+	"context"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/graph/model"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	CreateOrderUseCase usecase.CreateOrderUseCase
-	ListOrdersUseCase  usecase.ListOrdersUseCase
+	CreateOrderUseCase       usecase.CreateOrderUseCase
+	ListOrdersUseCase        usecase.ListOrdersUseCase
+	FindOrderInvoicesUseCase usecase.FindOrderInvoicesUseCase
+	GetOrderReportUseCase    usecase.GetOrderReportUseCase
+	// OrderEvents feeds the subscriptions; it is registered on the event
+	// dispatcher for the order events.
+	OrderEvents *events.Broadcaster
+}
+
+func newOrderModel(order usecase.OrderOutputDTO) *model.Order {
+	taxLines := []*model.TaxLine{}
+	for _, line := range order.TaxLines {
+		taxLines = append(taxLines, &model.TaxLine{
+			Name:     line.Name,
+			Rate:     line.Rate,
+			Compound: line.Compound,
+			Amount:   line.Amount,
+		})
+	}
+	return &model.Order{
+		ID:         order.ID,
+		Price:      order.Price,
+		Tax:        order.Tax,
+		FinalPrice: order.FinalPrice,
+		Status:     order.Status,
+		CreatedAt:  order.CreatedAt,
+		CreatedBy:  order.CreatedBy,
+		Category:   order.Category,
+		Region:     order.Region,
+		TaxLines:   taxLines,
+		CouponCode: order.CouponCode,
+		Discount:   order.Discount,
+	}
+}
+
+func newInvoiceModel(invoice usecase.InvoiceOutputDTO) *model.Invoice {
+	return &model.Invoice{
+		ID:       invoice.ID,
+		Amount:   invoice.Amount,
+		Tax:      invoice.Tax,
+		Total:    invoice.Total,
+		IssuedAt: invoice.IssuedAt,
+	}
+}
+
+func newOrderReportPeriodModel(period usecase.OrderReportPeriodDTO) *model.OrderReportPeriod {
+	return &model.OrderReportPeriod{
+		Start:             period.Start,
+		End:               period.End,
+		Orders:            period.Orders,
+		GrossSales:        period.GrossSales,
+		NetSales:          period.NetSales,
+		TaxBilled:         period.TaxBilled,
+		Discounts:         period.Discounts,
+		AverageOrderValue: period.AverageOrderValue,
+		OrdersPaid:        period.OrdersPaid,
+		PaidRevenue:       period.PaidRevenue,
+		OrdersCancelled:   period.OrdersCancelled,
+		OrdersRefunded:    period.OrdersRefunded,
+		RefundedAmount:    period.RefundedAmount,
+		NetRevenue:        period.NetRevenue,
+		TaxCollected:      period.TaxCollected,
+	}
+}
+
+// loaders returns the response's Loaders, which the Dataloaders extension
+// installs, or unshared ones when the resolver runs without it.
+func (r *Resolver) loaders(ctx context.Context) *Loaders {
+	if loaders, ok := LoadersFromContext(ctx); ok {
+		return loaders
+	}
+	return NewLoaders(&r.FindOrderInvoicesUseCase)
+}
+
+func newOrderCreatedModel(payload event.OrderCreatedPayload) *model.Order {
+	taxLines := []*model.TaxLine{}
+	for _, line := range payload.TaxLines {
+		taxLines = append(taxLines, &model.TaxLine{
+			Name:     line.Name,
+			Rate:     line.Rate,
+			Compound: line.Compound,
+			Amount:   line.Amount,
+		})
+	}
+	return &model.Order{
+		ID:         payload.ID,
+		Price:      payload.Price,
+		Tax:        payload.Tax,
+		FinalPrice: payload.FinalPrice,
+		Status:     payload.Status,
+		CreatedAt:  payload.CreatedAt,
+		CreatedBy:  payload.CreatedBy,
+		Category:   payload.Category,
+		Region:     payload.Region,
+		TaxLines:   taxLines,
+		CouponCode: payload.CouponCode,
+		Discount:   payload.Discount,
+	}
+}
+
+func newOrderStatusChangeModel(payload event.OrderStatusChangedPayload) *model.OrderStatusChange {
+	change := &model.OrderStatusChange{
+		ID:             payload.ID,
+		PreviousStatus: payload.PreviousStatus,
+		Status:         payload.Status,
+		FinalPrice:     payload.FinalPrice,
+		ChangedAt:      payload.ChangedAt,
+	}
+	if payload.Reason != "" {
+		change.Reason = &payload.Reason
+	}
+	return change
+}
+
+// relay converts the broadcast events accepted by filter for a subscription
+// until ctx, which gqlgen cancels when the client unsubscribes, is done.
+// While the client is slow the broadcaster buffers and, past its limit,
+// drops the oldest events.
+func relay[T any](ctx context.Context, broadcaster *events.Broadcaster, filter func(events.EventInterface) bool, convert func(events.EventInterface) T) <-chan T {
+	in := broadcaster.Subscribe(ctx, events.DefaultSubscriptionBuffer, filter)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			select {
+			case out <- convert(ev):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
 }
@@ -6,57 +6,161 @@ package graph
 
 import (
 	"context"
+	"strings"
+	"time"
 
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/graph/model"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
 )
 
+// Invoice is the resolver for the invoice field.
+func (r *orderResolver) Invoice(ctx context.Context, obj *model.Order) (*model.Invoice, error) {
+	return r.loaders(ctx).InvoiceByOrderID.Load(ctx, obj.ID)()
+}
+
 // CreateOrder is the resolver for the createOrder field.
-func (r *mutationResolver) CreateOrder(ctx context.Context, input *model.OrderInput) (*model.Order, error) {
+func (r *mutationResolver) CreateOrder(ctx context.Context, input *model.OrderInput, idempotencyKey *string) (*model.Order, error) {
 	dto := usecase.OrderInputDTO{
 		ID:    input.ID,
 		Price: float64(input.Price),
-		Tax:   float64(input.Tax),
 	}
-	output, err := r.CreateOrderUseCase.Execute(dto)
+	if input.Tax != nil {
+		dto.Tax = *input.Tax
+	}
+	if input.Category != nil {
+		dto.Category = *input.Category
+	}
+	if input.Region != nil {
+		dto.Region = *input.Region
+	}
+	if input.CouponCode != nil {
+		dto.CouponCode = *input.CouponCode
+	}
+	if idempotencyKey != nil {
+		dto.IdempotencyKey = *idempotencyKey
+	}
+	output, err := r.CreateOrderUseCase.Execute(ctx, dto)
 	if err != nil {
 		return nil, err
 	}
-	return &model.Order{
-		ID:         output.ID,
-		Price:      float64(output.Price),
-		Tax:        float64(output.Tax),
-		FinalPrice: float64(output.FinalPrice),
-	}, nil
+	return newOrderModel(output), nil
 }
 
 // ListOrders is the resolver for the listOrders field.
-func (r *queryResolver) ListOrders(ctx context.Context, input *model.ListOrdersInput) ([]*model.Order, error) {
-	dto := usecase.ListOrdersInputDTO{
-		Page:  input.Page,
-		Limit: input.Limit,
+func (r *queryResolver) ListOrders(ctx context.Context, first int, after *string, filter *model.OrderFilter, sort *model.OrderSort) (*model.OrderConnection, error) {
+	dto := usecase.ListOrdersInputDTO{Limit: first}
+	if after != nil {
+		dto.After = *after
+	}
+	if filter != nil {
+		if filter.Status != nil {
+			dto.Status = *filter.Status
+		}
+		dto.MinPrice = filter.MinPrice
+		dto.MaxPrice = filter.MaxPrice
+		dto.CreatedFrom = filter.CreatedFrom
+		dto.CreatedTo = filter.CreatedTo
 	}
-	output, err := r.ListOrdersUseCase.Execute(dto)
+	if sort != nil {
+		dto.SortBy = strings.ToLower(sort.Field.String())
+		dto.SortDirection = strings.ToLower(sort.Direction.String())
+	}
+	output, err := r.ListOrdersUseCase.Execute(ctx, dto)
 	if err != nil {
 		return nil, err
 	}
-	var orders []*model.Order
-	for _, o := range output.Orders {
-		orders = append(orders, &model.Order{
-			ID:         o.ID,
-			Price:      float64(o.Price),
-			Tax:        float64(o.Tax),
-			FinalPrice: float64(o.FinalPrice),
+
+	connection := &model.OrderConnection{
+		Edges: []*model.OrderEdge{},
+		PageInfo: &model.PageInfo{
+			HasNextPage:     output.PageInfo.HasNextPage,
+			HasPreviousPage: output.PageInfo.HasPreviousPage,
+		},
+		TotalCount: output.TotalCount,
+	}
+	if output.PageInfo.StartCursor != "" {
+		connection.PageInfo.StartCursor = &output.PageInfo.StartCursor
+		connection.PageInfo.EndCursor = &output.PageInfo.EndCursor
+	}
+	for _, edge := range output.Edges {
+		connection.Edges = append(connection.Edges, &model.OrderEdge{
+			Cursor: edge.Cursor,
+			Node:   newOrderModel(edge.Node),
 		})
 	}
-	return orders, nil
+	return connection, nil
+}
+
+// OrderReport is the resolver for the orderReport field.
+func (r *queryResolver) OrderReport(ctx context.Context, from time.Time, to time.Time, granularity *model.ReportGranularity) (*model.OrderReport, error) {
+	dto := usecase.OrderReportInputDTO{From: from, To: to}
+	if granularity != nil {
+		dto.Granularity = strings.ToLower(granularity.String())
+	}
+	output, err := r.GetOrderReportUseCase.Execute(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+	report := &model.OrderReport{
+		From:        output.From,
+		To:          output.To,
+		Granularity: model.ReportGranularity(strings.ToUpper(output.Granularity)),
+		Periods:     []*model.OrderReportPeriod{},
+		Total:       newOrderReportPeriodModel(output.Total),
+	}
+	for _, period := range output.Periods {
+		report.Periods = append(report.Periods, newOrderReportPeriodModel(period))
+	}
+	return report, nil
+}
+
+// OrderCreated is the resolver for the orderCreated field.
+func (r *subscriptionResolver) OrderCreated(ctx context.Context) (<-chan *model.Order, error) {
+	if _, err := usecase.Authorize(ctx, usecase.ScopeOrdersRead); err != nil {
+		return nil, err
+	}
+	return relay(ctx, r.OrderEvents,
+		func(ev events.EventInterface) bool {
+			_, ok := ev.GetPayload().(event.OrderCreatedPayload)
+			return ok
+		},
+		func(ev events.EventInterface) *model.Order {
+			return newOrderCreatedModel(ev.GetPayload().(event.OrderCreatedPayload))
+		},
+	), nil
+}
+
+// OrderStatusChanged is the resolver for the orderStatusChanged field.
+func (r *subscriptionResolver) OrderStatusChanged(ctx context.Context, id string) (<-chan *model.OrderStatusChange, error) {
+	if _, err := usecase.Authorize(ctx, usecase.ScopeOrdersRead); err != nil {
+		return nil, err
+	}
+	return relay(ctx, r.OrderEvents,
+		func(ev events.EventInterface) bool {
+			payload, ok := ev.GetPayload().(event.OrderStatusChangedPayload)
+			return ok && payload.ID == id
+		},
+		func(ev events.EventInterface) *model.OrderStatusChange {
+			return newOrderStatusChangeModel(ev.GetPayload().(event.OrderStatusChangedPayload))
+		},
+	), nil
 }
 
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Order returns OrderResolver implementation.
+func (r *Resolver) Order() OrderResolver { return &orderResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
 type mutationResolver struct{ *Resolver }
+type orderResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+)
+
+// Defaults for the zero fields of ServerConfig.
+const (
+	DefaultComplexityLimit = 1000
+	DefaultDepthLimit      = 10
+	DefaultAPQCacheSize    = 1000
+	queryCacheSize         = 1000
+)
+
+// ServerConfig holds the limits of the GraphQL server. Operations costing
+// more than ComplexityLimit, or nesting deeper than DepthLimit, are rejected
+// before they run. APQCacheSize bounds the LRU of automatic persisted
+// queries, which clients then send by hash alone.
+type ServerConfig struct {
+	Verifier        auth.VerifierInterface
+	ComplexityLimit int
+	DepthLimit      int
+	APQCacheSize    int
+}
+
+// NewServer is handler.NewDefaultServer with authentication, limits,
+// dataloaders and tracing. Queries and mutations carry their principal in
+// the request context, put there by web.AuthenticateIfPresent;
+// subscriptions authenticate with the Authorization entry of their
+// connection_init payload.
+func NewServer(resolver *Resolver, config ServerConfig) *handler.Server {
+	srv := handler.New(NewExecutableSchema(Config{
+		Resolvers:  resolver,
+		Directives: DirectiveRoot{Authenticated: Authenticated},
+		Complexity: complexityRoot(),
+	}))
+
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc: func(ctx context.Context, initPayload transport.InitPayload) (context.Context, error) {
+			if initPayload.Authorization() == "" {
+				return ctx, nil
+			}
+			return auth.Authenticate(ctx, config.Verifier, initPayload.Authorization())
+		},
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New(queryCacheSize))
+
+	srv.Use(Tracer{})
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New(orDefault(config.APQCacheSize, DefaultAPQCacheSize)),
+	})
+	srv.Use(extension.FixedComplexityLimit(orDefault(config.ComplexityLimit, DefaultComplexityLimit)))
+	srv.Use(DepthLimit{Max: orDefault(config.DepthLimit, DefaultDepthLimit)})
+	srv.Use(Dataloaders{FindOrderInvoices: &resolver.FindOrderInvoicesUseCase})
+	return srv
+}
+
+func orDefault(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// Authenticated implements the @authenticated directive: the field only
+// resolves for requests with a principal.
+func Authenticated(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if _, ok := auth.PrincipalFromContext(ctx); !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	return next(ctx)
+}
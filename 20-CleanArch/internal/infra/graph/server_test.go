@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	eventhandler "github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testAuthConfig = auth.Config{HMACSecret: "secret"}
+	testPrincipal  = auth.Principal{
+		Subject: "alice",
+		Scopes:  []string{usecase.ScopeOrdersRead, usecase.ScopeOrdersWrite},
+	}
+)
+
+func newTestServer(t *testing.T, resolver *Resolver) *handler.Server {
+	verifier, err := auth.NewVerifier(testAuthConfig)
+	assert.NoError(t, err)
+	return NewServer(resolver, ServerConfig{Verifier: verifier})
+}
+
+// authorization is a connection_init payload authenticating as principal.
+func authorization(t *testing.T, principal auth.Principal) map[string]interface{} {
+	token, err := auth.IssueHMACToken(testAuthConfig, principal, time.Minute)
+	assert.NoError(t, err)
+	return map[string]interface{}{"Authorization": "Bearer " + token}
+}
+
+// as sends an HTTP request as principal, as if it had passed
+// web.AuthenticateIfPresent.
+func as(principal auth.Principal) client.Option {
+	return func(request *client.Request) {
+		request.HTTP = request.HTTP.WithContext(auth.WithPrincipal(request.HTTP.Context(), principal))
+	}
+}
+
+// newOrdersResolver projects the orders it creates into the reports.
+func newOrdersResolver() *Resolver {
+	orderRepository := database.NewMemoryOrderRepository()
+	orderReportRepository := database.NewMemoryOrderReportRepository()
+	eventDispatcher := events.NewEventDispatcher()
+	eventDispatcher.Register("OrderCreated", eventhandler.NewOrderReportHandler(usecase.NewProjectOrderReportUseCase(orderReportRepository)))
+	return &Resolver{
+		CreateOrderUseCase:       *usecase.NewCreateOrderUseCase(orderRepository, nil, eventDispatcher, nil, nil),
+		ListOrdersUseCase:        *usecase.NewListOrdersUseCase(orderRepository),
+		FindOrderInvoicesUseCase: *usecase.NewFindOrderInvoicesUseCase(database.NewMemoryInvoiceRepository()),
+		GetOrderReportUseCase:    *usecase.NewGetOrderReportUseCase(orderReportRepository),
+	}
+}
+
+func TestGivenAnAuthenticatedRequest_WhenCreateOrder_ThenShouldRecordThePrincipal(t *testing.T) {
+	c := client.New(newTestServer(t, newOrdersResolver()))
+
+	var resp struct {
+		CreateOrder struct {
+			ID        string
+			CreatedBy string
+		}
+	}
+	err := c.Post(`mutation { createOrder(input: {id: "a", Price: 10, Tax: 1}) { id CreatedBy } }`, &resp, as(testPrincipal))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", resp.CreateOrder.CreatedBy)
+}
+
+func TestGivenATaxEngine_WhenCreateOrderWithoutTax_ThenShouldReturnTheTaxLines(t *testing.T) {
+	resolver := newOrdersResolver()
+	engine, err := entity.NewTaxEngine([]entity.TaxRule{{Name: "VAT", Rate: 10}, {Name: "State", Region: "north", Rate: 1}}, false)
+	assert.NoError(t, err)
+	resolver.CreateOrderUseCase.TaxEngine = engine
+	c := client.New(newTestServer(t, resolver))
+
+	var resp struct {
+		CreateOrder struct {
+			FinalPrice float64
+			Region     string
+			TaxLines   []struct {
+				Name   string
+				Amount float64
+			}
+		}
+	}
+	err = c.Post(`mutation { createOrder(input: {id: "a", Price: 10, Region: "north"}) { FinalPrice Region TaxLines { name amount } } }`, &resp, as(testPrincipal))
+	assert.NoError(t, err)
+	assert.Equal(t, 11.1, resp.CreateOrder.FinalPrice)
+	assert.Equal(t, "north", resp.CreateOrder.Region)
+	assert.Len(t, resp.CreateOrder.TaxLines, 2)
+	assert.Equal(t, "State", resp.CreateOrder.TaxLines[1].Name)
+	assert.Equal(t, 0.1, resp.CreateOrder.TaxLines[1].Amount)
+
+	err = c.Post(`mutation { createOrder(input: {id: "b", Price: 10, Tax: 2}) { id } }`, &resp, as(testPrincipal))
+	assert.ErrorContains(t, err, entity.ErrTaxMismatch.Error())
+}
+
+func TestGivenACoupon_WhenCreateOrderWithItsCode_ThenShouldReturnTheDiscount(t *testing.T) {
+	resolver := newOrdersResolver()
+	coupons := database.NewMemoryCouponRepository()
+	coupon, err := entity.NewCoupon("FIVE", entity.DiscountFixed, 5, time.Time{}, time.Time{}, 0, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, coupons.Save(context.Background(), coupon))
+	resolver.CreateOrderUseCase.CouponRepository = coupons
+	c := client.New(newTestServer(t, resolver))
+
+	var resp struct {
+		CreateOrder struct {
+			Price      float64
+			CouponCode string
+			Discount   float64
+		}
+	}
+	err = c.Post(`mutation { createOrder(input: {id: "a", Price: 10, Tax: 1, CouponCode: "five"}) { Price CouponCode Discount } }`, &resp, as(testPrincipal))
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, resp.CreateOrder.Price)
+	assert.Equal(t, "FIVE", resp.CreateOrder.CouponCode)
+	assert.Equal(t, 5.0, resp.CreateOrder.Discount)
+}
+
+func TestGivenAnAnonymousRequest_WhenCreateOrder_ThenShouldBeRejectedByTheDirective(t *testing.T) {
+	c := client.New(newTestServer(t, newOrdersResolver()))
+
+	var resp map[string]interface{}
+	err := c.Post(`mutation { createOrder(input: {id: "a", Price: 10, Tax: 1}) { id } }`, &resp)
+
+	assert.ErrorContains(t, err, auth.ErrUnauthenticated.Error())
+}
+
+func TestGivenAPrincipalWithoutTheWriteScope_WhenCreateOrder_ThenShouldBeForbidden(t *testing.T) {
+	c := client.New(newTestServer(t, newOrdersResolver()))
+	reader := auth.Principal{Subject: "bob", Scopes: []string{usecase.ScopeOrdersRead}}
+
+	var resp map[string]interface{}
+	err := c.Post(`mutation { createOrder(input: {id: "a", Price: 10, Tax: 1}) { id } }`, &resp, as(reader))
+	assert.ErrorContains(t, err, auth.ErrForbidden.Error())
+
+	err = c.Post(`query { listOrders(first: 10) { totalCount } }`, &resp, as(reader))
+	assert.NoError(t, err)
+}
+
+func TestGivenCreatedOrders_WhenQueryOrderReport_ThenShouldReportThemPerPeriod(t *testing.T) {
+	c := client.New(newTestServer(t, newOrdersResolver()))
+	var created map[string]interface{}
+	assert.NoError(t, c.Post(`mutation { createOrder(input: {id: "a", Price: 10, Tax: 1}) { id } }`, &created, as(testPrincipal)))
+	assert.NoError(t, c.Post(`mutation { createOrder(input: {id: "b", Price: 20, Tax: 2}) { id } }`, &created, as(testPrincipal)))
+	analyst := auth.Principal{Subject: "dave", Scopes: []string{usecase.ScopeReportsRead}}
+	today := time.Now().UTC()
+
+	var resp struct {
+		OrderReport struct {
+			Granularity string
+			Periods     []struct{ Orders int }
+			Total       struct {
+				Orders            int
+				GrossSales        float64
+				AverageOrderValue float64
+			}
+		}
+	}
+	err := c.Post(`query($from: Time!, $to: Time!) { orderReport(from: $from, to: $to, granularity: WEEK) { granularity periods { orders } total { orders grossSales averageOrderValue } } }`,
+		&resp, as(analyst),
+		client.Var("from", today.Format(time.RFC3339)), client.Var("to", today.Add(time.Second).Format(time.RFC3339)))
+	assert.NoError(t, err)
+	assert.Equal(t, "WEEK", resp.OrderReport.Granularity)
+	assert.Len(t, resp.OrderReport.Periods, 1)
+	assert.Equal(t, 2, resp.OrderReport.Total.Orders)
+	assert.Equal(t, 33.0, resp.OrderReport.Total.GrossSales)
+	assert.Equal(t, 16.5, resp.OrderReport.Total.AverageOrderValue)
+
+	err = c.Post(`query($from: Time!, $to: Time!) { orderReport(from: $from, to: $to) { granularity } }`,
+		&resp, as(testPrincipal),
+		client.Var("from", today.Format(time.RFC3339)), client.Var("to", today.Add(time.Second).Format(time.RFC3339)))
+	assert.ErrorContains(t, err, auth.ErrForbidden.Error())
+}
+
+func TestGivenASubscriptionWithAnInvalidToken_WhenConnecting_ThenShouldBeRejected(t *testing.T) {
+	broadcaster := events.NewBroadcaster()
+	c := client.New(newTestServer(t, &Resolver{OrderEvents: broadcaster}))
+
+	sub := c.WebsocketWithPayload(`subscription { orderCreated { id } }`, map[string]interface{}{"Authorization": "Bearer not-a-jwt"})
+	defer sub.Close()
+	var resp map[string]interface{}
+	assert.Error(t, sub.Next(&resp))
+
+	anonymous := c.Websocket(`subscription { orderCreated { id } }`)
+	defer anonymous.Close()
+	assert.ErrorContains(t, anonymous.Next(&resp), auth.ErrUnauthenticated.Error())
+	assert.Equal(t, 0, broadcaster.Subscribers())
+}
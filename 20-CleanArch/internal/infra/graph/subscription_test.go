@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func statusChanged(newEvent func() *event.OrderStatusChanged, id, status string) *event.OrderStatusChanged {
+	ev := newEvent()
+	ev.SetPayload(event.OrderStatusChangedPayload{ID: id, PreviousStatus: "pending", Status: status})
+	return ev
+}
+
+func TestGivenAnOrderStatusChangedSubscription_WhenOrdersChange_ThenShouldOnlyReceiveThatOrder(t *testing.T) {
+	broadcaster := events.NewBroadcaster()
+	c := client.New(newTestServer(t, &Resolver{OrderEvents: broadcaster}))
+
+	sub := c.WebsocketWithPayload(`subscription { orderStatusChanged(id: "a") { id status previousStatus } }`, authorization(t, testPrincipal))
+	defer sub.Close()
+	assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	assert.NoError(t, broadcaster.Handle(context.Background(), statusChanged(event.NewOrderPaid, "b", "paid")))
+	assert.NoError(t, broadcaster.Handle(context.Background(), statusChanged(event.NewOrderCancelled, "a", "cancelled")))
+
+	var resp struct {
+		OrderStatusChanged struct {
+			ID             string
+			Status         string
+			PreviousStatus string
+		}
+	}
+	assert.NoError(t, sub.Next(&resp))
+	assert.Equal(t, "a", resp.OrderStatusChanged.ID)
+	assert.Equal(t, "cancelled", resp.OrderStatusChanged.Status)
+	assert.Equal(t, "pending", resp.OrderStatusChanged.PreviousStatus)
+}
+
+func TestGivenAnOrderCreatedSubscription_WhenTheClientLeaves_ThenShouldUnsubscribe(t *testing.T) {
+	broadcaster := events.NewBroadcaster()
+	c := client.New(newTestServer(t, &Resolver{OrderEvents: broadcaster}))
+
+	sub := c.WebsocketWithPayload(`subscription { orderCreated { id FinalPrice } }`, authorization(t, testPrincipal))
+	assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 1 }, time.Second, time.Millisecond)
+	orderCreated := event.NewOrderCreated()
+	orderCreated.SetPayload(event.OrderCreatedPayload{ID: "a", Price: 10, Tax: 2, FinalPrice: 12})
+	assert.NoError(t, broadcaster.Handle(context.Background(), orderCreated))
+
+	var resp struct {
+		OrderCreated struct {
+			ID         string
+			FinalPrice float64
+		}
+	}
+	assert.NoError(t, sub.Next(&resp))
+	assert.Equal(t, 12.0, resp.OrderCreated.FinalPrice)
+
+	assert.NoError(t, sub.Close())
+	assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 0 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/telemetry"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/graph"
+
+// Tracer is a gqlgen extension that runs each query and mutation in a span,
+// with a child span per field that has a resolver, and records the
+// operations' RED metrics.
+type Tracer struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+func (Tracer) ExtensionName() string {
+	return "OpenTelemetry"
+}
+
+func (Tracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse leaves subscriptions untraced: each of their responses
+// waits for the next event, so its duration would measure the event rate.
+func (Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+	if oc.Operation == nil || oc.Operation.Operation == ast.Subscription {
+		return next(ctx)
+	}
+	name := oc.OperationName
+	if name == "" {
+		name = oc.Operation.Name
+	}
+	operation := string(oc.Operation.Operation)
+	if name != "" {
+		operation += " " + name
+	}
+
+	start := time.Now()
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, operation, trace.WithAttributes(
+		attribute.String("graphql.operation.type", string(oc.Operation.Operation)),
+		attribute.String("graphql.operation.name", name),
+	))
+	defer span.End()
+
+	response := next(ctx)
+	var err error
+	if response != nil && len(response.Errors) > 0 {
+		err = response.Errors
+		span.SetStatus(codes.Error, err.Error())
+	}
+	telemetry.RecordRED(ctx, "graphql", operation, start, err)
+	return response
+}
+
+func (Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
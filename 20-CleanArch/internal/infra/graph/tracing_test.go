@@ -0,0 +1,30 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGivenATracer_WhenAMutationRuns_ThenShouldTraceTheOperationAndItsResolvers(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	c := client.New(newTestServer(t, newOrdersResolver()))
+
+	var resp map[string]interface{}
+	err := c.Post(`mutation Create { createOrder(input: {id: "a", Price: 10, Tax: 1}) { id } }`, &resp, as(testPrincipal))
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	resolver, operation := spans[0], spans[1]
+	assert.Equal(t, "Mutation.createOrder", resolver.Name())
+	assert.Equal(t, "mutation Create", operation.Name())
+	assert.Equal(t, operation.SpanContext().SpanID(), resolver.Parent().SpanID())
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/pb"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/service"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// OpenAPI is the OpenAPI 2.0 document generated from order.proto alongside
+// the gateway.
+//
+//go:embed order.swagger.json
+var OpenAPI []byte
+
+// headerMetadata maps the HTTP headers the hand-written REST handlers read
+// to the gRPC metadata OrderService reads.
+var headerMetadata = map[string]string{
+	"Idempotency-Key":  service.IdempotencyKeyMetadata,
+	"X-Correlation-Id": service.CorrelationIDMetadata,
+}
+
+// NewHandler serves the REST mapping of the google.api.http annotations in
+// order.proto by calling OrderService over conn. JSON uses the proto field
+// names, like the hand-written handlers, and always includes every field.
+func NewHandler(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+		runtime.WithIncomingHeaderMatcher(func(header string) (string, bool) {
+			if key, ok := headerMetadata[header]; ok {
+				return key, true
+			}
+			return runtime.DefaultHeaderMatcher(header)
+		}),
+		runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+			if key == service.IdempotentReplayedMetadata {
+				return "Idempotent-Replayed", true
+			}
+			return runtime.MetadataHeaderPrefix + key, true
+		}),
+	)
+	if err := pb.RegisterOrderServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// ServeOpenAPI writes the OpenAPI document.
+func ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
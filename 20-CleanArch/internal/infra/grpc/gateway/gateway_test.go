@@ -0,0 +1,217 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/grpcserver"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/pb"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/service"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestGateway returns the gateway in front of an authenticating gRPC
+// server with the transport settings of config. Requests without an
+// Authorization header are sent as alice, who holds every order scope.
+func newTestGateway(t *testing.T, config grpcserver.Config) http.Handler {
+	orderRepository := database.NewMemoryOrderRepository()
+	orderReportRepository := database.NewMemoryOrderReportRepository()
+	eventDispatcher := events.NewEventDispatcher()
+	report := handler.NewOrderReportHandler(usecase.NewProjectOrderReportUseCase(orderReportRepository))
+	assert.NoError(t, eventDispatcher.Register("OrderCreated", report))
+	assert.NoError(t, eventDispatcher.Register("OrderPaid", report))
+	authConfig := auth.Config{HMACSecret: "secret"}
+	verifier, err := auth.NewVerifier(authConfig)
+	assert.NoError(t, err)
+	token, err := auth.IssueHMACToken(authConfig, auth.Principal{Subject: "alice", Roles: []string{usecase.RoleAdmin}}, time.Hour)
+	assert.NoError(t, err)
+	config.Verifier = verifier
+	server, err := grpcserver.NewGRPCServer(config)
+	assert.NoError(t, err)
+	pb.RegisterOrderServiceServer(server, service.NewOrderService(
+		usecase.NewCreateOrderUseCase(orderRepository, database.NewMemoryIdempotencyRepository(time.Hour), eventDispatcher, nil, nil),
+		usecase.NewListOrdersUseCase(orderRepository),
+		usecase.NewStreamOrdersUseCase(orderRepository, events.NewBroadcaster()),
+		usecase.NewUpdateOrderUseCase(orderRepository, eventDispatcher, nil, nil),
+		usecase.NewPayOrderUseCase(orderRepository, eventDispatcher),
+		usecase.NewCancelOrderUseCase(orderRepository, eventDispatcher),
+		usecase.NewRefundOrderUseCase(orderRepository, eventDispatcher),
+		usecase.NewGetOrderReportUseCase(orderReportRepository),
+	))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(lis)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	conn, err := server.DialLocal(context.Background())
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	gateway, err := NewHandler(context.Background(), conn)
+	assert.NoError(t, err)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		gateway.ServeHTTP(w, r)
+	})
+}
+
+func serve(handler http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGivenTheGateway_WhenCreateWithAnIdempotencyKey_ThenShouldAnswerLikeTheRESTHandler(t *testing.T) {
+	handler := newTestGateway(t, grpcserver.Config{})
+	body := `{"id":"a","price":100.5,"tax":0.5}`
+
+	first := serve(handler, http.MethodPost, "/v1/orders", body, "Idempotency-Key", "key-1")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "application/json", first.Header().Get("Content-Type"))
+	var order map[string]interface{}
+	assert.NoError(t, json.Unmarshal(first.Body.Bytes(), &order))
+	assert.Equal(t, 101.0, order["final_price"])
+	assert.Equal(t, "pending", order["status"])
+	assert.Equal(t, "alice", order["created_by"])
+
+	retry := serve(handler, http.MethodPost, "/v1/orders", body, "Idempotency-Key", "key-1")
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+
+	conflict := serve(handler, http.MethodPost, "/v1/orders", body)
+	assert.Equal(t, http.StatusConflict, conflict.Code)
+}
+
+func TestGivenAServerWithMutualTLS_WhenCallingTheGateway_ThenShouldReachItInProcess(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCertificate(t)
+	handler := newTestGateway(t, grpcserver.Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile})
+
+	created := serve(handler, http.MethodPost, "/v1/orders", `{"id":"a","price":10,"tax":1}`)
+	assert.Equal(t, http.StatusOK, created.Code, created.Body.String())
+	listed := serve(handler, http.MethodGet, "/v1/orders?limit=10", "")
+	assert.Equal(t, http.StatusOK, listed.Code, listed.Body.String())
+	assert.Contains(t, listed.Body.String(), `"id":"a"`)
+}
+
+// writeSelfSignedCertificate writes a certificate for localhost and its key
+// to a temporary directory and returns their paths.
+func writeSelfSignedCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestGivenTheGateway_WhenPayAndListOrders_ThenShouldRouteByTheProtoAnnotations(t *testing.T) {
+	handler := newTestGateway(t, grpcserver.Config{})
+	serve(handler, http.MethodPost, "/v1/orders", `{"id":"a","price":10,"tax":1}`)
+	serve(handler, http.MethodPost, "/v1/orders", `{"id":"b","price":20,"tax":2}`)
+
+	paid := serve(handler, http.MethodPost, "/v1/orders/a:pay", "")
+	assert.Equal(t, http.StatusOK, paid.Code)
+	assert.Contains(t, paid.Body.String(), `"status":"paid"`)
+	assert.Equal(t, http.StatusNotFound, serve(handler, http.MethodPost, "/v1/orders/missing:pay", "").Code)
+
+	list := serve(handler, http.MethodGet, "/v1/orders?limit=10&filter.status=paid", "")
+	assert.Equal(t, http.StatusOK, list.Code)
+	var response struct {
+		Orders     []map[string]interface{} `json:"orders"`
+		TotalCount string                   `json:"total_count"`
+	}
+	assert.NoError(t, json.Unmarshal(list.Body.Bytes(), &response))
+	assert.Equal(t, "1", response.TotalCount)
+	assert.Equal(t, "a", response.Orders[0]["id"])
+}
+
+func TestGivenTheGateway_WhenGetTheOrderReport_ThenShouldTakeTheRangeFromTheQuery(t *testing.T) {
+	handler := newTestGateway(t, grpcserver.Config{})
+	serve(handler, http.MethodPost, "/v1/orders", `{"id":"a","price":10,"tax":1}`)
+	serve(handler, http.MethodPost, "/v1/orders", `{"id":"b","price":20,"tax":2}`)
+	serve(handler, http.MethodPost, "/v1/orders/a:pay", "")
+
+	today := time.Now().UTC()
+	from := today.AddDate(0, 0, -1).Format(time.RFC3339)
+	to := today.AddDate(0, 0, 1).Format(time.RFC3339)
+	rec := serve(handler, http.MethodGet, "/v1/reports/orders?from="+from+"&to="+to+"&granularity=month", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report struct {
+		Granularity string `json:"granularity"`
+		Total       struct {
+			Orders      string  `json:"orders"`
+			GrossSales  float64 `json:"gross_sales"`
+			OrdersPaid  string  `json:"orders_paid"`
+			PaidRevenue float64 `json:"paid_revenue"`
+		} `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, "month", report.Granularity)
+	assert.Equal(t, "2", report.Total.Orders)
+	assert.Equal(t, 33.0, report.Total.GrossSales)
+	assert.Equal(t, "1", report.Total.OrdersPaid)
+	assert.Equal(t, 11.0, report.Total.PaidRevenue)
+
+	assert.Equal(t, http.StatusBadRequest, serve(handler, http.MethodGet, "/v1/reports/orders?to="+to, "").Code)
+}
+
+func TestGivenAnInvalidToken_WhenCallingTheGateway_ThenShouldAnswerUnauthorized(t *testing.T) {
+	handler := newTestGateway(t, grpcserver.Config{})
+
+	rec := serve(handler, http.MethodGet, "/v1/orders?limit=10", "", "Authorization", "Bearer not-a-jwt")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestGivenTheEmbeddedOpenAPIDocument_ThenShouldDescribeEveryGatewayRoute(t *testing.T) {
+	var document struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	assert.NoError(t, json.Unmarshal(OpenAPI, &document))
+	assert.Contains(t, document.Paths["/v1/orders"], "post")
+	assert.Contains(t, document.Paths["/v1/orders"], "get")
+	assert.Contains(t, document.Paths["/v1/orders/{id}"], "patch")
+	assert.Contains(t, document.Paths, "/v1/orders/{id}:pay")
+	assert.Contains(t, document.Paths, "/v1/orders:stream")
+	assert.Contains(t, document.Paths["/v1/reports/orders"], "get")
+}
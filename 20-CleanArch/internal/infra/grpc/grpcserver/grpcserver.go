@@ -0,0 +1,195 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/interceptor"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var errIncompleteTLSConfig = errors.New("grpc TLS needs both a certificate and a key file")
+
+const localBufferSize = 1 << 20
+
+// Config holds the transport settings of the gRPC server. TLS is enabled by
+// CertFile and KeyFile; ClientCAFile additionally requires clients to
+// present a certificate signed by that CA (mTLS). With a Verifier every
+// call but health checks and reflection needs a bearer token.
+type Config struct {
+	Port           string
+	CertFile       string
+	KeyFile        string
+	ClientCAFile   string
+	DefaultTimeout time.Duration
+	Verifier       auth.VerifierInterface
+}
+
+// publicMethodPrefixes are served without authentication.
+var publicMethodPrefixes = []string{"/grpc.health.v1.Health/", "/grpc.reflection."}
+
+// GRPCServer wraps grpc.Server with the standard health service, the
+// tracing, recovery, logging, metrics and deadline interceptors, and
+// reflection. Services are registered through RegisterService so that
+// in-process clients reach them too.
+type GRPCServer struct {
+	Server *grpc.Server
+	Health *health.Server
+	Port   string
+	// localServer serves local, an in-memory listener for in-process
+	// clients such as the REST gateway. It runs the same interceptors as
+	// Server but no transport credentials, so those clients need neither a
+	// network port nor certificates.
+	localServer *grpc.Server
+	local       *bufconn.Listener
+}
+
+func NewGRPCServer(config Config) (*GRPCServer, error) {
+	metrics := interceptor.NewMetrics("grpc_server")
+	// Tracing comes first so the span is in the context the logs and
+	// metrics of the call are recorded with.
+	unary := []grpc.UnaryServerInterceptor{otelgrpc.UnaryServerInterceptor(), interceptor.UnaryLogging(), metrics.Unary()}
+	stream := []grpc.StreamServerInterceptor{otelgrpc.StreamServerInterceptor(), interceptor.StreamLogging(), metrics.Stream()}
+	if config.Verifier != nil {
+		unary = append(unary, interceptor.UnaryAuth(config.Verifier, publicMethodPrefixes...))
+		stream = append(stream, interceptor.StreamAuth(config.Verifier, publicMethodPrefixes...))
+	}
+	// Recovery comes last so the other interceptors see the Internal error
+	// a panic is turned into.
+	unary = append(unary, interceptor.UnaryDeadline(config.DefaultTimeout), interceptor.UnaryRecovery())
+	stream = append(stream, interceptor.StreamRecovery())
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	creds, err := transportCredentials(config)
+	if err != nil {
+		return nil, err
+	}
+	localServer := grpc.NewServer(opts...)
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	server := grpc.NewServer(opts...)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	healthpb.RegisterHealthServer(localServer, healthServer)
+	reflection.Register(server)
+	return &GRPCServer{
+		Server:      server,
+		Health:      healthServer,
+		Port:        config.Port,
+		localServer: localServer,
+		local:       bufconn.Listen(localBufferSize),
+	}, nil
+}
+
+// RegisterService registers a service on both the network and the local
+// listener, which makes GRPCServer a grpc.ServiceRegistrar.
+func (s *GRPCServer) RegisterService(desc *grpc.ServiceDesc, impl interface{}) {
+	s.Server.RegisterService(desc, impl)
+	s.localServer.RegisterService(desc, impl)
+}
+
+// transportCredentials returns nil when TLS is not configured.
+func transportCredentials(config Config) (credentials.TransportCredentials, error) {
+	if config.CertFile == "" && config.KeyFile == "" {
+		if config.ClientCAFile != "" {
+			return nil, errIncompleteTLSConfig
+		}
+		return nil, nil
+	}
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errIncompleteTLSConfig
+	}
+	certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// Start listens on Port and serves until Shutdown. Every registered service is
+// reported as SERVING once the listener is up.
+func (s *GRPCServer) Start() error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", s.Port))
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+func (s *GRPCServer) Serve(lis net.Listener) error {
+	for service := range s.Server.GetServiceInfo() {
+		s.Health.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+	s.Health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	go s.localServer.Serve(s.local)
+	return s.Server.Serve(lis)
+}
+
+// DialLocal connects to the server in process. The connection works once
+// Serve has been called; calls carry the trace of their context.
+func (s *GRPCServer) DialLocal(ctx context.Context) (*grpc.ClientConn, error) {
+	return grpc.DialContext(ctx, "passthrough:///local",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.local.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+	)
+}
+
+// Shutdown reports NOT_SERVING so health checks drain traffic, then lets
+// the running calls finish. Calls still running when ctx is done, such as
+// followed streams, are cut off and ctx's error is returned.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	s.Health.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		s.localServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.Server.Stop()
+		s.localServer.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,214 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// panickingHealth stands in for an application service whose handler
+// panics or waits for its deadline.
+type panickingHealth struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (panickingHealth) Check(ctx context.Context, in *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if in.Service == "panic" {
+		panic("boom")
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func startServer(t *testing.T, config Config, register func(grpc.ServiceRegistrar)) (*GRPCServer, string) {
+	server, err := NewGRPCServer(config)
+	assert.NoError(t, err)
+	if register != nil {
+		register(server)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(lis)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+	return server, lis.Addr().String()
+}
+
+func dial(t *testing.T, address string, creds credentials.TransportCredentials) *grpc.ClientConn {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(creds))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGivenAStartedServer_WhenHealthCheck_ThenShouldBeServingUntilStopped(t *testing.T) {
+	server, address := startServer(t, Config{}, nil)
+	client := healthpb.NewHealthClient(dial(t, address, insecure.NewCredentials()))
+
+	response, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+
+	server.Health.Shutdown()
+	response, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "grpc.health.v1.Health"})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, response.Status)
+}
+
+func TestGivenAPanickingHandler_WhenCalled_ThenShouldReturnInternalAndKeepServing(t *testing.T) {
+	_, address := startServer(t, Config{DefaultTimeout: 50 * time.Millisecond}, func(s grpc.ServiceRegistrar) {
+		// Registered under a different name so it does not clash with the
+		// real health service.
+		desc := healthpb.Health_ServiceDesc
+		desc.ServiceName = "test.Panicking"
+		s.RegisterService(&desc, panickingHealth{})
+	})
+	conn := dial(t, address, insecure.NewCredentials())
+
+	err := conn.Invoke(context.Background(), "/test.Panicking/Check", &healthpb.HealthCheckRequest{Service: "panic"}, &healthpb.HealthCheckResponse{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	start := time.Now()
+	err = conn.Invoke(context.Background(), "/test.Panicking/Check", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// principalDesc describes a service that answers SERVING to callers with
+// a principal in their context.
+var principalDesc = grpc.ServiceDesc{
+	ServiceName: "test.Principal",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Check",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(healthpb.HealthCheckRequest)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				if _, ok := auth.PrincipalFromContext(ctx); !ok {
+					return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+				}
+				return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Principal/Check"}, handler)
+		},
+	}},
+}
+
+func TestGivenAVerifier_WhenCalledWithoutAToken_ThenShouldRejectAllButHealthChecks(t *testing.T) {
+	authConfig := auth.Config{HMACSecret: "secret"}
+	verifier, err := auth.NewVerifier(authConfig)
+	assert.NoError(t, err)
+	_, address := startServer(t, Config{Verifier: verifier}, func(s grpc.ServiceRegistrar) {
+		s.RegisterService(&principalDesc, struct{}{})
+	})
+	conn := dial(t, address, insecure.NewCredentials())
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	err = conn.Invoke(context.Background(), "/test.Principal/Check", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	token, err := auth.IssueHMACToken(authConfig, auth.Principal{Subject: "alice"}, time.Minute)
+	assert.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	response := &healthpb.HealthCheckResponse{}
+	assert.NoError(t, conn.Invoke(ctx, "/test.Principal/Check", &healthpb.HealthCheckRequest{}, response))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+}
+
+func TestGivenMutualTLS_WhenAClientHasNoCertificate_ThenShouldRejectIt(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCertificate(t, nil, nil, true)
+	serverCert, serverKey := newCertificate(t, ca, caKey, false)
+	clientCert, clientKey := newCertificate(t, ca, caKey, false)
+	config := Config{
+		CertFile:     writePEM(t, dir, "server.crt", "CERTIFICATE", serverCert.Raw),
+		KeyFile:      writeKey(t, dir, "server.key", serverKey),
+		ClientCAFile: writePEM(t, dir, "ca.crt", "CERTIFICATE", ca.Raw),
+	}
+	_, address := startServer(t, config, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	anonymous := healthpb.NewHealthClient(dial(t, address, credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "localhost"})))
+	_, err := anonymous.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	keyPair := tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}
+	authenticated := healthpb.NewHealthClient(dial(t, address, credentials.NewTLS(&tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{keyPair},
+	})))
+	response, err := authenticated.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+}
+
+func TestGivenOnlyACertificate_WhenNewGRPCServer_ThenShouldReturnAnError(t *testing.T) {
+	_, err := NewGRPCServer(Config{CertFile: "server.crt"})
+	assert.ErrorIs(t, err, errIncompleteTLSConfig)
+}
+
+// newCertificate issues a certificate for localhost signed by parent, or a
+// self-signed CA when parent is nil.
+func newCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return certificate, key
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func writeKey(t *testing.T, dir, name string, key *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return writePEM(t, dir, name, "EC PRIVATE KEY", der)
+}
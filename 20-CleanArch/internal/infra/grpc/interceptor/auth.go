@@ -0,0 +1,75 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryAuth rejects calls without a valid bearer token in the
+// authorization metadata with Unauthenticated and hands the token's
+// principal to the handler in its context. Methods whose full name starts
+// with one of publicPrefixes, such as the health service, are let through.
+func UnaryAuth(verifier auth.VerifierInterface, publicPrefixes ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isPublic(info.FullMethod, publicPrefixes) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, verifier)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamAuth(verifier auth.VerifierInterface, publicPrefixes ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod, publicPrefixes) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), verifier)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, verifier auth.VerifierInterface) (context.Context, error) {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	ctx, err := auth.Authenticate(ctx, verifier, authorization)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return ctx, nil
+}
+
+func isPublic(fullMethod string, publicPrefixes []string) bool {
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticatedStream is a ServerStream whose context carries the principal.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
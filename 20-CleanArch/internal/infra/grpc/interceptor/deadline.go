@@ -0,0 +1,28 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryDeadline bounds unary calls to timeout: a call without a deadline
+// gets one, and a longer one is shortened. Streams are left alone since
+// StreamOrders may follow new orders for as long as the client wants.
+func UnaryDeadline(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		resp, err := handler(ctx, req)
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return resp, err
+	}
+}
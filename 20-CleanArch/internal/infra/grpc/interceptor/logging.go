@@ -0,0 +1,40 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryLogging logs every call with its status code and duration, and
+// failed calls at warning level.
+func UnaryLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func StreamLogging() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	st := status.Convert(err)
+	attrs := []interface{}{"method", method, "code", st.Code().String(), "duration", time.Since(start)}
+	if err != nil {
+		slog.WarnContext(ctx, "grpc call failed", append(attrs, "error", st.Message())...)
+		return
+	}
+	slog.InfoContext(ctx, "grpc call", attrs...)
+}
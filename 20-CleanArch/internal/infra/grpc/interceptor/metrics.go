@@ -0,0 +1,60 @@
+package interceptor
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/telemetry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics counts calls per method and status code and sums their latency.
+// The maps are published through expvar, so they show up under
+// /debug/vars on any server that mounts http.DefaultServeMux; the calls
+// are also recorded as OpenTelemetry RED metrics.
+type Metrics struct {
+	Calls   *expvar.Map
+	Latency *expvar.Map
+}
+
+// NewMetrics publishes the counters under name. expvar names are global, so
+// a second Metrics with the same name reuses the first one's maps.
+func NewMetrics(name string) *Metrics {
+	return &Metrics{
+		Calls:   publishedMap(name + "_calls"),
+		Latency: publishedMap(name + "_latency_seconds"),
+	}
+}
+
+func publishedMap(name string) *expvar.Map {
+	if existing, ok := expvar.Get(name).(*expvar.Map); ok {
+		return existing
+	}
+	return expvar.NewMap(name)
+}
+
+func (m *Metrics) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observe(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func (m *Metrics) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observe(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (m *Metrics) observe(ctx context.Context, method string, start time.Time, err error) {
+	m.Calls.Add(method+" "+status.Code(err).String(), 1)
+	m.Latency.AddFloat(method, time.Since(start).Seconds())
+	telemetry.RecordRED(ctx, "grpc", method, start, err)
+}
@@ -0,0 +1,34 @@
+package interceptor
+
+import (
+	"context"
+	"runtime/debug"
+
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRecovery turns a panicking handler into an Internal error instead of
+// a crashed server.
+func UnaryRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer recoverTo(ctx, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverTo(ss.Context(), info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+func recoverTo(ctx context.Context, method string, err *error) {
+	if r := recover(); r != nil {
+		slog.ErrorContext(ctx, "grpc handler panicked", "method", method, "panic", r, "stack", string(debug.Stack()))
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
@@ -24,6 +24,13 @@ const _ = grpc.SupportPackageIsVersion7
 type OrderServiceClient interface {
 	CreateOrder(ctx context.Context, in *CreateOrderRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error)
 	ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error)
+	UpdateOrder(ctx context.Context, in *UpdateOrderRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error)
+	PayOrder(ctx context.Context, in *ChangeOrderStatusRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error)
+	CancelOrder(ctx context.Context, in *ChangeOrderStatusRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error)
+	RefundOrder(ctx context.Context, in *ChangeOrderStatusRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error)
+	StreamOrders(ctx context.Context, in *StreamOrdersRequest, opts ...grpc.CallOption) (OrderService_StreamOrdersClient, error)
+	CreateOrdersBatch(ctx context.Context, opts ...grpc.CallOption) (OrderService_CreateOrdersBatchClient, error)
+	GetOrderReport(ctx context.Context, in *GetOrderReportRequest, opts ...grpc.CallOption) (*OrderReport, error)
 }
 
 type orderServiceClient struct {
@@ -52,12 +59,127 @@ func (c *orderServiceClient) ListOrders(ctx context.Context, in *ListOrdersReque
 	return out, nil
 }
 
+func (c *orderServiceClient) UpdateOrder(ctx context.Context, in *UpdateOrderRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error) {
+	out := new(CreateOrderResponse)
+	err := c.cc.Invoke(ctx, "/pb.OrderService/UpdateOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) PayOrder(ctx context.Context, in *ChangeOrderStatusRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error) {
+	out := new(CreateOrderResponse)
+	err := c.cc.Invoke(ctx, "/pb.OrderService/PayOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CancelOrder(ctx context.Context, in *ChangeOrderStatusRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error) {
+	out := new(CreateOrderResponse)
+	err := c.cc.Invoke(ctx, "/pb.OrderService/CancelOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) RefundOrder(ctx context.Context, in *ChangeOrderStatusRequest, opts ...grpc.CallOption) (*CreateOrderResponse, error) {
+	out := new(CreateOrderResponse)
+	err := c.cc.Invoke(ctx, "/pb.OrderService/RefundOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) StreamOrders(ctx context.Context, in *StreamOrdersRequest, opts ...grpc.CallOption) (OrderService_StreamOrdersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderService_ServiceDesc.Streams[0], "/pb.OrderService/StreamOrders", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderServiceStreamOrdersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type OrderService_StreamOrdersClient interface {
+	Recv() (*CreateOrderResponse, error)
+	grpc.ClientStream
+}
+
+type orderServiceStreamOrdersClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderServiceStreamOrdersClient) Recv() (*CreateOrderResponse, error) {
+	m := new(CreateOrderResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *orderServiceClient) CreateOrdersBatch(ctx context.Context, opts ...grpc.CallOption) (OrderService_CreateOrdersBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &OrderService_ServiceDesc.Streams[1], "/pb.OrderService/CreateOrdersBatch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orderServiceCreateOrdersBatchClient{stream}
+	return x, nil
+}
+
+type OrderService_CreateOrdersBatchClient interface {
+	Send(*CreateOrdersBatchRequest) error
+	Recv() (*CreateOrderResult, error)
+	grpc.ClientStream
+}
+
+type orderServiceCreateOrdersBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *orderServiceCreateOrdersBatchClient) Send(m *CreateOrdersBatchRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *orderServiceCreateOrdersBatchClient) Recv() (*CreateOrderResult, error) {
+	m := new(CreateOrderResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *orderServiceClient) GetOrderReport(ctx context.Context, in *GetOrderReportRequest, opts ...grpc.CallOption) (*OrderReport, error) {
+	out := new(OrderReport)
+	err := c.cc.Invoke(ctx, "/pb.OrderService/GetOrderReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrderServiceServer is the server API for OrderService service.
 // All implementations must embed UnimplementedOrderServiceServer
 // for forward compatibility
 type OrderServiceServer interface {
 	CreateOrder(context.Context, *CreateOrderRequest) (*CreateOrderResponse, error)
 	ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error)
+	UpdateOrder(context.Context, *UpdateOrderRequest) (*CreateOrderResponse, error)
+	PayOrder(context.Context, *ChangeOrderStatusRequest) (*CreateOrderResponse, error)
+	CancelOrder(context.Context, *ChangeOrderStatusRequest) (*CreateOrderResponse, error)
+	RefundOrder(context.Context, *ChangeOrderStatusRequest) (*CreateOrderResponse, error)
+	StreamOrders(*StreamOrdersRequest, OrderService_StreamOrdersServer) error
+	CreateOrdersBatch(OrderService_CreateOrdersBatchServer) error
+	GetOrderReport(context.Context, *GetOrderReportRequest) (*OrderReport, error)
 	mustEmbedUnimplementedOrderServiceServer()
 }
 
@@ -71,6 +193,27 @@ func (UnimplementedOrderServiceServer) CreateOrder(context.Context, *CreateOrder
 func (UnimplementedOrderServiceServer) ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListOrders not implemented")
 }
+func (UnimplementedOrderServiceServer) UpdateOrder(context.Context, *UpdateOrderRequest) (*CreateOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) PayOrder(context.Context, *ChangeOrderStatusRequest) (*CreateOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PayOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) CancelOrder(context.Context, *ChangeOrderStatusRequest) (*CreateOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) RefundOrder(context.Context, *ChangeOrderStatusRequest) (*CreateOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefundOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) StreamOrders(*StreamOrdersRequest, OrderService_StreamOrdersServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamOrders not implemented")
+}
+func (UnimplementedOrderServiceServer) CreateOrdersBatch(OrderService_CreateOrdersBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method CreateOrdersBatch not implemented")
+}
+func (UnimplementedOrderServiceServer) GetOrderReport(context.Context, *GetOrderReportRequest) (*OrderReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderReport not implemented")
+}
 func (UnimplementedOrderServiceServer) mustEmbedUnimplementedOrderServiceServer() {}
 
 // UnsafeOrderServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +263,143 @@ func _OrderService_ListOrders_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OrderService_UpdateOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).UpdateOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.OrderService/UpdateOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).UpdateOrder(ctx, req.(*UpdateOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_PayOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeOrderStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).PayOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.OrderService/PayOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).PayOrder(ctx, req.(*ChangeOrderStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeOrderStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.OrderService/CancelOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CancelOrder(ctx, req.(*ChangeOrderStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_RefundOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeOrderStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).RefundOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.OrderService/RefundOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).RefundOrder(ctx, req.(*ChangeOrderStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_StreamOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamOrdersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrderServiceServer).StreamOrders(m, &orderServiceStreamOrdersServer{stream})
+}
+
+type OrderService_StreamOrdersServer interface {
+	Send(*CreateOrderResponse) error
+	grpc.ServerStream
+}
+
+type orderServiceStreamOrdersServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderServiceStreamOrdersServer) Send(m *CreateOrderResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _OrderService_CreateOrdersBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OrderServiceServer).CreateOrdersBatch(&orderServiceCreateOrdersBatchServer{stream})
+}
+
+type OrderService_CreateOrdersBatchServer interface {
+	Send(*CreateOrderResult) error
+	Recv() (*CreateOrdersBatchRequest, error)
+	grpc.ServerStream
+}
+
+type orderServiceCreateOrdersBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *orderServiceCreateOrdersBatchServer) Send(m *CreateOrderResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *orderServiceCreateOrdersBatchServer) Recv() (*CreateOrdersBatchRequest, error) {
+	m := new(CreateOrdersBatchRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _OrderService_GetOrderReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetOrderReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.OrderService/GetOrderReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetOrderReport(ctx, req.(*GetOrderReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // OrderService_ServiceDesc is the grpc.ServiceDesc for OrderService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,7 +415,39 @@ var OrderService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListOrders",
 			Handler:    _OrderService_ListOrders_Handler,
 		},
+		{
+			MethodName: "UpdateOrder",
+			Handler:    _OrderService_UpdateOrder_Handler,
+		},
+		{
+			MethodName: "PayOrder",
+			Handler:    _OrderService_PayOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _OrderService_CancelOrder_Handler,
+		},
+		{
+			MethodName: "RefundOrder",
+			Handler:    _OrderService_RefundOrder_Handler,
+		},
+		{
+			MethodName: "GetOrderReport",
+			Handler:    _OrderService_GetOrderReport_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamOrders",
+			Handler:       _OrderService_StreamOrders_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "CreateOrdersBatch",
+			Handler:       _OrderService_CreateOrdersBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "protofiles/order.proto",
 }
@@ -2,66 +2,345 @@ package service
 
 import (
 	"context"
+	"errors"
+	"io"
 
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/pb"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	IdempotencyKeyMetadata     = "idempotency-key"
+	IdempotentReplayedMetadata = "idempotent-replayed"
+	CorrelationIDMetadata      = "x-correlation-id"
 )
 
 type OrderService struct {
 	pb.UnimplementedOrderServiceServer
-	CreateOrderUseCase usecase.CreateOrderUseCase
-	ListOrdersUseCase  usecase.ListOrdersUseCase
+	CreateOrderUseCase  *usecase.CreateOrderUseCase
+	ListOrdersUseCase   *usecase.ListOrdersUseCase
+	StreamOrdersUseCase *usecase.StreamOrdersUseCase
+	UpdateOrderUseCase  *usecase.UpdateOrderUseCase
+	PayOrderUseCase     *usecase.PayOrderUseCase
+	CancelOrderUseCase  *usecase.CancelOrderUseCase
+	RefundOrderUseCase  *usecase.RefundOrderUseCase
+	// GetOrderReportUseCase serves the reports kept from the order events.
+	GetOrderReportUseCase *usecase.GetOrderReportUseCase
 }
 
-func NewOrderService(createOrderUseCase usecase.CreateOrderUseCase, listOrdersService usecase.ListOrdersUseCase) *OrderService {
+func NewOrderService(
+	createOrderUseCase *usecase.CreateOrderUseCase,
+	listOrdersService *usecase.ListOrdersUseCase,
+	streamOrdersUseCase *usecase.StreamOrdersUseCase,
+	updateOrderUseCase *usecase.UpdateOrderUseCase,
+	payOrderUseCase *usecase.PayOrderUseCase,
+	cancelOrderUseCase *usecase.CancelOrderUseCase,
+	refundOrderUseCase *usecase.RefundOrderUseCase,
+	getOrderReportUseCase *usecase.GetOrderReportUseCase,
+) *OrderService {
 	return &OrderService{
-		CreateOrderUseCase: createOrderUseCase,
-		ListOrdersUseCase:  listOrdersService,
+		CreateOrderUseCase:    createOrderUseCase,
+		ListOrdersUseCase:     listOrdersService,
+		StreamOrdersUseCase:   streamOrdersUseCase,
+		UpdateOrderUseCase:    updateOrderUseCase,
+		PayOrderUseCase:       payOrderUseCase,
+		CancelOrderUseCase:    cancelOrderUseCase,
+		RefundOrderUseCase:    refundOrderUseCase,
+		GetOrderReportUseCase: getOrderReportUseCase,
 	}
 }
 
 func (s *OrderService) CreateOrder(ctx context.Context, in *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
-	dto := usecase.OrderInputDTO{
+	dto := newOrderInputDTO(in)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if keys := md.Get(IdempotencyKeyMetadata); len(keys) > 0 {
+			dto.IdempotencyKey = keys[0]
+		}
+	}
+	output, err := s.CreateOrderUseCase.Execute(withCorrelationID(ctx), dto)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	if output.Replayed {
+		grpc.SetHeader(ctx, metadata.Pairs(IdempotentReplayedMetadata, "true"))
+	}
+	return newCreateOrderResponse(output), nil
+}
+
+func (s *OrderService) ListOrders(ctx context.Context, in *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	dto := newListOrdersInputDTO(in.Filter, in.SortBy, in.SortDirection)
+	dto.Limit = int(in.Limit)
+	dto.After = in.After
+	output, err := s.ListOrdersUseCase.Execute(ctx, dto)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	response := &pb.ListOrdersResponse{
+		PageInfo: &pb.PageInfo{
+			HasNextPage:     output.PageInfo.HasNextPage,
+			HasPreviousPage: output.PageInfo.HasPreviousPage,
+			StartCursor:     output.PageInfo.StartCursor,
+			EndCursor:       output.PageInfo.EndCursor,
+		},
+		TotalCount: int64(output.TotalCount),
+	}
+	for _, edge := range output.Edges {
+		response.Orders = append(response.Orders, newCreateOrderResponse(edge.Node))
+		response.Cursors = append(response.Cursors, edge.Cursor)
+	}
+
+	return response, nil
+}
+
+func (s *OrderService) UpdateOrder(ctx context.Context, in *pb.UpdateOrderRequest) (*pb.CreateOrderResponse, error) {
+	output, err := s.UpdateOrderUseCase.Execute(withCorrelationID(ctx), usecase.UpdateOrderInputDTO{
 		ID:    in.Id,
 		Price: float64(in.Price),
 		Tax:   float64(in.Tax),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
 	}
-	output, err := s.CreateOrderUseCase.Execute(dto)
+	return newCreateOrderResponse(output), nil
+}
+
+func (s *OrderService) PayOrder(ctx context.Context, in *pb.ChangeOrderStatusRequest) (*pb.CreateOrderResponse, error) {
+	return changeStatus(ctx, in, s.PayOrderUseCase.Execute)
+}
+
+func (s *OrderService) CancelOrder(ctx context.Context, in *pb.ChangeOrderStatusRequest) (*pb.CreateOrderResponse, error) {
+	return changeStatus(ctx, in, s.CancelOrderUseCase.Execute)
+}
+
+func (s *OrderService) RefundOrder(ctx context.Context, in *pb.ChangeOrderStatusRequest) (*pb.CreateOrderResponse, error) {
+	return changeStatus(ctx, in, s.RefundOrderUseCase.Execute)
+}
+
+func changeStatus(
+	ctx context.Context,
+	in *pb.ChangeOrderStatusRequest,
+	execute func(context.Context, usecase.ChangeOrderStatusInputDTO) (usecase.OrderOutputDTO, error),
+) (*pb.CreateOrderResponse, error) {
+	output, err := execute(withCorrelationID(ctx), usecase.ChangeOrderStatusInputDTO{ID: in.Id, Reason: in.Reason})
 	if err != nil {
-		return nil, err
+		return nil, toStatusError(err)
 	}
-	return &pb.CreateOrderResponse{
-		Id:         output.ID,
-		Price:      float32(output.Price),
-		Tax:        float32(output.Tax),
-		FinalPrice: float32(output.FinalPrice),
-	}, nil
+	return newCreateOrderResponse(output), nil
 }
 
-func (s *OrderService) ListOrders(ctx context.Context, in *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
-	dto := usecase.ListOrdersInputDTO{
-		Page:  int(in.Page),
-		Limit: int(in.Limit),
+// StreamOrders sends every matching order, then with follow set the ones
+// created afterwards until the client cancels. Send blocks while the
+// client's flow control window is full, which in turn pauses the database
+// reads. A follower falling too far behind the new orders gets
+// ResourceExhausted instead of a stream with gaps.
+func (s *OrderService) StreamOrders(in *pb.StreamOrdersRequest, stream pb.OrderService_StreamOrdersServer) error {
+	dto := usecase.StreamOrdersInputDTO{
+		ListOrdersInputDTO: newListOrdersInputDTO(in.Filter, in.SortBy, in.SortDirection),
+		Follow:             in.Follow,
+	}
+	dto.Limit = int(in.PageSize)
+	err := s.StreamOrdersUseCase.Execute(stream.Context(), dto, func(order usecase.OrderOutputDTO) error {
+		return stream.Send(newCreateOrderResponse(order))
+	})
+	if ctxErr := stream.Context().Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
 	}
-	output, err := s.ListOrdersUseCase.Execute(dto)
 	if err != nil {
-		return nil, err
+		return toStatusError(err)
 	}
+	return nil
+}
 
-	var orderResponses []*pb.CreateOrderResponse
-	for _, order := range output.Orders {
-		orderResponses = append(orderResponses, &pb.CreateOrderResponse{
-			Id:         order.ID,
-			Price:      float32(order.Price),
-			Tax:        float32(order.Tax),
-			FinalPrice: float32(order.FinalPrice),
-		})
+// CreateOrdersBatch creates the streamed orders one at a time and answers
+// each with a result carrying its index, so a failed item does not end the
+// batch. The next request is only read once the previous result is sent, so
+// a client that stops reading results stops the import too.
+func (s *OrderService) CreateOrdersBatch(stream pb.OrderService_CreateOrdersBatchServer) error {
+	ctx := withCorrelationID(stream.Context())
+	for index := int64(0); ; index++ {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result := &pb.CreateOrderResult{Index: index}
+		if in.Order == nil {
+			result.Code = uint32(codes.InvalidArgument)
+			result.Message = "missing order"
+		} else {
+			dto := newOrderInputDTO(in.Order)
+			dto.IdempotencyKey = in.IdempotencyKey
+			output, err := s.CreateOrderUseCase.Execute(ctx, dto)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return status.FromContextError(ctxErr).Err()
+			}
+			if err != nil {
+				st := status.Convert(toStatusError(err))
+				result.Code = uint32(st.Code())
+				result.Message = st.Message()
+			} else {
+				result.Order = newCreateOrderResponse(output)
+			}
+		}
+		if err := stream.Send(result); err != nil {
+			return err
+		}
 	}
+}
 
-	response := &pb.ListOrdersResponse{
-		Orders: orderResponses,
+func (s *OrderService) GetOrderReport(ctx context.Context, in *pb.GetOrderReportRequest) (*pb.OrderReport, error) {
+	dto := usecase.OrderReportInputDTO{Granularity: in.Granularity}
+	// Unset bounds stay zero, which the use case rejects, rather than
+	// becoming the Unix epoch.
+	if in.From != nil {
+		dto.From = in.From.AsTime()
+	}
+	if in.To != nil {
+		dto.To = in.To.AsTime()
+	}
+	output, err := s.GetOrderReportUseCase.Execute(ctx, dto)
+	if err != nil {
+		return nil, toStatusError(err)
 	}
+	report := &pb.OrderReport{
+		From:        timestamppb.New(output.From),
+		To:          timestamppb.New(output.To),
+		Granularity: output.Granularity,
+		Total:       newOrderReportPeriod(output.Total),
+	}
+	for _, period := range output.Periods {
+		report.Periods = append(report.Periods, newOrderReportPeriod(period))
+	}
+	return report, nil
+}
 
-	return response, nil
+// withCorrelationID carries the caller's correlation ID, if any, to the
+// events the call raises.
+func withCorrelationID(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(CorrelationIDMetadata); len(ids) > 0 {
+			return events.WithCorrelationID(ctx, ids[0])
+		}
+	}
+	return ctx
+}
 
+func newOrderInputDTO(in *pb.CreateOrderRequest) usecase.OrderInputDTO {
+	return usecase.OrderInputDTO{
+		ID:         in.Id,
+		Price:      float64(in.Price),
+		Tax:        float64(in.Tax),
+		Category:   in.Category,
+		Region:     in.Region,
+		CouponCode: in.CouponCode,
+	}
+}
+
+func newListOrdersInputDTO(filter *pb.OrderFilter, sortBy, sortDirection string) usecase.ListOrdersInputDTO {
+	dto := usecase.ListOrdersInputDTO{
+		SortBy:        sortBy,
+		SortDirection: sortDirection,
+	}
+	if filter != nil {
+		dto.Status = filter.Status
+		dto.MinPrice = filter.MinPrice
+		dto.MaxPrice = filter.MaxPrice
+		if filter.CreatedFrom != nil {
+			createdFrom := filter.CreatedFrom.AsTime()
+			dto.CreatedFrom = &createdFrom
+		}
+		if filter.CreatedTo != nil {
+			createdTo := filter.CreatedTo.AsTime()
+			dto.CreatedTo = &createdTo
+		}
+	}
+	return dto
+}
+
+// toStatusError maps use case errors to gRPC status codes.
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, entity.ErrIdempotencyKeyReused), errors.Is(err, entity.ErrTaxMismatch), errors.Is(err, entity.ErrInvalidReport),
+		errors.Is(err, entity.ErrInvalidListOrders), errors.Is(err, entity.ErrInvalidOrderID), errors.Is(err, entity.ErrInvalidOrderPrice),
+		errors.Is(err, entity.ErrInvalidOrderTax):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, entity.ErrCouponNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, entity.ErrCouponInactive), errors.Is(err, entity.ErrCouponExhausted), errors.Is(err, entity.ErrCouponLimitReached):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, entity.ErrIdempotencyKeyInProgress):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, entity.ErrOrderAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, entity.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, entity.ErrInvalidStatusTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, entity.ErrOrderModified):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, events.ErrSubscriberBehind):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, auth.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return err
+}
+
+func newCreateOrderResponse(order usecase.OrderOutputDTO) *pb.CreateOrderResponse {
+	response := &pb.CreateOrderResponse{
+		Id:         order.ID,
+		Price:      float32(order.Price),
+		Tax:        float32(order.Tax),
+		FinalPrice: float32(order.FinalPrice),
+		Status:     order.Status,
+		CreatedAt:  timestamppb.New(order.CreatedAt),
+		CreatedBy:  order.CreatedBy,
+		Category:   order.Category,
+		Region:     order.Region,
+		CouponCode: order.CouponCode,
+		Discount:   order.Discount,
+	}
+	for _, line := range order.TaxLines {
+		response.TaxLines = append(response.TaxLines, &pb.TaxLine{
+			Name:     line.Name,
+			Rate:     line.Rate,
+			Compound: line.Compound,
+			Amount:   line.Amount,
+		})
+	}
+	return response
+}
+
+func newOrderReportPeriod(period usecase.OrderReportPeriodDTO) *pb.OrderReportPeriod {
+	return &pb.OrderReportPeriod{
+		Start:             timestamppb.New(period.Start),
+		End:               timestamppb.New(period.End),
+		Orders:            int64(period.Orders),
+		GrossSales:        period.GrossSales,
+		NetSales:          period.NetSales,
+		TaxBilled:         period.TaxBilled,
+		Discounts:         period.Discounts,
+		AverageOrderValue: period.AverageOrderValue,
+		OrdersPaid:        int64(period.OrdersPaid),
+		PaidRevenue:       period.PaidRevenue,
+		OrdersCancelled:   int64(period.OrdersCancelled),
+		OrdersRefunded:    int64(period.OrdersRefunded),
+		RefundedAmount:    period.RefundedAmount,
+		NetRevenue:        period.NetRevenue,
+		TaxCollected:      period.TaxCollected,
+	}
 }
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/interceptor"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/grpc/pb"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// testPrincipal holds every order scope.
+var testPrincipal = auth.Principal{
+	Subject: "alice",
+	Scopes:  []string{usecase.ScopeOrdersRead, usecase.ScopeOrdersWrite, usecase.ScopeOrdersRefund},
+}
+
+// bearerCredentials sends a bearer token with every call.
+type bearerCredentials string
+
+func (c bearerCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(c)}, nil
+}
+
+func (bearerCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+type testServer struct {
+	client      pb.OrderServiceClient
+	createOrder *usecase.CreateOrderUseCase
+	orderEvents *events.Broadcaster
+}
+
+func newTestServer(t *testing.T) *testServer {
+	return newTestServerAs(t, testPrincipal)
+}
+
+// newTestServerAs returns a server whose client calls as principal.
+func newTestServerAs(t *testing.T, principal auth.Principal) *testServer {
+	orderRepository := database.NewMemoryOrderRepository()
+	orderReportRepository := database.NewMemoryOrderReportRepository()
+	orderEvents := events.NewBroadcaster()
+	eventDispatcher := events.NewEventDispatcher()
+	assert.NoError(t, eventDispatcher.Register("OrderCreated", orderEvents))
+	assert.NoError(t, eventDispatcher.Register("OrderCreated", handler.NewOrderReportHandler(usecase.NewProjectOrderReportUseCase(orderReportRepository))))
+	createOrder := usecase.NewCreateOrderUseCase(orderRepository, database.NewMemoryIdempotencyRepository(time.Hour), eventDispatcher, nil, nil)
+	service := NewOrderService(
+		createOrder,
+		usecase.NewListOrdersUseCase(orderRepository),
+		usecase.NewStreamOrdersUseCase(orderRepository, orderEvents),
+		usecase.NewUpdateOrderUseCase(orderRepository, eventDispatcher, nil, nil),
+		usecase.NewPayOrderUseCase(orderRepository, eventDispatcher),
+		usecase.NewCancelOrderUseCase(orderRepository, eventDispatcher),
+		usecase.NewRefundOrderUseCase(orderRepository, eventDispatcher),
+		usecase.NewGetOrderReportUseCase(orderReportRepository),
+	)
+
+	authConfig := auth.Config{HMACSecret: "secret"}
+	verifier, err := auth.NewVerifier(authConfig)
+	assert.NoError(t, err)
+	token, err := auth.IssueHMACToken(authConfig, principal, time.Hour)
+	assert.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.UnaryAuth(verifier)),
+		grpc.StreamInterceptor(interceptor.StreamAuth(verifier)),
+	)
+	pb.RegisterOrderServiceServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(bearerCredentials(token)),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &testServer{client: pb.NewOrderServiceClient(conn), createOrder: createOrder, orderEvents: orderEvents}
+}
+
+func (s *testServer) createOrders(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		_, err := s.createOrder.Execute(auth.WithPrincipal(context.Background(), testPrincipal), usecase.OrderInputDTO{ID: fmt.Sprintf("order-%02d", i), Price: 10, Tax: 1})
+		assert.NoError(t, err)
+	}
+}
+
+func TestGivenMoreOrdersThanAPage_WhenStreamOrders_ThenShouldSendEveryOrderOnce(t *testing.T) {
+	server := newTestServer(t)
+	server.createOrders(t, 7)
+
+	stream, err := server.client.StreamOrders(context.Background(), &pb.StreamOrdersRequest{PageSize: 3})
+	assert.NoError(t, err)
+	var ids []string
+	for {
+		order, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		ids = append(ids, order.Id)
+	}
+	assert.Equal(t, []string{"order-00", "order-01", "order-02", "order-03", "order-04", "order-05", "order-06"}, ids)
+}
+
+func TestGivenFollow_WhenAnOrderIsCreatedAfterTheExport_ThenShouldStreamItUntilCancelled(t *testing.T) {
+	server := newTestServer(t)
+	server.createOrders(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := server.client.StreamOrders(ctx, &pb.StreamOrdersRequest{Follow: true})
+	assert.NoError(t, err)
+	order, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "order-00", order.Id)
+
+	assert.Eventually(t, func() bool { return server.orderEvents.Subscribers() == 1 }, time.Second, time.Millisecond)
+	_, err = server.createOrder.Execute(auth.WithPrincipal(context.Background(), testPrincipal), usecase.OrderInputDTO{ID: "live", Price: 20, Tax: 2})
+	assert.NoError(t, err)
+	order, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "live", order.Id)
+	assert.Equal(t, float32(22), order.FinalPrice)
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.Eventually(t, func() bool { return server.orderEvents.Subscribers() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestGivenABatchWithInvalidItems_WhenCreateOrdersBatch_ThenShouldAnswerEachItem(t *testing.T) {
+	server := newTestServer(t)
+
+	stream, err := server.client.CreateOrdersBatch(context.Background())
+	assert.NoError(t, err)
+	requests := []*pb.CreateOrdersBatchRequest{
+		{Order: &pb.CreateOrderRequest{Id: "a", Price: 10, Tax: 1}},
+		{Order: &pb.CreateOrderRequest{Id: "a", Price: 10, Tax: 1}},
+		{},
+		{Order: &pb.CreateOrderRequest{Id: "b", Price: 20, Tax: 2}},
+	}
+	var results []*pb.CreateOrderResult
+	for _, request := range requests {
+		assert.NoError(t, stream.Send(request))
+		result, err := stream.Recv()
+		assert.NoError(t, err)
+		results = append(results, result)
+	}
+	assert.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	for i, result := range results {
+		assert.Equal(t, int64(i), result.Index)
+	}
+	assert.Equal(t, "a", results[0].Order.GetId())
+	assert.Equal(t, "alice", results[0].Order.GetCreatedBy())
+	assert.Equal(t, uint32(codes.AlreadyExists), results[1].Code)
+	assert.Nil(t, results[1].Order)
+	assert.Equal(t, uint32(codes.InvalidArgument), results[2].Code)
+	assert.Equal(t, float32(22), results[3].Order.GetFinalPrice())
+}
+
+func TestGivenAReadOnlyPrincipal_WhenCreateOrder_ThenShouldReturnPermissionDenied(t *testing.T) {
+	server := newTestServerAs(t, auth.Principal{Subject: "bob", Scopes: []string{usecase.ScopeOrdersRead}})
+
+	_, err := server.client.CreateOrder(context.Background(), &pb.CreateOrderRequest{Id: "a", Price: 10, Tax: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = server.client.ListOrders(context.Background(), &pb.ListOrdersRequest{Limit: 10})
+	assert.NoError(t, err)
+}
+
+func TestGivenATaxEngine_WhenCreateOrder_ThenShouldReturnTheTaxBreakdown(t *testing.T) {
+	server := newTestServer(t)
+	engine, err := entity.NewTaxEngine([]entity.TaxRule{
+		{Name: "VAT", Rate: 10},
+		{Name: "Luxury", Category: "luxury", Rate: 5, Compound: true},
+	}, false)
+	assert.NoError(t, err)
+	server.createOrder.TaxEngine = engine
+
+	order, err := server.client.CreateOrder(context.Background(), &pb.CreateOrderRequest{Id: "a", Price: 100, Category: "luxury", Region: "north"})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(115.5), order.FinalPrice)
+	assert.Equal(t, "luxury", order.Category)
+	assert.Equal(t, "north", order.Region)
+	assert.Len(t, order.TaxLines, 2)
+	assert.Equal(t, "Luxury", order.TaxLines[1].Name)
+	assert.True(t, order.TaxLines[1].Compound)
+	assert.Equal(t, 5.5, order.TaxLines[1].Amount)
+
+	_, err = server.client.CreateOrder(context.Background(), &pb.CreateOrderRequest{Id: "b", Price: 100, Tax: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = server.client.CreateOrder(context.Background(), &pb.CreateOrderRequest{Id: "c", Price: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGivenRulesThatLevyNoTax_WhenCreateOrder_ThenShouldCreateAnUntaxedOrder(t *testing.T) {
+	server := newTestServer(t)
+	engine, err := entity.NewTaxEngine([]entity.TaxRule{{Name: "VAT", Category: "food", Rate: 10}}, false)
+	assert.NoError(t, err)
+	server.createOrder.TaxEngine = engine
+
+	order, err := server.client.CreateOrder(context.Background(), &pb.CreateOrderRequest{Id: "a", Price: 100, Category: "books"})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(0), order.Tax)
+	assert.Equal(t, float32(100), order.FinalPrice)
+	assert.Empty(t, order.TaxLines)
+}
+
+func TestGivenAnExhaustedCoupon_WhenCreateOrder_ThenShouldFailThePrecondition(t *testing.T) {
+	server := newTestServer(t)
+	coupons := database.NewMemoryCouponRepository()
+	coupon, err := entity.NewCoupon("ONCE", entity.DiscountPercentage, 10, time.Time{}, time.Time{}, 1, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, coupons.Save(context.Background(), coupon))
+	server.createOrder.CouponRepository = coupons
+
+	order, err := server.client.CreateOrder(context.Background(), &pb.CreateOrderRequest{Id: "a", Price: 100, Tax: 9, CouponCode: "once"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ONCE", order.CouponCode)
+	assert.Equal(t, 10.0, order.Discount)
+	assert.Equal(t, float32(99), order.FinalPrice)
+
+	_, err = server.client.CreateOrder(context.Background(), &pb.CreateOrderRequest{Id: "b", Price: 100, Tax: 9, CouponCode: "ONCE"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestGivenCreatedOrders_WhenGetOrderReport_ThenShouldReportThemPerPeriod(t *testing.T) {
+	server := newTestServerAs(t, auth.Principal{Subject: "carol", Roles: []string{usecase.RoleAdmin}})
+	server.createOrders(t, 3)
+	today := time.Now().UTC()
+
+	report, err := server.client.GetOrderReport(context.Background(), &pb.GetOrderReportRequest{
+		From: timestamppb.New(today.AddDate(0, 0, -2)),
+		To:   timestamppb.New(today),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "day", report.Granularity)
+	assert.Len(t, report.Periods, 3)
+	assert.Equal(t, int64(3), report.Periods[2].Orders)
+	assert.Equal(t, 33.0, report.Total.GrossSales)
+	assert.Equal(t, 11.0, report.Total.AverageOrderValue)
+
+	_, err = server.client.GetOrderReport(context.Background(), &pb.GetOrderReportRequest{To: timestamppb.New(today)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGivenAPrincipalWithoutTheReportsScope_WhenGetOrderReport_ThenShouldReturnPermissionDenied(t *testing.T) {
+	server := newTestServer(t)
+	today := time.Now().UTC()
+
+	_, err := server.client.GetOrderReport(context.Background(), &pb.GetOrderReportRequest{
+		From: timestamppb.New(today.AddDate(0, 0, -1)),
+		To:   timestamppb.New(today),
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGivenAPrincipalWithoutTheReadScope_WhenListOrStreamOrders_ThenShouldReturnPermissionDenied(t *testing.T) {
+	server := newTestServerAs(t, auth.Principal{Subject: "bob", Scopes: []string{usecase.ScopeOrdersWrite}})
+
+	_, err := server.client.ListOrders(context.Background(), &pb.ListOrdersRequest{Limit: 10})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	stream, err := server.client.StreamOrders(context.Background(), &pb.StreamOrdersRequest{PageSize: 10})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGivenAnInvalidListing_WhenListOrStreamOrders_ThenShouldReturnInvalidArgument(t *testing.T) {
+	server := newTestServer(t)
+
+	_, err := server.client.ListOrders(context.Background(), &pb.ListOrdersRequest{Limit: 10, After: "not-a-cursor"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	stream, err := server.client.StreamOrders(context.Background(), &pb.StreamOrdersRequest{PageSize: 10, SortBy: "color"})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,306 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	ErrNotConnected     = errors.New("rabbitmq is not connected")
+	ErrConnectionClosed = errors.New("rabbitmq connection is closed")
+	ErrPublishNacked    = errors.New("rabbitmq did not confirm the publish")
+)
+
+// TopologyFunc declares exchanges, queues and bindings. It runs on every
+// (re)connect, so it must be idempotent.
+type TopologyFunc func(ch *amqp.Channel) error
+
+// Health describes the connection state as of Since.
+type Health struct {
+	Connected bool
+	LastError error
+	Since     time.Time
+}
+
+// Connection keeps a RabbitMQ connection alive: when the broker closes it,
+// it redials with exponential backoff and re-declares the topology.
+// Publishing goes through a channel in confirm mode and waits for the
+// broker's ack.
+type Connection struct {
+	url            string
+	topology       []TopologyFunc
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	dial           func(url string) (*amqp.Connection, error)
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	publisher *publisher
+	health    Health
+	connected chan struct{}
+	closed    bool
+
+	publishMu sync.Mutex
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// publisher is the confirm-mode channel of one connection. published counts
+// the deliveries sent on it, which is how the broker numbers confirmations.
+type publisher struct {
+	channel   *amqp.Channel
+	confirms  chan amqp.Confirmation
+	published uint64
+}
+
+type ConnectionOption func(*Connection)
+
+func WithTopology(topology ...TopologyFunc) ConnectionOption {
+	return func(c *Connection) { c.topology = append(c.topology, topology...) }
+}
+
+// WithBackoff sets the delay before the first reconnect attempt and the cap
+// it doubles up to.
+func WithBackoff(initial, max time.Duration) ConnectionOption {
+	return func(c *Connection) {
+		c.initialBackoff = initial
+		c.maxBackoff = max
+	}
+}
+
+func NewConnection(url string, opts ...ConnectionOption) *Connection {
+	c := &Connection{
+		url:            url,
+		initialBackoff: 500 * time.Millisecond,
+		maxBackoff:     30 * time.Second,
+		dial:           amqp.Dial,
+		connected:      make(chan struct{}),
+		done:           make(chan struct{}),
+		health:         Health{LastError: ErrNotConnected, Since: time.Now()},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Connect dials the broker, retrying with backoff until it succeeds or ctx
+// is done, then keeps the connection alive in the background until Close.
+func (c *Connection) Connect(ctx context.Context) error {
+	if err := c.connectWithBackoff(ctx); err != nil {
+		return err
+	}
+	go c.watch()
+	return nil
+}
+
+func (c *Connection) connectWithBackoff(ctx context.Context) error {
+	backoff := c.initialBackoff
+	for {
+		select {
+		case <-c.done:
+			return ErrConnectionClosed
+		default:
+		}
+		err := c.connect()
+		if err == nil {
+			return nil
+		}
+		c.setHealth(false, err)
+		slog.Warn("connecting to RabbitMQ failed, retrying", "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.done:
+			return ErrConnectionClosed
+		}
+		if backoff *= 2; backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+func (c *Connection) connect() error {
+	conn, err := c.dial(c.url)
+	if err != nil {
+		return err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	for _, declare := range c.topology {
+		if err := declare(ch); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return err
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 16))
+	// A channel exception leaves the connection up but the publisher
+	// unusable; dropping the connection makes watch rebuild both.
+	channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		if err := <-channelClosed; err != nil {
+			conn.Close()
+		}
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return ErrConnectionClosed
+	}
+	c.conn = conn
+	c.publisher = &publisher{channel: ch, confirms: confirms}
+	c.health = Health{Connected: true, Since: time.Now()}
+	close(c.connected)
+	return nil
+}
+
+// watch reconnects whenever the broker drops the connection.
+func (c *Connection) watch() {
+	for {
+		c.mu.RLock()
+		conn := c.conn
+		c.mu.RUnlock()
+		closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+		select {
+		case <-c.done:
+			return
+		case amqpErr := <-closed:
+			var err error = ErrNotConnected
+			if amqpErr != nil {
+				err = amqpErr
+			}
+			c.mu.Lock()
+			c.connected = make(chan struct{})
+			c.health = Health{LastError: err, Since: time.Now()}
+			c.mu.Unlock()
+			slog.Warn("RabbitMQ connection lost", "error", err)
+		}
+
+		if err := c.connectWithBackoff(context.Background()); err != nil {
+			return
+		}
+		slog.Info("RabbitMQ connection re-established")
+	}
+}
+
+func (c *Connection) setHealth(connected bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.health.Connected != connected {
+		c.health.Since = time.Now()
+	}
+	c.health.Connected = connected
+	c.health.LastError = err
+}
+
+func (c *Connection) Health() Health {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.health
+}
+
+// Healthy returns nil while connected, so it can be used as a health check.
+func (c *Connection) Healthy() error {
+	health := c.Health()
+	if health.Connected {
+		return nil
+	}
+	if health.LastError != nil && !errors.Is(health.LastError, ErrNotConnected) {
+		return fmt.Errorf("%w: %v", ErrNotConnected, health.LastError)
+	}
+	return ErrNotConnected
+}
+
+// Channel opens a new channel once the connection is up, waiting for a
+// reconnect in progress.
+func (c *Connection) Channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		c.mu.RLock()
+		connected, conn, up := c.connected, c.conn, c.health.Connected
+		c.mu.RUnlock()
+		if up {
+			ch, err := conn.Channel()
+			if err == nil {
+				return ch, nil
+			}
+		}
+		select {
+		case <-connected:
+		case <-time.After(c.initialBackoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrConnectionClosed
+		}
+	}
+}
+
+// Publish sends msg and waits for the broker to confirm it. It fails fast
+// with ErrNotConnected while reconnecting instead of blocking the caller.
+func (c *Connection) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	c.mu.RLock()
+	publisher, up := c.publisher, c.health.Connected
+	c.mu.RUnlock()
+	if !up {
+		return c.Healthy()
+	}
+
+	if err := publisher.channel.Publish(exchange, key, false, false, msg); err != nil {
+		return err
+	}
+	publisher.published++
+	// Confirmations left over from publishes whose caller gave up waiting
+	// are skipped.
+	for {
+		select {
+		case confirm, ok := <-publisher.confirms:
+			if !ok {
+				return ErrNotConnected
+			}
+			if confirm.DeliveryTag < publisher.published {
+				continue
+			}
+			if !confirm.Ack {
+				return ErrPublishNacked
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Connection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.closed = true
+		c.health = Health{LastError: ErrConnectionClosed, Since: time.Now()}
+		if c.conn != nil {
+			err = c.conn.Close()
+		}
+	})
+	return err
+}
@@ -0,0 +1,40 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"golang.org/x/exp/slog"
+)
+
+// OrderCreatedTopic is the topic OrderCreated events are published on.
+const OrderCreatedTopic = "OrderCreated"
+
+// NewInvoiceOnOrderCreatedHandler issues an invoice for each OrderCreated
+// message. Bodies that do not decode, and payload schema versions newer than
+// this build understands, are poison messages. Messages published before
+// events carried CloudEvents attributes are still accepted.
+func NewInvoiceOnOrderCreatedHandler(generateInvoice *usecase.GenerateInvoiceUseCase) events.MessageHandlerFunc {
+	return func(ctx context.Context, msg events.Message) error {
+		if cloudEvent, err := events.CloudEventFromMessage(msg); err == nil {
+			if cloudEvent.SchemaVersion > event.OrderCreatedSchemaVersion {
+				return fmt.Errorf("%w: unsupported %s schema version %d", events.ErrPoisonMessage, cloudEvent.Type, cloudEvent.SchemaVersion)
+			}
+			ctx = events.WithCorrelationID(ctx, cloudEvent.CorrelationID)
+		}
+		var input usecase.InvoiceInputDTO
+		if err := json.Unmarshal(msg.Body, &input); err != nil {
+			return fmt.Errorf("%w: %v", events.ErrPoisonMessage, err)
+		}
+		output, err := generateInvoice.Execute(ctx, input)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "invoice issued", "invoice_id", output.ID, "order_id", output.OrderID)
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAnOrderCreatedEvent_WhenPublishedOnTheMemoryTransport_ThenShouldIssueAnInvoice(t *testing.T) {
+	transport, err := NewTransport(TransportConfig{Transport: TransportMemory})
+	assert.NoError(t, err)
+	defer transport.Close()
+	invoices := database.NewMemoryInvoiceRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Subscribe(ctx, transport.Subscriber, OrderCreatedTopic, NewInvoiceOnOrderCreatedHandler(usecase.NewGenerateInvoiceUseCase(invoices)))
+
+	orderCreated := event.NewOrderCreated()
+	orderCreated.SetPayload(event.OrderCreatedPayload{ID: "123", Price: 10, Tax: 2, FinalPrice: 12})
+	assert.Eventually(t, func() bool {
+		if err := handler.NewPublishEventHandler(transport.Publisher).Handle(context.Background(), orderCreated); err != nil {
+			return false
+		}
+		_, err := invoices.FindByOrderID(context.Background(), "123")
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+
+	invoice, err := invoices.FindByOrderID(context.Background(), "123")
+	assert.NoError(t, err)
+	assert.Equal(t, 12.0, invoice.Total)
+}
+
+func TestGivenAnUndecodableBody_WhenInvoiceHandler_ThenShouldReturnErrPoisonMessage(t *testing.T) {
+	handle := NewInvoiceOnOrderCreatedHandler(usecase.NewGenerateInvoiceUseCase(database.NewMemoryInvoiceRepository()))
+
+	assert.ErrorIs(t, handle(context.Background(), events.Message{Body: []byte("not json")}), events.ErrPoisonMessage)
+}
+
+func TestGivenANewerSchemaVersion_WhenInvoiceHandler_ThenShouldReturnErrPoisonMessage(t *testing.T) {
+	handle := NewInvoiceOnOrderCreatedHandler(usecase.NewGenerateInvoiceUseCase(database.NewMemoryInvoiceRepository()))
+	orderCreated := event.NewOrderCreated()
+	orderCreated.SetPayload(event.OrderCreatedPayload{ID: "123", Price: 10, Tax: 2})
+	orderCreated.SchemaVersion = event.OrderCreatedSchemaVersion + 1
+	cloudEvent, err := events.NewCloudEvent("/test", orderCreated)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, handle(context.Background(), cloudEvent.Message()), events.ErrPoisonMessage)
+}
+
+func TestGivenARedeliveredOrder_WhenInvoiceHandler_ThenShouldNotInvoiceTwice(t *testing.T) {
+	invoices := database.NewMemoryInvoiceRepository()
+	handle := NewInvoiceOnOrderCreatedHandler(usecase.NewGenerateInvoiceUseCase(invoices))
+	msg := events.Message{Body: []byte(`{"id":"123","price":10,"tax":2}`)}
+
+	assert.NoError(t, handle(context.Background(), msg))
+	first, _ := invoices.FindByOrderID(context.Background(), "123")
+	assert.NoError(t, handle(context.Background(), msg))
+	second, err := invoices.FindByOrderID(context.Background(), "123")
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+type flakySubscriber struct {
+	calls int32
+}
+
+func (s *flakySubscriber) Subscribe(ctx context.Context, topic string, handler events.MessageHandlerFunc) error {
+	if atomic.AddInt32(&s.calls, 1) == 1 {
+		return errors.New("connection lost")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (s *flakySubscriber) Close() error {
+	return nil
+}
+
+func TestGivenASubscriptionThatFails_WhenSubscribe_ThenShouldResubscribe(t *testing.T) {
+	subscriber := &flakySubscriber{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Subscribe(ctx, subscriber, OrderCreatedTopic, nil) }()
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&subscriber.calls) == 2 }, 2*time.Second, 5*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestGivenAnUnknownTransport_WhenNewTransport_ThenShouldReturnAnError(t *testing.T) {
+	_, err := NewTransport(TransportConfig{Transport: "carrier-pigeon"})
+	assert.EqualError(t, err, `unsupported event transport "carrier-pigeon"`)
+}
@@ -0,0 +1,170 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events/kafka"
+	natsbus "github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events/nats"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events/rabbitmq"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/telemetry"
+	"github.com/nats-io/nats.go"
+	"github.com/streadway/amqp"
+	"golang.org/x/exp/slog"
+)
+
+// Supported values for configs.EventTransport.
+const (
+	TransportRabbitMQ = "rabbitmq"
+	TransportNATS     = "nats"
+	TransportKafka    = "kafka"
+	TransportMemory   = "memory"
+)
+
+// OrderEventsExchange is the RabbitMQ exchange order events are routed on,
+// with the event name as routing key.
+const OrderEventsExchange = "amq.direct"
+
+var ErrNATSDisconnected = errors.New("nats is not connected")
+
+type TransportConfig struct {
+	Transport    string
+	RabbitMQURL  string
+	NATSURL      string
+	KafkaBrokers []string
+	// Group names what subscribers of the same service share: the RabbitMQ
+	// queue prefix, the NATS queue group or the Kafka consumer group.
+	Group    string
+	Prefetch int
+	// RetryPolicy spaces out the RabbitMQ redeliveries of a message its
+	// handler fails on and caps how many it gets before being dead-lettered.
+	RetryPolicy events.RetryPolicy
+	// Topics get their RabbitMQ queue declared on connect, so messages
+	// published before the first subscriber starts are kept.
+	Topics []string
+}
+
+// Transport is the publisher and subscriber for the configured broker.
+type Transport struct {
+	Publisher  events.PublisherInterface
+	Subscriber events.SubscriberInterface
+	// Healthy returns nil while the broker is reachable.
+	Healthy func() error
+	close   func() error
+}
+
+// NewTransport connects to the broker named by config.Transport. Brokers
+// that are down at startup are retried in the background. Messages are
+// traced: publishing injects the trace context into their headers and
+// handlers continue it.
+func NewTransport(config TransportConfig) (*Transport, error) {
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	transport.Publisher = telemetry.NewTracingPublisher(config.Transport, transport.Publisher)
+	transport.Subscriber = telemetry.NewTracingSubscriber(config.Transport, transport.Subscriber)
+	return transport, nil
+}
+
+func newTransport(config TransportConfig) (*Transport, error) {
+	switch config.Transport {
+	case TransportRabbitMQ:
+		conn := NewConnection(config.RabbitMQURL, WithTopology(func(ch *amqp.Channel) error {
+			for _, topic := range config.Topics {
+				if err := rabbitmq.DeclareQueue(ch, OrderEventsExchange, rabbitmq.QueueName(config.Group, topic), topic); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		go conn.Connect(context.Background())
+		return &Transport{
+			Publisher:  rabbitmq.NewPublisher(conn, OrderEventsExchange),
+			Subscriber: rabbitmq.NewSubscriber(conn, OrderEventsExchange, config.Group, config.Prefetch, config.RetryPolicy),
+			Healthy:    conn.Healthy,
+			close:      conn.Close,
+		}, nil
+	case TransportNATS:
+		conn, err := nats.Connect(config.NATSURL, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+		if err != nil {
+			return nil, err
+		}
+		return &Transport{
+			Publisher:  natsbus.NewPublisher(conn),
+			Subscriber: natsbus.NewSubscriber(conn, config.Group),
+			Healthy: func() error {
+				if !conn.IsConnected() {
+					return ErrNATSDisconnected
+				}
+				return nil
+			},
+			close: func() error {
+				conn.Close()
+				return nil
+			},
+		}, nil
+	case TransportKafka:
+		publisher := kafka.NewPublisher(config.KafkaBrokers)
+		return &Transport{
+			Publisher:  publisher,
+			Subscriber: kafka.NewSubscriber(config.KafkaBrokers, config.Group),
+			Healthy:    func() error { return dialAny(config.KafkaBrokers) },
+			close:      publisher.Close,
+		}, nil
+	case TransportMemory:
+		bus := events.NewMemoryBus()
+		return &Transport{
+			Publisher:  bus,
+			Subscriber: bus,
+			Healthy:    func() error { return nil },
+			close:      bus.Close,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported event transport %q", config.Transport)
+}
+
+func (t *Transport) Close() error {
+	return t.close()
+}
+
+func dialAny(addresses []string) error {
+	var errs []error
+	for _, address := range addresses {
+		conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Subscribe keeps handler subscribed to topic until ctx is done,
+// resubscribing with backoff whenever the subscription ends with an error,
+// such as a lost connection.
+func Subscribe(ctx context.Context, subscriber events.SubscriberInterface, topic string, handler events.MessageHandlerFunc) error {
+	backoff := 500 * time.Millisecond
+	for {
+		err := subscriber.Subscribe(ctx, topic, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil || errors.Is(err, events.ErrBusClosed) {
+			return err
+		}
+		slog.WarnContext(ctx, "subscription ended, retrying", "topic", topic, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+)
+
+// Authenticate is chi middleware that rejects requests without a valid
+// bearer token and passes the token's principal on in the request context.
+// Which scopes the principal needs is up to the use cases.
+func Authenticate(verifier auth.VerifierInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := auth.Authenticate(r.Context(), verifier, r.Header.Get("Authorization"))
+			if err != nil {
+				unauthorized(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AuthenticateIfPresent is Authenticate for endpoints that also serve
+// anonymous requests, such as GraphQL where directives decide per field:
+// requests without an Authorization header pass through unauthenticated.
+func AuthenticateIfPresent(verifier auth.VerifierInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticate := Authenticate(verifier)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			authenticate.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="orders"`)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+// WebCouponHandler serves the coupon administration endpoints.
+type WebCouponHandler struct {
+	CouponRepository entity.CouponRepositoryInterface
+}
+
+func NewWebCouponHandler(CouponRepository entity.CouponRepositoryInterface) *WebCouponHandler {
+	return &WebCouponHandler{
+		CouponRepository: CouponRepository,
+	}
+}
+
+func (h *WebCouponHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto usecase.CouponInputDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createCoupon := usecase.NewCreateCouponUseCase(h.CouponRepository)
+	output, err := createCoupon.Execute(r.Context(), dto)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, output)
+}
+
+func (h *WebCouponHandler) List(w http.ResponseWriter, r *http.Request) {
+	listCoupons := usecase.NewListCouponsUseCase(h.CouponRepository)
+	output, err := listCoupons.Execute(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// Get serves the coupon in the path, with the count of its redemptions.
+func (h *WebCouponHandler) Get(w http.ResponseWriter, r *http.Request) {
+	getCoupon := usecase.NewGetCouponUseCase(h.CouponRepository)
+	output, err := getCoupon.Execute(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func (h *WebCouponHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	deactivateCoupon := usecase.NewDeactivateCouponUseCase(h.CouponRepository)
+	output, err := deactivateCoupon.Execute(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func writeJSON(w http.ResponseWriter, status int, output interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(output); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCouponRouter() chi.Router {
+	handler := NewWebCouponHandler(database.NewMemoryCouponRepository())
+	router := chi.NewRouter()
+	router.Post("/coupon", handler.Create)
+	router.Get("/coupons", handler.List)
+	router.Get("/coupon/{code}", handler.Get)
+	router.Post("/coupon/{code}/deactivate", handler.Deactivate)
+	return router
+}
+
+func sendAs(router http.Handler, principal auth.Principal, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req.WithContext(auth.WithPrincipal(req.Context(), principal)))
+	return rec
+}
+
+func TestGivenACouponAdmin_WhenCreateListGetAndDeactivate_ThenShouldAdministerTheCoupon(t *testing.T) {
+	router := newTestCouponRouter()
+	admin := auth.Principal{Subject: "bob", Scopes: []string{usecase.ScopeCouponsAdmin}}
+
+	rec := sendAs(router, admin, http.MethodPost, "/coupon",
+		`{"code":"summer10","type":"percentage","value":10,"valid_until":"2030-01-01T00:00:00Z","max_redemptions":100}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var coupon usecase.CouponOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &coupon))
+	assert.Equal(t, "SUMMER10", coupon.Code)
+	assert.Nil(t, coupon.ValidFrom)
+	assert.NotNil(t, coupon.ValidUntil)
+	assert.True(t, coupon.Active)
+
+	rec = sendAs(router, admin, http.MethodPost, "/coupon", `{"code":"SUMMER10","type":"fixed","value":5}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = sendAs(router, admin, http.MethodPost, "/coupon", `{"code":"HALF","type":"percentage","value":150}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = sendAs(router, admin, http.MethodGet, "/coupons", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var coupons []usecase.CouponOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &coupons))
+	assert.Len(t, coupons, 1)
+
+	rec = sendAs(router, admin, http.MethodPost, "/coupon/summer10/deactivate", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = sendAs(router, admin, http.MethodGet, "/coupon/SUMMER10", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &coupon))
+	assert.False(t, coupon.Active)
+
+	rec = sendAs(router, admin, http.MethodGet, "/coupon/WINTER", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGivenAPrincipalWithoutTheAdminScope_WhenCreateCoupon_ThenShouldBeForbidden(t *testing.T) {
+	router := newTestCouponRouter()
+
+	rec := sendAs(router, testPrincipal, http.MethodPost, "/coupon", `{"code":"SUMMER10","type":"fixed","value":5}`)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HealthCheckFunc reports a dependency as healthy by returning nil.
+type HealthCheckFunc func() error
+
+type WebHealthHandler struct {
+	Checks map[string]HealthCheckFunc
+}
+
+func NewWebHealthHandler(checks map[string]HealthCheckFunc) *WebHealthHandler {
+	return &WebHealthHandler{
+		Checks: checks,
+	}
+}
+
+// ServeHTTP answers 200 when every check passes and 503 otherwise, with the
+// state of each check in the body.
+func (h *WebHealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	results := make(map[string]string, len(h.Checks))
+	for name, check := range h.Checks {
+		if err := check(); err != nil {
+			status = http.StatusServiceUnavailable
+			results[name] = err.Error()
+			continue
+		}
+		results[name] = "ok"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,33 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenHealthyChecks_WhenGetHealth_ThenShouldReturnOK(t *testing.T) {
+	handler := NewWebHealthHandler(map[string]HealthCheckFunc{
+		"rabbitmq": func() error { return nil },
+	})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"rabbitmq":"ok"}`, recorder.Body.String())
+}
+
+func TestGivenAFailingCheck_WhenGetHealth_ThenShouldReturnServiceUnavailable(t *testing.T) {
+	handler := NewWebHealthHandler(map[string]HealthCheckFunc{
+		"rabbitmq": func() error { return errors.New("rabbitmq is not connected") },
+		"database": func() error { return nil },
+	})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.JSONEq(t, `{"rabbitmq":"rabbitmq is not connected","database":"ok"}`, recorder.Body.String())
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/bulk"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+)
+
+// importSummary is the last line of an import response.
+type importSummary struct {
+	Summary usecase.ImportSummaryDTO `json:"summary"`
+	// Error is why the import stopped early, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// Import creates the orders of a CSV (text/csv) or NDJSON
+// (application/x-ndjson) body. It responds with an NDJSON line per row,
+// written as each batch completes, and a last line with the summary.
+//
+// The body is spooled to a temporary file first: HTTP/1.1 servers close the
+// request body once the response starts, so it cannot be read while the
+// results are streamed.
+func (h *WebOrderHandler) Import(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.FormatFromContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := os.CreateTemp("", "order-import-*")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(body.Name())
+	defer body.Close()
+	if _, err := io.Copy(body, r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rows, err := bulk.NewReader(format, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createOrder := usecase.NewCreateOrderUseCase(h.OrderRepository, h.IdempotencyRepository, h.EventDispatcher, h.TaxEngine, h.CouponRepository)
+	importOrders := usecase.NewImportOrdersUseCase(createOrder, h.ImportBatchSize)
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	started := false
+	summary, err := importOrders.Execute(requestContext(r), rows, func(result usecase.ImportRowResultDTO) error {
+		if !started {
+			w.Header().Set("Content-Type", bulk.FormatNDJSON.ContentType())
+			started = true
+		}
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", bulk.FormatNDJSON.ContentType())
+	last := importSummary{Summary: summary}
+	if err != nil {
+		last.Error = err.Error()
+	}
+	encoder.Encode(last)
+}
+
+// Export streams every order, oldest first, as CSV or NDJSON according to
+// the format query parameter, which defaults to NDJSON. The orders are read
+// a page at a time, so the export never holds more than one page.
+func (h *WebOrderHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	streamOrders := usecase.NewStreamOrdersUseCase(h.OrderRepository, nil)
+	writer := bulk.NewWriter(format, w)
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="orders.`+string(format)+`"`)
+		started = true
+	}
+	err = streamOrders.Execute(r.Context(), usecase.StreamOrdersInputDTO{
+		ListOrdersInputDTO: usecase.ListOrdersInputDTO{Limit: entity.MaxListOrdersLimit},
+	}, func(order usecase.OrderOutputDTO) error {
+		if !started {
+			start()
+		}
+		return writer.Write(order)
+	})
+	if err != nil {
+		if !started {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		// Abort the response, so the client sees a truncated transfer
+		// instead of a complete looking export.
+		panic(http.ErrAbortHandler)
+	}
+	if !started {
+		start()
+	}
+	writer.Flush()
+}
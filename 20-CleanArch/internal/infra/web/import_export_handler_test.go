@@ -0,0 +1,135 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenACSVImport_WhenImport_ThenShouldReportEveryRowAndCreateTheValidOnes(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	handler.ImportBatchSize = 2
+	body := "id,price,tax\n" +
+		"a,100,10\n" +
+		"b,-1,1\n" +
+		"c,50,5\n" +
+		"a,100,10\n" +
+		"d,ten,1\n"
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+	handler.Import(rec, authorized(req))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	scanner := bufio.NewScanner(rec.Body)
+	var results []usecase.ImportRowResultDTO
+	for i := 0; i < 5 && scanner.Scan(); i++ {
+		var result usecase.ImportRowResultDTO
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, result)
+	}
+	assert.Len(t, results, 5)
+	assert.Equal(t, usecase.ImportRowResultDTO{Line: 2, ID: "a", Status: usecase.ImportRowCreated}, results[0])
+	assert.Equal(t, usecase.ImportRowResultDTO{Line: 3, ID: "b", Status: usecase.ImportRowFailed, Error: "invalid price"}, results[1])
+	assert.Equal(t, usecase.ImportRowCreated, results[2].Status)
+	assert.Equal(t, "order already exists", results[3].Error)
+	assert.Equal(t, `invalid price "ten"`, results[4].Error)
+	assert.True(t, scanner.Scan())
+	assert.JSONEq(t, `{"summary":{"rows":5,"created":2,"failed":3}}`, scanner.Text())
+
+	orders, err := usecase.NewListOrdersUseCase(handler.OrderRepository).Execute(authorized(req).Context(), usecase.ListOrdersInputDTO{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, orders.TotalCount)
+}
+
+func TestGivenALargeImportOverHTTP1_WhenImport_ThenShouldReadTheWholeBody(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	handler.ImportBatchSize = 10
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.Import(w, authorized(r))
+	}))
+	defer server.Close()
+	const rows = 500
+	var body strings.Builder
+	body.WriteString("id,price,tax\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&body, "order-%d,100,10\n", i)
+	}
+	assert.Greater(t, body.Len(), 4096)
+
+	resp, err := http.Post(server.URL, "text/csv", strings.NewReader(body.String()))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	scanner := bufio.NewScanner(resp.Body)
+	var last string
+	for scanner.Scan() {
+		last = scanner.Text()
+	}
+	assert.NoError(t, scanner.Err())
+	assert.JSONEq(t, fmt.Sprintf(`{"summary":{"rows":%d,"created":%d,"failed":0}}`, rows, rows), last)
+}
+
+func TestGivenAnUnsupportedBody_WhenImport_ThenShouldBeRejectedUpFront(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/import", strings.NewReader(`[]`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.Import(rec, authorized(req))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/orders/import", strings.NewReader("id,amount\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	rec = httptest.NewRecorder()
+	handler.Import(rec, authorized(req))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/orders/import", strings.NewReader(`{"id":"a","price":1,"tax":1}`))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec = httptest.NewRecorder()
+	handler.Import(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestGivenOrders_WhenExport_ThenShouldStreamThemInTheRequestedFormat(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	// One row per batch creates the orders in the order of the rows.
+	handler.ImportBatchSize = 1
+	req := httptest.NewRequest(http.MethodPost, "/orders/import", strings.NewReader(
+		`{"id":"a","price":100,"tax":10}`+"\n"+`{"id":"b","price":50,"tax":5}`+"\n"))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	handler.Import(httptest.NewRecorder(), authorized(req))
+
+	rec := httptest.NewRecorder()
+	handler.Export(rec, authorized(httptest.NewRequest(http.MethodGet, "/orders/export?format=csv", nil)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[1], "a,100,10,110,pending,"))
+	assert.True(t, strings.HasPrefix(lines[2], "b,50,5,55,pending,"))
+
+	rec = httptest.NewRecorder()
+	handler.Export(rec, authorized(httptest.NewRequest(http.MethodGet, "/orders/export", nil)))
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	lines = strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	var order usecase.OrderOutputDTO
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &order))
+	assert.Equal(t, "a", order.ID)
+
+	rec = httptest.NewRecorder()
+	handler.Export(rec, authorized(httptest.NewRequest(http.MethodGet, "/orders/export?format=xml", nil)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -1,30 +1,52 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	CorrelationIDHeader      = "X-Correlation-ID"
 )
 
 type WebOrderHandler struct {
-	EventDispatcher   events.EventDispatcherInterface
-	OrderRepository   entity.OrderRepositoryInterface
-	OrderCreatedEvent events.EventInterface
+	EventDispatcher       events.EventDispatcherInterface
+	OrderRepository       entity.OrderRepositoryInterface
+	IdempotencyRepository entity.IdempotencyRepositoryInterface
+	TaxEngine             *entity.TaxEngine
+	CouponRepository      entity.CouponRepositoryInterface
+	ImportBatchSize       int
 }
 
 func NewWebOrderHandler(
 	EventDispatcher events.EventDispatcherInterface,
 	OrderRepository entity.OrderRepositoryInterface,
-	OrderCreatedEvent events.EventInterface,
+	IdempotencyRepository entity.IdempotencyRepositoryInterface,
+	TaxEngine *entity.TaxEngine,
+	CouponRepository entity.CouponRepositoryInterface,
+	ImportBatchSize int,
 ) *WebOrderHandler {
 	return &WebOrderHandler{
-		EventDispatcher:   EventDispatcher,
-		OrderRepository:   OrderRepository,
-		OrderCreatedEvent: OrderCreatedEvent,
+		EventDispatcher:       EventDispatcher,
+		OrderRepository:       OrderRepository,
+		IdempotencyRepository: IdempotencyRepository,
+		TaxEngine:             TaxEngine,
+		CouponRepository:      CouponRepository,
+		ImportBatchSize:       ImportBatchSize,
 	}
 }
 
@@ -36,12 +58,18 @@ func (h *WebOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createOrder := usecase.NewCreateOrderUseCase(h.OrderRepository, h.OrderCreatedEvent, h.EventDispatcher)
-	output, err := createOrder.Execute(dto)
+	dto.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
+
+	createOrder := usecase.NewCreateOrderUseCase(h.OrderRepository, h.IdempotencyRepository, h.EventDispatcher, h.TaxEngine, h.CouponRepository)
+	output, err := createOrder.Execute(requestContext(r), dto)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
+	if output.Replayed {
+		w.Header().Set(IdempotentReplayedHeader, "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(output)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -49,32 +77,82 @@ func (h *WebOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *WebOrderHandler) List(w http.ResponseWriter, r *http.Request) {
-	var dto usecase.ListOrdersInputDTO
+// Update changes the price, and the tax, of the pending order in the path.
+func (h *WebOrderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var dto usecase.UpdateOrderInputDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dto.ID = chi.URLParam(r, "id")
 
-	queryValues := r.URL.Query()
-	page := queryValues.Get("page")
-	limit := queryValues.Get("limit")
+	updateOrder := usecase.NewUpdateOrderUseCase(h.OrderRepository, h.EventDispatcher, h.TaxEngine, h.CouponRepository)
+	output, err := updateOrder.Execute(requestContext(r), dto)
+	writeOrder(w, output, err)
+}
 
-	pageNumber, err := strconv.Atoi(page)
-	if err != nil {
+func (h *WebOrderHandler) Pay(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, usecase.NewPayOrderUseCase(h.OrderRepository, h.EventDispatcher).Execute)
+}
+
+func (h *WebOrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, usecase.NewCancelOrderUseCase(h.OrderRepository, h.EventDispatcher).Execute)
+}
+
+func (h *WebOrderHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, usecase.NewRefundOrderUseCase(h.OrderRepository, h.EventDispatcher).Execute)
+}
+
+// changeStatus runs a status transition on the order in the path. The body,
+// an optional {"reason": "..."}, may be empty.
+func (h *WebOrderHandler) changeStatus(
+	w http.ResponseWriter,
+	r *http.Request,
+	execute func(context.Context, usecase.ChangeOrderStatusInputDTO) (usecase.OrderOutputDTO, error),
+) {
+	var dto usecase.ChangeOrderStatusInputDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil && !errors.Is(err, io.EOF) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	dto.ID = chi.URLParam(r, "id")
 
-	limitNumber, err := strconv.Atoi(limit)
+	output, err := execute(requestContext(r), dto)
+	writeOrder(w, output, err)
+}
+
+func writeOrder(w http.ResponseWriter, output usecase.OrderOutputDTO, err error) {
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(output); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
 
-	dto.Page = pageNumber
-	dto.Limit = limitNumber
+// requestContext carries the caller's correlation ID, if any, to the events
+// the request raises.
+func requestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if correlationID := r.Header.Get(CorrelationIDHeader); correlationID != "" {
+		ctx = events.WithCorrelationID(ctx, correlationID)
+	}
+	return ctx
+}
+
+func (h *WebOrderHandler) List(w http.ResponseWriter, r *http.Request) {
+	dto, err := parseListOrdersQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	listOrders := usecase.NewListOrdersUseCase(h.OrderRepository)
-	output, err := listOrders.Execute(dto)
+	output, err := listOrders.Execute(r.Context(), dto)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -85,3 +163,81 @@ func (h *WebOrderHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// errorStatus maps use case errors to HTTP status codes, defaulting to 500.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, entity.ErrIdempotencyKeyReused), errors.Is(err, entity.ErrTaxMismatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, entity.ErrInvalidOrderID), errors.Is(err, entity.ErrInvalidOrderPrice), errors.Is(err, entity.ErrInvalidOrderTax),
+		errors.Is(err, entity.ErrInvalidListOrders), errors.Is(err, entity.ErrInvalidCoupon), errors.Is(err, entity.ErrInvalidReport),
+		errors.Is(err, entity.ErrInvalidWebhook):
+		return http.StatusBadRequest
+	case errors.Is(err, entity.ErrCouponInactive), errors.Is(err, entity.ErrCouponExhausted), errors.Is(err, entity.ErrCouponLimitReached):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, entity.ErrIdempotencyKeyInProgress), errors.Is(err, entity.ErrOrderAlreadyExists), errors.Is(err, entity.ErrCouponAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, entity.ErrOrderNotFound), errors.Is(err, entity.ErrCouponNotFound),
+		errors.Is(err, entity.ErrWebhookNotFound), errors.Is(err, entity.ErrWebhookDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entity.ErrInvalidStatusTransition), errors.Is(err, entity.ErrOrderModified), errors.Is(err, entity.ErrWebhookDeliveryNotReplayable):
+		return http.StatusConflict
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// parseListOrdersQuery reads limit, after, status, min_price, max_price,
+// created_from, created_to (RFC 3339), sort_by and sort_direction.
+func parseListOrdersQuery(queryValues url.Values) (usecase.ListOrdersInputDTO, error) {
+	var dto usecase.ListOrdersInputDTO
+	var err error
+
+	dto.Limit, err = strconv.Atoi(queryValues.Get("limit"))
+	if err != nil {
+		return dto, err
+	}
+	dto.After = queryValues.Get("after")
+	dto.Status = queryValues.Get("status")
+	dto.SortBy = queryValues.Get("sort_by")
+	dto.SortDirection = queryValues.Get("sort_direction")
+
+	if dto.MinPrice, err = parseOptionalFloat(queryValues.Get("min_price")); err != nil {
+		return dto, err
+	}
+	if dto.MaxPrice, err = parseOptionalFloat(queryValues.Get("max_price")); err != nil {
+		return dto, err
+	}
+	if dto.CreatedFrom, err = parseOptionalTime(queryValues.Get("created_from")); err != nil {
+		return dto, err
+	}
+	if dto.CreatedTo, err = parseOptionalTime(queryValues.Get("created_to")); err != nil {
+		return dto, err
+	}
+	return dto, nil
+}
+
+func parseOptionalFloat(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,498 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database/migration"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	// sqlite3
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testAuthConfig signs the tokens of the tests; testPrincipal holds every
+// order scope.
+var (
+	testAuthConfig = auth.Config{HMACSecret: "secret"}
+	testPrincipal  = auth.Principal{
+		Subject: "alice",
+		Scopes:  []string{usecase.ScopeOrdersRead, usecase.ScopeOrdersWrite, usecase.ScopeOrdersRefund},
+	}
+)
+
+// authorized returns req as sent by testPrincipal, as if it had passed the
+// Authenticate middleware.
+func authorized(req *http.Request) *http.Request {
+	return req.WithContext(auth.WithPrincipal(req.Context(), testPrincipal))
+}
+
+func newTestHandler(t *testing.T) (*WebOrderHandler, *sql.DB) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migration.NewMigrator(db, database.DriverSQLite)
+	assert.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	assert.NoError(t, err)
+	idempotencyRepository, err := database.NewIdempotencyRepositoryForDriver(database.DriverSQLite, db, time.Hour)
+	assert.NoError(t, err)
+	return NewWebOrderHandler(events.NewEventDispatcher(), database.NewSQLiteOrderRepository(db), idempotencyRepository, nil, nil, 0), db
+}
+
+func TestGivenACancelledRequest_WhenCreate_ThenShouldNotPersistTheOrder(t *testing.T) {
+	handler, db := newTestHandler(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"id":"a","price":100.5,"tax":0.5}`)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	handler.Create(rec, authorized(req))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), context.Canceled.Error())
+	var count int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestGivenACancelledRequest_WhenList_ThenShouldAbortTheQuery(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders?limit=10", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	handler.List(rec, authorized(req))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), context.Canceled.Error())
+}
+
+func TestGivenARejectedListing_WhenList_ThenShouldMapTheErrorStatus(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	writer := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "bob", Scopes: []string{usecase.ScopeOrdersWrite}})
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"missing scope", httptest.NewRequest(http.MethodGet, "/orders?limit=10", nil).WithContext(writer), http.StatusForbidden},
+		{"bad cursor", authorized(httptest.NewRequest(http.MethodGet, "/orders?limit=10&after=nope", nil)), http.StatusBadRequest},
+		{"limit out of range", authorized(httptest.NewRequest(http.MethodGet, "/orders?limit=1000", nil)), http.StatusBadRequest},
+		{"unknown status", authorized(httptest.NewRequest(http.MethodGet, "/orders?limit=10&status=shipped", nil)), http.StatusBadRequest},
+		{"unknown sort", authorized(httptest.NewRequest(http.MethodGet, "/orders?limit=10&sort_by=color", nil)), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.List(rec, tt.req)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestGivenALiveRequest_WhenCreate_ThenShouldPersistTheOrder(t *testing.T) {
+	handler, db := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"id":"a","price":100.5,"tax":0.5}`))
+	rec := httptest.NewRecorder()
+	handler.Create(rec, authorized(req))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var count int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestGivenARetriedIdempotencyKey_WhenCreate_ThenShouldReplayTheFirstResponse(t *testing.T) {
+	handler, db := newTestHandler(t)
+	body := `{"id":"a","price":100.5,"tax":0.5}`
+
+	first := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	handler.Create(first, authorized(req))
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	retry := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	handler.Create(retry, authorized(req))
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	var count int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestGivenAnIdempotencyKeyReusedWithAnotherPayload_WhenCreate_ThenShouldReject(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"id":"a","price":100.5,"tax":0.5}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	handler.Create(httptest.NewRecorder(), authorized(req))
+
+	rec := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"id":"b","price":10,"tax":1}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	handler.Create(rec, authorized(req))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestGivenADuplicateOrderWithoutIdempotencyKey_WhenCreate_ThenShouldReturnConflict(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	body := `{"id":"a","price":100.5,"tax":0.5}`
+
+	handler.Create(httptest.NewRecorder(), authorized(httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))))
+	rec := httptest.NewRecorder()
+	handler.Create(rec, authorized(httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(body))))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+type recordingHandler struct {
+	mu     sync.Mutex
+	events []events.EventInterface
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, event events.EventInterface) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingHandler) names() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var names []string
+	for _, event := range h.events {
+		names = append(names, event.GetName())
+	}
+	return names
+}
+
+func newTestRouter(t *testing.T, recorder *recordingHandler) http.Handler {
+	handler, _ := newTestHandler(t)
+	for _, name := range []string{"OrderCreated", "OrderUpdated", "OrderPaid", "OrderCancelled", "OrderRefunded"} {
+		assert.NoError(t, handler.EventDispatcher.Register(name, recorder))
+	}
+	verifier, err := auth.NewVerifier(testAuthConfig)
+	assert.NoError(t, err)
+	router := chi.NewRouter()
+	router.Use(Authenticate(verifier))
+	router.Post("/order", handler.Create)
+	router.Patch("/order/{id}", handler.Update)
+	router.Post("/order/{id}/pay", handler.Pay)
+	router.Post("/order/{id}/cancel", handler.Cancel)
+	router.Post("/order/{id}/refund", handler.Refund)
+	return router
+}
+
+func serve(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	return serveAs(router, testPrincipal, method, target, body)
+}
+
+// serveAs sends the request with a bearer token issued to principal.
+func serveAs(router http.Handler, principal auth.Principal, method, target, body string) *httptest.ResponseRecorder {
+	token, _ := auth.IssueHMACToken(testAuthConfig, principal, time.Minute)
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGivenAnOrder_WhenUpdatePayAndRefund_ThenShouldDispatchAnEventPerTransition(t *testing.T) {
+	recorder := &recordingHandler{}
+	router := newTestRouter(t, recorder)
+
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodPost, "/order", `{"id":"a","price":10,"tax":1}`).Code)
+	rec := serve(router, http.MethodPatch, "/order/a", `{"price":20,"tax":2}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"final_price":22`)
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodPost, "/order/a/pay", "").Code)
+	rec = serve(router, http.MethodPost, "/order/a/refund", `{"reason":"damaged"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"refunded"`)
+
+	assert.Equal(t, []string{"OrderCreated", "OrderUpdated", "OrderPaid", "OrderRefunded"}, recorder.names())
+	refunded := recorder.events[3].GetPayload().(event.OrderStatusChangedPayload)
+	assert.Equal(t, "paid", refunded.PreviousStatus)
+	assert.Equal(t, "damaged", refunded.Reason)
+	assert.NotEqual(t, recorder.events[2].(events.EnvelopeInterface).GetID(), recorder.events[3].(events.EnvelopeInterface).GetID())
+}
+
+func TestGivenAPaidOrder_WhenCancel_ThenShouldReturnConflictWithoutAnEvent(t *testing.T) {
+	recorder := &recordingHandler{}
+	router := newTestRouter(t, recorder)
+	serve(router, http.MethodPost, "/order", `{"id":"a","price":10,"tax":1}`)
+	serve(router, http.MethodPost, "/order/a/pay", "")
+
+	assert.Equal(t, http.StatusConflict, serve(router, http.MethodPost, "/order/a/cancel", "").Code)
+	assert.Equal(t, []string{"OrderCreated", "OrderPaid"}, recorder.names())
+}
+
+func TestGivenAnUnknownOrder_WhenPay_ThenShouldReturnNotFound(t *testing.T) {
+	router := newTestRouter(t, &recordingHandler{})
+
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodPost, "/order/missing/pay", "").Code)
+}
+
+func TestGivenNoOrAnInvalidToken_WhenCreate_ThenShouldReturnUnauthorized(t *testing.T) {
+	router := newTestRouter(t, &recordingHandler{})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"id":"a","price":10,"tax":1}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"id":"a","price":10,"tax":1}`))
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestGivenAPrincipalWithoutTheRefundScope_WhenRefund_ThenShouldReturnForbidden(t *testing.T) {
+	recorder := &recordingHandler{}
+	router := newTestRouter(t, recorder)
+	clerk := auth.Principal{Subject: "bob", Scopes: []string{usecase.ScopeOrdersWrite}}
+	assert.Equal(t, http.StatusOK, serveAs(router, clerk, http.MethodPost, "/order", `{"id":"a","price":10,"tax":1}`).Code)
+	assert.Equal(t, http.StatusOK, serveAs(router, clerk, http.MethodPost, "/order/a/pay", "").Code)
+
+	assert.Equal(t, http.StatusForbidden, serveAs(router, clerk, http.MethodPost, "/order/a/refund", "").Code)
+	admin := auth.Principal{Subject: "carol", Roles: []string{usecase.RoleAdmin}}
+	assert.Equal(t, http.StatusOK, serveAs(router, admin, http.MethodPost, "/order/a/refund", "").Code)
+	assert.Equal(t, []string{"OrderCreated", "OrderPaid", "OrderRefunded"}, recorder.names())
+}
+
+func TestGivenAnAuthenticatedRequest_WhenCreate_ThenShouldRecordThePrincipalAsCreator(t *testing.T) {
+	router := newTestRouter(t, &recordingHandler{})
+
+	rec := serve(router, http.MethodPost, "/order", `{"id":"a","price":10,"tax":1}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"created_by":"alice"`)
+}
+
+func TestGivenATaxEngine_WhenCreateAndUpdate_ThenShouldPriceTheTaxAndRejectAMismatch(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	engine, err := entity.NewTaxEngine([]entity.TaxRule{{Name: "VAT", Rate: 10}, {Name: "VAT", Category: "food", Rate: 5}}, false)
+	assert.NoError(t, err)
+	handler.TaxEngine = engine
+	router := chi.NewRouter()
+	router.Post("/order", handler.Create)
+	router.Patch("/order/{id}", handler.Update)
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authorized(httptest.NewRequest(method, target, strings.NewReader(body))))
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/order", `{"id":"a","price":100,"category":"food"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output usecase.OrderOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &output))
+	assert.Equal(t, 105.0, output.FinalPrice)
+	assert.Equal(t, []usecase.TaxLineOutputDTO{{Name: "VAT", Rate: 5, Amount: 5}}, output.TaxLines)
+
+	rec = send(http.MethodPost, "/order", `{"id":"b","price":100,"tax":5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), entity.ErrTaxMismatch.Error())
+
+	rec = send(http.MethodPatch, "/order/a", `{"price":200}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"final_price":210`)
+}
+
+func TestGivenRulesThatLevyNoTax_WhenCreate_ThenShouldCreateAnUntaxedOrder(t *testing.T) {
+	engine, err := entity.NewTaxEngine([]entity.TaxRule{{Name: "VAT", Rate: 10}, {Name: "VAT", Category: "books", Rate: 0}, {Name: "VAT", Region: "north", Rate: 0.1}}, false)
+	assert.NoError(t, err)
+	unmatched, err := entity.NewTaxEngine([]entity.TaxRule{{Name: "VAT", Region: "south", Rate: 10}}, false)
+	assert.NoError(t, err)
+	tests := []struct {
+		name   string
+		engine *entity.TaxEngine
+		body   string
+		lines  int
+	}{
+		{"zero-rate rule", engine, `{"id":"a","price":100,"category":"books"}`, 1},
+		{"no matching rule", unmatched, `{"id":"a","price":100,"region":"north"}`, 0},
+		{"rounded to zero", engine, `{"id":"a","price":1,"region":"north"}`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTestHandler(t)
+			handler.TaxEngine = tt.engine
+
+			rec := httptest.NewRecorder()
+			handler.Create(rec, authorized(httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(tt.body))))
+
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var output usecase.OrderOutputDTO
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &output))
+			assert.Equal(t, 0.0, output.Tax)
+			assert.Equal(t, output.Price, output.FinalPrice)
+			assert.Len(t, output.TaxLines, tt.lines)
+		})
+	}
+}
+
+func TestGivenAnInvalidOrder_WhenCreate_ThenShouldReturnBadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+	}{
+		{"missing id", `{"price":100,"tax":10}`, entity.ErrInvalidOrderID},
+		{"negative price", `{"id":"a","price":-1,"tax":10}`, entity.ErrInvalidOrderPrice},
+		{"missing tax", `{"id":"a","price":100}`, entity.ErrInvalidOrderTax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTestHandler(t)
+
+			rec := httptest.NewRecorder()
+			handler.Create(rec, authorized(httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(tt.body))))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.err.Error())
+		})
+	}
+}
+
+func TestGivenACoupon_WhenCreateAndUpdate_ThenShouldDiscountThePriceBeforeTax(t *testing.T) {
+	handler, db := newTestHandler(t)
+	engine, err := entity.NewTaxEngine([]entity.TaxRule{{Name: "VAT", Rate: 10}}, false)
+	assert.NoError(t, err)
+	handler.TaxEngine = engine
+	handler.CouponRepository, err = database.NewCouponRepositoryForDriver(database.DriverSQLite, db)
+	assert.NoError(t, err)
+	coupon, err := entity.NewCoupon("SUMMER10", entity.DiscountPercentage, 10, time.Time{}, time.Time{}, 0, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, handler.CouponRepository.Save(context.Background(), coupon))
+	router := chi.NewRouter()
+	router.Post("/order", handler.Create)
+	router.Patch("/order/{id}", handler.Update)
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authorized(httptest.NewRequest(method, target, strings.NewReader(body))))
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/order", `{"id":"a","price":100,"coupon_code":"summer10"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output usecase.OrderOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &output))
+	assert.Equal(t, "SUMMER10", output.CouponCode)
+	assert.Equal(t, 10.0, output.Discount)
+	assert.Equal(t, 90.0, output.Price)
+	assert.Equal(t, 9.0, output.Tax)
+	assert.Equal(t, 99.0, output.FinalPrice)
+
+	rec = send(http.MethodPost, "/order", `{"id":"b","price":100,"coupon_code":"SUMMER10"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), entity.ErrCouponLimitReached.Error())
+
+	rec = send(http.MethodPost, "/order", `{"id":"c","price":100,"coupon_code":"WINTER"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = send(http.MethodPatch, "/order/a", `{"price":200}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &output))
+	assert.Equal(t, 20.0, output.Discount)
+	assert.Equal(t, 198.0, output.FinalPrice)
+
+	found, err := handler.OrderRepository.FindByID(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "SUMMER10", found.CouponCode)
+	assert.Equal(t, 20.0, found.Discount)
+}
+
+func TestGivenACouponCoveringThePrice_WhenCreateAndUpdate_ThenShouldMakeTheOrderFree(t *testing.T) {
+	tests := []struct {
+		name         string
+		discountType entity.DiscountType
+		value        float64
+	}{
+		{"full percentage", entity.DiscountPercentage, 100},
+		{"fixed amount above the price", entity.DiscountFixed, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, db := newTestHandler(t)
+			engine, err := entity.NewTaxEngine([]entity.TaxRule{{Name: "VAT", Rate: 10}}, false)
+			assert.NoError(t, err)
+			handler.TaxEngine = engine
+			handler.CouponRepository, err = database.NewCouponRepositoryForDriver(database.DriverSQLite, db)
+			assert.NoError(t, err)
+			coupon, err := entity.NewCoupon("FREE", tt.discountType, tt.value, time.Time{}, time.Time{}, 0, 0)
+			assert.NoError(t, err)
+			assert.NoError(t, handler.CouponRepository.Save(context.Background(), coupon))
+			router := chi.NewRouter()
+			router.Post("/order", handler.Create)
+			router.Patch("/order/{id}", handler.Update)
+			send := func(method, target, body string) usecase.OrderOutputDTO {
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, authorized(httptest.NewRequest(method, target, strings.NewReader(body))))
+				assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+				var output usecase.OrderOutputDTO
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &output))
+				return output
+			}
+
+			created := send(http.MethodPost, "/order", `{"id":"a","price":100,"coupon_code":"free"}`)
+			assert.Equal(t, 100.0, created.Discount)
+			assert.Equal(t, 0.0, created.Price)
+			assert.Equal(t, 0.0, created.FinalPrice)
+
+			updated := send(http.MethodPatch, "/order/a", `{"price":200}`)
+			assert.Equal(t, 0.0, updated.FinalPrice)
+			found, err := handler.OrderRepository.FindByID(context.Background(), "a")
+			assert.NoError(t, err)
+			assert.Equal(t, 0.0, found.Price)
+			assert.Equal(t, updated.Discount, found.Discount)
+		})
+	}
+}
+
+func TestGivenAnExistingOrderID_WhenCreateWithACoupon_ThenShouldReleaseTheRedemption(t *testing.T) {
+	handler, db := newTestHandler(t)
+	var err error
+	handler.CouponRepository, err = database.NewCouponRepositoryForDriver(database.DriverSQLite, db)
+	assert.NoError(t, err)
+	coupon, err := entity.NewCoupon("SUMMER10", entity.DiscountFixed, 5, time.Time{}, time.Time{}, 1, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, handler.CouponRepository.Save(context.Background(), coupon))
+	order, err := entity.NewOrder("a", 100, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, order.CalculateFinalPrice())
+	assert.NoError(t, handler.OrderRepository.Save(context.Background(), order))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"id":"a","price":100,"tax":1,"coupon_code":"SUMMER10"}`))
+	handler.Create(rec, authorized(req))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	found, err := handler.CouponRepository.FindByCode(context.Background(), "SUMMER10")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, found.Redemptions)
+}
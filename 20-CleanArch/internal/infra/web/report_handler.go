@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+)
+
+// WebReportHandler serves the reports kept from the order events.
+type WebReportHandler struct {
+	OrderReportRepository entity.OrderReportRepositoryInterface
+}
+
+func NewWebReportHandler(OrderReportRepository entity.OrderReportRepositoryInterface) *WebReportHandler {
+	return &WebReportHandler{
+		OrderReportRepository: OrderReportRepository,
+	}
+}
+
+// Orders serves the order totals between from, inclusive, and to,
+// exclusive, per day, week or month as granularity asks. from and to are
+// dates, 2006-01-02, or RFC 3339 times.
+func (h *WebReportHandler) Orders(w http.ResponseWriter, r *http.Request) {
+	var dto usecase.OrderReportInputDTO
+	var err error
+	query := r.URL.Query()
+	if dto.From, err = parseReportTime(query.Get("from")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dto.To, err = parseReportTime(query.Get("to")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dto.Granularity = query.Get("granularity")
+
+	getOrderReport := usecase.NewGetOrderReportUseCase(h.OrderReportRepository)
+	output, err := getOrderReport.Execute(r.Context(), dto)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// parseReportTime leaves a missing time zero, for the use case to reject.
+func parseReportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event/handler"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestReportRouter serves the order routes with their events projected
+// into the reports, and the report route.
+func newTestReportRouter(t *testing.T) http.Handler {
+	orderHandler, db := newTestHandler(t)
+	reportRepository, err := database.NewOrderReportRepositoryForDriver(database.DriverSQLite, db)
+	assert.NoError(t, err)
+	report := handler.NewOrderReportHandler(usecase.NewProjectOrderReportUseCase(reportRepository))
+	for _, name := range []string{"OrderCreated", "OrderUpdated", "OrderPaid", "OrderCancelled", "OrderRefunded"} {
+		assert.NoError(t, orderHandler.EventDispatcher.Register(name, report))
+	}
+	verifier, err := auth.NewVerifier(testAuthConfig)
+	assert.NoError(t, err)
+	router := chi.NewRouter()
+	router.Use(Authenticate(verifier))
+	router.Post("/order", orderHandler.Create)
+	router.Patch("/order/{id}", orderHandler.Update)
+	router.Post("/order/{id}/pay", orderHandler.Pay)
+	router.Post("/order/{id}/cancel", orderHandler.Cancel)
+	router.Post("/order/{id}/refund", orderHandler.Refund)
+	router.Get("/reports/orders", NewWebReportHandler(reportRepository).Orders)
+	return router
+}
+
+func TestGivenOrderEvents_WhenGetTheOrderReport_ThenShouldReportTheirTotals(t *testing.T) {
+	router := newTestReportRouter(t)
+	analyst := auth.Principal{Subject: "dave", Scopes: []string{usecase.ScopeReportsRead}}
+	serve(router, http.MethodPost, "/order", `{"id":"a","price":10,"tax":1}`)
+	serve(router, http.MethodPost, "/order", `{"id":"b","price":20,"tax":2}`)
+	serve(router, http.MethodPatch, "/order/a", `{"price":20,"tax":2}`)
+	serve(router, http.MethodPost, "/order/a/pay", "")
+	serve(router, http.MethodPost, "/order/a/refund", `{"reason":"damaged"}`)
+	serve(router, http.MethodPost, "/order/b/pay", "")
+	serve(router, http.MethodPost, "/order", `{"id":"c","price":5,"tax":0.5}`)
+	serve(router, http.MethodPost, "/order/c/cancel", "")
+
+	today := time.Now().UTC()
+	from := today.AddDate(0, 0, -1).Format(time.DateOnly)
+	to := today.AddDate(0, 0, 2).Format(time.DateOnly)
+	rec := serveAs(router, analyst, http.MethodGet, "/reports/orders?from="+from+"&to="+to+"&granularity=day", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report usecase.OrderReportOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, "day", report.Granularity)
+	assert.Len(t, report.Periods, 3)
+	assert.Equal(t, 0, report.Periods[0].Orders)
+	total := report.Total
+	assert.Equal(t, 3, total.Orders)
+	assert.Equal(t, 49.5, total.GrossSales)
+	assert.Equal(t, 45.0, total.NetSales)
+	assert.Equal(t, 4.5, total.TaxBilled)
+	assert.Equal(t, 16.5, total.AverageOrderValue)
+	assert.Equal(t, 2, total.OrdersPaid)
+	assert.Equal(t, 44.0, total.PaidRevenue)
+	assert.Equal(t, 1, total.OrdersRefunded)
+	assert.Equal(t, 22.0, total.RefundedAmount)
+	assert.Equal(t, 1, total.OrdersCancelled)
+	assert.Equal(t, 22.0, total.NetRevenue)
+	assert.Equal(t, 2.0, total.TaxCollected)
+}
+
+func TestGivenAnInvalidRange_WhenGetTheOrderReport_ThenShouldReturnBadRequest(t *testing.T) {
+	router := newTestReportRouter(t)
+	admin := auth.Principal{Subject: "carol", Roles: []string{usecase.RoleAdmin}}
+
+	assert.Equal(t, http.StatusBadRequest, serveAs(router, admin, http.MethodGet, "/reports/orders?from=2023-11-01&to=2023-12-01&granularity=year", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serveAs(router, admin, http.MethodGet, "/reports/orders?from=2023-12-01&to=2023-11-01", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serveAs(router, admin, http.MethodGet, "/reports/orders?from=yesterday&to=2023-11-01", "").Code)
+	assert.Equal(t, http.StatusOK, serveAs(router, admin, http.MethodGet, "/reports/orders?from=2023-11-01T00:00:00Z&to=2023-12-01T00:00:00Z&granularity=week", "").Code)
+}
+
+func TestGivenAPrincipalWithoutTheReportsScope_WhenGetTheOrderReport_ThenShouldBeForbidden(t *testing.T) {
+	router := newTestReportRouter(t)
+
+	rec := serve(router, http.MethodGet, "/reports/orders?from=2023-11-01&to=2023-12-01", "")
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
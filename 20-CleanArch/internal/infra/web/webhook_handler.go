@@ -0,0 +1,155 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/go-chi/chi/v5"
+)
+
+// WebWebhookHandler serves the webhook administration endpoints. Worker,
+// when set, is woken up to send replayed deliveries right away.
+type WebWebhookHandler struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+	Worker            usecase.WebhookWorkerInterface
+}
+
+func NewWebWebhookHandler(WebhookRepository entity.WebhookRepositoryInterface, Worker usecase.WebhookWorkerInterface) *WebWebhookHandler {
+	return &WebWebhookHandler{
+		WebhookRepository: WebhookRepository,
+		Worker:            Worker,
+	}
+}
+
+// Create answers with the subscription's secret, which no other endpoint
+// reveals.
+func (h *WebWebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto usecase.WebhookInputDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createWebhook := usecase.NewCreateWebhookUseCase(h.WebhookRepository)
+	output, err := createWebhook.Execute(r.Context(), dto)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, output)
+}
+
+func (h *WebWebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	listWebhooks := usecase.NewListWebhooksUseCase(h.WebhookRepository)
+	output, err := listWebhooks.Execute(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// Webhook serves GET, PATCH and DELETE on the subscription in the path.
+func (h *WebWebhookHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Get(w, r)
+	case http.MethodPatch:
+		h.Update(w, r)
+	case http.MethodDelete:
+		h.Delete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PATCH, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *WebWebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	getWebhook := usecase.NewGetWebhookUseCase(h.WebhookRepository)
+	output, err := getWebhook.Execute(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// Update changes the fields present in the body; an empty secret rotates
+// the secret to a generated one, returned in the response.
+func (h *WebWebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var dto usecase.UpdateWebhookInputDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dto.ID = chi.URLParam(r, "id")
+
+	updateWebhook := usecase.NewUpdateWebhookUseCase(h.WebhookRepository)
+	output, err := updateWebhook.Execute(r.Context(), dto)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func (h *WebWebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	deleteWebhook := usecase.NewDeleteWebhookUseCase(h.WebhookRepository)
+	if err := deleteWebhook.Execute(r.Context(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries serves the delivery log of the subscription in the path,
+// newest first, filtered by the status and limit query parameters.
+func (h *WebWebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	dto := usecase.ListWebhookDeliveriesInputDTO{
+		WebhookID: chi.URLParam(r, "id"),
+		Status:    r.URL.Query().Get("status"),
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		var err error
+		if dto.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	listDeliveries := usecase.NewListWebhookDeliveriesUseCase(h.WebhookRepository)
+	output, err := listDeliveries.Execute(r.Context(), dto)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+func (h *WebWebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	replayDelivery := usecase.NewReplayWebhookDeliveryUseCase(h.WebhookRepository, h.Worker)
+	output, err := replayDelivery.Execute(r.Context(), usecase.ReplayWebhookDeliveryInputDTO{
+		WebhookID:  chi.URLParam(r, "id"),
+		DeliveryID: chi.URLParam(r, "delivery"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, output)
+}
+
+// ReplayFailed replays every failed delivery of the subscription in the
+// path.
+func (h *WebWebhookHandler) ReplayFailed(w http.ResponseWriter, r *http.Request) {
+	replayFailed := usecase.NewReplayFailedWebhookDeliveriesUseCase(h.WebhookRepository, h.Worker)
+	output, err := replayFailed.Execute(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, output)
+}
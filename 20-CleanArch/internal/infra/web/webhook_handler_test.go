@@ -0,0 +1,134 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var webhookAdmin = auth.Principal{Subject: "erin", Scopes: []string{usecase.ScopeWebhooksAdmin}}
+
+type countingWorker int
+
+func (c *countingWorker) Notify() { *c++ }
+
+func newTestWebhookRouter(repository entity.WebhookRepositoryInterface, worker usecase.WebhookWorkerInterface) chi.Router {
+	handler := NewWebWebhookHandler(repository, worker)
+	router := chi.NewRouter()
+	router.HandleFunc("/webhook", handler.Create)
+	router.HandleFunc("/webhooks", handler.List)
+	router.HandleFunc("/webhook/{id}", handler.Webhook)
+	router.HandleFunc("/webhook/{id}/deliveries", handler.Deliveries)
+	router.HandleFunc("/webhook/{id}/deliveries/{delivery}/replay", handler.ReplayDelivery)
+	router.HandleFunc("/webhook/{id}/replay", handler.ReplayFailed)
+	return router
+}
+
+func TestGivenAWebhookAdmin_WhenCreateListUpdateAndDelete_ThenShouldAdministerTheWebhook(t *testing.T) {
+	router := newTestWebhookRouter(database.NewMemoryWebhookRepository(), nil)
+
+	rec := sendAs(router, webhookAdmin, http.MethodPost, "/webhook", `{"url":"https://partner.example/hooks","events":["OrderPaid","OrderCreated"]}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var created usecase.WebhookOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, []string{"OrderCreated", "OrderPaid"}, created.Events)
+	assert.Equal(t, "erin", created.CreatedBy)
+
+	rec = sendAs(router, webhookAdmin, http.MethodPost, "/webhook", `{"url":"https://partner.example/hooks","events":["OrderShipped"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = sendAs(router, webhookAdmin, http.MethodGet, "/webhooks", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var webhooks []usecase.WebhookOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &webhooks))
+	assert.Len(t, webhooks, 1)
+	assert.Empty(t, webhooks[0].Secret)
+
+	rec = sendAs(router, webhookAdmin, http.MethodPatch, "/webhook/"+created.ID, `{"active":false,"url":"https://partner.example/v2"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var updated usecase.WebhookOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.False(t, updated.Active)
+	assert.Equal(t, "https://partner.example/v2", updated.URL)
+	assert.Equal(t, created.Events, updated.Events)
+	assert.Empty(t, updated.Secret)
+
+	rec = sendAs(router, webhookAdmin, http.MethodPatch, "/webhook/"+created.ID, `{"secret":""}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &updated))
+	assert.NotEmpty(t, updated.Secret)
+	assert.NotEqual(t, created.Secret, updated.Secret)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, sendAs(router, webhookAdmin, http.MethodPut, "/webhook/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNoContent, sendAs(router, webhookAdmin, http.MethodDelete, "/webhook/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(router, webhookAdmin, http.MethodGet, "/webhook/"+created.ID, "").Code)
+}
+
+func TestGivenFailedDeliveries_WhenReplay_ThenShouldMakeThemPendingAndWakeTheWorker(t *testing.T) {
+	repository := database.NewMemoryWebhookRepository()
+	var worker countingWorker
+	router := newTestWebhookRouter(repository, &worker)
+	subscription, err := entity.NewWebhookSubscription("wh-1", "https://partner.example/hooks", []string{entity.OrderPaidEvent}, "", "erin")
+	assert.NoError(t, err)
+	assert.NoError(t, repository.SaveSubscription(context.Background(), subscription))
+	deliveries := []*entity.WebhookDelivery{
+		entity.NewWebhookDelivery("d-1", "wh-1", "event-1", entity.OrderPaidEvent, []byte(`{"id":"event-1"}`)),
+		entity.NewWebhookDelivery("d-2", "wh-1", "event-2", entity.OrderPaidEvent, []byte(`{"id":"event-2"}`)),
+		entity.NewWebhookDelivery("d-3", "wh-1", "event-3", entity.OrderPaidEvent, []byte(`{"id":"event-3"}`)),
+	}
+	assert.NoError(t, repository.SaveDeliveries(context.Background(), deliveries))
+	for _, delivery := range deliveries[:2] {
+		delivery.Fail(delivery.CreatedAt, http.StatusBadGateway, "unexpected response 502 Bad Gateway", time.Time{})
+		assert.NoError(t, repository.UpdateDelivery(context.Background(), delivery))
+	}
+
+	rec := sendAs(router, webhookAdmin, http.MethodGet, "/webhook/wh-1/deliveries?status=failed", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var log []usecase.WebhookDeliveryOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &log))
+	assert.Len(t, log, 2)
+	assert.Equal(t, http.StatusBadGateway, log[0].ResponseStatus)
+	assert.Nil(t, log[0].NextAttemptAt)
+	assert.NotNil(t, log[0].LastAttemptAt)
+	assert.JSONEq(t, `{"id":"`+log[0].EventID+`"}`, string(log[0].Payload))
+	assert.Equal(t, http.StatusBadRequest, sendAs(router, webhookAdmin, http.MethodGet, "/webhook/wh-1/deliveries?status=lost", "").Code)
+	assert.Equal(t, http.StatusBadRequest, sendAs(router, webhookAdmin, http.MethodGet, "/webhook/wh-1/deliveries?limit=many", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(router, webhookAdmin, http.MethodGet, "/webhook/wh-2/deliveries", "").Code)
+
+	rec = sendAs(router, webhookAdmin, http.MethodPost, "/webhook/wh-1/deliveries/d-1/replay", "")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var replayed usecase.WebhookDeliveryOutputDTO
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &replayed))
+	assert.Equal(t, "pending", replayed.Status)
+	assert.Equal(t, 0, replayed.Attempts)
+	assert.Equal(t, http.StatusConflict, sendAs(router, webhookAdmin, http.MethodPost, "/webhook/wh-1/deliveries/d-3/replay", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendAs(router, webhookAdmin, http.MethodPost, "/webhook/wh-2/deliveries/d-2/replay", "").Code)
+
+	rec = sendAs(router, webhookAdmin, http.MethodPost, "/webhook/wh-1/replay", "")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.JSONEq(t, `{"replayed":1}`, rec.Body.String())
+	pending, err := repository.ListDeliveries(context.Background(), "wh-1", entity.WebhookDeliveryPending, 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+	assert.Equal(t, countingWorker(2), worker)
+}
+
+func TestGivenAPrincipalWithoutTheWebhooksScope_WhenCreateAWebhook_ThenShouldBeForbidden(t *testing.T) {
+	router := newTestWebhookRouter(database.NewMemoryWebhookRepository(), nil)
+	couponAdmin := auth.Principal{Subject: "bob", Scopes: []string{usecase.ScopeCouponsAdmin}}
+
+	rec := sendAs(router, couponAdmin, http.MethodPost, "/webhook", `{"url":"https://partner.example/hooks","events":["OrderPaid"]}`)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -1,37 +1,117 @@
 package webserver
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/telemetry"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
 )
 
 type WebServer struct {
-	Router        chi.Router
-	Handlers      map[string]http.HandlerFunc
-	WebServerPort string
+	Router   chi.Router
+	Handlers map[string]http.HandlerFunc
+	// MethodHandlers holds the handlers of the routes that serve a method
+	// each, by path and then method.
+	MethodHandlers map[string]map[string]http.HandlerFunc
+	WebServerPort  string
 }
 
 func NewWebServer(serverPort string) *WebServer {
 	return &WebServer{
-		Router:        chi.NewRouter(),
-		Handlers:      make(map[string]http.HandlerFunc),
-		WebServerPort: serverPort,
+		Router:         chi.NewRouter(),
+		Handlers:       make(map[string]http.HandlerFunc),
+		MethodHandlers: make(map[string]map[string]http.HandlerFunc),
+		WebServerPort:  serverPort,
 	}
 }
 
-func (s *WebServer) AddHandler(path string, handler http.HandlerFunc) {
+// AddHandler routes path to handler, wrapped in middlewares in the order
+// given.
+func (s *WebServer) AddHandler(path string, handler http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
+	if len(middlewares) > 0 {
+		handler = chi.Chain(middlewares...).HandlerFunc(handler).ServeHTTP
+	}
 	s.Handlers[path] = handler
 }
 
+// AddMethodHandler routes requests of method to path to handler, wrapped in
+// middlewares in the order given. Other methods on path are answered with
+// 405 Method Not Allowed.
+func (s *WebServer) AddMethodHandler(method, path string, handler http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) {
+	if len(middlewares) > 0 {
+		handler = chi.Chain(middlewares...).HandlerFunc(handler).ServeHTTP
+	}
+	if s.MethodHandlers[path] == nil {
+		s.MethodHandlers[path] = make(map[string]http.HandlerFunc)
+	}
+	s.MethodHandlers[path][method] = handler
+}
+
 // loop through the handlers and add them to the router
 // register middeleware logger
 // start the server
 func (s *WebServer) Start() {
-	s.Router.Use(middleware.Logger)
+	http.ListenAndServe(s.WebServerPort, s.Handler())
+}
+
+// Handler registers the telemetry middleware and the handlers on the
+// router and returns it traced, for callers that run their own
+// http.Server. Call it once, after every AddHandler.
+func (s *WebServer) Handler() http.Handler {
+	s.Router.Use(observe)
 	for path, handler := range s.Handlers {
 		s.Router.Handle(path, handler)
 	}
-	http.ListenAndServe(s.WebServerPort, s.Router)
+	for path, handlers := range s.MethodHandlers {
+		for method, handler := range handlers {
+			s.Router.Method(method, path, handler)
+		}
+	}
+	return otelhttp.NewHandler(s.Router, "http.server")
+}
+
+// observe names the request's span after the matched route, records its
+// RED metrics and logs it. The route is only known once chi has routed the
+// request, so all of it happens after next returns.
+func observe(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		// Unmatched requests keep the bare method, so stray paths do not
+		// turn into span names or metric attributes.
+		operation := r.Method
+		if route := chi.RouteContext(r.Context()).RoutePattern(); route != "" {
+			operation += " " + route
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(operation)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		var err error
+		if status >= http.StatusInternalServerError {
+			err = fmt.Errorf("%d %s", status, http.StatusText(status))
+		}
+		telemetry.RecordRED(r.Context(), "http", operation, start, err)
+		slog.InfoContext(r.Context(), "http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"operation", operation,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+		)
+	})
 }
@@ -0,0 +1,73 @@
+package webserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+func TestGivenARoutedRequest_WhenServed_ThenShouldNameItsSpanAfterTheRoute(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	server := NewWebServer(":0")
+	server.AddHandler("/order/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := server.Handler()
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/order/42", nil))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/42", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "GET /order/{id}", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), semconv.HTTPRoute("/order/{id}"))
+	assert.Equal(t, "http.server", spans[1].Name())
+}
+
+func TestGivenMethodHandlers_WhenAnotherMethodIsUsed_ThenShouldAnswerMethodNotAllowed(t *testing.T) {
+	server := NewWebServer(":0")
+	for _, method := range []string{http.MethodGet, http.MethodPatch} {
+		server.AddMethodHandler(method, "/order/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Method))
+		})
+	}
+	server.AddMethodHandler(http.MethodPost, "/order/{id}/refund", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := server.Handler()
+	tests := []struct {
+		method string
+		target string
+		status int
+		body   string
+	}{
+		{http.MethodGet, "/order/42", http.StatusOK, http.MethodGet},
+		{http.MethodPatch, "/order/42", http.StatusOK, http.MethodPatch},
+		{http.MethodDelete, "/order/42", http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "/order/42/refund", http.StatusNoContent, ""},
+		{http.MethodGet, "/order/42/refund", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, httptest.NewRequest(tt.method, tt.target, nil))
+
+			assert.Equal(t, tt.status, response.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, response.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package webhook sends the order events to the partners' webhooks.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	signature "github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/webhook"
+)
+
+// maxDrainedBody bounds what is read of a response before the connection
+// is reused; the body itself is ignored.
+const maxDrainedBody = 64 << 10
+
+// HTTPSender POSTs a delivery's payload, a structured mode CloudEvent, to
+// its subscription's URL, signed with the subscription's secret. Any 2xx
+// response accepts the delivery; redirects are not followed.
+type HTTPSender struct {
+	Client *http.Client
+}
+
+func NewHTTPSender(timeout time.Duration) *HTTPSender {
+	return &HTTPSender{
+		Client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", events.CloudEventsContentType)
+	req.Header.Set(signature.SignatureHeader, signature.Sign(subscription.Secret, time.Now(), delivery.Payload))
+	req.Header.Set(signature.DeliveryHeader, delivery.ID)
+	req.Header.Set(signature.EventHeader, delivery.Event)
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	signature "github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver answers each webhook request with the next of statuses, the
+// last one repeating, and records the requests.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := r.statuses[0]
+	if len(r.statuses) > 1 {
+		r.statuses = r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// newTestDelivery subscribes url to OrderPaid and enqueues one event for it.
+func newTestDelivery(t *testing.T, repository entity.WebhookRepositoryInterface, url string) *entity.WebhookSubscription {
+	subscription, err := entity.NewWebhookSubscription("wh-1", url, []string{entity.OrderPaidEvent}, "", "alice")
+	assert.NoError(t, err)
+	assert.NoError(t, repository.SaveSubscription(context.Background(), subscription))
+	enqueued, err := usecase.NewEnqueueWebhookDeliveriesUseCase(repository).Execute(context.Background(), usecase.EnqueueWebhookDeliveriesInputDTO{
+		EventID: "event-1",
+		Event:   entity.OrderPaidEvent,
+		Payload: []byte(`{"specversion":"1.0","id":"event-1","type":"OrderPaid"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, enqueued)
+	return subscription
+}
+
+func newTestDeliverUseCase(repository entity.WebhookRepositoryInterface, maxAttempts int) *usecase.DeliverWebhooksUseCase {
+	policy := events.RetryPolicy{MaxAttempts: maxAttempts, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Multiplier: 2}
+	return usecase.NewDeliverWebhooksUseCase(repository, NewHTTPSender(time.Second), policy, time.Minute, 10)
+}
+
+func TestGivenAFailingReceiver_WhenTheWorkerRuns_ThenShouldRetryUntilItAcceptsTheSignedDelivery(t *testing.T) {
+	received := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusNoContent}}
+	server := httptest.NewServer(received)
+	defer server.Close()
+	repository := database.NewMemoryWebhookRepository()
+	subscription := newTestDelivery(t, repository, server.URL)
+	worker := NewWorker(newTestDeliverUseCase(repository, 5), time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- worker.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		deliveries, err := repository.ListDeliveries(context.Background(), "wh-1", entity.WebhookDeliverySucceeded, 1)
+		return err == nil && len(deliveries) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+
+	deliveries, err := repository.ListDeliveries(context.Background(), "wh-1", "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseStatus)
+	assert.Equal(t, 3, received.count())
+	for i, req := range received.requests {
+		assert.Equal(t, events.CloudEventsContentType, req.Header.Get("Content-Type"))
+		assert.Equal(t, deliveries[0].ID, req.Header.Get(signature.DeliveryHeader))
+		assert.Equal(t, entity.OrderPaidEvent, req.Header.Get(signature.EventHeader))
+		assert.NoError(t, signature.Verify(subscription.Secret, req.Header.Get(signature.SignatureHeader), received.bodies[i], time.Now(), time.Minute))
+	}
+}
+
+func TestGivenAReceiverThatKeepsFailing_WhenDeliver_ThenShouldFailTheDeliveryUntilReplayed(t *testing.T) {
+	received := &receiver{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(received)
+	defer server.Close()
+	repository := database.NewMemoryWebhookRepository()
+	newTestDelivery(t, repository, server.URL)
+	deliver := newTestDeliverUseCase(repository, 2)
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		sent, err := deliver.Execute(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+	}
+	deliveries, err := repository.ListDeliveries(context.Background(), "wh-1", entity.WebhookDeliveryFailed, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseStatus)
+	assert.Equal(t, "unexpected response 500 Internal Server Error", deliveries[0].LastError)
+	sent, err := deliver.Execute(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	received.statuses = []int{http.StatusOK}
+	assert.NoError(t, deliveries[0].Replay(time.Now()))
+	assert.NoError(t, repository.UpdateDelivery(context.Background(), deliveries[0]))
+	sent, err = deliver.Execute(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	delivery, err := repository.FindDelivery(context.Background(), deliveries[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.WebhookDeliverySucceeded, delivery.Status)
+	assert.Equal(t, 3, received.count())
+}
+
+func TestGivenARedirect_WhenSend_ThenShouldNotFollowIt(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("https://elsewhere.example/", http.StatusFound))
+	defer server.Close()
+	subscription := &entity.WebhookSubscription{URL: server.URL, Secret: "0123456789abcdef"}
+
+	status, err := NewHTTPSender(time.Second).Send(context.Background(), subscription, entity.NewWebhookDelivery("d-1", "wh-1", "event-1", entity.OrderPaidEvent, []byte(`{}`)))
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusFound, status)
+}
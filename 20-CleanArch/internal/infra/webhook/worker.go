@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/usecase"
+	"golang.org/x/exp/slog"
+)
+
+// Worker sends the due webhook deliveries every Interval, and as soon as it
+// is notified of new ones. Several workers, in one process or many, may
+// share a store: each delivery is claimed by one of them.
+type Worker struct {
+	Deliver  *usecase.DeliverWebhooksUseCase
+	Interval time.Duration
+	notify   chan struct{}
+}
+
+func NewWorker(deliver *usecase.DeliverWebhooksUseCase, interval time.Duration) *Worker {
+	return &Worker{
+		Deliver:  deliver,
+		Interval: interval,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the worker up without blocking; notifications arriving
+// while it is busy are coalesced into one.
+func (w *Worker) Notify() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers until ctx is done. Failures are logged and retried at the
+// next wake-up.
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		w.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-w.notify:
+		}
+	}
+}
+
+// drain sends batches until one is not full, or empty when the batch size
+// is left to its default.
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		sent, err := w.Deliver.Execute(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "delivering webhooks failed", "error", err)
+			return
+		}
+		if sent == 0 || sent < w.Deliver.BatchSize {
+			return
+		}
+	}
+}
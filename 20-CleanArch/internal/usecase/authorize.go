@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+)
+
+// Scopes a principal needs for each order, coupon, report and webhook
+// operation.
+const (
+	ScopeOrdersRead    = "orders:read"
+	ScopeOrdersWrite   = "orders:write"
+	ScopeOrdersRefund  = "orders:refund"
+	ScopeCouponsAdmin  = "coupons:admin"
+	ScopeReportsRead   = "reports:read"
+	ScopeWebhooksAdmin = "webhooks:admin"
+)
+
+// RoleAdmin holds every scope.
+const RoleAdmin = "admin"
+
+// Authorize returns the principal of ctx if it may perform operations that
+// need scope. Transports that do not go through a use case, such as the
+// GraphQL subscriptions, call it directly.
+func Authorize(ctx context.Context, scope string) (auth.Principal, error) {
+	return auth.Authorize(ctx, scope, RoleAdmin)
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+type UpdateOrderInputDTO struct {
+	ID    string  `json:"id"`
+	Price float64 `json:"price"`
+	Tax   float64 `json:"tax"`
+}
+
+type ChangeOrderStatusInputDTO struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// changeOrder checks that the caller holds scope, loads an order, applies
+// change and stores it only if nobody changed its status in between, then
+// dispatches the events it raised.
+func changeOrder(
+	ctx context.Context,
+	repository entity.OrderRepositoryInterface,
+	dispatcher events.EventDispatcherInterface,
+	scope string,
+	id string,
+	change func(order *entity.Order) error,
+) (OrderOutputDTO, error) {
+	if _, err := Authorize(ctx, scope); err != nil {
+		return OrderOutputDTO{}, err
+	}
+	order, err := repository.FindByID(ctx, id)
+	if err != nil {
+		return OrderOutputDTO{}, err
+	}
+	previousStatus := order.Status
+	if err := change(order); err != nil {
+		return OrderOutputDTO{}, err
+	}
+	if err := repository.Update(ctx, order, previousStatus); err != nil {
+		return OrderOutputDTO{}, err
+	}
+	dispatchEvents(ctx, dispatcher, order)
+	return newOrderOutputDTO(*order), nil
+}
+
+type UpdateOrderUseCase struct {
+	OrderRepository  entity.OrderRepositoryInterface
+	EventDispatcher  events.EventDispatcherInterface
+	TaxEngine        *entity.TaxEngine
+	CouponRepository entity.CouponRepositoryInterface
+}
+
+func NewUpdateOrderUseCase(
+	OrderRepository entity.OrderRepositoryInterface,
+	EventDispatcher events.EventDispatcherInterface,
+	TaxEngine *entity.TaxEngine,
+	CouponRepository entity.CouponRepositoryInterface,
+) *UpdateOrderUseCase {
+	return &UpdateOrderUseCase{
+		OrderRepository:  OrderRepository,
+		EventDispatcher:  EventDispatcher,
+		TaxEngine:        TaxEngine,
+		CouponRepository: CouponRepository,
+	}
+}
+
+// Execute changes the price of a pending order and, like CreateOrderUseCase,
+// takes the discount of the order's coupon off it and prices its tax with
+// the tax engine for the order's category and region. The coupon was
+// redeemed when the order was created, so it applies even if it has since
+// been deactivated or expired.
+func (u *UpdateOrderUseCase) Execute(ctx context.Context, input UpdateOrderInputDTO) (OrderOutputDTO, error) {
+	return changeOrder(ctx, u.OrderRepository, u.EventDispatcher, ScopeOrdersWrite, input.ID, func(order *entity.Order) error {
+		price, discount := input.Price, 0.0
+		if order.CouponCode != "" {
+			coupon, err := findCoupon(ctx, u.CouponRepository, order.CouponCode)
+			if err != nil {
+				return err
+			}
+			price, discount = coupon.Apply(input.Price)
+		}
+		breakdown, err := priceOrder(u.TaxEngine, price, input.Tax, order.Category, order.Region)
+		if err != nil {
+			return err
+		}
+		breakdown.Discount = discount
+		return order.Reprice(breakdown)
+	})
+}
+
+type PayOrderUseCase struct {
+	OrderRepository entity.OrderRepositoryInterface
+	EventDispatcher events.EventDispatcherInterface
+}
+
+func NewPayOrderUseCase(OrderRepository entity.OrderRepositoryInterface, EventDispatcher events.EventDispatcherInterface) *PayOrderUseCase {
+	return &PayOrderUseCase{
+		OrderRepository: OrderRepository,
+		EventDispatcher: EventDispatcher,
+	}
+}
+
+func (u *PayOrderUseCase) Execute(ctx context.Context, input ChangeOrderStatusInputDTO) (OrderOutputDTO, error) {
+	return changeOrder(ctx, u.OrderRepository, u.EventDispatcher, ScopeOrdersWrite, input.ID, func(order *entity.Order) error {
+		return order.Pay()
+	})
+}
+
+type CancelOrderUseCase struct {
+	OrderRepository entity.OrderRepositoryInterface
+	EventDispatcher events.EventDispatcherInterface
+}
+
+func NewCancelOrderUseCase(OrderRepository entity.OrderRepositoryInterface, EventDispatcher events.EventDispatcherInterface) *CancelOrderUseCase {
+	return &CancelOrderUseCase{
+		OrderRepository: OrderRepository,
+		EventDispatcher: EventDispatcher,
+	}
+}
+
+func (u *CancelOrderUseCase) Execute(ctx context.Context, input ChangeOrderStatusInputDTO) (OrderOutputDTO, error) {
+	return changeOrder(ctx, u.OrderRepository, u.EventDispatcher, ScopeOrdersWrite, input.ID, func(order *entity.Order) error {
+		return order.Cancel(input.Reason)
+	})
+}
+
+type RefundOrderUseCase struct {
+	OrderRepository entity.OrderRepositoryInterface
+	EventDispatcher events.EventDispatcherInterface
+}
+
+func NewRefundOrderUseCase(OrderRepository entity.OrderRepositoryInterface, EventDispatcher events.EventDispatcherInterface) *RefundOrderUseCase {
+	return &RefundOrderUseCase{
+		OrderRepository: OrderRepository,
+		EventDispatcher: EventDispatcher,
+	}
+}
+
+// Execute refunds a paid order; it needs the orders:refund scope.
+func (u *RefundOrderUseCase) Execute(ctx context.Context, input ChangeOrderStatusInputDTO) (OrderOutputDTO, error) {
+	return changeOrder(ctx, u.OrderRepository, u.EventDispatcher, ScopeOrdersRefund, input.ID, func(order *entity.Order) error {
+		return order.Refund(input.Reason)
+	})
+}
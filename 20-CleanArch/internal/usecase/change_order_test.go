@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenAnOrder_WhenChangeIt_ThenShouldStoreTheChangeAndAnnounceIt(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		status entity.OrderStatus
+		change func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error)
+		err    error
+		event  string
+		want   entity.OrderStatus
+	}{
+		{
+			name:   "pay",
+			ctx:    testContext(),
+			status: entity.OrderStatusPending,
+			change: func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error) {
+				return NewPayOrderUseCase(orders, dispatcher).Execute(ctx, ChangeOrderStatusInputDTO{ID: "a"})
+			},
+			event: entity.OrderPaidEvent,
+			want:  entity.OrderStatusPaid,
+		},
+		{
+			name:   "cancel",
+			ctx:    testContext(),
+			status: entity.OrderStatusPending,
+			change: func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error) {
+				return NewCancelOrderUseCase(orders, dispatcher).Execute(ctx, ChangeOrderStatusInputDTO{ID: "a", Reason: "duplicate"})
+			},
+			event: entity.OrderCancelledEvent,
+			want:  entity.OrderStatusCancelled,
+		},
+		{
+			name:   "refund",
+			ctx:    testContext(),
+			status: entity.OrderStatusPaid,
+			change: func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error) {
+				return NewRefundOrderUseCase(orders, dispatcher).Execute(ctx, ChangeOrderStatusInputDTO{ID: "a"})
+			},
+			event: entity.OrderRefundedEvent,
+			want:  entity.OrderStatusRefunded,
+		},
+		{
+			name:   "update to the same price",
+			ctx:    testContext(),
+			status: entity.OrderStatusPending,
+			change: func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error) {
+				return NewUpdateOrderUseCase(orders, dispatcher, nil, nil).Execute(ctx, UpdateOrderInputDTO{ID: "a", Price: 10, Tax: 1})
+			},
+			event: entity.OrderUpdatedEvent,
+			want:  entity.OrderStatusPending,
+		},
+		{
+			name:   "pay twice",
+			ctx:    testContext(),
+			status: entity.OrderStatusPaid,
+			change: func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error) {
+				return NewPayOrderUseCase(orders, dispatcher).Execute(ctx, ChangeOrderStatusInputDTO{ID: "a"})
+			},
+			err:  entity.ErrInvalidStatusTransition,
+			want: entity.OrderStatusPaid,
+		},
+		{
+			name:   "refund without the refund scope",
+			ctx:    contextAs("bob", ScopeOrdersRead, ScopeOrdersWrite),
+			status: entity.OrderStatusPaid,
+			change: func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error) {
+				return NewRefundOrderUseCase(orders, dispatcher).Execute(ctx, ChangeOrderStatusInputDTO{ID: "a"})
+			},
+			err:  auth.ErrForbidden,
+			want: entity.OrderStatusPaid,
+		},
+		{
+			name:   "unknown order",
+			ctx:    testContext(),
+			status: entity.OrderStatusPending,
+			change: func(ctx context.Context, orders entity.OrderRepositoryInterface, dispatcher events.EventDispatcherInterface) (OrderOutputDTO, error) {
+				return NewPayOrderUseCase(orders, dispatcher).Execute(ctx, ChangeOrderStatusInputDTO{ID: "b"})
+			},
+			err:  entity.ErrOrderNotFound,
+			want: entity.OrderStatusPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := database.NewMemoryOrderRepository()
+			saveTestOrder(t, orders, tt.status)
+			dispatcher, recorder := newTestDispatcher(t)
+
+			output, err := tt.change(tt.ctx, orders, dispatcher)
+
+			found, findErr := orders.FindByID(context.Background(), "a")
+			assert.NoError(t, findErr)
+			assert.Equal(t, tt.want, found.Status)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Empty(t, recorder.names())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, string(tt.want), output.Status)
+			assert.Equal(t, []string{tt.event}, recorder.names())
+		})
+	}
+}
+
+func TestGivenAConcurrentChange_WhenPayOrder_ThenShouldReturnErrOrderModifiedAndAnnounceNothing(t *testing.T) {
+	memory := database.NewMemoryOrderRepository()
+	saveTestOrder(t, memory, entity.OrderStatusPending)
+	orders := &stubOrderRepository{OrderRepositoryInterface: memory}
+	// Someone cancels the order between the read and the write.
+	orders.beforeUpdate = func() {
+		orders.beforeUpdate = nil
+		_, err := NewCancelOrderUseCase(orders, events.NewEventDispatcher()).
+			Execute(testContext(), ChangeOrderStatusInputDTO{ID: "a", Reason: "duplicate"})
+		assert.NoError(t, err)
+	}
+	dispatcher, recorder := newTestDispatcher(t)
+
+	_, err := NewPayOrderUseCase(orders, dispatcher).Execute(testContext(), ChangeOrderStatusInputDTO{ID: "a"})
+
+	assert.ErrorIs(t, err, entity.ErrOrderModified)
+	assert.Empty(t, recorder.names())
+	found, err := memory.FindByID(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.OrderStatusCancelled, found.Status)
+}
+
+// saveTestOrder stores order "a", priced 10 plus 1 of tax, in status.
+func saveTestOrder(t *testing.T, orders entity.OrderRepositoryInterface, status entity.OrderStatus) {
+	order, err := entity.NewOrder("a", 10, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, order.CalculateFinalPrice())
+	order.Status = status
+	assert.NoError(t, orders.Save(context.Background(), order))
+}
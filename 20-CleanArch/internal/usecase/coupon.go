@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// CouponInputDTO leaves an end of the validity window open when it is
+// omitted, and a cap unlimited when it is zero.
+type CouponInputDTO struct {
+	Code                      string    `json:"code"`
+	Type                      string    `json:"type"`
+	Value                     float64   `json:"value"`
+	ValidFrom                 time.Time `json:"valid_from"`
+	ValidUntil                time.Time `json:"valid_until"`
+	MaxRedemptions            int       `json:"max_redemptions"`
+	MaxRedemptionsPerCustomer int       `json:"max_redemptions_per_customer"`
+}
+
+type CouponOutputDTO struct {
+	Code                      string     `json:"code"`
+	Type                      string     `json:"type"`
+	Value                     float64    `json:"value"`
+	ValidFrom                 *time.Time `json:"valid_from,omitempty"`
+	ValidUntil                *time.Time `json:"valid_until,omitempty"`
+	MaxRedemptions            int        `json:"max_redemptions"`
+	MaxRedemptionsPerCustomer int        `json:"max_redemptions_per_customer"`
+	Redemptions               int        `json:"redemptions"`
+	Active                    bool       `json:"active"`
+	CreatedAt                 time.Time  `json:"created_at"`
+}
+
+func newCouponOutputDTO(coupon *entity.Coupon) CouponOutputDTO {
+	output := CouponOutputDTO{
+		Code:                      coupon.Code,
+		Type:                      string(coupon.Type),
+		Value:                     coupon.Value,
+		MaxRedemptions:            coupon.MaxRedemptions,
+		MaxRedemptionsPerCustomer: coupon.MaxRedemptionsPerCustomer,
+		Redemptions:               coupon.Redemptions,
+		Active:                    coupon.Active,
+		CreatedAt:                 coupon.CreatedAt,
+	}
+	if !coupon.ValidFrom.IsZero() {
+		output.ValidFrom = &coupon.ValidFrom
+	}
+	if !coupon.ValidUntil.IsZero() {
+		output.ValidUntil = &coupon.ValidUntil
+	}
+	return output
+}
+
+// CreateCouponUseCase and the other coupon use cases administer coupons;
+// they need the coupons:admin scope.
+type CreateCouponUseCase struct {
+	CouponRepository entity.CouponRepositoryInterface
+}
+
+func NewCreateCouponUseCase(CouponRepository entity.CouponRepositoryInterface) *CreateCouponUseCase {
+	return &CreateCouponUseCase{
+		CouponRepository: CouponRepository,
+	}
+}
+
+func (c *CreateCouponUseCase) Execute(ctx context.Context, input CouponInputDTO) (CouponOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeCouponsAdmin); err != nil {
+		return CouponOutputDTO{}, err
+	}
+	coupon, err := entity.NewCoupon(input.Code, entity.DiscountType(input.Type), input.Value,
+		input.ValidFrom, input.ValidUntil, input.MaxRedemptions, input.MaxRedemptionsPerCustomer)
+	if err != nil {
+		return CouponOutputDTO{}, err
+	}
+	if err := c.CouponRepository.Save(ctx, coupon); err != nil {
+		return CouponOutputDTO{}, err
+	}
+	return newCouponOutputDTO(coupon), nil
+}
+
+type ListCouponsUseCase struct {
+	CouponRepository entity.CouponRepositoryInterface
+}
+
+func NewListCouponsUseCase(CouponRepository entity.CouponRepositoryInterface) *ListCouponsUseCase {
+	return &ListCouponsUseCase{
+		CouponRepository: CouponRepository,
+	}
+}
+
+func (l *ListCouponsUseCase) Execute(ctx context.Context) ([]CouponOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeCouponsAdmin); err != nil {
+		return nil, err
+	}
+	coupons, err := l.CouponRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]CouponOutputDTO, 0, len(coupons))
+	for _, coupon := range coupons {
+		output = append(output, newCouponOutputDTO(coupon))
+	}
+	return output, nil
+}
+
+type GetCouponUseCase struct {
+	CouponRepository entity.CouponRepositoryInterface
+}
+
+func NewGetCouponUseCase(CouponRepository entity.CouponRepositoryInterface) *GetCouponUseCase {
+	return &GetCouponUseCase{
+		CouponRepository: CouponRepository,
+	}
+}
+
+func (g *GetCouponUseCase) Execute(ctx context.Context, code string) (CouponOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeCouponsAdmin); err != nil {
+		return CouponOutputDTO{}, err
+	}
+	coupon, err := g.CouponRepository.FindByCode(ctx, code)
+	if err != nil {
+		return CouponOutputDTO{}, err
+	}
+	return newCouponOutputDTO(coupon), nil
+}
+
+// DeactivateCouponUseCase stops further redemptions of a coupon; orders that
+// already redeemed it keep their discount.
+type DeactivateCouponUseCase struct {
+	CouponRepository entity.CouponRepositoryInterface
+}
+
+func NewDeactivateCouponUseCase(CouponRepository entity.CouponRepositoryInterface) *DeactivateCouponUseCase {
+	return &DeactivateCouponUseCase{
+		CouponRepository: CouponRepository,
+	}
+}
+
+func (d *DeactivateCouponUseCase) Execute(ctx context.Context, code string) (CouponOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeCouponsAdmin); err != nil {
+		return CouponOutputDTO{}, err
+	}
+	if err := d.CouponRepository.SetActive(ctx, code, false); err != nil {
+		return CouponOutputDTO{}, err
+	}
+	coupon, err := d.CouponRepository.FindByCode(ctx, code)
+	if err != nil {
+		return CouponOutputDTO{}, err
+	}
+	return newCouponOutputDTO(coupon), nil
+}
@@ -1,61 +1,295 @@
 package usecase
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"golang.org/x/exp/slog"
 )
 
 type OrderInputDTO struct {
 	ID    string  `json:"id"`
 	Price float64 `json:"price"`
-	Tax   float64 `json:"tax"`
+	// Tax is optional when a tax engine prices the orders; if given, it
+	// must match the engine's.
+	Tax      float64 `json:"tax"`
+	Category string  `json:"category"`
+	Region   string  `json:"region"`
+	// CouponCode, if given, takes the coupon's discount off Price before
+	// tax.
+	CouponCode string `json:"coupon_code"`
+	// IdempotencyKey is read from transport metadata, not from the payload,
+	// and is left out of the request hash.
+	IdempotencyKey string `json:"-"`
 }
 
 type OrderOutputDTO struct {
-	ID         string  `json:"id"`
-	Price      float64 `json:"price"`
-	Tax        float64 `json:"tax"`
-	FinalPrice float64 `json:"final_price"`
+	ID         string             `json:"id"`
+	Price      float64            `json:"price"`
+	Tax        float64            `json:"tax"`
+	FinalPrice float64            `json:"final_price"`
+	Status     string             `json:"status"`
+	CreatedAt  time.Time          `json:"created_at"`
+	CreatedBy  string             `json:"created_by"`
+	Category   string             `json:"category"`
+	Region     string             `json:"region"`
+	TaxLines   []TaxLineOutputDTO `json:"tax_lines"`
+	CouponCode string             `json:"coupon_code"`
+	Discount   float64            `json:"discount"`
+	// Replayed is set when the output is the stored response of an earlier
+	// request with the same idempotency key.
+	Replayed bool `json:"-"`
+}
+
+type TaxLineOutputDTO struct {
+	Name     string  `json:"name"`
+	Rate     float64 `json:"rate"`
+	Compound bool    `json:"compound"`
+	Amount   float64 `json:"amount"`
 }
 
 type CreateOrderUseCase struct {
-	OrderRepository entity.OrderRepositoryInterface
-	OrderCreated    events.EventInterface
-	EventDispatcher events.EventDispatcherInterface
+	OrderRepository       entity.OrderRepositoryInterface
+	IdempotencyRepository entity.IdempotencyRepositoryInterface
+	EventDispatcher       events.EventDispatcherInterface
+	// TaxEngine works out the tax of new orders; without one the caller's
+	// tax is taken as given.
+	TaxEngine *entity.TaxEngine
+	// CouponRepository redeems the coupons of new orders; without one no
+	// coupon is found.
+	CouponRepository entity.CouponRepositoryInterface
 }
 
 func NewCreateOrderUseCase(
 	OrderRepository entity.OrderRepositoryInterface,
-	OrderCreated events.EventInterface,
+	IdempotencyRepository entity.IdempotencyRepositoryInterface,
 	EventDispatcher events.EventDispatcherInterface,
+	TaxEngine *entity.TaxEngine,
+	CouponRepository entity.CouponRepositoryInterface,
 ) *CreateOrderUseCase {
 	return &CreateOrderUseCase{
-		OrderRepository: OrderRepository,
-		OrderCreated:    OrderCreated,
-		EventDispatcher: EventDispatcher,
+		OrderRepository:       OrderRepository,
+		IdempotencyRepository: IdempotencyRepository,
+		EventDispatcher:       EventDispatcher,
+		TaxEngine:             TaxEngine,
+		CouponRepository:      CouponRepository,
+	}
+}
+
+// Execute creates the order. When the input carries an idempotency key, a
+// retry with the same payload returns the first response without creating
+// or announcing the order again, and a retry with a different payload fails
+// with entity.ErrIdempotencyKeyReused. A retry while the first request
+// still holds the key's lease fails with entity.ErrIdempotencyKeyInProgress;
+// once the lease ran out it takes the key over and returns the order the
+// first request saved, if any. The caller needs the orders:write scope and
+// is recorded as the order's creator.
+func (c *CreateOrderUseCase) Execute(ctx context.Context, input OrderInputDTO) (OrderOutputDTO, error) {
+	principal, err := Authorize(ctx, ScopeOrdersWrite)
+	if err != nil {
+		return OrderOutputDTO{}, err
+	}
+	if input.IdempotencyKey == "" || c.IdempotencyRepository == nil {
+		return c.create(ctx, input, principal.Subject)
+	}
+
+	// The creator is part of the request, so another principal reusing the
+	// key does not get this principal's response.
+	payload, err := json.Marshal(struct {
+		OrderInputDTO
+		CreatedBy string `json:"created_by"`
+	}{input, principal.Subject})
+	if err != nil {
+		return OrderOutputDTO{}, err
+	}
+	hash := sha256.Sum256(payload)
+	record, err := entity.NewIdempotencyRecord(input.IdempotencyKey, hex.EncodeToString(hash[:]))
+	if err != nil {
+		return OrderOutputDTO{}, err
+	}
+	existing, err := c.IdempotencyRepository.Reserve(ctx, record)
+	if err != nil {
+		return OrderOutputDTO{}, err
+	}
+	if existing != nil {
+		return replay(existing, record.RequestHash)
+	}
+
+	if record.Attempts > 1 {
+		// An earlier attempt lost its lease, possibly after saving the order.
+		output, found, err := c.findCreated(ctx, input.ID, principal.Subject)
+		if err != nil {
+			c.IdempotencyRepository.Release(context.Background(), record.Key)
+			return OrderOutputDTO{}, err
+		}
+		if found {
+			c.complete(ctx, record.Key, output)
+			output.Replayed = true
+			return output, nil
+		}
+	}
+	output, err := c.create(ctx, input, principal.Subject)
+	if err != nil {
+		// Let the client retry a request that did not go through.
+		c.IdempotencyRepository.Release(context.Background(), record.Key)
+		return OrderOutputDTO{}, err
+	}
+	c.complete(ctx, record.Key, output)
+	return output, nil
+}
+
+// findCreated looks up order id, reporting whether createdBy created it.
+func (c *CreateOrderUseCase) findCreated(ctx context.Context, id, createdBy string) (OrderOutputDTO, bool, error) {
+	order, err := c.OrderRepository.FindByID(ctx, id)
+	if errors.Is(err, entity.ErrOrderNotFound) {
+		return OrderOutputDTO{}, false, nil
+	}
+	if err != nil {
+		return OrderOutputDTO{}, false, err
+	}
+	if order.CreatedBy != createdBy {
+		return OrderOutputDTO{}, false, nil
+	}
+	return newOrderOutputDTO(*order), true, nil
+}
+
+// complete stores output as the response to replay for key. The order is
+// saved by then, so a failure is only logged: the key stays pending until
+// its lease runs out, and a retry then takes it over and finds the order.
+func (c *CreateOrderUseCase) complete(ctx context.Context, key string, output OrderOutputDTO) {
+	response, err := json.Marshal(output)
+	if err == nil {
+		err = c.IdempotencyRepository.Complete(context.Background(), key, response)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "completing idempotency key of saved order",
+			"idempotency_key", key, "order_id", output.ID, "error", err)
 	}
 }
 
-func (c *CreateOrderUseCase) Execute(input OrderInputDTO) (OrderOutputDTO, error) {
-	order := entity.Order{
-		ID:    input.ID,
-		Price: input.Price,
-		Tax:   input.Tax,
+func replay(record *entity.IdempotencyRecord, requestHash string) (OrderOutputDTO, error) {
+	if record.RequestHash != requestHash {
+		return OrderOutputDTO{}, entity.ErrIdempotencyKeyReused
 	}
-	order.CalculateFinalPrice()
-	if err := c.OrderRepository.Save(&order); err != nil {
+	if !record.IsCompleted() {
+		return OrderOutputDTO{}, entity.ErrIdempotencyKeyInProgress
+	}
+	var output OrderOutputDTO
+	if err := json.Unmarshal(record.Response, &output); err != nil {
 		return OrderOutputDTO{}, err
 	}
+	output.Replayed = true
+	return output, nil
+}
 
-	dto := OrderOutputDTO{
+// create prices the order, redeems its coupon and saves it. The redemption
+// is what claims the coupon, so the coupon is only checked up front to fail
+// early, and is released again if the order is not saved.
+func (c *CreateOrderUseCase) create(ctx context.Context, input OrderInputDTO, createdBy string) (OrderOutputDTO, error) {
+	price, discount := input.Price, 0.0
+	var coupon *entity.Coupon
+	if input.CouponCode != "" {
+		var err error
+		coupon, err = findCoupon(ctx, c.CouponRepository, input.CouponCode)
+		if err != nil {
+			return OrderOutputDTO{}, err
+		}
+		if err := coupon.CanRedeem(time.Now()); err != nil {
+			return OrderOutputDTO{}, err
+		}
+		price, discount = coupon.Apply(input.Price)
+	}
+	breakdown, err := priceOrder(c.TaxEngine, price, input.Tax, input.Category, input.Region)
+	if err != nil {
+		return OrderOutputDTO{}, err
+	}
+	breakdown.Discount = discount
+	order, err := entity.NewPricedOrder(input.ID, breakdown)
+	if err != nil {
+		return OrderOutputDTO{}, err
+	}
+	order.CreatedBy = createdBy
+	order.Category = input.Category
+	order.Region = input.Region
+	if err := order.CalculateFinalPrice(); err != nil {
+		return OrderOutputDTO{}, err
+	}
+	if coupon != nil {
+		order.CouponCode = coupon.Code
+		redemption := entity.NewCouponRedemption(coupon.Code, order.ID, createdBy, discount)
+		if err := c.CouponRepository.Redeem(ctx, redemption); err != nil {
+			return OrderOutputDTO{}, err
+		}
+	}
+	if err := c.OrderRepository.Save(ctx, order); err != nil {
+		if coupon != nil {
+			// A redemption left behind counts against the coupon's limits
+			// for good, so it needs someone to release it by hand.
+			if releaseErr := c.CouponRepository.Release(context.Background(), coupon.Code, order.ID); releaseErr != nil {
+				slog.ErrorContext(ctx, "releasing coupon redemption of unsaved order",
+					"coupon_code", coupon.Code, "order_id", order.ID, "error", releaseErr)
+			}
+		}
+		return OrderOutputDTO{}, err
+	}
+
+	dispatchEvents(ctx, c.EventDispatcher, order)
+	return newOrderOutputDTO(*order), nil
+}
+
+// priceOrder works out the price before tax and the taxes of an order.
+// Without a tax engine the caller's tax is taken as given; with one, a tax
+// the caller supplied must match the engine's or ErrTaxMismatch is
+// returned.
+func priceOrder(engine *entity.TaxEngine, price, tax float64, category, region string) (entity.TaxBreakdown, error) {
+	if engine == nil {
+		return entity.TaxBreakdown{Price: price, Tax: tax}, nil
+	}
+	breakdown := engine.Calculate(price, category, region)
+	if tax != 0 && !breakdown.Matches(tax) {
+		return entity.TaxBreakdown{}, fmt.Errorf("%w: expected %.2f, got %.2f", entity.ErrTaxMismatch, breakdown.Tax, tax)
+	}
+	return breakdown, nil
+}
+
+// findCoupon looks up code in coupons, which may be nil when coupons are
+// not set up.
+func findCoupon(ctx context.Context, coupons entity.CouponRepositoryInterface, code string) (*entity.Coupon, error) {
+	if coupons == nil {
+		return nil, entity.ErrCouponNotFound
+	}
+	return coupons.FindByCode(ctx, code)
+}
+
+func newOrderOutputDTO(order entity.Order) OrderOutputDTO {
+	output := OrderOutputDTO{
 		ID:         order.ID,
 		Price:      order.Price,
 		Tax:        order.Tax,
-		FinalPrice: order.Price + order.Tax,
+		FinalPrice: order.FinalPrice,
+		Status:     string(order.Status),
+		CreatedAt:  order.CreatedAt,
+		CreatedBy:  order.CreatedBy,
+		Category:   order.Category,
+		Region:     order.Region,
+		TaxLines:   []TaxLineOutputDTO{},
+		CouponCode: order.CouponCode,
+		Discount:   order.Discount,
 	}
-
-	c.OrderCreated.SetPayload(dto)
-	c.EventDispatcher.Dispatch(c.OrderCreated)
-
-	return dto, nil
+	for _, line := range order.TaxLines {
+		output.TaxLines = append(output.TaxLines, TaxLineOutputDTO{
+			Name:     line.Name,
+			Rate:     line.Rate,
+			Compound: line.Compound,
+			Amount:   line.Amount,
+		})
+	}
+	return output
 }
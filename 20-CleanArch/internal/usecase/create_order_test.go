@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+var errDatabaseDown = errors.New("database down")
+
+func TestGivenARetriedIdempotencyKey_WhenCreateOrder_ThenShouldReplayOnlyTheSameRequest(t *testing.T) {
+	first := OrderInputDTO{ID: "a", Price: 10, Tax: 1, IdempotencyKey: "key-1"}
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		retry OrderInputDTO
+		err   error
+	}{
+		{"same request", testContext(), first, nil},
+		{"another payload", testContext(), OrderInputDTO{ID: "a", Price: 20, Tax: 1, IdempotencyKey: "key-1"}, entity.ErrIdempotencyKeyReused},
+		{"another principal", contextAs("bob", ScopeOrdersWrite), first, entity.ErrIdempotencyKeyReused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher, recorder := newTestDispatcher(t)
+			createOrder := NewCreateOrderUseCase(database.NewMemoryOrderRepository(), database.NewMemoryIdempotencyRepository(time.Hour), dispatcher, nil, nil)
+			created, err := createOrder.Execute(testContext(), first)
+			assert.NoError(t, err)
+			assert.False(t, created.Replayed)
+
+			replayed, err := createOrder.Execute(tt.ctx, tt.retry)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, replayed.Replayed)
+				assert.True(t, created.CreatedAt.Equal(replayed.CreatedAt))
+				replayed.Replayed, replayed.CreatedAt = false, created.CreatedAt
+				assert.Equal(t, created, replayed)
+			}
+			assert.Equal(t, []string{entity.OrderCreatedEvent}, recorder.names())
+		})
+	}
+}
+
+// blockingOrderRepository holds every Save until release is closed.
+type blockingOrderRepository struct {
+	entity.OrderRepositoryInterface
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingOrderRepository) Save(ctx context.Context, order *entity.Order) error {
+	close(r.saving)
+	<-r.release
+	return r.OrderRepositoryInterface.Save(ctx, order)
+}
+
+func TestGivenAKeyStillInProgress_WhenCreateOrder_ThenShouldRejectTheRetry(t *testing.T) {
+	orders := &blockingOrderRepository{
+		OrderRepositoryInterface: database.NewMemoryOrderRepository(),
+		saving:                   make(chan struct{}),
+		release:                  make(chan struct{}),
+	}
+	createOrder := NewCreateOrderUseCase(orders, database.NewMemoryIdempotencyRepository(time.Hour), events.NewEventDispatcher(), nil, nil)
+	input := OrderInputDTO{ID: "a", Price: 10, Tax: 1, IdempotencyKey: "key-1"}
+	done := make(chan error)
+	go func() {
+		_, err := createOrder.Execute(testContext(), input)
+		done <- err
+	}()
+	<-orders.saving
+
+	_, err := createOrder.Execute(testContext(), input)
+	assert.ErrorIs(t, err, entity.ErrIdempotencyKeyInProgress)
+	close(orders.release)
+	assert.NoError(t, <-done)
+	replayed, err := createOrder.Execute(testContext(), input)
+	assert.NoError(t, err)
+	assert.True(t, replayed.Replayed)
+}
+
+func TestGivenAFailedCreate_WhenRetriedWithTheSameKey_ThenShouldCreateTheOrder(t *testing.T) {
+	orders := newStubOrderRepository()
+	dispatcher, recorder := newTestDispatcher(t)
+	createOrder := NewCreateOrderUseCase(orders, database.NewMemoryIdempotencyRepository(time.Hour), dispatcher, nil, nil)
+	input := OrderInputDTO{ID: "a", Price: 10, Tax: 1, IdempotencyKey: "key-1"}
+	orders.failSaves(errDatabaseDown)
+
+	_, err := createOrder.Execute(testContext(), input)
+	assert.ErrorIs(t, err, errDatabaseDown)
+	orders.failSaves(nil)
+	output, err := createOrder.Execute(testContext(), input)
+	assert.NoError(t, err)
+	assert.False(t, output.Replayed)
+	assert.Equal(t, 11.0, output.FinalPrice)
+	assert.Equal(t, []string{entity.OrderCreatedEvent}, recorder.names())
+}
+
+// leaselessIdempotencyRepository reserves keys with a lease that has run
+// out at once, and fails every Complete, like a request that died after
+// saving its order.
+type leaselessIdempotencyRepository struct {
+	entity.IdempotencyRepositoryInterface
+}
+
+func (r *leaselessIdempotencyRepository) Reserve(ctx context.Context, record *entity.IdempotencyRecord) (*entity.IdempotencyRecord, error) {
+	record.LockedUntil = record.CreatedAt
+	return r.IdempotencyRepositoryInterface.Reserve(ctx, record)
+}
+
+func (r *leaselessIdempotencyRepository) Complete(ctx context.Context, key string, response []byte) error {
+	return errDatabaseDown
+}
+
+func TestGivenASavedOrderWhoseKeyWasNotCompleted_WhenRetriedAfterTheLease_ThenShouldReturnTheSavedOrder(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+	orders := database.NewMemoryOrderRepository()
+	dispatcher, recorder := newTestDispatcher(t)
+	createOrder := NewCreateOrderUseCase(orders, &leaselessIdempotencyRepository{database.NewMemoryIdempotencyRepository(time.Hour)}, dispatcher, nil, nil)
+	input := OrderInputDTO{ID: "a", Price: 10, Tax: 1, IdempotencyKey: "key-1"}
+
+	created, err := createOrder.Execute(testContext(), input)
+	assert.NoError(t, err)
+	assert.False(t, created.Replayed)
+	assert.Contains(t, logs.String(), "idempotency_key=key-1")
+	assert.Contains(t, logs.String(), errDatabaseDown.Error())
+
+	retried, err := createOrder.Execute(testContext(), input)
+	assert.NoError(t, err)
+	assert.True(t, retried.Replayed)
+	assert.Equal(t, created.ID, retried.ID)
+	assert.Equal(t, created.FinalPrice, retried.FinalPrice)
+	assert.Equal(t, []string{entity.OrderCreatedEvent}, recorder.names())
+
+	_, err = createOrder.Execute(contextAs("bob", ScopeOrdersWrite), input)
+	assert.ErrorIs(t, err, entity.ErrIdempotencyKeyReused)
+}
+
+// saveTestCoupon stores a 10% coupon that can be redeemed once.
+func saveTestCoupon(t *testing.T, coupons entity.CouponRepositoryInterface) {
+	coupon, err := entity.NewCoupon("SUMMER10", entity.DiscountPercentage, 10, time.Time{}, time.Time{}, 1, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, coupons.Save(context.Background(), coupon))
+}
+
+func couponRedemptions(t *testing.T, coupons entity.CouponRepositoryInterface) int {
+	coupon, err := coupons.FindByCode(context.Background(), "SUMMER10")
+	assert.NoError(t, err)
+	return coupon.Redemptions
+}
+
+func TestGivenACouponAndAnOrderThatIsNotSaved_WhenCreateOrder_ThenShouldReleaseTheRedemption(t *testing.T) {
+	tests := []struct {
+		name    string
+		saveErr error
+	}{
+		{"database down", errDatabaseDown},
+		{"duplicate order", entity.ErrOrderAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, coupons := newStubOrderRepository(), database.NewMemoryCouponRepository()
+			dispatcher, recorder := newTestDispatcher(t)
+			createOrder := NewCreateOrderUseCase(orders, database.NewMemoryIdempotencyRepository(time.Hour), dispatcher, nil, coupons)
+			saveTestCoupon(t, coupons)
+			orders.failSaves(tt.saveErr)
+
+			_, err := createOrder.Execute(testContext(), OrderInputDTO{ID: "a", Price: 100, Tax: 9, CouponCode: "summer10"})
+			assert.ErrorIs(t, err, tt.saveErr)
+			assert.Equal(t, 0, couponRedemptions(t, coupons))
+			assert.Empty(t, recorder.names())
+
+			// The coupon's only redemption is still there to take.
+			orders.failSaves(nil)
+			output, err := createOrder.Execute(testContext(), OrderInputDTO{ID: "b", Price: 100, Tax: 9, CouponCode: "summer10"})
+			assert.NoError(t, err)
+			assert.Equal(t, 10.0, output.Discount)
+			assert.Equal(t, 99.0, output.FinalPrice)
+			assert.Equal(t, 1, couponRedemptions(t, coupons))
+		})
+	}
+}
+
+// unreleasableCouponRepository fails every Release.
+type unreleasableCouponRepository struct {
+	entity.CouponRepositoryInterface
+}
+
+func (r *unreleasableCouponRepository) Release(ctx context.Context, code string, orderID string) error {
+	return errDatabaseDown
+}
+
+func TestGivenARedemptionThatCannotBeReleased_WhenCreateOrderFails_ThenShouldLogIt(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+	orders, coupons := newStubOrderRepository(), database.NewMemoryCouponRepository()
+	createOrder := NewCreateOrderUseCase(orders, database.NewMemoryIdempotencyRepository(time.Hour), events.NewEventDispatcher(), nil, &unreleasableCouponRepository{coupons})
+	saveTestCoupon(t, coupons)
+	orders.failSaves(entity.ErrOrderAlreadyExists)
+
+	_, err := createOrder.Execute(testContext(), OrderInputDTO{ID: "a", Price: 100, Tax: 9, CouponCode: "SUMMER10"})
+
+	assert.ErrorIs(t, err, entity.ErrOrderAlreadyExists)
+	assert.Equal(t, 1, couponRedemptions(t, coupons))
+	assert.Contains(t, logs.String(), "coupon_code=SUMMER10")
+	assert.Contains(t, logs.String(), "order_id=a")
+	assert.Contains(t, logs.String(), errDatabaseDown.Error())
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+// EnqueueWebhookDeliveriesInputDTO is an event to send to the webhooks
+// subscribed to it. Payload is the request body, the same for every
+// subscription.
+type EnqueueWebhookDeliveriesInputDTO struct {
+	EventID string
+	Event   string
+	Payload []byte
+}
+
+// EnqueueWebhookDeliveriesUseCase records a pending delivery of an event to
+// each active subscription to it. Enqueuing an event again adds nothing, so
+// the dispatcher may retry it.
+type EnqueueWebhookDeliveriesUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+}
+
+func NewEnqueueWebhookDeliveriesUseCase(WebhookRepository entity.WebhookRepositoryInterface) *EnqueueWebhookDeliveriesUseCase {
+	return &EnqueueWebhookDeliveriesUseCase{
+		WebhookRepository: WebhookRepository,
+	}
+}
+
+// Execute returns how many subscriptions the event is for.
+func (e *EnqueueWebhookDeliveriesUseCase) Execute(ctx context.Context, input EnqueueWebhookDeliveriesInputDTO) (int, error) {
+	subscriptions, err := e.WebhookRepository.ListSubscriptions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deliveries := []*entity.WebhookDelivery{}
+	for _, subscription := range subscriptions {
+		if subscription.Active && subscription.Subscribes(input.Event) {
+			deliveries = append(deliveries, entity.NewWebhookDelivery(events.NewEventID(), subscription.ID, input.EventID, input.Event, input.Payload))
+		}
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+	return len(deliveries), e.WebhookRepository.SaveDeliveries(ctx, deliveries)
+}
+
+// WebhookSenderInterface makes one attempt at a delivery. Send returns the
+// response's status code, 0 when no response came back, and an error unless
+// the subscriber accepted the delivery.
+type WebhookSenderInterface interface {
+	Send(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) (int, error)
+}
+
+// DefaultWebhookBatchSize is the batch size of deliveries when none is set.
+const DefaultWebhookBatchSize = 50
+
+// DeliverWebhooksUseCase sends the deliveries that are due, BatchSize at a
+// time and concurrently. Each is claimed for Lease, which must outlast an
+// attempt, and retried with RetryPolicy's backoff until it succeeds or
+// runs out of attempts. Deliveries to deleted or inactive subscriptions
+// fail right away.
+type DeliverWebhooksUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+	Sender            WebhookSenderInterface
+	RetryPolicy       events.RetryPolicy
+	Lease             time.Duration
+	BatchSize         int
+}
+
+func NewDeliverWebhooksUseCase(WebhookRepository entity.WebhookRepositoryInterface, Sender WebhookSenderInterface, RetryPolicy events.RetryPolicy, Lease time.Duration, BatchSize int) *DeliverWebhooksUseCase {
+	return &DeliverWebhooksUseCase{
+		WebhookRepository: WebhookRepository,
+		Sender:            Sender,
+		RetryPolicy:       RetryPolicy,
+		Lease:             Lease,
+		BatchSize:         BatchSize,
+	}
+}
+
+// Execute sends one batch and returns its size; a full batch means more
+// deliveries may be due. An attempt cut short by ctx is not recorded and
+// is made again once its lease runs out.
+func (d *DeliverWebhooksUseCase) Execute(ctx context.Context) (int, error) {
+	batchSize := d.BatchSize
+	if batchSize < 1 {
+		batchSize = DefaultWebhookBatchSize
+	}
+	deliveries, err := d.WebhookRepository.ClaimDueDeliveries(ctx, time.Now(), batchSize, d.Lease)
+	if err != nil {
+		return 0, err
+	}
+	subscriptions := map[string]*entity.WebhookSubscription{}
+	for _, delivery := range deliveries {
+		if _, ok := subscriptions[delivery.SubscriptionID]; ok {
+			continue
+		}
+		subscription, err := d.WebhookRepository.FindSubscription(ctx, delivery.SubscriptionID)
+		if err != nil && !errors.Is(err, entity.ErrWebhookNotFound) {
+			return 0, err
+		}
+		subscriptions[delivery.SubscriptionID] = subscription
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(deliveries))
+	for i, delivery := range deliveries {
+		wg.Add(1)
+		go func(i int, delivery *entity.WebhookDelivery) {
+			defer wg.Done()
+			errs[i] = d.deliver(ctx, subscriptions[delivery.SubscriptionID], delivery)
+		}(i, delivery)
+	}
+	wg.Wait()
+	return len(deliveries), errors.Join(errs...)
+}
+
+func (d *DeliverWebhooksUseCase) deliver(ctx context.Context, subscription *entity.WebhookSubscription, delivery *entity.WebhookDelivery) error {
+	if subscription == nil || !subscription.Active {
+		delivery.Fail(time.Now(), 0, "webhook deleted or inactive", time.Time{})
+	} else {
+		status, err := d.Sender.Send(ctx, subscription, delivery)
+		if ctx.Err() != nil {
+			return nil
+		}
+		at := time.Now()
+		if err == nil {
+			delivery.Succeed(at, status)
+		} else {
+			var retryAt time.Time
+			if attempt := delivery.Attempts + 1; attempt < d.RetryPolicy.MaxAttempts {
+				retryAt = at.Add(d.RetryPolicy.Backoff(attempt))
+			}
+			delivery.Fail(at, status, err.Error(), retryAt)
+		}
+	}
+	err := d.WebhookRepository.UpdateDelivery(ctx, delivery)
+	// A delivery goes away with its subscription.
+	if err != nil && !errors.Is(err, entity.ErrWebhookDeliveryNotFound) {
+		return fmt.Errorf("recording delivery %s: %w", delivery.ID, err)
+	}
+	return nil
+}
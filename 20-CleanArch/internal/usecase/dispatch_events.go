@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"golang.org/x/exp/slog"
+)
+
+// dispatchEvents announces the changes order recorded, once they are
+// persisted. The change already happened, so handler failures are left to
+// the dispatcher's retries and dead letters instead of failing the request.
+func dispatchEvents(ctx context.Context, dispatcher events.EventDispatcherInterface, order *entity.Order) {
+	for _, domainEvent := range order.PullEvents() {
+		ev, err := event.FromDomain(ctx, domainEvent, *order)
+		if err != nil {
+			slog.WarnContext(ctx, "skipping event", "error", err)
+			continue
+		}
+		dispatcher.Dispatch(ctx, ev)
+	}
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// FindOrderInvoicesUseCase looks up the invoices of several orders at once.
+type FindOrderInvoicesUseCase struct {
+	InvoiceRepository entity.InvoiceRepositoryInterface
+}
+
+func NewFindOrderInvoicesUseCase(InvoiceRepository entity.InvoiceRepositoryInterface) *FindOrderInvoicesUseCase {
+	return &FindOrderInvoicesUseCase{
+		InvoiceRepository: InvoiceRepository,
+	}
+}
+
+// Execute returns the invoices by order ID; orders not invoiced yet have no
+// entry.
+func (f *FindOrderInvoicesUseCase) Execute(ctx context.Context, orderIDs []string) (map[string]InvoiceOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeOrdersRead); err != nil {
+		return nil, err
+	}
+	invoices, err := f.InvoiceRepository.FindByOrderIDs(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	output := make(map[string]InvoiceOutputDTO, len(invoices))
+	for _, invoice := range invoices {
+		output[invoice.OrderID] = newInvoiceOutputDTO(invoice)
+	}
+	return output, nil
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// InvoiceInputDTO matches the OrderOutputDTO carried by OrderCreated.
+type InvoiceInputDTO struct {
+	OrderID string  `json:"id"`
+	Price   float64 `json:"price"`
+	Tax     float64 `json:"tax"`
+}
+
+type InvoiceOutputDTO struct {
+	ID       string    `json:"id"`
+	OrderID  string    `json:"order_id"`
+	Amount   float64   `json:"amount"`
+	Tax      float64   `json:"tax"`
+	Total    float64   `json:"total"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+type GenerateInvoiceUseCase struct {
+	InvoiceRepository entity.InvoiceRepositoryInterface
+}
+
+func NewGenerateInvoiceUseCase(InvoiceRepository entity.InvoiceRepositoryInterface) *GenerateInvoiceUseCase {
+	return &GenerateInvoiceUseCase{
+		InvoiceRepository: InvoiceRepository,
+	}
+}
+
+// Execute issues the invoice for an order. Running it again for the same
+// order returns the invoice already issued, so redelivered events are safe.
+func (g *GenerateInvoiceUseCase) Execute(ctx context.Context, input InvoiceInputDTO) (InvoiceOutputDTO, error) {
+	invoice, err := entity.NewInvoice(input.OrderID, input.Price, input.Tax)
+	if err != nil {
+		return InvoiceOutputDTO{}, err
+	}
+	err = g.InvoiceRepository.Save(ctx, invoice)
+	if errors.Is(err, entity.ErrInvoiceAlreadyExists) {
+		invoice, err = g.InvoiceRepository.FindByOrderID(ctx, input.OrderID)
+	}
+	if err != nil {
+		return InvoiceOutputDTO{}, err
+	}
+	return newInvoiceOutputDTO(invoice), nil
+}
+
+func newInvoiceOutputDTO(invoice *entity.Invoice) InvoiceOutputDTO {
+	return InvoiceOutputDTO{
+		ID:       invoice.ID,
+		OrderID:  invoice.OrderID,
+		Amount:   invoice.Amount,
+		Tax:      invoice.Tax,
+		Total:    invoice.Total,
+		IssuedAt: invoice.IssuedAt,
+	}
+}
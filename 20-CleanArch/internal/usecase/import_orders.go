@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ImportRow is one row of an import: the order it describes, or the reason
+// it could not be read.
+type ImportRow struct {
+	// Line is where the row starts in the source, counting from one.
+	Line  int
+	Order OrderInputDTO
+	Err   error
+}
+
+// ImportRowReader yields the rows of an import in order. Next returns
+// io.EOF after the last row; any other error ends the import, while a row
+// that cannot be read is returned with its Err set.
+type ImportRowReader interface {
+	Next() (ImportRow, error)
+}
+
+const (
+	ImportRowCreated = "created"
+	ImportRowFailed  = "failed"
+)
+
+type ImportRowResultDTO struct {
+	Line   int    `json:"line"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportSummaryDTO struct {
+	Rows    int `json:"rows"`
+	Created int `json:"created"`
+	Failed  int `json:"failed"`
+}
+
+// DefaultImportBatchSize is the batch size of imports that set none.
+const DefaultImportBatchSize = 100
+
+// ImportOrdersUseCase creates orders from a stream of rows, BatchSize rows
+// at a time. The rows of a batch are created concurrently through
+// CreateOrderUseCase, so each one is priced, discounted and announced like
+// an order created through the APIs.
+type ImportOrdersUseCase struct {
+	CreateOrderUseCase *CreateOrderUseCase
+	BatchSize          int
+}
+
+func NewImportOrdersUseCase(CreateOrderUseCase *CreateOrderUseCase, BatchSize int) *ImportOrdersUseCase {
+	return &ImportOrdersUseCase{
+		CreateOrderUseCase: CreateOrderUseCase,
+		BatchSize:          BatchSize,
+	}
+}
+
+// Execute imports every row of rows and hands the result of each one to
+// report, in the order of the rows. A row that fails does not stop the
+// import; a read or report error, or ctx being done, does, after the batch
+// in flight. Only one batch is held in memory at a time.
+func (i *ImportOrdersUseCase) Execute(ctx context.Context, rows ImportRowReader, report func(ImportRowResultDTO) error) (ImportSummaryDTO, error) {
+	if _, err := Authorize(ctx, ScopeOrdersWrite); err != nil {
+		return ImportSummaryDTO{}, err
+	}
+	batchSize := i.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+
+	var summary ImportSummaryDTO
+	batch := make([]ImportRow, 0, batchSize)
+	for {
+		batch = batch[:0]
+		var readErr error
+		for len(batch) < batchSize {
+			row, err := rows.Next()
+			if err != nil {
+				readErr = err
+				break
+			}
+			batch = append(batch, row)
+		}
+
+		for _, result := range i.importBatch(ctx, batch) {
+			summary.Rows++
+			if result.Status == ImportRowCreated {
+				summary.Created++
+			} else {
+				summary.Failed++
+			}
+			if err := report(result); err != nil {
+				return summary, err
+			}
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return summary, nil
+			}
+			return summary, readErr
+		}
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+	}
+}
+
+func (i *ImportOrdersUseCase) importBatch(ctx context.Context, batch []ImportRow) []ImportRowResultDTO {
+	results := make([]ImportRowResultDTO, len(batch))
+	var wg sync.WaitGroup
+	for n, row := range batch {
+		results[n] = ImportRowResultDTO{Line: row.Line, ID: row.Order.ID, Status: ImportRowFailed}
+		if row.Err != nil {
+			results[n].Error = row.Err.Error()
+			continue
+		}
+		wg.Add(1)
+		go func(result *ImportRowResultDTO, input OrderInputDTO) {
+			defer wg.Done()
+			if _, err := i.CreateOrderUseCase.Execute(ctx, input); err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Status = ImportRowCreated
+		}(&results[n], row.Order)
+	}
+	wg.Wait()
+	return results
+}
@@ -1,16 +1,50 @@
 package usecase
 
 import (
+	"context"
+	"time"
+
 	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
 )
 
 type ListOrdersInputDTO struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
+	Limit         int        `json:"limit"`
+	After         string     `json:"after"`
+	Status        string     `json:"status"`
+	MinPrice      *float64   `json:"min_price"`
+	MaxPrice      *float64   `json:"max_price"`
+	CreatedFrom   *time.Time `json:"created_from"`
+	CreatedTo     *time.Time `json:"created_to"`
+	SortBy        string     `json:"sort_by"`
+	SortDirection string     `json:"sort_direction"`
+}
+
+func (input ListOrdersInputDTO) filter() entity.OrderFilter {
+	return entity.OrderFilter{
+		Status:      entity.OrderStatus(input.Status),
+		MinPrice:    input.MinPrice,
+		MaxPrice:    input.MaxPrice,
+		CreatedFrom: input.CreatedFrom,
+		CreatedTo:   input.CreatedTo,
+	}
+}
+
+type OrderEdgeDTO struct {
+	Cursor string         `json:"cursor"`
+	Node   OrderOutputDTO `json:"node"`
+}
+
+type PageInfoDTO struct {
+	HasNextPage     bool   `json:"has_next_page"`
+	HasPreviousPage bool   `json:"has_previous_page"`
+	StartCursor     string `json:"start_cursor,omitempty"`
+	EndCursor       string `json:"end_cursor,omitempty"`
 }
 
 type ListOrdersOutputDTO struct {
-	Orders []OrderOutputDTO `json:"orders"`
+	Edges      []OrderEdgeDTO `json:"edges"`
+	PageInfo   PageInfoDTO    `json:"page_info"`
+	TotalCount int            `json:"total_count"`
 }
 
 type ListOrdersUseCase struct {
@@ -23,26 +57,42 @@ func NewListOrdersUseCase(OrderRepository entity.OrderRepositoryInterface) *List
 	}
 }
 
-func (lo *ListOrdersUseCase) Execute(input ListOrdersInputDTO) (ListOrdersOutputDTO, error) {
-	listOrders, err := entity.NewListOrders(input.Page, input.Limit)
+func (lo *ListOrdersUseCase) Execute(ctx context.Context, input ListOrdersInputDTO) (ListOrdersOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeOrdersRead); err != nil {
+		return ListOrdersOutputDTO{}, err
+	}
+	sort := entity.OrderSort{
+		Field:     entity.OrderSortField(input.SortBy),
+		Direction: entity.SortDirection(input.SortDirection),
+	}
+	listOrders, err := entity.NewListOrders(input.Limit, input.After, input.filter(), sort)
 	if err != nil {
 		return ListOrdersOutputDTO{}, err
 	}
 
-	orders, err := lo.OrderRepository.GetOrders(listOrders)
+	page, err := lo.OrderRepository.GetOrders(ctx, listOrders)
 	if err != nil {
 		return ListOrdersOutputDTO{}, err
 	}
 
-	dto := ListOrdersOutputDTO{}
-	for _, order := range orders {
-		dto.Orders = append(dto.Orders, OrderOutputDTO{
-			ID:         order.ID,
-			Price:      order.Price,
-			Tax:        order.Tax,
-			FinalPrice: order.FinalPrice,
+	dto := ListOrdersOutputDTO{
+		Edges:      []OrderEdgeDTO{},
+		TotalCount: page.TotalCount,
+		PageInfo: PageInfoDTO{
+			HasNextPage:     page.HasNextPage,
+			HasPreviousPage: listOrders.After != nil,
+		},
+	}
+	for _, order := range page.Orders {
+		dto.Edges = append(dto.Edges, OrderEdgeDTO{
+			Cursor: entity.NewOrderCursor(order, listOrders.Sort.Field).Encode(),
+			Node:   newOrderOutputDTO(order),
 		})
 	}
+	if len(dto.Edges) > 0 {
+		dto.PageInfo.StartCursor = dto.Edges[0].Cursor
+		dto.PageInfo.EndCursor = dto.Edges[len(dto.Edges)-1].Cursor
+	}
 
 	return dto, nil
 }
@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+)
+
+// OrderReportEventInputDTO is an order event as the reports need it. Price,
+// Tax, FinalPrice and Discount are the order's after the event, and only
+// carried by OrderCreated and OrderUpdated; CreatedAt only by OrderCreated.
+type OrderReportEventInputDTO struct {
+	EventID    string
+	Event      string
+	OrderID    string
+	OccurredAt time.Time
+	CreatedAt  time.Time
+	Price      float64
+	Tax        float64
+	FinalPrice float64
+	Discount   float64
+}
+
+// ProjectOrderReportUseCase keeps the reports up to date with the order
+// events, applying each event once.
+type ProjectOrderReportUseCase struct {
+	OrderReportRepository entity.OrderReportRepositoryInterface
+}
+
+func NewProjectOrderReportUseCase(OrderReportRepository entity.OrderReportRepositoryInterface) *ProjectOrderReportUseCase {
+	return &ProjectOrderReportUseCase{
+		OrderReportRepository: OrderReportRepository,
+	}
+}
+
+// Execute returns entity.ErrReportOrderNotFound for a change to an order
+// the reports have not seen created yet, for the dispatcher to retry once
+// the creation has been projected.
+func (p *ProjectOrderReportUseCase) Execute(ctx context.Context, input OrderReportEventInputDTO) error {
+	return p.OrderReportRepository.Project(ctx, input.EventID, input.OrderID, func(order *entity.ReportOrder) (*entity.ReportOrder, entity.OrderStats, error) {
+		if input.Event == entity.OrderCreatedEvent {
+			if order != nil {
+				// Created under another event ID, by a replay from the
+				// broker; it was counted then.
+				return order, entity.OrderStats{Day: order.CreatedOn}, nil
+			}
+			created := &entity.ReportOrder{
+				ID:         input.OrderID,
+				CreatedOn:  entity.ReportDay(input.CreatedAt),
+				Price:      input.Price,
+				Tax:        input.Tax,
+				FinalPrice: input.FinalPrice,
+				Discount:   input.Discount,
+				Status:     entity.OrderStatusPending,
+			}
+			return created, entity.OrderStats{
+				Day:           created.CreatedOn,
+				OrdersCreated: 1,
+				GrossSales:    created.FinalPrice,
+				NetSales:      created.Price,
+				TaxBilled:     created.Tax,
+				Discounts:     created.Discount,
+			}, nil
+		}
+		if order == nil {
+			return nil, entity.OrderStats{}, fmt.Errorf("%w: %s of order %s", entity.ErrReportOrderNotFound, input.Event, input.OrderID)
+		}
+		next := *order
+		stats := entity.OrderStats{Day: input.OccurredAt}
+		switch input.Event {
+		case entity.OrderUpdatedEvent:
+			// The order's sales move with it, on the day it was created.
+			next.Price, next.Tax, next.FinalPrice, next.Discount = input.Price, input.Tax, input.FinalPrice, input.Discount
+			stats = entity.OrderStats{
+				Day:        order.CreatedOn,
+				GrossSales: next.FinalPrice - order.FinalPrice,
+				NetSales:   next.Price - order.Price,
+				TaxBilled:  next.Tax - order.Tax,
+				Discounts:  next.Discount - order.Discount,
+			}
+		case entity.OrderPaidEvent:
+			next.Status = entity.OrderStatusPaid
+			stats.OrdersPaid, stats.PaidRevenue, stats.PaidTax = 1, order.FinalPrice, order.Tax
+		case entity.OrderCancelledEvent:
+			next.Status = entity.OrderStatusCancelled
+			stats.OrdersCancelled = 1
+		case entity.OrderRefundedEvent:
+			next.Status = entity.OrderStatusRefunded
+			stats.OrdersRefunded, stats.RefundedAmount, stats.RefundedTax = 1, order.FinalPrice, order.Tax
+		default:
+			return nil, entity.OrderStats{}, fmt.Errorf("unsupported order event %s", input.Event)
+		}
+		return &next, stats, nil
+	})
+}
+
+// OrderReportInputDTO asks for the report of [From, To), widened to whole
+// periods of Granularity: day, the default, week or month. Days are UTC
+// days.
+type OrderReportInputDTO struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+}
+
+// OrderReportPeriodDTO sums a period. Orders count towards the sales of the
+// period they were created in, and towards the payments, cancellations and
+// refunds of the period those happened in.
+type OrderReportPeriodDTO struct {
+	Start             time.Time `json:"start"`
+	End               time.Time `json:"end"`
+	Orders            int       `json:"orders"`
+	GrossSales        float64   `json:"gross_sales"`
+	NetSales          float64   `json:"net_sales"`
+	TaxBilled         float64   `json:"tax_billed"`
+	Discounts         float64   `json:"discounts"`
+	AverageOrderValue float64   `json:"average_order_value"`
+	OrdersPaid        int       `json:"orders_paid"`
+	PaidRevenue       float64   `json:"paid_revenue"`
+	OrdersCancelled   int       `json:"orders_cancelled"`
+	OrdersRefunded    int       `json:"orders_refunded"`
+	RefundedAmount    float64   `json:"refunded_amount"`
+	NetRevenue        float64   `json:"net_revenue"`
+	TaxCollected      float64   `json:"tax_collected"`
+}
+
+func newOrderReportPeriodDTO(stats entity.OrderStats, end time.Time) OrderReportPeriodDTO {
+	return OrderReportPeriodDTO{
+		Start:             stats.Day,
+		End:               end,
+		Orders:            stats.OrdersCreated,
+		GrossSales:        stats.GrossSales,
+		NetSales:          stats.NetSales,
+		TaxBilled:         stats.TaxBilled,
+		Discounts:         stats.Discounts,
+		AverageOrderValue: stats.AverageOrderValue(),
+		OrdersPaid:        stats.OrdersPaid,
+		PaidRevenue:       stats.PaidRevenue,
+		OrdersCancelled:   stats.OrdersCancelled,
+		OrdersRefunded:    stats.OrdersRefunded,
+		RefundedAmount:    stats.RefundedAmount,
+		NetRevenue:        stats.NetRevenue(),
+		TaxCollected:      stats.TaxCollected(),
+	}
+}
+
+type OrderReportOutputDTO struct {
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	Granularity string                 `json:"granularity"`
+	Periods     []OrderReportPeriodDTO `json:"periods"`
+	Total       OrderReportPeriodDTO   `json:"total"`
+}
+
+// GetOrderReportUseCase reports the order totals per period from the
+// projection kept by ProjectOrderReportUseCase. It needs the reports:read
+// scope.
+type GetOrderReportUseCase struct {
+	OrderReportRepository entity.OrderReportRepositoryInterface
+}
+
+func NewGetOrderReportUseCase(OrderReportRepository entity.OrderReportRepositoryInterface) *GetOrderReportUseCase {
+	return &GetOrderReportUseCase{
+		OrderReportRepository: OrderReportRepository,
+	}
+}
+
+func (g *GetOrderReportUseCase) Execute(ctx context.Context, input OrderReportInputDTO) (OrderReportOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeReportsRead); err != nil {
+		return OrderReportOutputDTO{}, err
+	}
+	reportRange, err := entity.NewReportRange(input.From, input.To, entity.ReportGranularity(input.Granularity))
+	if err != nil {
+		return OrderReportOutputDTO{}, err
+	}
+	days, err := g.OrderReportRepository.Daily(ctx, reportRange.From, reportRange.To)
+	if err != nil {
+		return OrderReportOutputDTO{}, err
+	}
+	output := OrderReportOutputDTO{
+		From:        reportRange.From,
+		To:          reportRange.To,
+		Granularity: string(reportRange.Granularity),
+		Periods:     []OrderReportPeriodDTO{},
+	}
+	total := entity.OrderStats{Day: reportRange.From}
+	for _, period := range reportRange.Periods(days) {
+		output.Periods = append(output.Periods, newOrderReportPeriodDTO(period, reportRange.Granularity.NextPeriod(period.Day)))
+		total.Add(period)
+	}
+	output.Total = newOrderReportPeriodDTO(total, reportRange.To)
+	return output, nil
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+var reportDay = time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+
+func orderCreatedReportEvent(eventID string) OrderReportEventInputDTO {
+	return OrderReportEventInputDTO{
+		EventID: eventID, Event: entity.OrderCreatedEvent, OrderID: "a", OccurredAt: reportDay, CreatedAt: reportDay,
+		Price: 90, Tax: 9, FinalPrice: 99, Discount: 10,
+	}
+}
+
+func orderPaidReportEvent(eventID string) OrderReportEventInputDTO {
+	return OrderReportEventInputDTO{EventID: eventID, Event: entity.OrderPaidEvent, OrderID: "a", OccurredAt: reportDay.AddDate(0, 0, 1)}
+}
+
+// twoDayReport reports the day of reportDay and the next one.
+func twoDayReport(repository entity.OrderReportRepositoryInterface) (OrderReportOutputDTO, error) {
+	from := entity.ReportDay(reportDay)
+	return NewGetOrderReportUseCase(repository).Execute(contextAs("alice", ScopeReportsRead), OrderReportInputDTO{
+		From: from, To: from.AddDate(0, 0, 2),
+	})
+}
+
+func TestGivenReplayedOrReorderedEvents_WhenProjectOrderReport_ThenShouldCountEachChangeOnce(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []OrderReportEventInputDTO
+		// notProjected are the indexes of the events that must come back
+		// for a retry.
+		notProjected []int
+	}{
+		{"in order", []OrderReportEventInputDTO{orderCreatedReportEvent("e-1"), orderPaidReportEvent("e-2")}, nil},
+		{"redelivered", []OrderReportEventInputDTO{
+			orderCreatedReportEvent("e-1"), orderCreatedReportEvent("e-1"), orderPaidReportEvent("e-2"), orderPaidReportEvent("e-2"),
+		}, nil},
+		{"created replayed under a new ID", []OrderReportEventInputDTO{
+			orderCreatedReportEvent("e-1"), orderPaidReportEvent("e-2"), orderCreatedReportEvent("e-3"),
+		}, nil},
+		{"paid before created, then retried", []OrderReportEventInputDTO{
+			orderPaidReportEvent("e-2"), orderCreatedReportEvent("e-1"), orderPaidReportEvent("e-2"),
+		}, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := database.NewMemoryOrderReportRepository()
+			project := NewProjectOrderReportUseCase(repository)
+
+			for i, event := range tt.events {
+				err := project.Execute(context.Background(), event)
+				if len(tt.notProjected) > 0 && tt.notProjected[0] == i {
+					tt.notProjected = tt.notProjected[1:]
+					assert.ErrorIs(t, err, entity.ErrReportOrderNotFound)
+					continue
+				}
+				assert.NoError(t, err)
+			}
+
+			report, err := twoDayReport(repository)
+			assert.NoError(t, err)
+			assert.Len(t, report.Periods, 2)
+			assert.Equal(t, 1, report.Periods[0].Orders)
+			assert.Equal(t, 99.0, report.Periods[0].GrossSales)
+			assert.Equal(t, 10.0, report.Periods[0].Discounts)
+			assert.Equal(t, 0, report.Periods[0].OrdersPaid)
+			assert.Equal(t, 1, report.Periods[1].OrdersPaid)
+			assert.Equal(t, 99.0, report.Periods[1].PaidRevenue)
+			assert.Equal(t, 1, report.Total.Orders)
+			assert.Equal(t, 1, report.Total.OrdersPaid)
+		})
+	}
+}
+
+func TestGivenAnOrderUpdatedTheNextDay_WhenProjectOrderReport_ThenShouldMoveTheSalesOfTheCreationDay(t *testing.T) {
+	repository := database.NewMemoryOrderReportRepository()
+	project := NewProjectOrderReportUseCase(repository)
+	assert.NoError(t, project.Execute(context.Background(), orderCreatedReportEvent("e-1")))
+	assert.NoError(t, project.Execute(context.Background(), OrderReportEventInputDTO{
+		EventID: "e-2", Event: entity.OrderUpdatedEvent, OrderID: "a", OccurredAt: reportDay.AddDate(0, 0, 1),
+		Price: 180, Tax: 18, FinalPrice: 198, Discount: 20,
+	}))
+
+	report, err := twoDayReport(repository)
+	assert.NoError(t, err)
+	assert.Len(t, report.Periods, 2)
+	assert.Equal(t, 198.0, report.Periods[0].GrossSales)
+	assert.Equal(t, 180.0, report.Periods[0].NetSales)
+	assert.Equal(t, 20.0, report.Periods[0].Discounts)
+	assert.Equal(t, 0.0, report.Periods[1].GrossSales)
+	assert.Equal(t, 198.0, report.Total.GrossSales)
+}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/event"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+// StreamOrdersInputDTO selects the orders like ListOrdersInputDTO, with
+// Limit as the number of orders read per query. Follow keeps the stream
+// open for the matching orders created after it started.
+type StreamOrdersInputDTO struct {
+	ListOrdersInputDTO
+	Follow bool `json:"follow"`
+}
+
+type StreamOrdersUseCase struct {
+	OrderRepository entity.OrderRepositoryInterface
+	OrderEvents     *events.Broadcaster
+}
+
+func NewStreamOrdersUseCase(OrderRepository entity.OrderRepositoryInterface, OrderEvents *events.Broadcaster) *StreamOrdersUseCase {
+	return &StreamOrdersUseCase{
+		OrderRepository: OrderRepository,
+		OrderEvents:     OrderEvents,
+	}
+}
+
+// followOverlap is how long before a follow subscription starts an order
+// may have been created and still be announced after it: the time between
+// creating an order and dispatching its event.
+const followOverlap = time.Minute
+
+// Execute walks every matching order page by page and hands each one to
+// send, which may block to slow the walk down to the reader's pace. Only one
+// page is held in memory at a time. It stops at the first send error or once
+// ctx is done; in follow mode it only returns then, or with
+// events.ErrSubscriberBehind once send falls too far behind the new orders.
+func (s *StreamOrdersUseCase) Execute(ctx context.Context, input StreamOrdersInputDTO, send func(OrderOutputDTO) error) error {
+	if _, err := Authorize(ctx, ScopeOrdersRead); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before the export so that no order created meanwhile is
+	// missed; the ones the export already sent are skipped afterwards. Only
+	// orders created since shortly before subscribing can be both exported
+	// and announced, so only those are remembered, and an announcement
+	// removes its order again: the set stays as small as the overlap.
+	var created <-chan events.EventInterface
+	var behind func() error
+	var since time.Time
+	var exported map[string]struct{}
+	filter := input.filter()
+	if input.Follow {
+		since = time.Now().Add(-followOverlap)
+		created, behind = s.OrderEvents.SubscribeLossless(ctx, events.DefaultSubscriptionBuffer, func(ev events.EventInterface) bool {
+			_, ok := ev.GetPayload().(event.OrderCreatedPayload)
+			return ok
+		})
+		exported = make(map[string]struct{})
+	}
+
+	page := input.ListOrdersInputDTO
+	if page.Limit <= 0 {
+		page.Limit = entity.MaxListOrdersLimit
+	}
+	listOrders := NewListOrdersUseCase(s.OrderRepository)
+	for {
+		output, err := listOrders.Execute(ctx, page)
+		if err != nil {
+			return err
+		}
+		for _, edge := range output.Edges {
+			if err := send(edge.Node); err != nil {
+				return err
+			}
+			if exported != nil && !edge.Node.CreatedAt.Before(since) {
+				exported[edge.Node.ID] = struct{}{}
+			}
+		}
+		if !output.PageInfo.HasNextPage {
+			break
+		}
+		page.After = output.PageInfo.EndCursor
+	}
+	if !input.Follow {
+		return nil
+	}
+
+	for ev := range created {
+		payload := ev.GetPayload().(event.OrderCreatedPayload)
+		if payload.CreatedAt.Before(since) {
+			// Created well before the export started, so exported already.
+			continue
+		}
+		if _, ok := exported[payload.ID]; ok {
+			delete(exported, payload.ID)
+			continue
+		}
+		order := entity.Order{
+			ID:         payload.ID,
+			Price:      payload.Price,
+			Tax:        payload.Tax,
+			FinalPrice: payload.FinalPrice,
+			Status:     entity.OrderStatus(payload.Status),
+			CreatedAt:  payload.CreatedAt,
+		}
+		if !filter.Matches(order) {
+			continue
+		}
+		if err := send(newOrderOutputDTO(order)); err != nil {
+			return err
+		}
+	}
+	// A reader too slow to keep up would miss orders; end the stream
+	// rather than leave a silent gap.
+	if err := behind(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStream returns a stream over a memory repository whose orders,
+// created with the returned use case, are announced to the stream.
+func newTestStream(t *testing.T) (*StreamOrdersUseCase, *CreateOrderUseCase) {
+	orderRepository := database.NewMemoryOrderRepository()
+	orderEvents := events.NewBroadcaster()
+	eventDispatcher := events.NewEventDispatcher()
+	assert.NoError(t, eventDispatcher.Register("OrderCreated", orderEvents))
+	return NewStreamOrdersUseCase(orderRepository, orderEvents), NewCreateOrderUseCase(orderRepository, nil, eventDispatcher, nil, nil)
+}
+
+func createTestOrders(t *testing.T, createOrder *CreateOrderUseCase, from, to int) {
+	for i := from; i < to; i++ {
+		_, err := createOrder.Execute(testContext(), OrderInputDTO{ID: fmt.Sprintf("order-%03d", i), Price: 10, Tax: 1})
+		assert.NoError(t, err)
+	}
+}
+
+func TestGivenAnOrderCreatedDuringTheExport_WhenFollow_ThenShouldSendItOnce(t *testing.T) {
+	stream, createOrder := newTestStream(t)
+	createTestOrders(t, createOrder, 0, 2)
+	ctx, cancel := context.WithCancel(testContext())
+	sent := make(chan string, 10)
+
+	done := make(chan error)
+	go func() {
+		done <- stream.Execute(ctx, StreamOrdersInputDTO{ListOrdersInputDTO: ListOrdersInputDTO{Limit: 1}, Follow: true}, func(order OrderOutputDTO) error {
+			if order.ID == "order-000" {
+				// Created after subscribing and before the export reads it.
+				createTestOrders(t, createOrder, 2, 3)
+			}
+			sent <- order.ID
+			return nil
+		})
+	}()
+	for _, id := range []string{"order-000", "order-001", "order-002"} {
+		assert.Equal(t, id, <-sent)
+	}
+	createTestOrders(t, createOrder, 3, 4)
+	assert.Equal(t, "order-003", <-sent)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, sent)
+}
+
+func TestGivenAFollowerThatFallsBehind_WhenFollow_ThenShouldEndTheStreamWithAnError(t *testing.T) {
+	stream, createOrder := newTestStream(t)
+	ctx, cancel := context.WithCancel(testContext())
+	defer cancel()
+	started, release := make(chan struct{}), make(chan struct{})
+
+	done := make(chan error)
+	go func() {
+		first := true
+		done <- stream.Execute(ctx, StreamOrdersInputDTO{Follow: true}, func(order OrderOutputDTO) error {
+			if first {
+				first = false
+				close(started)
+				<-release
+			}
+			return nil
+		})
+	}()
+	createTestOrders(t, createOrder, 0, 1)
+	<-started
+	createTestOrders(t, createOrder, 1, events.DefaultSubscriptionBuffer+2)
+	close(release)
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, events.ErrSubscriberBehind)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream kept going after missing orders")
+	}
+}
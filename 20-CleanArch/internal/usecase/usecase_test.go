@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/infra/database"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/auth"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// testContext carries a principal holding every order scope.
+func testContext() context.Context {
+	return contextAs("alice", ScopeOrdersRead, ScopeOrdersWrite, ScopeOrdersRefund)
+}
+
+func contextAs(subject string, scopes ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Subject: subject, Scopes: scopes})
+}
+
+// eventRecorder records the names of the events it handles.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) Handle(ctx context.Context, event events.EventInterface) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event.GetName())
+	return nil
+}
+
+func (r *eventRecorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// newTestDispatcher returns a dispatcher recording every order event.
+func newTestDispatcher(t *testing.T) (*events.EventDispatcher, *eventRecorder) {
+	dispatcher, recorder := events.NewEventDispatcher(), &eventRecorder{}
+	for _, name := range []string{entity.OrderCreatedEvent, entity.OrderUpdatedEvent, entity.OrderPaidEvent, entity.OrderCancelledEvent, entity.OrderRefundedEvent} {
+		assert.NoError(t, dispatcher.Register(name, recorder))
+	}
+	return dispatcher, recorder
+}
+
+// stubOrderRepository is a memory repository whose Save fails with saveErr
+// while it is set, and which runs beforeUpdate before each Update, to let a
+// test change the order in between.
+type stubOrderRepository struct {
+	entity.OrderRepositoryInterface
+	mu           sync.Mutex
+	saveErr      error
+	beforeUpdate func()
+}
+
+func newStubOrderRepository() *stubOrderRepository {
+	return &stubOrderRepository{OrderRepositoryInterface: database.NewMemoryOrderRepository()}
+}
+
+func (r *stubOrderRepository) Save(ctx context.Context, order *entity.Order) error {
+	r.mu.Lock()
+	err := r.saveErr
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return r.OrderRepositoryInterface.Save(ctx, order)
+}
+
+func (r *stubOrderRepository) Update(ctx context.Context, order *entity.Order, previousStatus entity.OrderStatus) error {
+	if r.beforeUpdate != nil {
+		r.beforeUpdate()
+	}
+	return r.OrderRepositoryInterface.Update(ctx, order, previousStatus)
+}
+
+func (r *stubOrderRepository) failSaves(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saveErr = err
+}
@@ -0,0 +1,396 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/internal/entity"
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+)
+
+// WebhookInputDTO subscribes URL to Events. A secret is generated when
+// Secret is empty.
+type WebhookInputDTO struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// UpdateWebhookInputDTO changes the fields that are set, leaving the others
+// as they are.
+type UpdateWebhookInputDTO struct {
+	ID     string   `json:"id"`
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Secret *string  `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookOutputDTO carries the secret only in the response to the
+// subscription's creation or to a change of its secret; partners keep it
+// from there.
+type WebhookOutputDTO struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newWebhookOutputDTO(subscription *entity.WebhookSubscription) WebhookOutputDTO {
+	return WebhookOutputDTO{
+		ID:        subscription.ID,
+		URL:       subscription.URL,
+		Events:    subscription.Events,
+		Active:    subscription.Active,
+		CreatedBy: subscription.CreatedBy,
+		CreatedAt: subscription.CreatedAt,
+	}
+}
+
+// WebhookDeliveryOutputDTO is an entry of a subscription's delivery log.
+// NextAttemptAt is only set while the delivery is pending.
+type WebhookDeliveryOutputDTO struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+func newWebhookDeliveryOutputDTO(delivery *entity.WebhookDelivery) WebhookDeliveryOutputDTO {
+	output := WebhookDeliveryOutputDTO{
+		ID:             delivery.ID,
+		WebhookID:      delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == entity.WebhookDeliveryPending {
+		output.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if !delivery.LastAttemptAt.IsZero() {
+		output.LastAttemptAt = &delivery.LastAttemptAt
+	}
+	return output
+}
+
+// CreateWebhookUseCase and the other webhook use cases administer the
+// subscriptions of partners to the order events; they need the
+// webhooks:admin scope.
+type CreateWebhookUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+}
+
+func NewCreateWebhookUseCase(WebhookRepository entity.WebhookRepositoryInterface) *CreateWebhookUseCase {
+	return &CreateWebhookUseCase{
+		WebhookRepository: WebhookRepository,
+	}
+}
+
+func (c *CreateWebhookUseCase) Execute(ctx context.Context, input WebhookInputDTO) (WebhookOutputDTO, error) {
+	principal, err := Authorize(ctx, ScopeWebhooksAdmin)
+	if err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	subscription, err := entity.NewWebhookSubscription(events.NewEventID(), input.URL, input.Events, input.Secret, principal.Subject)
+	if err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	if err := c.WebhookRepository.SaveSubscription(ctx, subscription); err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	output := newWebhookOutputDTO(subscription)
+	output.Secret = subscription.Secret
+	return output, nil
+}
+
+type ListWebhooksUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+}
+
+func NewListWebhooksUseCase(WebhookRepository entity.WebhookRepositoryInterface) *ListWebhooksUseCase {
+	return &ListWebhooksUseCase{
+		WebhookRepository: WebhookRepository,
+	}
+}
+
+func (l *ListWebhooksUseCase) Execute(ctx context.Context) ([]WebhookOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeWebhooksAdmin); err != nil {
+		return nil, err
+	}
+	subscriptions, err := l.WebhookRepository.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]WebhookOutputDTO, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		output = append(output, newWebhookOutputDTO(subscription))
+	}
+	return output, nil
+}
+
+type GetWebhookUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+}
+
+func NewGetWebhookUseCase(WebhookRepository entity.WebhookRepositoryInterface) *GetWebhookUseCase {
+	return &GetWebhookUseCase{
+		WebhookRepository: WebhookRepository,
+	}
+}
+
+func (g *GetWebhookUseCase) Execute(ctx context.Context, id string) (WebhookOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeWebhooksAdmin); err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	subscription, err := g.WebhookRepository.FindSubscription(ctx, id)
+	if err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	return newWebhookOutputDTO(subscription), nil
+}
+
+// UpdateWebhookUseCase changes a subscription. A new secret signs the
+// deliveries sent from then on, retries of earlier ones included; a
+// deactivated subscription fails its pending deliveries, which can be
+// replayed once it is active again.
+type UpdateWebhookUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+}
+
+func NewUpdateWebhookUseCase(WebhookRepository entity.WebhookRepositoryInterface) *UpdateWebhookUseCase {
+	return &UpdateWebhookUseCase{
+		WebhookRepository: WebhookRepository,
+	}
+}
+
+func (u *UpdateWebhookUseCase) Execute(ctx context.Context, input UpdateWebhookInputDTO) (WebhookOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeWebhooksAdmin); err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	subscription, err := u.WebhookRepository.FindSubscription(ctx, input.ID)
+	if err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	if input.URL != nil {
+		subscription.URL = *input.URL
+	}
+	if input.Events != nil {
+		subscription.Events = entity.NormalizeWebhookEvents(input.Events)
+	}
+	if input.Secret != nil {
+		subscription.Secret = *input.Secret
+		if subscription.Secret == "" {
+			subscription.Secret = entity.NewWebhookSecret()
+		}
+	}
+	if input.Active != nil {
+		subscription.Active = *input.Active
+	}
+	if err := subscription.IsValid(); err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	if err := u.WebhookRepository.UpdateSubscription(ctx, subscription); err != nil {
+		return WebhookOutputDTO{}, err
+	}
+	output := newWebhookOutputDTO(subscription)
+	if input.Secret != nil {
+		output.Secret = subscription.Secret
+	}
+	return output, nil
+}
+
+// DeleteWebhookUseCase deletes a subscription along with its delivery log.
+type DeleteWebhookUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+}
+
+func NewDeleteWebhookUseCase(WebhookRepository entity.WebhookRepositoryInterface) *DeleteWebhookUseCase {
+	return &DeleteWebhookUseCase{
+		WebhookRepository: WebhookRepository,
+	}
+}
+
+func (d *DeleteWebhookUseCase) Execute(ctx context.Context, id string) error {
+	if _, err := Authorize(ctx, ScopeWebhooksAdmin); err != nil {
+		return err
+	}
+	return d.WebhookRepository.DeleteSubscription(ctx, id)
+}
+
+// Page sizes of the delivery log: DefaultWebhookDeliveryLimit when the
+// request sets none, and never more than MaxWebhookDeliveryLimit.
+const (
+	DefaultWebhookDeliveryLimit = 50
+	MaxWebhookDeliveryLimit     = 500
+)
+
+// ListWebhookDeliveriesInputDTO asks for the latest Limit deliveries of a
+// subscription, only those in Status unless it is empty.
+type ListWebhookDeliveriesInputDTO struct {
+	WebhookID string
+	Status    string
+	Limit     int
+}
+
+type ListWebhookDeliveriesUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+}
+
+func NewListWebhookDeliveriesUseCase(WebhookRepository entity.WebhookRepositoryInterface) *ListWebhookDeliveriesUseCase {
+	return &ListWebhookDeliveriesUseCase{
+		WebhookRepository: WebhookRepository,
+	}
+}
+
+func (l *ListWebhookDeliveriesUseCase) Execute(ctx context.Context, input ListWebhookDeliveriesInputDTO) ([]WebhookDeliveryOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeWebhooksAdmin); err != nil {
+		return nil, err
+	}
+	status := entity.WebhookDeliveryStatus(input.Status)
+	if status != "" && !status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown delivery status %q", entity.ErrInvalidWebhook, input.Status)
+	}
+	if input.Limit < 0 || input.Limit > MaxWebhookDeliveryLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", entity.ErrInvalidWebhook, MaxWebhookDeliveryLimit)
+	}
+	if input.Limit == 0 {
+		input.Limit = DefaultWebhookDeliveryLimit
+	}
+	if _, err := l.WebhookRepository.FindSubscription(ctx, input.WebhookID); err != nil {
+		return nil, err
+	}
+	deliveries, err := l.WebhookRepository.ListDeliveries(ctx, input.WebhookID, status, input.Limit)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]WebhookDeliveryOutputDTO, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		output = append(output, newWebhookDeliveryOutputDTO(delivery))
+	}
+	return output, nil
+}
+
+// ReplayWebhookDeliveryInputDTO names a delivery of the webhook WebhookID.
+type ReplayWebhookDeliveryInputDTO struct {
+	WebhookID  string
+	DeliveryID string
+}
+
+// ReplayWebhookDeliveryUseCase sends a failed delivery again, with all its
+// attempts ahead of it, under the same delivery ID so partners can tell it
+// is the same event.
+type ReplayWebhookDeliveryUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+	Worker            WebhookWorkerInterface
+}
+
+// WebhookWorkerInterface wakes the webhook worker up, to send deliveries
+// that just became due without waiting for its next poll.
+type WebhookWorkerInterface interface {
+	Notify()
+}
+
+func NewReplayWebhookDeliveryUseCase(WebhookRepository entity.WebhookRepositoryInterface, Worker WebhookWorkerInterface) *ReplayWebhookDeliveryUseCase {
+	return &ReplayWebhookDeliveryUseCase{
+		WebhookRepository: WebhookRepository,
+		Worker:            Worker,
+	}
+}
+
+func (r *ReplayWebhookDeliveryUseCase) Execute(ctx context.Context, input ReplayWebhookDeliveryInputDTO) (WebhookDeliveryOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeWebhooksAdmin); err != nil {
+		return WebhookDeliveryOutputDTO{}, err
+	}
+	delivery, err := r.WebhookRepository.FindDelivery(ctx, input.DeliveryID)
+	if err != nil {
+		return WebhookDeliveryOutputDTO{}, err
+	}
+	if delivery.SubscriptionID != input.WebhookID {
+		return WebhookDeliveryOutputDTO{}, entity.ErrWebhookDeliveryNotFound
+	}
+	if err := delivery.Replay(time.Now()); err != nil {
+		return WebhookDeliveryOutputDTO{}, err
+	}
+	if err := r.WebhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+		return WebhookDeliveryOutputDTO{}, err
+	}
+	notify(r.Worker)
+	return newWebhookDeliveryOutputDTO(delivery), nil
+}
+
+func notify(worker WebhookWorkerInterface) {
+	if worker != nil {
+		worker.Notify()
+	}
+}
+
+type ReplayFailedWebhookDeliveriesOutputDTO struct {
+	Replayed int `json:"replayed"`
+}
+
+// ReplayFailedWebhookDeliveriesUseCase replays every failed delivery of a
+// subscription, say once the partner fixed its endpoint.
+type ReplayFailedWebhookDeliveriesUseCase struct {
+	WebhookRepository entity.WebhookRepositoryInterface
+	Worker            WebhookWorkerInterface
+}
+
+func NewReplayFailedWebhookDeliveriesUseCase(WebhookRepository entity.WebhookRepositoryInterface, Worker WebhookWorkerInterface) *ReplayFailedWebhookDeliveriesUseCase {
+	return &ReplayFailedWebhookDeliveriesUseCase{
+		WebhookRepository: WebhookRepository,
+		Worker:            Worker,
+	}
+}
+
+func (r *ReplayFailedWebhookDeliveriesUseCase) Execute(ctx context.Context, webhookID string) (ReplayFailedWebhookDeliveriesOutputDTO, error) {
+	if _, err := Authorize(ctx, ScopeWebhooksAdmin); err != nil {
+		return ReplayFailedWebhookDeliveriesOutputDTO{}, err
+	}
+	if _, err := r.WebhookRepository.FindSubscription(ctx, webhookID); err != nil {
+		return ReplayFailedWebhookDeliveriesOutputDTO{}, err
+	}
+	output := ReplayFailedWebhookDeliveriesOutputDTO{}
+	// Replayed deliveries are pending, so each page holds the failed
+	// deliveries left.
+	for {
+		failed, err := r.WebhookRepository.ListDeliveries(ctx, webhookID, entity.WebhookDeliveryFailed, MaxWebhookDeliveryLimit)
+		if err != nil {
+			return output, err
+		}
+		at := time.Now()
+		for _, delivery := range failed {
+			if err := delivery.Replay(at); err != nil {
+				return output, err
+			}
+			if err := r.WebhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+				return output, err
+			}
+			output.Replayed++
+		}
+		if len(failed) < MaxWebhookDeliveryLimit {
+			break
+		}
+	}
+	if output.Replayed > 0 {
+		notify(r.Worker)
+	}
+	return output, nil
+}
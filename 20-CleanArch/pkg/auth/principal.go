@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Roles   []string
+	Scopes  []string
+}
+
+func (p Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Authorize returns the principal of ctx when it holds scope or any of
+// roles. It fails with ErrUnauthenticated when ctx carries no principal and
+// with ErrForbidden when the principal lacks the permission.
+func Authorize(ctx context.Context, scope string, roles ...string) (Principal, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	if principal.HasScope(scope) {
+		return principal, nil
+	}
+	for _, role := range roles {
+		if principal.HasRole(role) {
+			return principal, nil
+		}
+	}
+	return Principal{}, ErrForbidden
+}
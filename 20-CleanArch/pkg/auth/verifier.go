@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	errNoVerificationKey   = errors.New("auth needs an HMAC secret or a JWKS file")
+	errTwoVerificationKeys = errors.New("auth takes an HMAC secret or a JWKS file, not both")
+	errUnknownKey          = errors.New("unknown signing key")
+)
+
+var (
+	hmacMethods = []string{"HS256", "HS384", "HS512"}
+	jwksMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+// Config selects how bearer tokens are verified: with a shared HMACSecret
+// or with the public keys of a local JWKS file. Issuer and Audience, when
+// set, must match the token's iss and aud claims.
+type Config struct {
+	HMACSecret string
+	JWKSFile   string
+	Issuer     string
+	Audience   string
+}
+
+// claims are the JWT claims a Principal is read from: sub, the space
+// separated OAuth 2.0 scope and a list of roles.
+type claims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// VerifierInterface turns a bearer token into the principal it was issued
+// to; the transports authenticate through it.
+type VerifierInterface interface {
+	Verify(token string) (Principal, error)
+}
+
+// Verifier validates JWT bearer tokens and turns them into principals.
+type Verifier struct {
+	keyFunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+func NewVerifier(config Config) (*Verifier, error) {
+	var keyFunc jwt.Keyfunc
+	var methods []string
+	switch {
+	case config.HMACSecret != "" && config.JWKSFile != "":
+		return nil, errTwoVerificationKeys
+	case config.HMACSecret != "":
+		secret := []byte(config.HMACSecret)
+		keyFunc = func(*jwt.Token) (interface{}, error) { return secret, nil }
+		methods = hmacMethods
+	case config.JWKSFile != "":
+		keys, err := loadJWKS(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keyFunc = keys.keyFunc
+		methods = jwksMethods
+	default:
+		return nil, errNoVerificationKey
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(config.Audience))
+	}
+	return &Verifier{keyFunc: keyFunc, parser: jwt.NewParser(opts...)}, nil
+}
+
+// Verify checks the signature and claims of token. Every failure wraps
+// ErrUnauthenticated.
+func (v *Verifier) Verify(token string) (Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(token, &c, v.keyFunc); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	if c.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+	return Principal{
+		Subject: c.Subject,
+		Roles:   c.Roles,
+		Scopes:  strings.Fields(c.Scope),
+	}, nil
+}
+
+// Authenticate verifies the bearer token of an Authorization header value
+// and returns ctx carrying its principal.
+func Authenticate(ctx context.Context, verifier VerifierInterface, authorization string) (context.Context, error) {
+	token, ok := BearerToken(authorization)
+	if !ok {
+		return ctx, fmt.Errorf("%w: missing bearer token", ErrUnauthenticated)
+	}
+	principal, err := verifier.Verify(token)
+	if err != nil {
+		return ctx, err
+	}
+	return WithPrincipal(ctx, principal), nil
+}
+
+// BearerToken extracts the token of an "Authorization: Bearer <token>"
+// header value.
+func BearerToken(authorization string) (string, bool) {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// IssueHMACToken signs an HS256 token for principal that the Verifier of
+// the same Config accepts until ttl has passed. It exists for development
+// and tests; production tokens come from the identity provider.
+func IssueHMACToken(config Config, principal Principal, ttl time.Duration) (string, error) {
+	if config.HMACSecret == "" {
+		return "", errNoVerificationKey
+	}
+	now := time.Now()
+	c := claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   principal.Subject,
+			Issuer:    config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Scope: strings.Join(principal.Scopes, " "),
+		Roles: principal.Roles,
+	}
+	if config.Audience != "" {
+		c.Audience = jwt.ClaimStrings{config.Audience}
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(config.HMACSecret))
+}
+
+// jwks holds the public keys of a JSON Web Key Set by key ID.
+type jwks map[string]crypto.PublicKey
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads the RSA and EC keys of a JWKS file; keys of other types
+// are skipped.
+func loadJWKS(path string) (jwks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	keys := make(jwks)
+	for _, key := range set.Keys {
+		var publicKey crypto.PublicKey
+		switch key.Kty {
+		case "RSA":
+			publicKey, err = key.rsaPublicKey()
+		case "EC":
+			publicKey, err = key.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing key %q of %s: %w", key.Kid, path, err)
+		}
+		keys[key.Kid] = publicKey
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA or EC key found in %s", path)
+	}
+	return keys, nil
+}
+
+// keyFunc picks the key named by the token's kid header; a token without
+// kid is accepted when the set holds a single key.
+func (k jwks) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(k) == 1 {
+		for _, key := range k {
+			return key, nil
+		}
+	}
+	key, ok := k[kid]
+	if !ok {
+		return nil, errUnknownKey
+	}
+	return key, nil
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(encoded string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifierTestSuite struct {
+	suite.Suite
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	jwksFile string
+}
+
+func (suite *VerifierTestSuite) SetupSuite() {
+	var err error
+	suite.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	suite.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "n": encode(suite.rsaKey.N), "e": encode(big.NewInt(int64(suite.rsaKey.E)))},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(suite.ecKey.X), "y": encode(suite.ecKey.Y)},
+		{"kty": "oct", "kid": "skipped", "k": "c2VjcmV0"},
+	}}
+	data, err := json.Marshal(set)
+	suite.Require().NoError(err)
+	suite.jwksFile = filepath.Join(suite.T().TempDir(), "jwks.json")
+	suite.Require().NoError(os.WriteFile(suite.jwksFile, data, 0o600))
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(VerifierTestSuite))
+}
+
+func (suite *VerifierTestSuite) sign(method jwt.SigningMethod, kid string, key interface{}, c claims) string {
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	suite.Require().NoError(err)
+	return signed
+}
+
+func validClaims() claims {
+	return claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			Issuer:    "https://issuer.example",
+			Audience:  jwt.ClaimStrings{"orders"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		Scope: "orders:read orders:write",
+		Roles: []string{"clerk"},
+	}
+}
+
+func (suite *VerifierTestSuite) TestGivenAnHMACToken_WhenVerify_ThenShouldReturnThePrincipal() {
+	config := Config{HMACSecret: "secret", Issuer: "https://issuer.example", Audience: "orders"}
+	verifier, err := NewVerifier(config)
+	suite.Require().NoError(err)
+	token, err := IssueHMACToken(config, Principal{Subject: "alice", Scopes: []string{"orders:read"}, Roles: []string{"admin"}}, time.Minute)
+	suite.Require().NoError(err)
+
+	principal, err := verifier.Verify(token)
+
+	suite.NoError(err)
+	suite.Equal(Principal{Subject: "alice", Scopes: []string{"orders:read"}, Roles: []string{"admin"}}, principal)
+}
+
+func (suite *VerifierTestSuite) TestGivenJWKSKeys_WhenVerify_ThenShouldAcceptRSAAndECTokens() {
+	verifier, err := NewVerifier(Config{JWKSFile: suite.jwksFile, Audience: "orders"})
+	suite.Require().NoError(err)
+
+	principal, err := verifier.Verify(suite.sign(jwt.SigningMethodRS256, "rsa-1", suite.rsaKey, validClaims()))
+	suite.NoError(err)
+	suite.Equal("alice", principal.Subject)
+	suite.Equal([]string{"orders:read", "orders:write"}, principal.Scopes)
+
+	principal, err = verifier.Verify(suite.sign(jwt.SigningMethodES256, "ec-1", suite.ecKey, validClaims()))
+	suite.NoError(err)
+	suite.Equal([]string{"clerk"}, principal.Roles)
+}
+
+func (suite *VerifierTestSuite) TestGivenInvalidTokens_WhenVerify_ThenShouldFailAsUnauthenticated() {
+	hmacVerifier, err := NewVerifier(Config{HMACSecret: "secret", Issuer: "https://issuer.example", Audience: "orders"})
+	suite.Require().NoError(err)
+	jwksVerifier, err := NewVerifier(Config{JWKSFile: suite.jwksFile})
+	suite.Require().NoError(err)
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+	wrongAudience := validClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"billing"}
+	noSubject := validClaims()
+	noSubject.Subject = ""
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	cases := map[string]struct {
+		verifier *Verifier
+		token    string
+	}{
+		"malformed":         {hmacVerifier, "not-a-jwt"},
+		"wrong secret":      {hmacVerifier, suite.sign(jwt.SigningMethodHS256, "", []byte("other"), validClaims())},
+		"expired":           {hmacVerifier, suite.sign(jwt.SigningMethodHS256, "", []byte("secret"), expired)},
+		"without expiry":    {hmacVerifier, suite.sign(jwt.SigningMethodHS256, "", []byte("secret"), noExpiry)},
+		"wrong audience":    {hmacVerifier, suite.sign(jwt.SigningMethodHS256, "", []byte("secret"), wrongAudience)},
+		"without subject":   {hmacVerifier, suite.sign(jwt.SigningMethodHS256, "", []byte("secret"), noSubject)},
+		"unknown kid":       {jwksVerifier, suite.sign(jwt.SigningMethodRS256, "rsa-2", suite.rsaKey, validClaims())},
+		"wrong RSA key":     {jwksVerifier, suite.sign(jwt.SigningMethodRS256, "rsa-1", otherKey, validClaims())},
+		"HMAC against JWKS": {jwksVerifier, suite.sign(jwt.SigningMethodHS256, "rsa-1", []byte("secret"), validClaims())},
+	}
+	for name, c := range cases {
+		_, err := c.verifier.Verify(c.token)
+		suite.ErrorIs(err, ErrUnauthenticated, name)
+	}
+}
+
+func (suite *VerifierTestSuite) TestGivenAnIncompleteConfig_WhenNewVerifier_ThenShouldFail() {
+	_, err := NewVerifier(Config{})
+	suite.ErrorIs(err, errNoVerificationKey)
+	_, err = NewVerifier(Config{HMACSecret: "secret", JWKSFile: suite.jwksFile})
+	suite.ErrorIs(err, errTwoVerificationKeys)
+	_, err = NewVerifier(Config{JWKSFile: filepath.Join(suite.T().TempDir(), "missing.json")})
+	suite.Error(err)
+}
+
+func TestGivenAuthorizationHeaders_WhenBearerToken_ThenShouldExtractOnlyBearerTokens(t *testing.T) {
+	token, ok := BearerToken("Bearer abc")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", token)
+	token, ok = BearerToken("bearer  abc ")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", token)
+	_, ok = BearerToken("Basic abc")
+	assert.False(t, ok)
+	_, ok = BearerToken("Bearer ")
+	assert.False(t, ok)
+	_, ok = BearerToken("")
+	assert.False(t, ok)
+}
+
+func TestGivenPrincipals_WhenAuthorize_ThenShouldCheckScopesAndRoles(t *testing.T) {
+	_, err := Authorize(context.Background(), "orders:write")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	reader := WithPrincipal(context.Background(), Principal{Subject: "bob", Scopes: []string{"orders:read"}})
+	principal, err := Authorize(reader, "orders:read")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", principal.Subject)
+	_, err = Authorize(reader, "orders:write", "admin")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	admin := WithPrincipal(context.Background(), Principal{Subject: "carol", Roles: []string{"admin"}})
+	_, err = Authorize(admin, "orders:write", "admin")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultSubscriptionBuffer is how many events a subscriber may fall behind
+// before the oldest ones are dropped.
+const DefaultSubscriptionBuffer = 64
+
+// ErrSubscriberBehind ends a lossless subscription whose reader fell so far
+// behind that its buffer filled up.
+var ErrSubscriberBehind = errors.New("subscriber fell behind and would have missed events")
+
+// Broadcaster is an event handler that fans every event it handles out to
+// live subscribers, such as GraphQL subscriptions. Handle never blocks on a
+// subscriber: each one has a bounded buffer, and when it is full the oldest
+// event is dropped to make room, so a slow client only loses stale events
+// and never holds up the dispatcher or the other subscribers.
+type Broadcaster struct {
+	mu          sync.RWMutex
+	subscribers map[*broadcastSubscription]struct{}
+	dropped     atomic.Int64
+}
+
+type broadcastSubscription struct {
+	mu     sync.Mutex
+	events chan EventInterface
+	filter func(EventInterface) bool
+	closed bool
+	// lossless subscriptions are closed on overflow instead of dropping
+	// events, and remember it in behind.
+	lossless bool
+	behind   bool
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscribers: make(map[*broadcastSubscription]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the handled events that filter
+// accepts, or all of them when filter is nil. The channel is closed once ctx
+// is done. buffer defaults to DefaultSubscriptionBuffer when not positive.
+func (b *Broadcaster) Subscribe(ctx context.Context, buffer int, filter func(EventInterface) bool) <-chan EventInterface {
+	return b.subscribe(ctx, buffer, filter, false).events
+}
+
+// SubscribeLossless is Subscribe for readers that must see every event.
+// Instead of dropping the oldest event when the buffer is full, it closes
+// the channel after the events already queued, and err returns
+// ErrSubscriberBehind from then on. err returns nil for a subscription that
+// kept up, including one ended by ctx.
+func (b *Broadcaster) SubscribeLossless(ctx context.Context, buffer int, filter func(EventInterface) bool) (events <-chan EventInterface, err func() error) {
+	sub := b.subscribe(ctx, buffer, filter, true)
+	return sub.events, sub.err
+}
+
+func (b *Broadcaster) subscribe(ctx context.Context, buffer int, filter func(EventInterface) bool, lossless bool) *broadcastSubscription {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	sub := &broadcastSubscription{events: make(chan EventInterface, buffer), filter: filter, lossless: lossless}
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		b.mu.Unlock()
+		sub.close()
+	}()
+	return sub
+}
+
+// Subscribers returns how many subscriptions are live.
+func (b *Broadcaster) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+func (b *Broadcaster) Handle(ctx context.Context, event EventInterface) error {
+	b.mu.RLock()
+	subscribers := make([]*broadcastSubscription, 0, len(b.subscribers))
+	for sub := range b.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		if sub.filter == nil || sub.filter(event) {
+			b.dropped.Add(sub.send(event))
+		}
+	}
+	return nil
+}
+
+// Dropped returns how many events were discarded across all subscribers
+// because they fell behind.
+func (b *Broadcaster) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// send queues event and returns how many events it dropped: older ones it
+// pushed out or, for a lossless subscription, event itself.
+func (s *broadcastSubscription) send(event EventInterface) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0
+	}
+	var dropped int64
+	for {
+		select {
+		case s.events <- event:
+			return dropped
+		default:
+		}
+		if s.lossless {
+			s.behind, s.closed = true, true
+			close(s.events)
+			return 1
+		}
+		// Full: drop the oldest event, unless the reader just took it.
+		select {
+		case <-s.events:
+			dropped++
+		default:
+		}
+	}
+}
+
+func (s *broadcastSubscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
+
+func (s *broadcastSubscription) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.behind {
+		return ErrSubscriberBehind
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func broadcastEvent(name string, payload interface{}) *TestEvent {
+	return &TestEvent{Name: name, Payload: payload}
+}
+
+func TestGivenAFilteredSubscriber_WhenHandle_ThenShouldReceiveOnlyMatchingEvents(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	paid := broadcaster.Subscribe(ctx, 10, func(event EventInterface) bool { return event.GetName() == "OrderPaid" })
+	all := broadcaster.Subscribe(ctx, 10, nil)
+
+	assert.NoError(t, broadcaster.Handle(context.Background(), broadcastEvent("OrderCreated", "1")))
+	assert.NoError(t, broadcaster.Handle(context.Background(), broadcastEvent("OrderPaid", "1")))
+
+	assert.Equal(t, "OrderPaid", (<-paid).GetName())
+	assert.Empty(t, paid)
+	assert.Equal(t, "OrderCreated", (<-all).GetName())
+	assert.Equal(t, "OrderPaid", (<-all).GetName())
+}
+
+func TestGivenASlowSubscriber_WhenItsBufferIsFull_ThenShouldDropTheOldestWithoutBlocking(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := broadcaster.Subscribe(ctx, 2, nil)
+	fast := broadcaster.Subscribe(ctx, 10, nil)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			broadcaster.Handle(context.Background(), broadcastEvent("OrderCreated", fmt.Sprint(i)))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Handle blocked on a slow subscriber")
+	}
+
+	assert.Equal(t, "3", (<-slow).GetPayload())
+	assert.Equal(t, "4", (<-slow).GetPayload())
+	assert.Len(t, fast, 5)
+	assert.Equal(t, int64(3), broadcaster.Dropped())
+}
+
+func TestGivenACancelledSubscription_WhenHandle_ThenShouldCloseItsChannelAndForgetIt(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := broadcaster.Subscribe(ctx, 1, nil)
+	assert.Equal(t, 1, broadcaster.Subscribers())
+
+	cancel()
+	_, open := <-ch
+	assert.False(t, open)
+	assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, broadcaster.Handle(context.Background(), broadcastEvent("OrderCreated", "1")))
+}
+
+func TestGivenALosslessSubscriber_WhenItsBufferIsFull_ThenShouldEndTheSubscriptionWithAnError(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := broadcaster.SubscribeLossless(ctx, 2, nil)
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, broadcaster.Handle(context.Background(), broadcastEvent("OrderCreated", fmt.Sprint(i))))
+	}
+
+	var received []interface{}
+	for event := range ch {
+		received = append(received, event.GetPayload())
+	}
+	assert.Equal(t, []interface{}{"0", "1"}, received)
+	assert.ErrorIs(t, err(), ErrSubscriberBehind)
+	assert.Equal(t, int64(1), broadcaster.Dropped())
+}
+
+func TestGivenALosslessSubscriber_WhenCancelled_ThenShouldEndWithoutAnError(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := broadcaster.SubscribeLossless(ctx, 2, nil)
+
+	cancel()
+	_, open := <-ch
+	assert.False(t, open)
+	assert.NoError(t, err())
+}
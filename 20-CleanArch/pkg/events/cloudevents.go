@@ -0,0 +1,195 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType marks an HTTP body holding a whole event in
+	// structured mode.
+	CloudEventsContentType = "application/cloudevents+json"
+
+	// Binary mode header prefixes. Message headers use MessageHeaderPrefix;
+	// transports with a binding of their own rewrite it.
+	MessageHeaderPrefix = "ce_"
+	AMQPHeaderPrefix    = "cloudEvents_"
+	HTTPHeaderPrefix    = "ce-"
+)
+
+var ErrInvalidCloudEvent = errors.New("invalid cloud event")
+
+// CloudEvent is an event in CloudEvents 1.0 form. The schema version and
+// correlation ID travel as the schemaversion and correlationid extensions;
+// the aggregate ID is the subject.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	SchemaVersion   int             `json:"schemaversion,omitempty"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewCloudEvent encodes event's payload as JSON data. Events that do not
+// carry an envelope get a fresh ID and no subject.
+func NewCloudEvent(source string, event EventInterface) (*CloudEvent, error) {
+	data, err := json.Marshal(event.GetPayload())
+	if err != nil {
+		return nil, err
+	}
+	cloudEvent := &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              NewEventID(),
+		Source:          source,
+		Type:            event.GetName(),
+		Time:            event.GetDateTime().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if enveloped, ok := event.(EnvelopeInterface); ok {
+		cloudEvent.ID = enveloped.GetID()
+		cloudEvent.Subject = enveloped.GetAggregateID()
+		cloudEvent.SchemaVersion = enveloped.GetSchemaVersion()
+		cloudEvent.CorrelationID = enveloped.GetCorrelationID()
+	}
+	return cloudEvent, nil
+}
+
+func (e *CloudEvent) Validate() error {
+	if e.SpecVersion != CloudEventsSpecVersion || e.ID == "" || e.Source == "" || e.Type == "" {
+		return ErrInvalidCloudEvent
+	}
+	return nil
+}
+
+// Headers returns the attributes as binary mode headers with prefix. The
+// content type goes in content-type, unprefixed, as every binding requires.
+func (e *CloudEvent) Headers(prefix string) map[string]string {
+	headers := map[string]string{
+		prefix + "specversion": e.SpecVersion,
+		prefix + "id":          e.ID,
+		prefix + "source":      e.Source,
+		prefix + "type":        e.Type,
+		prefix + "time":        e.Time.Format(time.RFC3339Nano),
+	}
+	if e.Subject != "" {
+		headers[prefix+"subject"] = e.Subject
+	}
+	if e.SchemaVersion != 0 {
+		headers[prefix+"schemaversion"] = strconv.Itoa(e.SchemaVersion)
+	}
+	if e.CorrelationID != "" {
+		headers[prefix+"correlationid"] = e.CorrelationID
+	}
+	if e.DataContentType != "" {
+		headers["content-type"] = e.DataContentType
+	}
+	return headers
+}
+
+// CloudEventFromHeaders reads a binary mode event. Header names are matched
+// case-insensitively, as HTTP canonicalizes them.
+func CloudEventFromHeaders(prefix string, headers map[string]string, data []byte) (*CloudEvent, error) {
+	attributes := make(map[string]string, len(headers))
+	for name, value := range headers {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, strings.ToLower(prefix)) {
+			attributes[name[len(prefix):]] = value
+		} else if name == "content-type" {
+			attributes["datacontenttype"] = value
+		}
+	}
+	cloudEvent := &CloudEvent{
+		SpecVersion:     attributes["specversion"],
+		ID:              attributes["id"],
+		Source:          attributes["source"],
+		Type:            attributes["type"],
+		Subject:         attributes["subject"],
+		DataContentType: attributes["datacontenttype"],
+		CorrelationID:   attributes["correlationid"],
+		Data:            data,
+	}
+	if value, ok := attributes["time"]; ok {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, ErrInvalidCloudEvent
+		}
+		cloudEvent.Time = t
+	}
+	if value, ok := attributes["schemaversion"]; ok {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, ErrInvalidCloudEvent
+		}
+		cloudEvent.SchemaVersion = version
+	}
+	if err := cloudEvent.Validate(); err != nil {
+		return nil, err
+	}
+	return cloudEvent, nil
+}
+
+// Message encodes the event in binary mode: attributes in headers, data as
+// the body, and the subject as the key so one aggregate's events stay in
+// order.
+func (e *CloudEvent) Message() Message {
+	return Message{
+		Topic:   e.Type,
+		Key:     e.Subject,
+		Headers: e.Headers(MessageHeaderPrefix),
+		Body:    e.Data,
+	}
+}
+
+func CloudEventFromMessage(msg Message) (*CloudEvent, error) {
+	return CloudEventFromHeaders(MessageHeaderPrefix, msg.Headers, msg.Body)
+}
+
+// NewHTTPRequest builds a binary mode POST delivering the event to url.
+func (e *CloudEvent) NewHTTPRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(e.Data))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range e.Headers(HTTPHeaderPrefix) {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+// CloudEventFromHTTPRequest reads an event sent in structured or binary
+// mode.
+func CloudEventFromHTTPRequest(r *http.Request) (*CloudEvent, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), CloudEventsContentType) {
+		var cloudEvent CloudEvent
+		if err := json.Unmarshal(body, &cloudEvent); err != nil {
+			return nil, ErrInvalidCloudEvent
+		}
+		if err := cloudEvent.Validate(); err != nil {
+			return nil, err
+		}
+		return &cloudEvent, nil
+	}
+	headers := make(map[string]string, len(r.Header))
+	for name := range r.Header {
+		headers[name] = r.Header.Get(name)
+	}
+	return CloudEventFromHeaders(HTTPHeaderPrefix, headers, body)
+}
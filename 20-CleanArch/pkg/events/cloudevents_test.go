@@ -0,0 +1,114 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type orderPaid struct {
+	Envelope
+	Payload map[string]interface{}
+}
+
+func (e *orderPaid) GetName() string                { return "OrderPaid" }
+func (e *orderPaid) GetPayload() interface{}        { return e.Payload }
+func (e *orderPaid) SetPayload(payload interface{}) { e.Payload = payload.(map[string]interface{}) }
+
+type CloudEventsTestSuite struct {
+	suite.Suite
+	event *orderPaid
+}
+
+func (suite *CloudEventsTestSuite) SetupTest() {
+	suite.event = &orderPaid{
+		Envelope: NewEnvelope("order-1", 2),
+		Payload:  map[string]interface{}{"id": "order-1", "amount": 12.5},
+	}
+	suite.event.CorrelationID = "request-1"
+}
+
+func TestCloudEventsSuite(t *testing.T) {
+	suite.Run(t, new(CloudEventsTestSuite))
+}
+
+func (suite *CloudEventsTestSuite) TestGivenAnEnvelopedEvent_WhenNewCloudEvent_ThenShouldMapTheEnvelope() {
+	cloudEvent, err := NewCloudEvent("/ordersystem", suite.event)
+	suite.NoError(err)
+	suite.Equal(CloudEventsSpecVersion, cloudEvent.SpecVersion)
+	suite.Equal(suite.event.ID, cloudEvent.ID)
+	suite.Equal("/ordersystem", cloudEvent.Source)
+	suite.Equal("OrderPaid", cloudEvent.Type)
+	suite.Equal("order-1", cloudEvent.Subject)
+	suite.Equal(2, cloudEvent.SchemaVersion)
+	suite.Equal("request-1", cloudEvent.CorrelationID)
+	suite.True(suite.event.OccurredAt.Equal(cloudEvent.Time))
+	suite.JSONEq(`{"id":"order-1","amount":12.5}`, string(cloudEvent.Data))
+}
+
+func (suite *CloudEventsTestSuite) TestGivenACloudEvent_WhenSentAsAMessage_ThenShouldRoundTrip() {
+	cloudEvent, err := NewCloudEvent("/ordersystem", suite.event)
+	suite.NoError(err)
+
+	msg := cloudEvent.Message()
+	suite.Equal("OrderPaid", msg.Topic)
+	suite.Equal("order-1", msg.Key)
+	suite.Equal("application/json", msg.Headers["content-type"])
+
+	received, err := CloudEventFromMessage(msg)
+	suite.NoError(err)
+	suite.Equal(cloudEvent, received)
+}
+
+func (suite *CloudEventsTestSuite) TestGivenACloudEvent_WhenSentOverHTTPInBinaryMode_ThenShouldRoundTrip() {
+	cloudEvent, err := NewCloudEvent("/ordersystem", suite.event)
+	suite.NoError(err)
+
+	req, err := cloudEvent.NewHTTPRequest(context.Background(), "http://example.com/webhook")
+	suite.NoError(err)
+	suite.Equal(cloudEvent.ID, req.Header.Get("ce-id"))
+	suite.Equal("application/json", req.Header.Get("Content-Type"))
+
+	received, err := CloudEventFromHTTPRequest(req)
+	suite.NoError(err)
+	suite.Equal(cloudEvent, received)
+}
+
+func (suite *CloudEventsTestSuite) TestGivenAStructuredModeRequest_WhenCloudEventFromHTTPRequest_ThenShouldDecodeTheBody() {
+	cloudEvent, err := NewCloudEvent("/ordersystem", suite.event)
+	suite.NoError(err)
+	body, err := json.Marshal(cloudEvent)
+	suite.NoError(err)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", CloudEventsContentType+"; charset=utf-8")
+
+	received, err := CloudEventFromHTTPRequest(req)
+	suite.NoError(err)
+	suite.Equal(cloudEvent.ID, received.ID)
+	suite.Equal(cloudEvent.Subject, received.Subject)
+	suite.JSONEq(string(cloudEvent.Data), string(received.Data))
+}
+
+func (suite *CloudEventsTestSuite) TestGivenMissingRequiredAttributes_WhenCloudEventFromHeaders_ThenShouldReturnErrInvalidCloudEvent() {
+	_, err := CloudEventFromHeaders(MessageHeaderPrefix, map[string]string{"ce_id": "1"}, nil)
+	suite.ErrorIs(err, ErrInvalidCloudEvent)
+
+	_, err = CloudEventFromMessage(Message{Body: []byte(`{"id":"1"}`)})
+	suite.ErrorIs(err, ErrInvalidCloudEvent)
+}
+
+func (suite *CloudEventsTestSuite) TestGivenAPlainEvent_WhenNewCloudEvent_ThenShouldGenerateAnID() {
+	event := &TestEvent{Name: "test", Payload: "payload"}
+	cloudEvent, err := NewCloudEvent("/ordersystem", event)
+	suite.NoError(err)
+	suite.Regexp(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), cloudEvent.ID)
+	suite.Empty(cloudEvent.Subject)
+	suite.WithinDuration(time.Now(), cloudEvent.Time, time.Minute)
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DeadLetter describes a handler invocation that exhausted its retries.
+type DeadLetter struct {
+	Event    EventInterface
+	Handler  EventHandlerInterface
+	Err      error
+	Attempts int
+	FailedAt time.Time
+}
+
+// DeadLetterSinkFunc adapts a function to DeadLetterSinkInterface.
+type DeadLetterSinkFunc func(ctx context.Context, letter DeadLetter) error
+
+func (f DeadLetterSinkFunc) Send(ctx context.Context, letter DeadLetter) error {
+	return f(ctx, letter)
+}
+
+// MemoryDeadLetterSink keeps dead letters in memory so they can be inspected
+// or replayed.
+type MemoryDeadLetterSink struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+func NewMemoryDeadLetterSink() *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{}
+}
+
+func (s *MemoryDeadLetterSink) Send(ctx context.Context, letter DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, letter)
+	return nil
+}
+
+func (s *MemoryDeadLetterSink) Letters() []DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]DeadLetter(nil), s.letters...)
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+// EnvelopeInterface is implemented by events that carry envelope metadata,
+// usually by embedding Envelope.
+type EnvelopeInterface interface {
+	EventInterface
+	GetID() string
+	GetAggregateID() string
+	GetSchemaVersion() int
+	GetCorrelationID() string
+}
+
+// Envelope is the metadata shared by every event: a unique ID, when it
+// occurred, the aggregate it is about, the version of its payload schema
+// and the correlation ID of the request that caused it.
+type Envelope struct {
+	ID            string
+	OccurredAt    time.Time
+	AggregateID   string
+	SchemaVersion int
+	CorrelationID string
+}
+
+// NewEnvelope stamps a new event ID and the current time.
+func NewEnvelope(aggregateID string, schemaVersion int) Envelope {
+	return Envelope{
+		ID:            NewEventID(),
+		OccurredAt:    time.Now().UTC(),
+		AggregateID:   aggregateID,
+		SchemaVersion: schemaVersion,
+	}
+}
+
+func (e *Envelope) GetID() string {
+	return e.ID
+}
+
+func (e *Envelope) GetDateTime() time.Time {
+	return e.OccurredAt
+}
+
+func (e *Envelope) GetAggregateID() string {
+	return e.AggregateID
+}
+
+func (e *Envelope) GetSchemaVersion() int {
+	return e.SchemaVersion
+}
+
+func (e *Envelope) GetCorrelationID() string {
+	return e.CorrelationID
+}
+
+// NewEventID returns a random (version 4) UUID.
+func NewEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying the correlation ID of the
+// request being served, so events raised while serving it can record it.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
@@ -1,69 +1,258 @@
 package events
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"time"
 )
 
-var ErrHandlerAlreadyRegistered = errors.New("handler already registered")
+var (
+	ErrHandlerAlreadyRegistered = errors.New("handler already registered")
+	ErrDispatcherClosed         = errors.New("event dispatcher is shut down")
+)
+
+type DispatchMode int
+
+const (
+	// DispatchSync runs the handlers before Dispatch returns and reports
+	// their errors.
+	DispatchSync DispatchMode = iota
+	// DispatchAsync queues the handlers on the worker pool and returns once
+	// they are queued.
+	DispatchAsync
+)
 
+// EventDispatcher is safe for concurrent use. Handler slices are never
+// modified in place, so Dispatch works on a snapshot and handlers may be
+// registered or removed while a dispatch is running.
 type EventDispatcher struct {
-	handlers map[string][]EventHandlerInterface
+	mu             sync.RWMutex
+	handlers       map[string][]EventHandlerInterface
+	mode           DispatchMode
+	workers        int
+	queueSize      int
+	retryPolicy    RetryPolicy
+	deadLetterSink DeadLetterSinkInterface
+
+	queue     chan job
+	done      chan struct{}
+	closeOnce sync.Once
+	closeMu   sync.RWMutex
+	closed    bool
+	workerWg  sync.WaitGroup
+}
+
+type job struct {
+	ctx     context.Context
+	event   EventInterface
+	handler EventHandlerInterface
+}
+
+type Option func(*EventDispatcher)
+
+func WithMode(mode DispatchMode) Option {
+	return func(ed *EventDispatcher) { ed.mode = mode }
+}
+
+// WithWorkers sets how many handlers run at once in async mode.
+func WithWorkers(workers int) Option {
+	return func(ed *EventDispatcher) { ed.workers = workers }
 }
 
-func NewEventDispatcher() *EventDispatcher {
-	return &EventDispatcher{
-		handlers: make(map[string][]EventHandlerInterface),
+// WithQueueSize bounds how many handler invocations may wait for a worker;
+// Dispatch blocks while the queue is full.
+func WithQueueSize(size int) Option {
+	return func(ed *EventDispatcher) { ed.queueSize = size }
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(ed *EventDispatcher) { ed.retryPolicy = policy }
+}
+
+func WithDeadLetterSink(sink DeadLetterSinkInterface) Option {
+	return func(ed *EventDispatcher) { ed.deadLetterSink = sink }
+}
+
+func NewEventDispatcher(opts ...Option) *EventDispatcher {
+	ed := &EventDispatcher{
+		handlers:    make(map[string][]EventHandlerInterface),
+		mode:        DispatchSync,
+		workers:     4,
+		queueSize:   100,
+		retryPolicy: DefaultRetryPolicy,
+		done:        make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(ed)
+	}
+	if ed.mode == DispatchAsync {
+		if ed.workers < 1 {
+			ed.workers = 1
+		}
+		ed.queue = make(chan job, ed.queueSize)
+		for i := 0; i < ed.workers; i++ {
+			ed.workerWg.Add(1)
+			go ed.work()
+		}
+	}
+	return ed
 }
 
-func (ev *EventDispatcher) Dispatch(event EventInterface) error {
-	if handlers, ok := ev.handlers[event.GetName()]; ok {
-		wg := &sync.WaitGroup{}
-		for _, handler := range handlers {
-			wg.Add(1)
-			go handler.Handle(event, wg)
+// Dispatch delivers the event to every handler registered for its name. In
+// sync mode the joined handler errors are returned; in async mode only a
+// failure to queue is.
+func (ev *EventDispatcher) Dispatch(ctx context.Context, event EventInterface) error {
+	handlers := ev.snapshot(event.GetName())
+	if len(handlers) == 0 {
+		return nil
+	}
+	if ev.mode == DispatchAsync {
+		return ev.enqueue(ctx, event, handlers)
+	}
+
+	errs := make([]error, len(handlers))
+	wg := &sync.WaitGroup{}
+	for i, handler := range handlers {
+		wg.Add(1)
+		go func(i int, handler EventHandlerInterface) {
+			defer wg.Done()
+			errs[i] = ev.handle(ctx, event, handler)
+		}(i, handler)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// enqueue hands the event to the workers under a context that keeps the
+// caller's values but not its cancellation, since the caller usually returns
+// before the handlers run.
+func (ev *EventDispatcher) enqueue(ctx context.Context, event EventInterface, handlers []EventHandlerInterface) error {
+	ev.closeMu.RLock()
+	defer ev.closeMu.RUnlock()
+	if ev.closed {
+		return ErrDispatcherClosed
+	}
+	handlerCtx := detachedContext{ctx}
+	for _, handler := range handlers {
+		select {
+		case ev.queue <- job{ctx: handlerCtx, event: event, handler: handler}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ev.done:
+			return ErrDispatcherClosed
 		}
-		wg.Wait()
 	}
 	return nil
 }
 
+func (ev *EventDispatcher) work() {
+	defer ev.workerWg.Done()
+	for j := range ev.queue {
+		ev.handle(j.ctx, j.event, j.handler)
+	}
+}
+
+func (ev *EventDispatcher) handle(ctx context.Context, event EventInterface, handler EventHandlerInterface) error {
+	attempts, err := handleWithRetry(ctx, handler, event, ev.retryPolicy)
+	if err != nil && ev.deadLetterSink != nil {
+		ev.deadLetterSink.Send(context.Background(), DeadLetter{
+			Event:    event,
+			Handler:  handler,
+			Err:      err,
+			Attempts: attempts,
+			FailedAt: time.Now(),
+		})
+	}
+	return err
+}
+
+// Shutdown stops accepting async events and waits for queued handlers to
+// finish or for ctx to be done.
+func (ev *EventDispatcher) Shutdown(ctx context.Context) error {
+	ev.closeOnce.Do(func() {
+		// Release senders blocked on a full queue before waiting for them.
+		close(ev.done)
+		ev.closeMu.Lock()
+		ev.closed = true
+		if ev.queue != nil {
+			close(ev.queue)
+		}
+		ev.closeMu.Unlock()
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		ev.workerWg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// snapshot returns the handlers registered for eventName at this moment.
+func (ed *EventDispatcher) snapshot(eventName string) []EventHandlerInterface {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
+	return ed.handlers[eventName]
+}
+
 func (ed *EventDispatcher) Register(eventName string, handler EventHandlerInterface) error {
-	if _, ok := ed.handlers[eventName]; ok {
-		for _, h := range ed.handlers[eventName] {
-			if h == handler {
-				return ErrHandlerAlreadyRegistered
-			}
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	current := ed.handlers[eventName]
+	for _, h := range current {
+		if h == handler {
+			return ErrHandlerAlreadyRegistered
 		}
 	}
-	ed.handlers[eventName] = append(ed.handlers[eventName], handler)
+	handlers := make([]EventHandlerInterface, len(current), len(current)+1)
+	copy(handlers, current)
+	ed.handlers[eventName] = append(handlers, handler)
 	return nil
 }
 
 func (ed *EventDispatcher) Has(eventName string, handler EventHandlerInterface) bool {
-	if _, ok := ed.handlers[eventName]; ok {
-		for _, h := range ed.handlers[eventName] {
-			if h == handler {
-				return true
-			}
+	for _, h := range ed.snapshot(eventName) {
+		if h == handler {
+			return true
 		}
 	}
 	return false
 }
 
 func (ed *EventDispatcher) Remove(eventName string, handler EventHandlerInterface) error {
-	if _, ok := ed.handlers[eventName]; ok {
-		for i, h := range ed.handlers[eventName] {
-			if h == handler {
-				ed.handlers[eventName] = append(ed.handlers[eventName][:i], ed.handlers[eventName][i+1:]...)
-				return nil
-			}
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	current := ed.handlers[eventName]
+	for i, h := range current {
+		if h == handler {
+			handlers := make([]EventHandlerInterface, 0, len(current)-1)
+			handlers = append(handlers, current[:i]...)
+			ed.handlers[eventName] = append(handlers, current[i+1:]...)
+			return nil
 		}
 	}
 	return nil
 }
 
 func (ed *EventDispatcher) Clear() {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
 	ed.handlers = make(map[string][]EventHandlerInterface)
 }
+
+// detachedContext carries the values of its parent without its deadline or
+// cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
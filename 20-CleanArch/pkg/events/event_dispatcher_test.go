@@ -1,7 +1,10 @@
 package events
 
 import (
+	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -35,7 +38,8 @@ type TestEventHandler struct {
 	ID int
 }
 
-func (h *TestEventHandler) Handle(event EventInterface, wg *sync.WaitGroup) {
+func (h *TestEventHandler) Handle(ctx context.Context, event EventInterface) error {
+	return nil
 }
 
 type EventDispatcherTestSuite struct {
@@ -150,28 +154,263 @@ type MockHandler struct {
 	mock.Mock
 }
 
-func (m *MockHandler) Handle(event EventInterface, wg *sync.WaitGroup) {
-	m.Called(event)
-	wg.Done()
+func (m *MockHandler) Handle(ctx context.Context, event EventInterface) error {
+	args := m.Called(event)
+	return args.Error(0)
 }
 
 func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch() {
 	eh := &MockHandler{}
-	eh.On("Handle", &suite.event)
+	eh.On("Handle", &suite.event).Return(nil)
 
 	eh2 := &MockHandler{}
-	eh2.On("Handle", &suite.event)
+	eh2.On("Handle", &suite.event).Return(nil)
 
 	suite.eventDispatcher.Register(suite.event.GetName(), eh)
 	suite.eventDispatcher.Register(suite.event.GetName(), eh2)
 
-	suite.eventDispatcher.Dispatch(&suite.event)
+	suite.eventDispatcher.Dispatch(context.Background(), &suite.event)
 	eh.AssertExpectations(suite.T())
 	eh2.AssertExpectations(suite.T())
 	eh.AssertNumberOfCalls(suite.T(), "Handle", 1)
 	eh2.AssertNumberOfCalls(suite.T(), "Handle", 1)
 }
 
+type funcHandler struct {
+	fn func(ctx context.Context, event EventInterface) error
+}
+
+func (h *funcHandler) Handle(ctx context.Context, event EventInterface) error {
+	return h.fn(ctx, event)
+}
+
+type policyHandler struct {
+	funcHandler
+	policy RetryPolicy
+}
+
+func (h *policyHandler) RetryPolicy() RetryPolicy {
+	return h.policy
+}
+
+var fastRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Multiplier: 2}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_RetriesUntilTheHandlerSucceeds() {
+	dispatcher := NewEventDispatcher(WithRetryPolicy(fastRetryPolicy))
+	var calls int32
+	dispatcher.Register(suite.event.GetName(), &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}})
+
+	suite.NoError(dispatcher.Dispatch(context.Background(), &suite.event))
+	suite.Equal(int32(3), atomic.LoadInt32(&calls))
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_DeadLettersAHandlerThatKeepsFailing() {
+	sink := NewMemoryDeadLetterSink()
+	dispatcher := NewEventDispatcher(WithRetryPolicy(fastRetryPolicy), WithDeadLetterSink(sink))
+	failure := errors.New("broker unavailable")
+	failing := &funcHandler{fn: func(ctx context.Context, event EventInterface) error { return failure }}
+	dispatcher.Register(suite.event.GetName(), failing)
+	dispatcher.Register(suite.event.GetName(), &suite.handler)
+
+	err := dispatcher.Dispatch(context.Background(), &suite.event)
+	suite.ErrorIs(err, failure)
+	letters := sink.Letters()
+	suite.Len(letters, 1)
+	suite.Equal(failing, letters[0].Handler)
+	suite.Equal(&suite.event, letters[0].Event)
+	suite.Equal(3, letters[0].Attempts)
+	suite.ErrorIs(letters[0].Err, failure)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_RecoversFromAHandlerPanic() {
+	sink := NewMemoryDeadLetterSink()
+	dispatcher := NewEventDispatcher(WithRetryPolicy(RetryPolicy{MaxAttempts: 1}), WithDeadLetterSink(sink))
+	dispatcher.Register(suite.event.GetName(), &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		panic("boom")
+	}})
+
+	err := dispatcher.Dispatch(context.Background(), &suite.event)
+	var panicErr *PanicError
+	suite.ErrorAs(err, &panicErr)
+	suite.Equal("boom", panicErr.Value)
+	suite.Len(sink.Letters(), 1)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_Dispatch_UsesTheHandlerRetryPolicy() {
+	dispatcher := NewEventDispatcher(WithRetryPolicy(fastRetryPolicy))
+	var calls int32
+	handler := &policyHandler{
+		funcHandler: funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+			atomic.AddInt32(&calls, 1)
+			return errors.New("failed")
+		}},
+		policy: RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+	}
+	dispatcher.Register(suite.event.GetName(), handler)
+
+	suite.Error(dispatcher.Dispatch(context.Background(), &suite.event))
+	suite.Equal(int32(5), atomic.LoadInt32(&calls))
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_DispatchAsync_BoundsConcurrencyAndDrainsOnShutdown() {
+	dispatcher := NewEventDispatcher(WithMode(DispatchAsync), WithWorkers(2), WithQueueSize(10))
+	var running, maxRunning, handled int32
+	handler := &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}}
+	dispatcher.Register(suite.event.GetName(), handler)
+
+	for i := 0; i < 8; i++ {
+		suite.NoError(dispatcher.Dispatch(context.Background(), &suite.event))
+	}
+	suite.NoError(dispatcher.Shutdown(context.Background()))
+	suite.Equal(int32(8), atomic.LoadInt32(&handled))
+	suite.LessOrEqual(atomic.LoadInt32(&maxRunning), int32(2))
+
+	suite.ErrorIs(dispatcher.Dispatch(context.Background(), &suite.event), ErrDispatcherClosed)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_DispatchAsync_OutlivesTheCallerContext() {
+	dispatcher := NewEventDispatcher(WithMode(DispatchAsync), WithWorkers(1))
+	handled := make(chan error, 1)
+	dispatcher.Register(suite.event.GetName(), &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		handled <- ctx.Err()
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.NoError(dispatcher.Dispatch(ctx, &suite.event))
+	cancel()
+	suite.NoError(<-handled)
+	suite.NoError(dispatcher.Shutdown(context.Background()))
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatch_DispatchAsync_UnblocksSendersOnShutdown() {
+	release := make(chan struct{})
+	dispatcher := NewEventDispatcher(WithMode(DispatchAsync), WithWorkers(1), WithQueueSize(1))
+	dispatcher.Register(suite.event.GetName(), &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		<-release
+		return nil
+	}})
+	suite.NoError(dispatcher.Dispatch(context.Background(), &suite.event))
+	suite.NoError(dispatcher.Dispatch(context.Background(), &suite.event))
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- dispatcher.Dispatch(context.Background(), &suite.event) }()
+	time.Sleep(10 * time.Millisecond)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	suite.ErrorIs(dispatcher.Shutdown(shutdownCtx), context.DeadlineExceeded)
+	suite.ErrorIs(<-blocked, ErrDispatcherClosed)
+	close(release)
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatcher_ConcurrentRegisterRemoveAndDispatch() {
+	dispatcher := NewEventDispatcher()
+	var handled int32
+	handlers := make([]*funcHandler, 10)
+	for i := range handlers {
+		handlers[i] = &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+			atomic.AddInt32(&handled, 1)
+			return nil
+		}}
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range handlers {
+		wg.Add(3)
+		go func(handler *funcHandler) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				dispatcher.Register(suite.event.GetName(), handler)
+				dispatcher.Remove(suite.event.GetName(), handler)
+			}
+		}(handlers[i])
+		go func(handler *funcHandler) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				suite.NoError(dispatcher.Dispatch(context.Background(), &suite.event))
+				dispatcher.Has(suite.event.GetName(), handler)
+			}
+		}(handlers[i])
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				dispatcher.Clear()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatcher_HandlersCanRegisterAndRemoveDuringDispatch() {
+	dispatcher := NewEventDispatcher()
+	var lateCalls int32
+	late := &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		atomic.AddInt32(&lateCalls, 1)
+		return nil
+	}}
+	var self *funcHandler
+	self = &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		suite.NoError(dispatcher.Remove(event.GetName(), self))
+		return dispatcher.Register(event.GetName(), late)
+	}}
+	suite.NoError(dispatcher.Register(suite.event.GetName(), self))
+
+	suite.NoError(dispatcher.Dispatch(context.Background(), &suite.event))
+	suite.Equal(int32(0), atomic.LoadInt32(&lateCalls))
+	suite.False(dispatcher.Has(suite.event.GetName(), self))
+	suite.True(dispatcher.Has(suite.event.GetName(), late))
+
+	suite.NoError(dispatcher.Dispatch(context.Background(), &suite.event))
+	suite.Equal(int32(1), atomic.LoadInt32(&lateCalls))
+}
+
+func (suite *EventDispatcherTestSuite) TestEventDispatcher_RemoveDoesNotChangeARunningSnapshot() {
+	dispatcher := NewEventDispatcher()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		close(started)
+		<-release
+		return nil
+	}}
+	var calls int32
+	counting := &funcHandler{fn: func(ctx context.Context, event EventInterface) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}}
+	dispatcher.Register(suite.event.GetName(), blocking)
+	dispatcher.Register(suite.event.GetName(), counting)
+	snapshot := dispatcher.snapshot(suite.event.GetName())
+
+	done := make(chan error, 1)
+	go func() { done <- dispatcher.Dispatch(context.Background(), &suite.event) }()
+	<-started
+	dispatcher.Remove(suite.event.GetName(), blocking)
+	suite.Equal([]EventHandlerInterface{blocking, counting}, snapshot)
+	close(release)
+
+	suite.NoError(<-done)
+	suite.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(EventDispatcherTestSuite))
 }
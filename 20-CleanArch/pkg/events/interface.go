@@ -1,7 +1,7 @@
 package events
 
 import (
-	"sync"
+	"context"
 	"time"
 )
 
@@ -13,13 +13,35 @@ type EventInterface interface {
 }
 
 type EventHandlerInterface interface {
-	Handle(event EventInterface, wg *sync.WaitGroup)
+	Handle(ctx context.Context, event EventInterface) error
 }
 
 type EventDispatcherInterface interface {
 	Register(eventName string, handler EventHandlerInterface) error
-	Dispatch(event EventInterface) error
+	Dispatch(ctx context.Context, event EventInterface) error
 	Remove(eventName string, handler EventHandlerInterface) error
 	Has(eventName string, handler EventHandlerInterface) bool
 	Clear()
 }
+
+// DeadLetterSinkInterface receives handler invocations that still failed
+// after every retry.
+type DeadLetterSinkInterface interface {
+	Send(ctx context.Context, letter DeadLetter) error
+}
+
+// PublisherInterface sends messages to a broker, whichever transport backs
+// it.
+type PublisherInterface interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// SubscriberInterface delivers the messages published to topic to handler
+// until ctx is done. Subscribe blocks for that time. A message whose handler
+// fails is redelivered when the transport supports it, unless the error
+// wraps ErrPoisonMessage.
+type SubscriberInterface interface {
+	Subscribe(ctx context.Context, topic string, handler MessageHandlerFunc) error
+	Close() error
+}
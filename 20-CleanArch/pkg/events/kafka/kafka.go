@@ -0,0 +1,103 @@
+// Package kafka adapts Kafka to events.PublisherInterface and
+// events.SubscriberInterface. Message keys pick the partition, so messages
+// sharing a key keep their order.
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/segmentio/kafka-go"
+	"golang.org/x/exp/slog"
+)
+
+type Publisher struct {
+	writer *kafka.Writer
+}
+
+func NewPublisher(brokers []string) *Publisher {
+	return &Publisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context, msg events.Message) error {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for key, value := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   msg.Topic,
+		Key:     []byte(msg.Key),
+		Headers: headers,
+		Value:   msg.Body,
+	})
+}
+
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
+
+// Subscriber reads topics as a consumer group and commits an offset only
+// once its message is handled or found to be poison. Any other handler
+// error ends Subscribe without committing, so subscribing again resumes
+// from that message.
+type Subscriber struct {
+	brokers []string
+	groupID string
+}
+
+func NewSubscriber(brokers []string, groupID string) *Subscriber {
+	return &Subscriber{
+		brokers: brokers,
+		groupID: groupID,
+	}
+}
+
+func (s *Subscriber) Subscribe(ctx context.Context, topic string, handler events.MessageHandlerFunc) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: s.brokers,
+		GroupID: s.groupID,
+		Topic:   topic,
+	})
+	defer reader.Close()
+	for {
+		kafkaMsg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		headers := make(map[string]string, len(kafkaMsg.Headers))
+		for _, header := range kafkaMsg.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+		err = handler(context.Background(), events.Message{
+			Topic:   topic,
+			Key:     string(kafkaMsg.Key),
+			Headers: headers,
+			Body:    kafkaMsg.Value,
+		})
+		if err != nil && !errors.Is(err, events.ErrPoisonMessage) {
+			return err
+		}
+		if err != nil {
+			slog.Warn("skipping poison message", "topic", topic, "offset", kafkaMsg.Offset, "error", err)
+		}
+		if err := reader.CommitMessages(context.Background(), kafkaMsg); err != nil {
+			return err
+		}
+	}
+}
+
+// Close is a no-op; each Subscribe owns and closes its reader.
+func (s *Subscriber) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrBusClosed = errors.New("event bus is closed")
+
+// MemoryBus is an in-process PublisherInterface and SubscriberInterface.
+// Publish runs the topic's handlers before returning and reports their
+// errors, which makes it convenient in tests.
+type MemoryBus struct {
+	mu            sync.RWMutex
+	subscriptions map[string]map[*subscription]struct{}
+	closed        bool
+	done          chan struct{}
+}
+
+type subscription struct {
+	handler MessageHandlerFunc
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		subscriptions: make(map[string]map[*subscription]struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrBusClosed
+	}
+	handlers := make([]MessageHandlerFunc, 0, len(b.subscriptions[msg.Topic]))
+	for sub := range b.subscriptions[msg.Topic] {
+		handlers = append(handlers, sub.handler)
+	}
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, topic string, handler MessageHandlerFunc) error {
+	sub := &subscription{handler: handler}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBusClosed
+	}
+	if b.subscriptions[topic] == nil {
+		b.subscriptions[topic] = make(map[*subscription]struct{})
+	}
+	b.subscriptions[topic][sub] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscriptions[topic], sub)
+		b.mu.Unlock()
+	}()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-b.done:
+		return ErrBusClosed
+	}
+}
+
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func subscribeAsync(bus *MemoryBus, ctx context.Context, topic string, handler MessageHandlerFunc) chan error {
+	done := make(chan error, 1)
+	go func() { done <- bus.Subscribe(ctx, topic, handler) }()
+	// Wait until the subscription is registered.
+	for {
+		bus.mu.RLock()
+		registered := len(bus.subscriptions[topic])
+		bus.mu.RUnlock()
+		if registered > 0 {
+			return done
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGivenASubscriber_WhenPublish_ThenShouldDeliverOnlyItsTopic(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	var received []Message
+	done := subscribeAsync(bus, ctx, "OrderCreated", func(ctx context.Context, msg Message) error {
+		received = append(received, msg)
+		return nil
+	})
+
+	assert.NoError(t, bus.Publish(context.Background(), Message{Topic: "OrderCreated", Key: "1", Body: []byte("a")}))
+	assert.NoError(t, bus.Publish(context.Background(), Message{Topic: "OrderPaid", Body: []byte("b")}))
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, []Message{{Topic: "OrderCreated", Key: "1", Body: []byte("a")}}, received)
+	assert.NoError(t, bus.Publish(context.Background(), Message{Topic: "OrderCreated"}))
+	assert.Len(t, received, 1)
+}
+
+func TestGivenAFailingSubscriber_WhenPublish_ThenShouldReturnItsError(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	failure := errors.New("failed")
+	subscribeAsync(bus, ctx, "OrderCreated", func(ctx context.Context, msg Message) error { return failure })
+
+	assert.ErrorIs(t, bus.Publish(context.Background(), Message{Topic: "OrderCreated"}), failure)
+}
+
+func TestGivenAClosedBus_WhenPublishOrSubscribe_ThenShouldReturnErrBusClosed(t *testing.T) {
+	bus := NewMemoryBus()
+	done := subscribeAsync(bus, context.Background(), "OrderCreated", func(ctx context.Context, msg Message) error { return nil })
+	assert.NoError(t, bus.Close())
+
+	assert.ErrorIs(t, <-done, ErrBusClosed)
+	assert.ErrorIs(t, bus.Publish(context.Background(), Message{Topic: "OrderCreated"}), ErrBusClosed)
+	assert.ErrorIs(t, bus.Subscribe(context.Background(), "OrderCreated", nil), ErrBusClosed)
+}
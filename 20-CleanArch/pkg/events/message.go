@@ -0,0 +1,23 @@
+package events
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPoisonMessage marks a message that can never be processed, so
+// subscribers drop or dead-letter it instead of redelivering it.
+var ErrPoisonMessage = errors.New("poison message")
+
+// Message is the transport-neutral unit exchanged through a
+// PublisherInterface and a SubscriberInterface. Topic maps to a RabbitMQ
+// routing key, a NATS subject or a Kafka topic; Key, when set, keeps related
+// messages in order where the transport partitions.
+type Message struct {
+	Topic   string
+	Key     string
+	Headers map[string]string
+	Body    []byte
+}
+
+type MessageHandlerFunc func(ctx context.Context, msg Message) error
@@ -0,0 +1,97 @@
+// Package nats adapts NATS core messaging to events.PublisherInterface and
+// events.SubscriberInterface. Topics are subjects; subscribers sharing a
+// queue group split the messages between them.
+package nats
+
+import (
+	"context"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/nats-io/nats.go"
+	"golang.org/x/exp/slog"
+)
+
+// keyHeader carries events.Message.Key.
+const keyHeader = "Message-Key"
+
+type Publisher struct {
+	conn *nats.Conn
+}
+
+func NewPublisher(conn *nats.Conn) *Publisher {
+	return &Publisher{conn: conn}
+}
+
+func (p *Publisher) Publish(ctx context.Context, msg events.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	natsMsg := nats.NewMsg(msg.Topic)
+	for key, value := range msg.Headers {
+		natsMsg.Header.Set(key, value)
+	}
+	if msg.Key != "" {
+		natsMsg.Header.Set(keyHeader, msg.Key)
+	}
+	natsMsg.Data = msg.Body
+	if err := p.conn.PublishMsg(natsMsg); err != nil {
+		return err
+	}
+	// Flush so a publish error surfaces here rather than on a later call.
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Close is a no-op; the connection belongs to the caller.
+func (p *Publisher) Close() error {
+	return nil
+}
+
+// Subscriber receives at most once: NATS core does not redeliver, so a
+// failed handler is only logged.
+type Subscriber struct {
+	conn       *nats.Conn
+	queueGroup string
+}
+
+func NewSubscriber(conn *nats.Conn, queueGroup string) *Subscriber {
+	return &Subscriber{
+		conn:       conn,
+		queueGroup: queueGroup,
+	}
+}
+
+func (s *Subscriber) Subscribe(ctx context.Context, topic string, handler events.MessageHandlerFunc) error {
+	messages := make(chan *nats.Msg, 64)
+	sub, err := s.conn.ChanQueueSubscribe(topic, s.queueGroup, messages)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case natsMsg := <-messages:
+			headers := make(map[string]string, len(natsMsg.Header))
+			for key := range natsMsg.Header {
+				if key != keyHeader {
+					headers[key] = natsMsg.Header.Get(key)
+				}
+			}
+			err := handler(context.Background(), events.Message{
+				Topic:   topic,
+				Key:     natsMsg.Header.Get(keyHeader),
+				Headers: headers,
+				Body:    natsMsg.Data,
+			})
+			if err != nil {
+				slog.Warn("dropping message", "topic", topic, "error", err)
+			}
+		}
+	}
+}
+
+// Close is a no-op; the connection belongs to the caller.
+func (s *Subscriber) Close() error {
+	return nil
+}
@@ -0,0 +1,264 @@
+// Package rabbitmq adapts RabbitMQ to events.PublisherInterface and
+// events.SubscriberInterface. Topics are routing keys on a single exchange.
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/streadway/amqp"
+	"golang.org/x/exp/slog"
+)
+
+var ErrChannelClosed = errors.New("amqp channel closed")
+
+// keyHeader carries events.Message.Key, which AMQP has no property for.
+const keyHeader = "x-message-key"
+
+// retryHeader counts the times a message has been sent back for another
+// attempt. Unlike Redelivered, it survives the trip through the retry queue.
+const retryHeader = "x-retry"
+
+// ConfirmPublisherInterface publishes and waits for the broker to confirm.
+type ConfirmPublisherInterface interface {
+	Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error
+}
+
+// ChannelProviderInterface opens channels on a live connection.
+type ChannelProviderInterface interface {
+	Channel(ctx context.Context) (*amqp.Channel, error)
+}
+
+// channelPublisher is the part of *amqp.Channel a subscriber retries
+// messages with.
+type channelPublisher interface {
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+type Publisher struct {
+	publisher ConfirmPublisherInterface
+	exchange  string
+}
+
+func NewPublisher(publisher ConfirmPublisherInterface, exchange string) *Publisher {
+	return &Publisher{
+		publisher: publisher,
+		exchange:  exchange,
+	}
+}
+
+// Publish routes msg by its topic and also sets it as the AMQP type.
+func (p *Publisher) Publish(ctx context.Context, msg events.Message) error {
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		// CloudEvents attributes follow the AMQP binding's naming.
+		if strings.HasPrefix(key, events.MessageHeaderPrefix) {
+			key = events.AMQPHeaderPrefix + key[len(events.MessageHeaderPrefix):]
+		}
+		headers[key] = value
+	}
+	if msg.Key != "" {
+		headers[keyHeader] = msg.Key
+	}
+	return p.publisher.Publish(ctx, p.exchange, msg.Topic, amqp.Publishing{
+		ContentType:  msg.Headers["content-type"],
+		DeliveryMode: amqp.Persistent,
+		Type:         msg.Topic,
+		Timestamp:    time.Now(),
+		Headers:      headers,
+		Body:         msg.Body,
+	})
+}
+
+// Close is a no-op; the connection belongs to the caller.
+func (p *Publisher) Close() error {
+	return nil
+}
+
+// DeclareQueue declares a durable queue bound to exchange for each topic,
+// plus a <queue>.dlx exchange and <queue>.dlq queue receiving the messages
+// the queue rejects, and a <queue>.retry queue holding failed messages until
+// their expiration sends them back to the queue. Declaring is idempotent.
+func DeclareQueue(ch *amqp.Channel, exchange, queue string, topics ...string) error {
+	deadLetterExchange := queue + ".dlx"
+	deadLetterQueue := queue + ".dlq"
+	retryArgs := amqp.Table{"x-dead-letter-exchange": "", "x-dead-letter-routing-key": queue}
+	if _, err := ch.QueueDeclare(retryQueueName(queue), true, false, false, false, retryArgs); err != nil {
+		return err
+	}
+	if err := ch.ExchangeDeclare(deadLetterExchange, amqp.ExchangeFanout, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := ch.QueueDeclare(deadLetterQueue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(deadLetterQueue, "", deadLetterExchange, false, nil); err != nil {
+		return err
+	}
+	args := amqp.Table{"x-dead-letter-exchange": deadLetterExchange}
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, args); err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		if err := ch.QueueBind(queue, topic, exchange, false, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueueName is the queue a Subscriber with the given queue prefix consumes
+// topic from; each topic gets its own queue so subscriptions do not steal
+// each other's messages.
+func QueueName(queue, topic string) string {
+	return queue + "." + topic
+}
+
+func retryQueueName(queue string) string {
+	return queue + ".retry"
+}
+
+// Subscriber consumes one queue per topic with manual acks. A failed message
+// is parked in the queue's retry queue for retryPolicy's backoff and then
+// handled again; once it has failed retryPolicy.MaxAttempts times, or at once
+// if it is a poison message, it is rejected to the queue's dead-letter
+// exchange.
+type Subscriber struct {
+	channels    ChannelProviderInterface
+	exchange    string
+	queue       string
+	prefetch    int
+	retryPolicy events.RetryPolicy
+}
+
+func NewSubscriber(channels ChannelProviderInterface, exchange, queue string, prefetch int, retryPolicy events.RetryPolicy) *Subscriber {
+	return &Subscriber{
+		channels:    channels,
+		exchange:    exchange,
+		queue:       queue,
+		prefetch:    prefetch,
+		retryPolicy: retryPolicy,
+	}
+}
+
+// Subscribe declares the topic's queue, binds it and consumes until ctx is
+// done or the channel closes. On cancellation the message being handled is
+// finished and acknowledged; prefetched messages not yet handled are
+// returned to the queue by the broker.
+func (s *Subscriber) Subscribe(ctx context.Context, topic string, handler events.MessageHandlerFunc) error {
+	ch, err := s.channels.Channel(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+	queue := QueueName(s.queue, topic)
+	if err := DeclareQueue(ch, s.exchange, queue, topic); err != nil {
+		return err
+	}
+	if err := ch.Qos(s.prefetch, 0, false); err != nil {
+		return err
+	}
+	tag := queue + "-consumer"
+	deliveries, err := ch.Consume(queue, tag, false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ch.Cancel(tag, false)
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return ErrChannelClosed
+			}
+			s.process(context.Background(), ch, delivery, queue, topic, handler)
+		}
+	}
+}
+
+// process hands delivery to handler and settles it. A message the handler
+// fails on is republished to the retry queue with its retry count bumped
+// and an expiration of the policy's backoff; a failed republish falls back
+// to requeueing it at once.
+func (s *Subscriber) process(ctx context.Context, ch channelPublisher, delivery amqp.Delivery, queue, topic string, handler events.MessageHandlerFunc) {
+	// x- headers are ours or the broker's, such as the x-death ones it adds
+	// on dead-lettering, not the publisher's.
+	headers := make(map[string]string, len(delivery.Headers))
+	for key, value := range delivery.Headers {
+		value, ok := value.(string)
+		if !ok || strings.HasPrefix(key, "x-") {
+			continue
+		}
+		if strings.HasPrefix(key, events.AMQPHeaderPrefix) {
+			key = events.MessageHeaderPrefix + key[len(events.AMQPHeaderPrefix):]
+		}
+		headers[key] = value
+	}
+	key, _ := delivery.Headers[keyHeader].(string)
+	err := handler(ctx, events.Message{
+		Topic:   topic,
+		Key:     key,
+		Headers: headers,
+		Body:    delivery.Body,
+	})
+	if err == nil {
+		delivery.Ack(false)
+		return
+	}
+	attempt := retries(delivery.Headers) + 1
+	if errors.Is(err, events.ErrPoisonMessage) || attempt >= s.retryPolicy.MaxAttempts {
+		slog.Warn("dead-lettering message", "topic", topic, "delivery_tag", delivery.DeliveryTag, "attempts", attempt, "error", err)
+		delivery.Nack(false, false)
+		return
+	}
+	backoff := s.retryPolicy.Backoff(attempt)
+	if retryErr := ch.Publish("", retryQueueName(queue), false, false, retry(delivery, attempt, backoff)); retryErr != nil {
+		slog.Warn("requeueing message", "topic", topic, "delivery_tag", delivery.DeliveryTag, "attempts", attempt, "error", err, "retry_error", retryErr)
+		delivery.Nack(false, true)
+		return
+	}
+	slog.Warn("retrying message", "topic", topic, "delivery_tag", delivery.DeliveryTag, "attempts", attempt, "backoff", backoff, "error", err)
+	delivery.Ack(false)
+}
+
+// retries reads retryHeader, which comes back from the broker as whichever
+// integer type it was published with.
+func retries(headers amqp.Table) int {
+	switch n := headers[retryHeader].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// retry copies delivery for the retry queue, recording that attempt failed.
+// The broker drops the expiration when it dead-letters the copy back.
+func retry(delivery amqp.Delivery, attempt int, backoff time.Duration) amqp.Publishing {
+	headers := make(amqp.Table, len(delivery.Headers)+1)
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	headers[retryHeader] = int32(attempt)
+	return amqp.Publishing{
+		ContentType:  delivery.ContentType,
+		DeliveryMode: amqp.Persistent,
+		Type:         delivery.Type,
+		Timestamp:    delivery.Timestamp,
+		Expiration:   strconv.FormatInt(backoff.Milliseconds(), 10),
+		Headers:      headers,
+		Body:         delivery.Body,
+	}
+}
+
+// Close is a no-op; the connection belongs to the caller.
+func (s *Subscriber) Close() error {
+	return nil
+}
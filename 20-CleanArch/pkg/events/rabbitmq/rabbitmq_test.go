@@ -0,0 +1,181 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/suite"
+)
+
+type acknowledgement struct {
+	ack     bool
+	requeue bool
+}
+
+type fakeAcknowledger struct {
+	acknowledgements []acknowledgement
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acknowledgements = append(a.acknowledgements, acknowledgement{ack: true})
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.acknowledgements = append(a.acknowledgements, acknowledgement{requeue: requeue})
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+type fakeConfirmPublisher struct {
+	exchange, key string
+	msg           amqp.Publishing
+}
+
+func (p *fakeConfirmPublisher) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	p.exchange, p.key, p.msg = exchange, key, msg
+	return nil
+}
+
+type fakeChannel struct {
+	err       error
+	key       string
+	published []amqp.Publishing
+}
+
+func (c *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.key = key
+	c.published = append(c.published, msg)
+	return nil
+}
+
+type RabbitMQTestSuite struct {
+	suite.Suite
+	acknowledger *fakeAcknowledger
+	channel      *fakeChannel
+	subscriber   *Subscriber
+}
+
+func (suite *RabbitMQTestSuite) SetupTest() {
+	suite.acknowledger = &fakeAcknowledger{}
+	suite.channel = &fakeChannel{}
+	suite.subscriber = NewSubscriber(nil, "amq.direct", "orders", 1, events.DefaultRetryPolicy)
+}
+
+func TestRabbitMQSuite(t *testing.T) {
+	suite.Run(t, new(RabbitMQTestSuite))
+}
+
+func (suite *RabbitMQTestSuite) deliver(publishing amqp.Publishing, redelivered bool, handler events.MessageHandlerFunc) acknowledgement {
+	suite.subscriber.process(context.Background(), suite.channel, amqp.Delivery{
+		Acknowledger: suite.acknowledger,
+		Headers:      publishing.Headers,
+		Type:         publishing.Type,
+		Body:         publishing.Body,
+		Redelivered:  redelivered,
+	}, QueueName("orders", "OrderCreated"), "OrderCreated", handler)
+	suite.Require().NotEmpty(suite.acknowledger.acknowledgements)
+	return suite.acknowledger.acknowledgements[len(suite.acknowledger.acknowledgements)-1]
+}
+
+func (suite *RabbitMQTestSuite) TestGivenAMessage_WhenPublishedAndDelivered_ThenShouldRoundTripAndAck() {
+	fake := &fakeConfirmPublisher{}
+	sent := events.Message{
+		Topic:   "OrderCreated",
+		Key:     "123",
+		Headers: map[string]string{"content-type": "application/json", "ce_id": "event-1"},
+		Body:    []byte(`{"id":"123"}`),
+	}
+	suite.NoError(NewPublisher(fake, "amq.direct").Publish(context.Background(), sent))
+	suite.Equal("amq.direct", fake.exchange)
+	suite.Equal("OrderCreated", fake.key)
+	suite.Equal("OrderCreated", fake.msg.Type)
+	suite.Equal("application/json", fake.msg.ContentType)
+	suite.Equal(amqp.Persistent, fake.msg.DeliveryMode)
+	suite.Equal("event-1", fake.msg.Headers["cloudEvents_id"])
+
+	var received events.Message
+	result := suite.deliver(fake.msg, false, func(ctx context.Context, msg events.Message) error {
+		received = msg
+		return nil
+	})
+	suite.True(result.ack)
+	suite.Equal(sent, received)
+}
+
+func (suite *RabbitMQTestSuite) TestGivenAPoisonMessage_WhenDelivered_ThenShouldDeadLetter() {
+	result := suite.deliver(amqp.Publishing{Body: []byte("not json")}, false, func(ctx context.Context, msg events.Message) error {
+		return fmt.Errorf("%w: invalid body", events.ErrPoisonMessage)
+	})
+	suite.False(result.ack)
+	suite.False(result.requeue)
+}
+
+func (suite *RabbitMQTestSuite) TestGivenAFailingHandler_WhenDelivered_ThenShouldRetryWithBackoffUntilMaxAttemptsThenDeadLetter() {
+	failing := func(ctx context.Context, msg events.Message) error { return errors.New("database unavailable") }
+	message := amqp.Publishing{Type: "OrderCreated", Headers: amqp.Table{"cloudEvents_id": "event-1"}, Body: []byte(`{"id":"123"}`)}
+
+	first := suite.deliver(message, false, failing)
+	suite.True(first.ack)
+	suite.Equal("orders.OrderCreated.retry", suite.channel.key)
+	suite.Require().Len(suite.channel.published, 1)
+	retried := suite.channel.published[0]
+	suite.Equal(int32(1), retried.Headers[retryHeader])
+	suite.Equal("event-1", retried.Headers["cloudEvents_id"])
+	suite.Equal("100", retried.Expiration)
+	suite.Equal(message.Body, retried.Body)
+	suite.Nil(message.Headers[retryHeader], "the delivered headers are left alone")
+
+	// The broker hands the copy back marked as a fresh delivery.
+	second := suite.deliver(retried, false, failing)
+	suite.True(second.ack)
+	suite.Require().Len(suite.channel.published, 2)
+	suite.Equal(int32(2), suite.channel.published[1].Headers[retryHeader])
+	suite.Equal("200", suite.channel.published[1].Expiration)
+
+	third := suite.deliver(suite.channel.published[1], false, failing)
+	suite.False(third.ack)
+	suite.False(third.requeue)
+	suite.Len(suite.channel.published, 2)
+}
+
+func (suite *RabbitMQTestSuite) TestGivenARedeliveredMessage_WhenTheHandlerFails_ThenShouldStillBeRetried() {
+	result := suite.deliver(amqp.Publishing{}, true, func(ctx context.Context, msg events.Message) error {
+		return errors.New("database unavailable")
+	})
+	suite.True(result.ack)
+	suite.Len(suite.channel.published, 1)
+}
+
+func (suite *RabbitMQTestSuite) TestGivenTheRetryQueueIsUnavailable_WhenTheHandlerFails_ThenShouldRequeue() {
+	suite.channel.err = ErrChannelClosed
+
+	result := suite.deliver(amqp.Publishing{}, false, func(ctx context.Context, msg events.Message) error {
+		return errors.New("database unavailable")
+	})
+	suite.False(result.ack)
+	suite.True(result.requeue)
+}
+
+func (suite *RabbitMQTestSuite) TestGivenBrokerHeaders_WhenDelivered_ThenShouldNotPassThemToTheHandler() {
+	var received events.Message
+	suite.deliver(amqp.Publishing{Headers: amqp.Table{
+		"content-type":        "application/json",
+		"x-first-death-queue": "orders.OrderCreated.retry",
+		retryHeader:           int32(1),
+	}}, false, func(ctx context.Context, msg events.Message) error {
+		received = msg
+		return nil
+	})
+	suite.Equal(map[string]string{"content-type": "application/json"}, received.Headers)
+}
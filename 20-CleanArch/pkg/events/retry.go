@@ -0,0 +1,87 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy controls how often a failing handler is retried. Backoff starts
+// at InitialBackoff and grows by Multiplier up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+}
+
+// RetryPolicyProvider lets a handler override the dispatcher's retry policy.
+type RetryPolicyProvider interface {
+	RetryPolicy() RetryPolicy
+}
+
+// Backoff is the wait after the failed attempt numbered attempt, counting
+// from one.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		backoff = time.Duration(float64(backoff) * p.Multiplier)
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// PanicError is returned in place of a panic raised by a handler.
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("event handler panicked: %v", e.Value)
+}
+
+// handleWithRetry calls the handler until it succeeds, the policy runs out of
+// attempts or ctx is done. It returns the attempts made and the last error.
+func handleWithRetry(ctx context.Context, handler EventHandlerInterface, event EventInterface, policy RetryPolicy) (int, error) {
+	if provider, ok := handler.(RetryPolicyProvider); ok {
+		policy = provider.RetryPolicy()
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if err = safeHandle(ctx, handler, event); err == nil {
+			return attempt, nil
+		}
+		if attempt == policy.MaxAttempts {
+			return attempt, err
+		}
+		timer := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+	return policy.MaxAttempts, err
+}
+
+func safeHandle(ctx context.Context, handler EventHandlerInterface, event EventInterface) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r}
+		}
+	}()
+	return handler.Handle(ctx, event)
+}
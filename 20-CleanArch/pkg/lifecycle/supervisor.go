@@ -0,0 +1,179 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"golang.org/x/sync/errgroup"
+)
+
+var errNotReady = errors.New("not ready")
+
+// Server is a listener based server run by the Supervisor. *http.Server and
+// grpcserver.GRPCServer satisfy it.
+type Server interface {
+	Serve(lis net.Listener) error
+	Shutdown(ctx context.Context) error
+}
+
+type namedServer struct {
+	name    string
+	address string
+	server  Server
+	lis     net.Listener
+}
+
+type namedFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Supervisor runs servers and background tasks as one unit: the first one
+// to fail stops all of them, as does cancelling the context given to Run.
+// Once the servers have drained, the closers release the resources they
+// used, in reverse order of registration.
+type Supervisor struct {
+	shutdownTimeout time.Duration
+	servers         []*namedServer
+	tasks           []namedFunc
+	closers         []namedFunc
+	ready           atomic.Bool
+	stopping        atomic.Bool
+}
+
+func NewSupervisor(shutdownTimeout time.Duration) *Supervisor {
+	return &Supervisor{
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+// AddServer registers server to be served on a TCP listener bound to
+// address.
+func (s *Supervisor) AddServer(name, address string, server Server) {
+	s.servers = append(s.servers, &namedServer{name: name, address: address, server: server})
+}
+
+// AddTask registers a background loop. run must return once ctx is done.
+func (s *Supervisor) AddTask(name string, run func(ctx context.Context) error) {
+	s.tasks = append(s.tasks, namedFunc{name: name, fn: run})
+}
+
+// AddCloser registers a resource to release once every server has stopped.
+func (s *Supervisor) AddCloser(name string, close func(ctx context.Context) error) {
+	s.closers = append(s.closers, namedFunc{name: name, fn: close})
+}
+
+// Ready returns nil while every listener is up and shutdown has not begun;
+// it is meant as a readiness check.
+func (s *Supervisor) Ready() error {
+	if !s.ready.Load() {
+		return errNotReady
+	}
+	return nil
+}
+
+// Run binds every listener, then serves until ctx is done or a server or
+// task fails, shuts everything down within the shutdown timeout and runs
+// the closers. It returns the error that stopped the supervisor, if any,
+// joined with the errors of the shutdown.
+func (s *Supervisor) Run(ctx context.Context) error {
+	if err := s.listen(); err != nil {
+		return errors.Join(err, s.close())
+	}
+	s.ready.Store(true)
+	slog.Info("ready", "listeners", len(s.servers))
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, server := range s.servers {
+		server := server
+		group.Go(func() error {
+			err := server.server.Serve(server.lis)
+			if errors.Is(err, http.ErrServerClosed) || (err == nil && s.stopping.Load()) {
+				return nil
+			}
+			if err == nil {
+				err = errors.New("stopped unexpectedly")
+			}
+			return fmt.Errorf("%s server: %w", server.name, err)
+		})
+	}
+	for _, task := range s.tasks {
+		task := task
+		group.Go(func() error {
+			err := task.fn(groupCtx)
+			if err == nil || groupCtx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", task.name, err)
+		})
+	}
+	group.Go(func() error {
+		<-groupCtx.Done()
+		return s.shutdown()
+	})
+	err := group.Wait()
+	return errors.Join(err, s.close())
+}
+
+// listen binds every server's listener up front, so that readiness means
+// all of them accept connections.
+func (s *Supervisor) listen() error {
+	for i, server := range s.servers {
+		lis, err := net.Listen("tcp", server.address)
+		if err != nil {
+			for _, bound := range s.servers[:i] {
+				bound.lis.Close()
+			}
+			return fmt.Errorf("%s server: %w", server.name, err)
+		}
+		server.lis = lis
+		slog.Info("server listening", "server", server.name, "address", lis.Addr().String())
+	}
+	return nil
+}
+
+// shutdown reports not ready and stops the servers concurrently; servers
+// still draining after the shutdown timeout are cut off by their Shutdown.
+func (s *Supervisor) shutdown() error {
+	s.ready.Store(false)
+	s.stopping.Store(true)
+	slog.Info("shutting down", "servers", len(s.servers))
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.servers))
+	for i, server := range s.servers {
+		wg.Add(1)
+		go func(i int, server *namedServer) {
+			defer wg.Done()
+			if err := server.server.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s server shutdown: %w", server.name, err)
+			}
+		}(i, server)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// close runs the closers in reverse order of registration, so resources
+// are released after whatever was registered later and depends on them.
+func (s *Supervisor) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		closer := s.closers[i]
+		if err := closer.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("closing %s: %w", closer.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
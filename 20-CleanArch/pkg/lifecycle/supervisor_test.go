@@ -0,0 +1,174 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer accepts connections until Shutdown, or fails with serveErr
+// when it is set.
+type fakeServer struct {
+	serveErr  error
+	served    chan error
+	stopped   chan struct{}
+	stopOnce  sync.Once
+	shutdowns int
+	mu        sync.Mutex
+}
+
+func newFakeServer(serveErr error) *fakeServer {
+	return &fakeServer{serveErr: serveErr, served: make(chan error, 1), stopped: make(chan struct{})}
+}
+
+func (s *fakeServer) Serve(lis net.Listener) error {
+	defer lis.Close()
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	<-s.stopped
+	return http.ErrServerClosed
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shutdowns++
+	s.mu.Unlock()
+	s.stopOnce.Do(func() { close(s.stopped) })
+	return nil
+}
+
+func (s *fakeServer) Shutdowns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdowns
+}
+
+func TestGivenRunningServers_WhenContextIsCancelled_ThenShouldShutDownAllAndCloseInReverseOrder(t *testing.T) {
+	supervisor := NewSupervisor(time.Second)
+	web, grpc := newFakeServer(nil), newFakeServer(nil)
+	supervisor.AddServer("web", "127.0.0.1:0", web)
+	supervisor.AddServer("grpc", "127.0.0.1:0", grpc)
+	var closed []string
+	supervisor.AddCloser("database", func(context.Context) error { closed = append(closed, "database"); return nil })
+	supervisor.AddCloser("broker", func(context.Context) error { closed = append(closed, "broker"); return nil })
+	taskStopped := make(chan struct{})
+	supervisor.AddTask("purge", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(taskStopped)
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- supervisor.Run(ctx) }()
+	assert.Eventually(t, func() bool { return supervisor.Ready() == nil }, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	assert.Equal(t, 1, web.Shutdowns())
+	assert.Equal(t, 1, grpc.Shutdowns())
+	assert.Equal(t, []string{"broker", "database"}, closed)
+	assert.Error(t, supervisor.Ready())
+	<-taskStopped
+}
+
+func TestGivenAFailingServer_WhenRunning_ThenShouldStopTheOthersAndReturnItsError(t *testing.T) {
+	supervisor := NewSupervisor(time.Second)
+	failure := errors.New("boom")
+	healthy := newFakeServer(nil)
+	supervisor.AddServer("web", "127.0.0.1:0", healthy)
+	supervisor.AddServer("grpc", "127.0.0.1:0", newFakeServer(failure))
+	closed := false
+	supervisor.AddCloser("database", func(context.Context) error { closed = true; return nil })
+
+	err := supervisor.Run(context.Background())
+
+	assert.ErrorIs(t, err, failure)
+	assert.Contains(t, err.Error(), "grpc server")
+	assert.Equal(t, 1, healthy.Shutdowns())
+	assert.True(t, closed)
+}
+
+func TestGivenAnAddressInUse_WhenRunning_ThenShouldNotServeAndStillClose(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer taken.Close()
+	supervisor := NewSupervisor(time.Second)
+	first := newFakeServer(nil)
+	supervisor.AddServer("web", "127.0.0.1:0", first)
+	supervisor.AddServer("grpc", taken.Addr().String(), newFakeServer(nil))
+	closed := false
+	supervisor.AddCloser("database", func(context.Context) error { closed = true; return nil })
+
+	err = supervisor.Run(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "grpc server")
+	assert.Equal(t, 0, first.Shutdowns())
+	assert.True(t, closed)
+	assert.Error(t, supervisor.Ready())
+}
+
+func TestGivenAFailingTask_WhenRunning_ThenShouldStopTheServers(t *testing.T) {
+	supervisor := NewSupervisor(time.Second)
+	server := newFakeServer(nil)
+	supervisor.AddServer("web", "127.0.0.1:0", server)
+	failure := errors.New("purge failed")
+	supervisor.AddTask("purge", func(context.Context) error { return failure })
+
+	err := supervisor.Run(context.Background())
+
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, server.Shutdowns())
+}
+
+func TestGivenAnHTTPServer_WhenShuttingDown_ThenShouldFinishInFlightRequests(t *testing.T) {
+	supervisor := NewSupervisor(time.Second)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := lis.Addr().String()
+	lis.Close()
+	supervisor.AddServer("web", address, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- supervisor.Run(ctx) }()
+	assert.Eventually(t, func() bool { return supervisor.Ready() == nil }, time.Second, time.Millisecond)
+
+	responses := make(chan int, 1)
+	go func() {
+		response, err := http.Get("http://" + address)
+		if err != nil {
+			responses <- 0
+			return
+		}
+		response.Body.Close()
+		responses <- response.StatusCode
+	}()
+	<-started
+	cancel()
+	assert.Eventually(t, func() bool { return supervisor.Ready() != nil }, time.Second, time.Millisecond)
+	close(release)
+
+	assert.Equal(t, http.StatusNoContent, <-responses)
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+)
+
+// Supported log formats.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// NewLogger returns a logger writing format records of level and above to
+// w. Records logged with a context that carries a span get its trace_id
+// and span_id, so logs and traces can be joined.
+func NewLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: minLevel}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case LogFormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported log format %q", format)
+	}
+	return slog.New(traceHandler{handler}), nil
+}
+
+// traceHandler adds the IDs of the span in the record's context.
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
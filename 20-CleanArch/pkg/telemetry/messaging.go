@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingPublisher publishes each message in a producer span and injects
+// the span's context into the message headers, so consumers continue the
+// trace. System names the broker, such as rabbitmq.
+type TracingPublisher struct {
+	publisher events.PublisherInterface
+	system    string
+}
+
+func NewTracingPublisher(system string, publisher events.PublisherInterface) *TracingPublisher {
+	return &TracingPublisher{
+		publisher: publisher,
+		system:    system,
+	}
+}
+
+func (p *TracingPublisher) Publish(ctx context.Context, msg events.Message) error {
+	start := time.Now()
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, msg.Topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(messagingAttributes(p.system, msg, "publish")...),
+	)
+	defer span.End()
+
+	// The caller's map is left as it is; it may be published again.
+	headers := make(map[string]string, len(msg.Headers)+2)
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+	msg.Headers = headers
+
+	err := p.publisher.Publish(ctx, msg)
+	endSpan(span, err)
+	RecordRED(ctx, p.system, msg.Topic+" publish", start, err)
+	return err
+}
+
+func (p *TracingPublisher) Close() error {
+	return p.publisher.Close()
+}
+
+// TracingSubscriber hands each message to its handler in a consumer span
+// that continues the trace found in the message headers.
+type TracingSubscriber struct {
+	subscriber events.SubscriberInterface
+	system     string
+}
+
+func NewTracingSubscriber(system string, subscriber events.SubscriberInterface) *TracingSubscriber {
+	return &TracingSubscriber{
+		subscriber: subscriber,
+		system:     system,
+	}
+}
+
+func (s *TracingSubscriber) Subscribe(ctx context.Context, topic string, handler events.MessageHandlerFunc) error {
+	return s.subscriber.Subscribe(ctx, topic, TraceHandler(s.system, handler))
+}
+
+func (s *TracingSubscriber) Close() error {
+	return s.subscriber.Close()
+}
+
+// TraceHandler wraps handler so it runs in a consumer span, child of the
+// producer span whose context the message headers carry.
+func TraceHandler(system string, handler events.MessageHandlerFunc) events.MessageHandlerFunc {
+	return func(ctx context.Context, msg events.Message) error {
+		start := time.Now()
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Headers))
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, msg.Topic+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(messagingAttributes(system, msg, "process")...),
+		)
+		defer span.End()
+
+		err := handler(ctx, msg)
+		endSpan(span, err)
+		RecordRED(ctx, system, msg.Topic+" process", start, err)
+		return err
+	}
+}
+
+func messagingAttributes(system string, msg events.Message, operation string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystem(system),
+		semconv.MessagingDestinationName(msg.Topic),
+		semconv.MessagingOperationKey.String(operation),
+		semconv.MessagingMessagePayloadSizeBytes(len(msg.Body)),
+	}
+	if id := msg.Headers[events.MessageHeaderPrefix+"id"]; id != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(id))
+	}
+	return attrs
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
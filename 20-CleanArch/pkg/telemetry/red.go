@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Attribute keys of the RED metrics.
+const (
+	TransportKey = attribute.Key("transport")
+	OperationKey = attribute.Key("operation")
+)
+
+// red holds the rate, errors and duration instruments every transport
+// records into, told apart by the transport and operation attributes.
+type red struct {
+	requests metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+var (
+	redOnce        sync.Once
+	redInstruments red
+)
+
+// instruments are created on the global meter provider, which forwards to
+// the one Setup installs even when they were created before.
+func instruments() red {
+	redOnce.Do(func() {
+		meter := otel.Meter(instrumentationName)
+		redInstruments.requests, _ = meter.Int64Counter("red.requests",
+			metric.WithDescription("Requests handled"))
+		redInstruments.errors, _ = meter.Int64Counter("red.errors",
+			metric.WithDescription("Requests that failed"))
+		redInstruments.duration, _ = meter.Float64Histogram("red.duration",
+			metric.WithDescription("Time taken to handle a request"), metric.WithUnit("s"))
+	})
+	return redInstruments
+}
+
+// RecordRED counts a request of operation over transport that started at
+// start, as failed when err is not nil.
+func RecordRED(ctx context.Context, transport, operation string, start time.Time, err error) {
+	instruments := instruments()
+	attrs := metric.WithAttributes(TransportKey.String(transport), OperationKey.String(operation))
+	instruments.requests.Add(ctx, 1, attrs)
+	if err != nil {
+		instruments.errors.Add(ctx, 1, attrs)
+	}
+	instruments.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+}
@@ -0,0 +1,147 @@
+// Package telemetry sets up OpenTelemetry tracing and metrics, the slog
+// logger that stamps records with the active trace, and the RED metrics and
+// message tracing the transports share.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"golang.org/x/exp/slog"
+)
+
+// Supported values for Config.TracesExporter and Config.MetricsExporter.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// instrumentationName names the tracer and meter of this package.
+const instrumentationName = "github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/telemetry"
+
+// Config selects where traces and metrics go. OTLPEndpoint is the
+// collector's gRPC address, host:port; an http:// prefix is accepted and
+// implies OTLPInsecure. SampleRatio is the share of new traces recorded;
+// traces started upstream follow the caller's decision.
+type Config struct {
+	ServiceName     string
+	TracesExporter  string
+	MetricsExporter string
+	OTLPEndpoint    string
+	OTLPInsecure    bool
+	SampleRatio     float64
+	MetricInterval  time.Duration
+}
+
+// Setup installs the global tracer and meter providers and the W3C trace
+// context and baggage propagators. The returned function flushes and stops
+// the exporters; call it on shutdown.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(config.ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, insecure := otlpEndpoint(config.OTLPEndpoint, config.OTLPInsecure)
+
+	spanExporter, err := newSpanExporter(ctx, config.TracesExporter, endpoint, insecure)
+	if err != nil {
+		return nil, err
+	}
+	traceOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	}
+	if spanExporter != nil {
+		traceOptions = append(traceOptions, sdktrace.WithBatcher(spanExporter))
+	}
+	tracerProvider := sdktrace.NewTracerProvider(traceOptions...)
+
+	metricExporter, err := newMetricExporter(ctx, config.MetricsExporter, endpoint, insecure)
+	if err != nil {
+		tracerProvider.Shutdown(ctx)
+		return nil, err
+	}
+	meterOptions := []sdkmetric.Option{sdkmetric.WithResource(res)}
+	if metricExporter != nil {
+		meterOptions = append(meterOptions, sdkmetric.WithReader(
+			sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(config.MetricInterval)),
+		))
+	}
+	meterProvider := sdkmetric.NewMeterProvider(meterOptions...)
+
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("opentelemetry export failed", "error", err)
+	}))
+
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// otlpEndpoint strips the scheme the OTEL_EXPORTER_OTLP_ENDPOINT convention
+// allows, which the gRPC exporters do not take.
+func otlpEndpoint(endpoint string, insecure bool) (string, bool) {
+	if rest, ok := strings.CutPrefix(endpoint, "http://"); ok {
+		return rest, true
+	}
+	if rest, ok := strings.CutPrefix(endpoint, "https://"); ok {
+		return rest, false
+	}
+	return endpoint, insecure
+}
+
+// newSpanExporter returns nil for ExporterNone: spans are still created, so
+// trace IDs reach the logs and message headers, but not exported.
+func newSpanExporter(ctx context.Context, exporter, endpoint string, insecure bool) (sdktrace.SpanExporter, error) {
+	switch exporter {
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		return stdouttrace.New()
+	case ExporterNone, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported traces exporter %q", exporter)
+}
+
+func newMetricExporter(ctx context.Context, exporter, endpoint string, insecure bool) (sdkmetric.Exporter, error) {
+	switch exporter {
+	case ExporterOTLP:
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint)}
+		if insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	case ExporterStdout:
+		return stdoutmetric.New()
+	case ExporterNone, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported metrics exporter %q", exporter)
+}
@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/codeis4fun/pos-go-expert/20-CleanArch/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that keeps every ended span.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+type capturingPublisher struct {
+	msg events.Message
+	err error
+}
+
+func (p *capturingPublisher) Publish(ctx context.Context, msg events.Message) error {
+	p.msg = msg
+	return p.err
+}
+
+func (p *capturingPublisher) Close() error {
+	return nil
+}
+
+func TestGivenATracedPublish_WhenTheMessageIsHandled_ThenShouldContinueTheTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	broker := &capturingPublisher{}
+	publisher := NewTracingPublisher("rabbitmq", broker)
+	headers := map[string]string{"ce_id": "event-1"}
+
+	err := publisher.Publish(context.Background(), events.Message{Topic: "OrderCreated", Headers: headers, Body: []byte("{}")})
+	assert.NoError(t, err)
+	assert.NotContains(t, headers, "traceparent")
+	assert.Contains(t, broker.msg.Headers, "traceparent")
+	assert.Equal(t, "event-1", broker.msg.Headers["ce_id"])
+
+	var handled trace.SpanContext
+	handler := TraceHandler("rabbitmq", func(ctx context.Context, msg events.Message) error {
+		handled = trace.SpanContextFromContext(ctx)
+		return errors.New("boom")
+	})
+	assert.Error(t, handler(context.Background(), broker.msg))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	producer, consumer := spans[0], spans[1]
+	assert.Equal(t, "OrderCreated publish", producer.Name())
+	assert.Equal(t, trace.SpanKindProducer, producer.SpanKind())
+	assert.Equal(t, "OrderCreated process", consumer.Name())
+	assert.Equal(t, trace.SpanKindConsumer, consumer.SpanKind())
+	assert.Equal(t, producer.SpanContext().TraceID(), consumer.SpanContext().TraceID())
+	assert.Equal(t, producer.SpanContext().SpanID(), consumer.Parent().SpanID())
+	assert.Equal(t, consumer.SpanContext().SpanID(), handled.SpanID())
+	assert.Equal(t, codes.Error, consumer.Status().Code)
+}
+
+func TestGivenASpanInContext_WhenLogging_ThenShouldAddItsTraceAndSpanIDs(t *testing.T) {
+	recordSpans(t)
+	var out bytes.Buffer
+	logger, err := NewLogger(&out, "info", LogFormatJSON)
+	assert.NoError(t, err)
+	ctx, span := otel.Tracer("test").Start(context.Background(), "operation")
+	defer span.End()
+
+	logger.With("component", "test").InfoContext(ctx, "hello")
+	logger.DebugContext(ctx, "below the level")
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "hello", record["msg"])
+	assert.Equal(t, "test", record["component"])
+	assert.Equal(t, span.SpanContext().TraceID().String(), record["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), record["span_id"])
+}
+
+func TestGivenInvalidSettings_WhenSettingUp_ThenShouldReturnAnError(t *testing.T) {
+	_, err := NewLogger(&bytes.Buffer{}, "loud", LogFormatJSON)
+	assert.Error(t, err)
+	_, err = NewLogger(&bytes.Buffer{}, "info", "xml")
+	assert.Error(t, err)
+	_, err = Setup(context.Background(), Config{ServiceName: "test", TracesExporter: "zipkin"})
+	assert.Error(t, err)
+	_, err = Setup(context.Background(), Config{ServiceName: "test", MetricsExporter: "prometheus"})
+	assert.Error(t, err)
+}
+
+func TestGivenNoExporters_WhenSettingUp_ThenShouldStillTraceAndShutDown(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	shutdown, err := Setup(context.Background(), Config{ServiceName: "test", TracesExporter: ExporterNone, SampleRatio: 1})
+	assert.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	assert.True(t, span.SpanContext().IsValid())
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestGivenAnOTLPEndpointURL_WhenSettingUp_ThenShouldStripTheScheme(t *testing.T) {
+	endpoint, insecure := otlpEndpoint("http://collector:4317", false)
+	assert.Equal(t, "collector:4317", endpoint)
+	assert.True(t, insecure)
+	endpoint, insecure = otlpEndpoint("https://collector:4317", true)
+	assert.Equal(t, "collector:4317", endpoint)
+	assert.False(t, insecure)
+	endpoint, insecure = otlpEndpoint("collector:4317", true)
+	assert.Equal(t, "collector:4317", endpoint)
+	assert.True(t, insecure)
+}
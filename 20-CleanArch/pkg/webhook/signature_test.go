@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGivenASignedBody_WhenVerify_ThenShouldAcceptItWithTheSameSecretOnly(t *testing.T) {
+	sentAt := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"a"}`)
+	header := Sign("secret", sentAt, body)
+
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+	assert.NoError(t, Verify("secret", header, body, sentAt.Add(time.Minute), 5*time.Minute))
+	assert.ErrorIs(t, Verify("other", header, body, sentAt, 5*time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header, []byte(`{"id":"b"}`), sentAt, 5*time.Minute), ErrInvalidSignature)
+}
+
+func TestGivenAnOldSignature_WhenVerify_ThenShouldRejectItOutsideTheTolerance(t *testing.T) {
+	sentAt := time.Unix(1700000000, 0)
+	body := []byte(`{}`)
+	header := Sign("secret", sentAt, body)
+
+	assert.ErrorIs(t, Verify("secret", header, body, sentAt.Add(10*time.Minute), 5*time.Minute), ErrInvalidSignature)
+	assert.NoError(t, Verify("secret", header, body, sentAt.Add(10*time.Minute), 0))
+}
+
+func TestGivenSeveralSignatures_WhenVerify_ThenShouldAcceptAnyMatchingOne(t *testing.T) {
+	sentAt := time.Unix(1700000000, 0)
+	body := []byte(`{}`)
+	header := "t=1700000000,v1=" + Sign("old", sentAt, body)[len("t=1700000000,v1="):] + ",v1=" + Sign("new", sentAt, body)[len("t=1700000000,v1="):]
+
+	assert.NoError(t, Verify("new", header, body, sentAt, time.Minute))
+	assert.NoError(t, Verify("old", header, body, sentAt, time.Minute))
+	assert.ErrorIs(t, Verify("new", "v1=abc", body, sentAt, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("new", "t=1700000000", body, sentAt, time.Minute), ErrInvalidSignature)
+}